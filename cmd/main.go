@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -8,8 +9,13 @@ import (
 
 	"geminibot/configs"
 	"geminibot/internal/application"
+	"geminibot/internal/domain"
+	appconfig "geminibot/internal/infrastructure/config"
 	discordInfra "geminibot/internal/infrastructure/discord"
 	"geminibot/internal/infrastructure/gemini"
+	"geminibot/internal/infrastructure/secret"
+	"geminibot/internal/infrastructure/sqlite"
+	"geminibot/internal/infrastructure/tools"
 	discordPres "geminibot/internal/presentation/discord"
 
 	"github.com/bwmarrin/discordgo"
@@ -39,24 +45,61 @@ func main() {
 
 	log.Printf("Bot情報: %s#%s (ID: %s)", user.Username, user.Discriminator, user.ID)
 
+	// 関数呼び出しでモデルが使用できる組み込みツールを登録（GEMINI_MAX_TOOL_ITERATIONS=0 の場合は使用しない）
+	toolRegistry, err := newToolRegistry(session, config.Gemini.MaxToolIterations)
+	if err != nil {
+		log.Fatalf("ツールの登録に失敗: %v", err)
+	}
+
 	// Gemini APIクライアントを作成
 	geminiClient, err := gemini.NewGeminiAPIClient(&config.Gemini)
 	if err != nil {
 		log.Fatalf("Gemini APIクライアントの作成に失敗: %v", err)
 	}
+	geminiClient.SetTools(toolRegistry)
 
 	// リポジトリを作成
-	conversationRepo := discordInfra.NewDiscordConversationRepository(session)
-	apiKeyRepo := discordInfra.NewGuildConfigManager(config.Gemini.ModelName)
+	conversationRepo := discordInfra.NewDiscordConversationRepository(session, config.Bot.IncludeOtherBots)
+	stores, closeStores, err := newStores(config)
+	if err != nil {
+		log.Fatalf("ギルド設定ストアの作成に失敗: %v", err)
+	}
+	defer closeStores()
 
 	// アプリケーションサービスを作成
-	apiKeyService := application.NewAPIKeyApplicationService(apiKeyRepo)
+	apiKeyService := application.NewAPIKeyApplicationService(stores.guildConfig)
+	channelConfigService := application.NewChannelConfigApplicationService(stores.channelConfigs, apiKeyService, config.Bot.SystemPrompt)
+	permissionService := application.NewPermissionApplicationService(stores.permissions)
+	usageService := application.NewUsageApplicationService(stores.usage)
+
+	// レート制限を作成（設定は起動時に検証済み）
+	rateLimitPolicy, err := config.RateLimit.Policy()
+	if err != nil {
+		log.Fatalf("レート制限の設定の読み込みに失敗: %v", err)
+	}
+	rateLimiter := application.NewRateLimiter(rateLimitPolicy, stores.rateLimits)
 
 	// Geminiクライアントファクトリー関数を作成
 	geminiClientFactory := func(apiKey string) (application.GeminiClient, error) {
-		return gemini.NewStructuredGeminiClientWithAPIKey(apiKey, &config.Gemini)
+		client, err := gemini.NewStructuredGeminiClientWithAPIKey(apiKey, &config.Gemini)
+		if err != nil {
+			return nil, err
+		}
+		client.SetTools(toolRegistry)
+		return client, nil
 	}
 
+	attachmentDownloader := discordInfra.NewHTTPAttachmentDownloader(nil)
+
+	// 履歴から外れた古いメッセージを要約するサービスを作成（HISTORY_SUMMARY_MODEL=off の場合は要約しない）
+	var summaryService *application.ConversationSummaryService
+	if config.Bot.HistorySummaryEnabled() {
+		summaryService = application.NewConversationSummaryService(stores.summaries, config.Bot.HistorySummaryModel)
+	}
+
+	// コンテキスト長をトークン数で数える場合に使用するTokenizerを作成（ギルドのAPIキーのクライアントでCountTokens APIを呼び出し、使用できない場合は見積もりを使用）
+	tokenizer := gemini.NewTokenizer()
+
 	mentionService, err := application.NewMentionApplicationService(
 		conversationRepo,
 		geminiClient,
@@ -64,16 +107,21 @@ func main() {
 		apiKeyService,
 		&config.Gemini,
 		geminiClientFactory,
+		attachmentDownloader,
+		channelConfigService,
+		usageService,
+		tokenizer,
+		summaryService,
 	)
 	if err != nil {
 		log.Fatalf("MentionApplicationServiceの作成に失敗: %v", err)
 	}
 
 	// スラッシュコマンドハンドラを作成
-	slashCommandHandler := discordPres.NewSlashCommandHandler(session, apiKeyService, &config.Gemini)
+	slashCommandHandler := discordPres.NewSlashCommandHandler(session, apiKeyService, channelConfigService, permissionService, rateLimiter, usageService, &config.Gemini, attachmentDownloader, config.Bot.MaxAttachmentBytes)
 
 	// Discordハンドラを作成
-	handler := discordPres.NewDiscordHandler(session, mentionService, user.ID, slashCommandHandler)
+	handler := discordPres.NewDiscordHandler(session, mentionService, user.ID, slashCommandHandler, stores.answers, permissionService, rateLimiter, usageService)
 	handler.SetupHandlers()
 
 	// Discordに接続
@@ -92,8 +140,16 @@ func main() {
 	log.Println("  /set-api - このサーバー用のGemini APIキーを設定")
 	log.Println("  /del-api - このサーバー用のGemini APIキーを削除")
 	log.Println("  /set-model - このサーバーで使用するAIモデルを設定")
+	log.Println("  /channel-config - チャンネル単位の設定を変更・表示・リセット")
+	log.Println("  /permissions - ロール・チャンネルごとの権限を付与・削除・一覧表示")
+	log.Println("  /usage - このサーバーのトークンの使用量と推定コストの表示、利用上限の設定")
 	log.Println("  /status - このサーバーのGemini APIキー設定状況を表示")
 	log.Println("  /generate-image - Nano Bananaを使って画像を生成")
+	log.Println("  /ask - Botに質問（Google 検索で調べて情報源付きで回答することも可能）")
+	log.Println("  /set-search - このサーバーでGoogle 検索による情報源付きの回答を使用するかどうかを設定")
+	log.Println("  /set-thinking - このサーバーで思考に使うトークン数と思考の要約の表示を設定")
+	log.Println("  /set-fallback - このサーバーのモデルが使えないときに代わりに使うモデルの順番を設定")
+	log.Println("  /extract - 直近の会話からアクションアイテム・決定事項・Q&AをJSONとして抽出")
 
 	// シグナルハンドリング
 	stop := make(chan os.Signal, 1)
@@ -110,3 +166,90 @@ func main() {
 
 	log.Println("Botが正常に停止しました。")
 }
+
+// newToolRegistry は、関数呼び出しでモデルが使用できる組み込みツールを登録したレジストリを作成します
+// maxIterations が0の場合はツールを使用しないため、空のレジストリを返します
+func newToolRegistry(session *discordgo.Session, maxIterations int) (*domain.ToolRegistry, error) {
+	if maxIterations <= 0 {
+		return domain.NewToolRegistry()
+	}
+	return domain.NewToolRegistry(
+		tools.NewCurrentTimeTool(),
+		tools.NewConvertTimezoneTool(),
+		tools.NewCalculatorTool(),
+		discordInfra.NewServerInfoTool(session),
+		discordInfra.NewPinnedMessagesTool(session),
+	)
+}
+
+// appStores は、GUILD_CONFIG_STORE の設定に応じて作成した永続化先の一覧です
+type appStores struct {
+	guildConfig    domain.GuildConfigManager
+	answers        domain.AnswerStore
+	channelConfigs domain.ChannelConfigStore
+	permissions    domain.PermissionStore
+	usage          domain.UsageStore
+	summaries      domain.ConversationSummaryStore
+	rateLimits     domain.RateLimitStore // nil の場合、レート制限の状態はメモリにのみ保持します
+}
+
+// newStores は、GUILD_CONFIG_STORE の設定に応じたギルド設定ストア・回答の記録・チャンネル設定ストア・権限ルール・使用量・会話の要約のストアを作成します
+// SQLiteを使用する場合は、いずれも同じデータベースに保存し、レート制限の状態も永続化します
+// 戻り値の関数はストアのクリーンアップ処理です
+func newStores(config *appconfig.AppConfig) (*appStores, func(), error) {
+	kind, path, err := config.Storage.ParseGuildConfigStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if kind != appconfig.StoreKindSQLite {
+		log.Println("ギルド設定・回答の記録・チャンネル設定・権限ルール・使用量・会話の要約はメモリに保存されます（再起動で失われます）")
+		return &appStores{
+			guildConfig:    discordInfra.NewGuildConfigManager(config.Gemini.ModelName),
+			answers:        discordInfra.NewAnswerStore(discordInfra.DefaultAnswerStoreCapacity),
+			channelConfigs: discordInfra.NewChannelConfigStore(),
+			permissions:    discordInfra.NewPermissionStore(),
+			usage:          discordInfra.NewUsageStore(),
+			summaries:      discordInfra.NewConversationSummaryStore(),
+		}, func() {}, nil
+	}
+
+	masterKey, err := secret.LoadMasterKey(config.Storage.MasterKey, config.Storage.MasterKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("ギルド設定をSQLiteに保存します: %s（マスターキーID: %s）", path, masterKey.ID())
+
+	closeStore := func() {
+		if err := db.Close(); err != nil {
+			log.Printf("データベースのクローズに失敗: %v", err)
+		}
+	}
+
+	manager := sqlite.NewGuildConfigManager(db, secret.NewKeyring(masterKey), config.Gemini.ModelName)
+
+	// 暗号化導入前に平文で保存されたAPIキーがあれば暗号化する
+	encrypted, err := manager.EncryptLegacyAPIKeys(context.Background())
+	if err != nil {
+		closeStore()
+		return nil, nil, err
+	}
+	if encrypted > 0 {
+		log.Printf("平文で保存されていたAPIキーを暗号化しました: %d件", encrypted)
+	}
+
+	return &appStores{
+		guildConfig:    manager,
+		answers:        sqlite.NewAnswerStore(db, sqlite.DefaultAnswerRetention),
+		channelConfigs: sqlite.NewChannelConfigStore(db),
+		permissions:    sqlite.NewPermissionStore(db),
+		usage:          sqlite.NewUsageStore(db),
+		summaries:      sqlite.NewConversationSummaryStore(db),
+		rateLimits:     sqlite.NewRateLimitStore(db),
+	}, closeStore, nil
+}
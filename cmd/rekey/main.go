@@ -0,0 +1,91 @@
+// rekey は、SQLiteに保存されたギルドAPIキーのマスターキーをローテーションするオフラインツールです。
+// Botを停止した状態で実行してください。
+//
+//	# 新しいマスターキーを生成
+//	go run ./cmd/rekey -generate
+//
+//	# 旧マスターキーから新マスターキーへ再暗号化
+//	GUILD_CONFIG_MASTER_KEY=<旧キー> GUILD_CONFIG_NEW_MASTER_KEY=<新キー> \
+//	  go run ./cmd/rekey -db /app/data/bot.db
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	appconfig "geminibot/internal/infrastructure/config"
+	"geminibot/internal/infrastructure/secret"
+	"geminibot/internal/infrastructure/sqlite"
+)
+
+func main() {
+	var (
+		generate   = flag.Bool("generate", false, "新しいマスターキーを生成して標準出力に表示します")
+		dbPath     = flag.String("db", "", "SQLiteデータベースのパス（未指定時は GUILD_CONFIG_STORE から取得）")
+		oldKey     = flag.String("old-key", os.Getenv("GUILD_CONFIG_MASTER_KEY"), "現在のマスターキー（Base64）")
+		oldKeyFile = flag.String("old-key-file", os.Getenv("GUILD_CONFIG_MASTER_KEY_FILE"), "現在のマスターキーのファイルパス")
+		newKey     = flag.String("new-key", os.Getenv("GUILD_CONFIG_NEW_MASTER_KEY"), "新しいマスターキー（Base64）")
+		newKeyFile = flag.String("new-key-file", os.Getenv("GUILD_CONFIG_NEW_MASTER_KEY_FILE"), "新しいマスターキーのファイルパス")
+	)
+	flag.Parse()
+
+	if *generate {
+		key, err := secret.GenerateMasterKey()
+		if err != nil {
+			log.Fatalf("マスターキーの生成に失敗: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	path, err := resolveDBPath(*dbPath)
+	if err != nil {
+		log.Fatalf("データベースパスの解決に失敗: %v", err)
+	}
+
+	previous, err := secret.LoadMasterKey(*oldKey, *oldKeyFile)
+	if err != nil {
+		log.Fatalf("現在のマスターキーの読み込みに失敗: %v", err)
+	}
+	next, err := secret.LoadMasterKey(*newKey, *newKeyFile)
+	if err != nil {
+		log.Fatalf("新しいマスターキーの読み込みに失敗: %v", err)
+	}
+
+	db, err := sqlite.Open(path)
+	if err != nil {
+		log.Fatalf("データベースのオープンに失敗: %v", err)
+	}
+	defer db.Close()
+
+	// ギルドのモデル設定には触れないため、既定モデルは空で問題ない
+	manager := sqlite.NewGuildConfigManager(db, secret.NewKeyring(next, previous), "")
+
+	updated, err := manager.RotateMasterKey(context.Background())
+	if err != nil {
+		log.Fatalf("マスターキーのローテーションに失敗: %v", err)
+	}
+
+	log.Printf("マスターキーのローテーションが完了しました: %s → %s（%d件更新）", previous.ID(), next.ID(), updated)
+	log.Println("Botの GUILD_CONFIG_MASTER_KEY を新しいマスターキーに更新してから再起動してください")
+}
+
+// resolveDBPath は、フラグまたは GUILD_CONFIG_STORE からSQLiteデータベースのパスを決定します
+func resolveDBPath(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+
+	storage := appconfig.StorageConfig{GuildConfigStore: os.Getenv("GUILD_CONFIG_STORE")}
+	kind, path, err := storage.ParseGuildConfigStore()
+	if err != nil {
+		return "", err
+	}
+	if kind != appconfig.StoreKindSQLite {
+		return "", fmt.Errorf("-db または GUILD_CONFIG_STORE=sqlite://<path> を指定してください")
+	}
+	return path, nil
+}
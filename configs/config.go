@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"geminibot/internal/domain"
 	"geminibot/internal/infrastructure/config"
 
 	"github.com/joho/godotenv"
@@ -36,6 +37,15 @@ func LoadConfig() (*config.AppConfig, error) {
 			MaxRetries:     getEnvAsIntOrDefault("GEMINI_MAX_RETRIES", 3),
 			EnableImageGen: getEnvAsBoolOrDefault("GEMINI_ENABLE_IMAGE_GEN", true),
 
+			MaxToolIterations: getEnvAsIntOrDefault("GEMINI_MAX_TOOL_ITERATIONS", 5),
+
+			// 思考の設定（GEMINI_THINKING_BUDGET 未指定時はモデルの既定を使用）
+			ThinkingBudget:  getEnvAsOptionalInt("GEMINI_THINKING_BUDGET"),
+			IncludeThoughts: getEnvAsBoolOrDefault("GEMINI_INCLUDE_THOUGHTS", false),
+
+			// 生成できなかった場合に順に使用するモデル（カンマ区切り、未指定時はフォールバックしない）
+			FallbackModels: domain.ParseModelList(getEnvOrDefault("GEMINI_FALLBACK_MODELS", "")),
+
 			// 画像生成関連の設定
 			ImageModelName: getEnvOrDefault("GEMINI_IMAGE_MODEL_NAME", "gemini-2.5-flash-image-preview"),
 			ImageStyle:     getEnvOrDefault("GEMINI_IMAGE_STYLE", "photographic"),
@@ -44,10 +54,30 @@ func LoadConfig() (*config.AppConfig, error) {
 			ImageCount:     getEnvAsIntOrDefault("GEMINI_IMAGE_COUNT", 1),
 		},
 		Bot: config.BotConfig{
-			MaxContextLength: getEnvAsIntOrDefault("MAX_CONTEXT_LENGTH", 8000),
-			MaxHistoryLength: getEnvAsIntOrDefault("MAX_HISTORY_LENGTH", 4000),
-			RequestTimeout:   getEnvAsDurationOrDefault("REQUEST_TIMEOUT", 30*time.Second),
-			SystemPrompt:     getEnvOrDefault("SYSTEM_PROMPT", "あなたは親切で役立つAIアシスタントです。最も重要なのは、ユーザーが今送信した質問やリクエストに直接答えることです。会話履歴は参考情報として使用し、ユーザーの現在の質問を最優先で回答してください。有害な内容や不適切な内容については、適切に断るか、代替案を提案してください。"),
+			MaxContextLength:    getEnvAsIntOrDefault("MAX_CONTEXT_LENGTH", 8000),
+			MaxHistoryLength:    getEnvAsIntOrDefault("MAX_HISTORY_LENGTH", 4000),
+			ContextBudgetMode:   getEnvOrDefault("CONTEXT_BUDGET_MODE", "chars"),
+			ContextWindowRatio:  getEnvAsFloatOrDefault("CONTEXT_WINDOW_RATIO", 0.05),
+			HistorySummaryModel: getEnvOrDefault("HISTORY_SUMMARY_MODEL", "gemini-2.5-flash-lite"),
+			ReplyChainDepth:     getEnvAsIntOrDefault("REPLY_CHAIN_DEPTH", 5),
+			RequestTimeout:      getEnvAsDurationOrDefault("REQUEST_TIMEOUT", 30*time.Second),
+			IncludeOtherBots:    getEnvAsBoolOrDefault("INCLUDE_OTHER_BOTS", false),
+			MaxAttachmentBytes:  int64(getEnvAsIntOrDefault("MAX_ATTACHMENT_BYTES", 10*1024*1024)),
+			SystemPrompt:        getEnvOrDefault("SYSTEM_PROMPT", "あなたは親切で役立つAIアシスタントです。最も重要なのは、ユーザーが今送信した質問やリクエストに直接答えることです。会話履歴は参考情報として使用し、ユーザーの現在の質問を最優先で回答してください。有害な内容や不適切な内容については、適切に断るか、代替案を提案してください。"),
+		},
+		Storage: config.StorageConfig{
+			GuildConfigStore: getEnvOrDefault("GUILD_CONFIG_STORE", config.StoreKindMemory),
+			MasterKey:        os.Getenv("GUILD_CONFIG_MASTER_KEY"),
+			MasterKeyFile:    os.Getenv("GUILD_CONFIG_MASTER_KEY_FILE"),
+		},
+		RateLimit: config.RateLimitConfig{
+			UserText:      getEnvOrDefault("RATE_LIMIT_USER_TEXT", "10/1m"),
+			UserImage:     getEnvOrDefault("RATE_LIMIT_USER_IMAGE", "3/10m"),
+			ChannelText:   getEnvOrDefault("RATE_LIMIT_CHANNEL_TEXT", "30/1m"),
+			ChannelImage:  getEnvOrDefault("RATE_LIMIT_CHANNEL_IMAGE", "10/10m"),
+			GuildText:     getEnvOrDefault("RATE_LIMIT_GUILD_TEXT", "100/1m"),
+			GuildImage:    getEnvOrDefault("RATE_LIMIT_GUILD_IMAGE", "30/10m"),
+			SpamThreshold: getEnvAsIntOrDefault("RATE_LIMIT_SPAM_THRESHOLD", 5),
 		},
 	}
 
@@ -76,6 +106,16 @@ func getEnvAsIntOrDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsOptionalInt は、環境変数を整数として取得し、存在しない場合や整数でない場合は nil を返します
+func getEnvAsOptionalInt(key string) *int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return &intValue
+		}
+	}
+	return nil
+}
+
 // getEnvAsFloatOrDefault は、環境変数を浮動小数点数として取得し、存在しない場合はデフォルト値を返します
 func getEnvAsFloatOrDefault(key string, defaultValue float64) float64 {
 	if value := os.Getenv(key); value != "" {
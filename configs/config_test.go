@@ -31,10 +31,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: false,
@@ -55,10 +56,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -80,10 +82,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -105,10 +108,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -130,10 +134,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -155,10 +160,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -180,10 +186,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -205,10 +212,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -230,10 +238,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -255,15 +264,96 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  -1,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
 			errMsg:  "GEMINI_MAX_RETRIES は0以上の整数である必要があります",
 		},
+		{
+			name: "MaxToolIterationsが負の値",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:            "test-api-key",
+					ModelName:         "gemini-2.5-pro",
+					MaxTokens:         1000,
+					Temperature:       0.7,
+					TopP:              0.9,
+					TopK:              40,
+					MaxRetries:        3,
+					MaxToolIterations: -1,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+			},
+			wantErr: true,
+			errMsg:  "GEMINI_MAX_TOOL_ITERATIONS は0以上の整数である必要があります",
+		},
+		{
+			name: "ThinkingBudgetが範囲外",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:         "test-api-key",
+					ModelName:      "gemini-2.5-pro",
+					MaxTokens:      1000,
+					Temperature:    0.7,
+					TopP:           0.9,
+					TopK:           40,
+					MaxRetries:     3,
+					ThinkingBudget: func() *int { budget := -2; return &budget }(),
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "FallbackModelsにサポートされていないモデル",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:         "test-api-key",
+					ModelName:      "gemini-2.5-pro",
+					MaxTokens:      1000,
+					Temperature:    0.7,
+					TopP:           0.9,
+					TopK:           40,
+					MaxRetries:     3,
+					FallbackModels: []string{"gemini-2.5-flash", "gemini-1.0-pro"},
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+			},
+			wantErr: true,
+			errMsg:  "GEMINI_FALLBACK_MODELS が不正です: サポートされていないモデルです: gemini-1.0-pro",
+		},
 		{
 			name: "MaxContextLengthが0以下",
 			config: &Config{
@@ -280,10 +370,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 0,
-					MaxHistoryLength: 4000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   0,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -305,10 +396,11 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 8000,
-					MaxHistoryLength: 0,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   8000,
+					MaxHistoryLength:   0,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
@@ -330,15 +422,152 @@ func TestConfig_Validate(t *testing.T) {
 					MaxRetries:  3,
 				},
 				Bot: config.BotConfig{
-					MaxContextLength: 4000,
-					MaxHistoryLength: 8000,
-					RequestTimeout:   30 * time.Second,
-					SystemPrompt:     "test prompt",
+					MaxContextLength:   4000,
+					MaxHistoryLength:   8000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
 				},
 			},
 			wantErr: true,
 			errMsg:  "MAX_HISTORY_LENGTH は MAX_CONTEXT_LENGTH 以下である必要があります",
 		},
+		{
+			name: "トークン数の単位で有効な設定",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					ContextBudgetMode:  "tokens",
+					ContextWindowRatio: 0.05,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ContextBudgetModeが不正",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					ContextBudgetMode:  "words",
+					ContextWindowRatio: 0.05,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ContextWindowRatioが範囲外",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					ContextBudgetMode:  "tokens",
+					ContextWindowRatio: 1.5,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+			},
+			wantErr: true,
+			errMsg:  "CONTEXT_WINDOW_RATIO は0より大きく1以下の値である必要があります",
+		},
+		{
+			name: "HistorySummaryModelがサポート外",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:    8000,
+					MaxHistoryLength:    4000,
+					HistorySummaryModel: "unknown-model",
+					RequestTimeout:      30 * time.Second,
+					MaxAttachmentBytes:  10 * 1024 * 1024,
+					SystemPrompt:        "test prompt",
+				},
+			},
+			wantErr: true,
+			errMsg:  "HISTORY_SUMMARY_MODEL はサポートされているモデルまたは off である必要があります: unknown-model",
+		},
+		{
+			name: "ReplyChainDepthが負の値",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					ReplyChainDepth:    -1,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+			},
+			wantErr: true,
+			errMsg:  "REPLY_CHAIN_DEPTH は0以上の整数である必要があります",
+		},
 		{
 			name: "RequestTimeoutが0以下",
 			config: &Config{
@@ -364,6 +593,91 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "REQUEST_TIMEOUT は正の値である必要があります",
 		},
+		{
+			name: "GuildConfigStoreが不正",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+				Storage: config.StorageConfig{
+					GuildConfigStore: "redis://localhost",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "SQLiteストアでマスターキーが未設定",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+				Storage: config.StorageConfig{
+					GuildConfigStore: "sqlite:///app/data/bot.db",
+				},
+			},
+			wantErr: true,
+			errMsg:  "GUILD_CONFIG_STORE に sqlite を指定する場合は GUILD_CONFIG_MASTER_KEY または GUILD_CONFIG_MASTER_KEY_FILE が必要です",
+		},
+		{
+			name: "レート制限の形式が不正",
+			config: &Config{
+				Discord: config.DiscordConfig{
+					BotToken: "test-token",
+				},
+				Gemini: config.GeminiConfig{
+					APIKey:      "test-api-key",
+					ModelName:   "gemini-2.5-pro",
+					MaxTokens:   1000,
+					Temperature: 0.7,
+					TopP:        0.9,
+					TopK:        40,
+					MaxRetries:  3,
+				},
+				Bot: config.BotConfig{
+					MaxContextLength:   8000,
+					MaxHistoryLength:   4000,
+					RequestTimeout:     30 * time.Second,
+					MaxAttachmentBytes: 10 * 1024 * 1024,
+					SystemPrompt:       "test prompt",
+				},
+				RateLimit: config.RateLimitConfig{
+					UserText: "10 per minute",
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -431,6 +745,30 @@ func TestGetEnvAsIntOrDefault(t *testing.T) {
 	}
 }
 
+func TestGetEnvAsOptionalInt(t *testing.T) {
+	// 環境変数をクリア
+	os.Unsetenv("TEST_OPTIONAL_INT_VAR")
+
+	// 未設定の場合は nil
+	if result := getEnvAsOptionalInt("TEST_OPTIONAL_INT_VAR"); result != nil {
+		t.Errorf("未設定の場合は nil であるべきです: %d", *result)
+	}
+
+	// 負の値を含む整数値のテスト
+	os.Setenv("TEST_OPTIONAL_INT_VAR", "-1")
+	defer os.Unsetenv("TEST_OPTIONAL_INT_VAR")
+
+	if result := getEnvAsOptionalInt("TEST_OPTIONAL_INT_VAR"); result == nil || *result != -1 {
+		t.Errorf("期待される値: -1, 実際: %v", result)
+	}
+
+	// 無効な値のテスト
+	os.Setenv("TEST_OPTIONAL_INT_VAR", "invalid")
+	if result := getEnvAsOptionalInt("TEST_OPTIONAL_INT_VAR"); result != nil {
+		t.Errorf("無効な値の場合は nil であるべきです: %d", *result)
+	}
+}
+
 func TestGetEnvAsFloatOrDefault(t *testing.T) {
 	// 環境変数をクリア
 	os.Unsetenv("TEST_FLOAT_VAR")
@@ -486,3 +824,62 @@ func TestGetEnvAsDurationOrDefault(t *testing.T) {
 		t.Errorf("無効な値の場合、デフォルト値が返されるべきです。期待: %v, 実際: %v", defaultDuration, result)
 	}
 }
+
+func TestStorageConfig_ParseGuildConfigStore(t *testing.T) {
+	tests := []struct {
+		store    string
+		wantKind string
+		wantPath string
+		wantErr  bool
+	}{
+		{store: "", wantKind: config.StoreKindMemory},
+		{store: "memory", wantKind: config.StoreKindMemory},
+		{store: "sqlite:///app/data/bot.db", wantKind: config.StoreKindSQLite, wantPath: "/app/data/bot.db"},
+		{store: "sqlite://data/bot.db", wantKind: config.StoreKindSQLite, wantPath: "data/bot.db"},
+		{store: "sqlite://", wantErr: true},
+		{store: "postgres://localhost/bot", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		kind, path, err := config.StorageConfig{GuildConfigStore: tt.store}.ParseGuildConfigStore()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: エラーが期待されましたが、発生しませんでした", tt.store)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: 予期しないエラーが発生しました: %v", tt.store, err)
+			continue
+		}
+		if kind != tt.wantKind || path != tt.wantPath {
+			t.Errorf("%q: 期待される値: (%s, %s), 実際: (%s, %s)", tt.store, tt.wantKind, tt.wantPath, kind, path)
+		}
+	}
+}
+
+func TestRateLimitConfig_Policy(t *testing.T) {
+	policy, err := config.RateLimitConfig{
+		UserText:      "10/1m",
+		UserImage:     "3/10m",
+		GuildText:     "off",
+		SpamThreshold: 5,
+	}.Policy()
+	if err != nil {
+		t.Fatalf("予期しないエラーが発生しました: %v", err)
+	}
+
+	if policy.User.Text.Requests != 10 || policy.User.Text.Per != time.Minute {
+		t.Errorf("ユーザーごとのテキスト生成の上限が正しくありません: %+v", policy.User.Text)
+	}
+	if policy.User.Image.Requests != 3 || policy.User.Image.Per != 10*time.Minute {
+		t.Errorf("ユーザーごとの画像生成の上限が正しくありません: %+v", policy.User.Image)
+	}
+	if !policy.Guild.Text.IsUnlimited() || !policy.Channel.Image.IsUnlimited() {
+		t.Error("off や未指定の上限は無制限として扱うべきです")
+	}
+
+	if _, err := (config.RateLimitConfig{SpamThreshold: -1}).Policy(); err == nil {
+		t.Error("負のスパム判定回数はエラーになるべきです")
+	}
+}
@@ -0,0 +1,39 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"geminibot/internal/domain"
+)
+
+// AttachmentDownloader は、メッセージの添付ファイルをダウンロードするためのインターフェースです
+type AttachmentDownloader interface {
+	// Download は、指定されたURLのファイルを maxBytes を上限としてダウンロードします
+	// 上限を超える場合は domain.ErrAttachmentTooLarge を返します
+	Download(ctx context.Context, url string, maxBytes int64) ([]byte, error)
+}
+
+// LoadSourceImages は、画像編集の元画像を検証し、データが未取得の画像をダウンロードします
+func LoadSourceImages(ctx context.Context, downloader AttachmentDownloader, images []domain.Attachment, maxBytes int64) ([]domain.Attachment, error) {
+	loaded := make([]domain.Attachment, 0, len(images))
+	for _, image := range images {
+		if err := image.ValidateSourceImage(maxBytes); err != nil {
+			return nil, err
+		}
+
+		if !image.HasData() {
+			if downloader == nil {
+				return nil, fmt.Errorf("添付ファイルのダウンロード機能が設定されていません")
+			}
+			data, err := downloader.Download(ctx, image.URL, maxBytes)
+			if err != nil {
+				return nil, fmt.Errorf("編集元の画像 %s のダウンロードに失敗: %w", image.Filename, err)
+			}
+			image.Data = data
+			image.Size = int64(len(data))
+		}
+		loaded = append(loaded, image)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,157 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+)
+
+// ChannelConfigApplicationService は、チャンネル単位の設定の管理と、チャンネル → ギルド → 全体の既定の順での設定の解決を行うアプリケーションサービスです
+type ChannelConfigApplicationService struct {
+	store              domain.ChannelConfigStore
+	apiKeyService      *APIKeyApplicationService
+	globalSystemPrompt string
+}
+
+// NewChannelConfigApplicationService は新しいChannelConfigApplicationServiceインスタンスを作成します
+// store が nil の場合はチャンネル設定を使用せず、ギルドまたは全体の既定の設定のみを解決します
+func NewChannelConfigApplicationService(store domain.ChannelConfigStore, apiKeyService *APIKeyApplicationService, globalSystemPrompt string) *ChannelConfigApplicationService {
+	return &ChannelConfigApplicationService{
+		store:              store,
+		apiKeyService:      apiKeyService,
+		globalSystemPrompt: globalSystemPrompt,
+	}
+}
+
+// UpdateChannelConfig は、update で指定された項目のみを検証してチャンネルの設定に反映し、反映後の設定を返します
+func (s *ChannelConfigApplicationService) UpdateChannelConfig(ctx context.Context, update domain.ChannelConfig) (domain.ChannelConfig, error) {
+	if s.store == nil {
+		return domain.ChannelConfig{}, fmt.Errorf("チャンネル設定の保存先が設定されていません")
+	}
+
+	update.SystemPrompt = strings.TrimSpace(update.SystemPrompt)
+	if update.IsEmpty() {
+		return domain.ChannelConfig{}, fmt.Errorf("変更する設定が指定されていません")
+	}
+	if err := validateChannelConfig(update); err != nil {
+		return domain.ChannelConfig{}, err
+	}
+
+	current, err := s.store.GetChannelConfig(ctx, update.ChannelID)
+	if err != nil {
+		return domain.ChannelConfig{}, err
+	}
+
+	merged := current.Merge(update)
+	merged.ChannelID = update.ChannelID
+	merged.GuildID = update.GuildID
+	merged.UpdatedBy = update.UpdatedBy
+	merged.UpdatedAt = time.Now()
+
+	if err := s.store.SaveChannelConfig(ctx, merged); err != nil {
+		return domain.ChannelConfig{}, err
+	}
+	return merged, nil
+}
+
+// GetChannelConfig は、指定されたチャンネルで上書きしている設定を取得します（未設定の場合は項目が空の設定）
+func (s *ChannelConfigApplicationService) GetChannelConfig(ctx context.Context, channelID string) (domain.ChannelConfig, error) {
+	if s.store == nil {
+		return domain.ChannelConfig{ChannelID: channelID}, nil
+	}
+	return s.store.GetChannelConfig(ctx, channelID)
+}
+
+// ResetChannelConfig は、指定されたチャンネルの設定を削除し、ギルドまたは全体の既定の設定に戻します
+func (s *ChannelConfigApplicationService) ResetChannelConfig(ctx context.Context, channelID string) error {
+	if s.store == nil {
+		return fmt.Errorf("チャンネル設定の保存先が設定されていません")
+	}
+	return s.store.DeleteChannelConfig(ctx, channelID)
+}
+
+// ResolveSettings は、チャンネル → ギルド → 全体の既定の順に設定を解決します
+// 設定の取得に失敗した階層は、より上位の階層の設定を使用します
+func (s *ChannelConfigApplicationService) ResolveSettings(ctx context.Context, guildID, channelID string) domain.ChannelSettings {
+	settings := s.resolveGuildSettings(ctx, guildID)
+
+	if s.store == nil || channelID == "" {
+		return settings
+	}
+
+	channelConfig, err := s.store.GetChannelConfig(ctx, channelID)
+	if err != nil {
+		log.Printf("チャンネル %s の設定取得に失敗: %v, ギルドの設定を使用", channelID, err)
+		return settings
+	}
+	return channelConfig.ApplyTo(settings)
+}
+
+// resolveGuildSettings は、ギルドの設定を全体の既定の設定に適用した設定を返します
+func (s *ChannelConfigApplicationService) resolveGuildSettings(ctx context.Context, guildID string) domain.ChannelSettings {
+	settings := domain.DefaultChannelSettings(s.globalSystemPrompt)
+
+	if guildID == "" || s.apiKeyService == nil {
+		return settings
+	}
+
+	model, err := s.apiKeyService.GetGuildModel(ctx, guildID)
+	if err != nil {
+		log.Printf("ギルド %s のモデル設定取得に失敗: %v, デフォルト設定を使用", guildID, err)
+	} else {
+		settings.Model = model
+	}
+
+	prompt, err := s.apiKeyService.GetGuildSystemPrompt(ctx, guildID)
+	if err != nil {
+		log.Printf("ギルド %s のシステムプロンプト取得に失敗: %v, 既定のプロンプトを使用", guildID, err)
+	} else if prompt != "" {
+		settings.SystemPrompt = prompt
+	}
+
+	grounding, err := s.apiKeyService.GetGuildSearchGrounding(ctx, guildID)
+	if err != nil {
+		log.Printf("ギルド %s のグラウンディング設定取得に失敗: %v, 無効として扱います", guildID, err)
+	} else {
+		settings.SearchGrounding = grounding
+	}
+
+	thinking, err := s.apiKeyService.GetGuildThinking(ctx, guildID)
+	if err != nil {
+		log.Printf("ギルド %s の思考の設定取得に失敗: %v, 全体の既定を使用します", guildID, err)
+	} else {
+		settings.Thinking = thinking
+	}
+
+	fallbackModels, err := s.apiKeyService.GetGuildFallbackModels(ctx, guildID)
+	if err != nil {
+		log.Printf("ギルド %s のフォールバックチェーン取得に失敗: %v, 全体の既定を使用します", guildID, err)
+	} else {
+		settings.FallbackModels = fallbackModels
+	}
+
+	return settings
+}
+
+// validateChannelConfig は、チャンネル設定で指定された項目を検証します
+func validateChannelConfig(c domain.ChannelConfig) error {
+	if c.Model != "" && !config.IsSupportedGeminiTextModel(c.Model) {
+		return fmt.Errorf("無効なモデルです: %s", c.Model)
+	}
+	if length := utf8.RuneCountInString(c.SystemPrompt); length > domain.MaxGuildSystemPromptLength {
+		return fmt.Errorf("システムプロンプトが長すぎます（%d文字、上限は%d文字）", length, domain.MaxGuildSystemPromptLength)
+	}
+	if c.HistoryLength < 0 || c.HistoryLength > domain.MaxChannelHistoryLength {
+		return fmt.Errorf("会話履歴の件数は1〜%d件で指定してください: %d", domain.MaxChannelHistoryLength, c.HistoryLength)
+	}
+	if c.ReplyStyle != "" && !c.ReplyStyle.IsValid() {
+		return fmt.Errorf("無効な応答方法です: %s", c.ReplyStyle)
+	}
+	return nil
+}
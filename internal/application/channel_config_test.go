@@ -0,0 +1,371 @@
+package application
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+	discordInfra "geminibot/internal/infrastructure/discord"
+)
+
+// limitRecordingConversationRepository は、会話履歴の取得件数を記録するモックです
+type limitRecordingConversationRepository struct {
+	MockConversationRepository
+	lastLimit int
+}
+
+func (r *limitRecordingConversationRepository) GetMessagesBefore(ctx context.Context, channelID string, messageID string, limit int) ([]domain.Message, error) {
+	r.lastLimit = limit
+	return r.MockConversationRepository.GetMessagesBefore(ctx, channelID, messageID, limit)
+}
+
+func (r *limitRecordingConversationRepository) GetReplyChain(ctx context.Context, channelID string, messageID string, depth int) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func TestChannelConfigApplicationService_ResolveSettings(t *testing.T) {
+	ctx := context.Background()
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	if err := apiKeyService.SetGuildSystemPrompt(ctx, "guild1", "ギルドのプロンプト"); err != nil {
+		t.Fatalf("システムプロンプトの設定に失敗: %v", err)
+	}
+	service := NewChannelConfigApplicationService(discordInfra.NewChannelConfigStore(), apiKeyService, "全体のプロンプト")
+
+	// チャンネル設定がない場合はギルドの設定を使用する
+	settings := service.ResolveSettings(ctx, "guild1", "help")
+	if settings.Model != config.DefaultGeminiTextModel || settings.SystemPrompt != "ギルドのプロンプト" {
+		t.Errorf("ギルドの設定が使用されていません: %+v", settings)
+	}
+	if !settings.ImageGeneration || settings.ReplyStyle != domain.ReplyStyleThread || settings.HistoryLength != 0 {
+		t.Errorf("全体の既定の設定が使用されていません: %+v", settings)
+	}
+
+	disabled := false
+	_, err := service.UpdateChannelConfig(ctx, domain.ChannelConfig{
+		ChannelID:       "help",
+		GuildID:         "guild1",
+		Model:           "gemini-2.5-flash-lite",
+		ImageGeneration: &disabled,
+		ReplyStyle:      domain.ReplyStyleReply,
+	})
+	if err != nil {
+		t.Fatalf("チャンネル設定の変更に失敗: %v", err)
+	}
+	// 別の項目を変更しても、設定済みの項目は保持される
+	if _, err := service.UpdateChannelConfig(ctx, domain.ChannelConfig{ChannelID: "help", GuildID: "guild1", HistoryLength: 30}); err != nil {
+		t.Fatalf("チャンネル設定の変更に失敗: %v", err)
+	}
+
+	settings = service.ResolveSettings(ctx, "guild1", "help")
+	if settings.Model != "gemini-2.5-flash-lite" || settings.HistoryLength != 30 || settings.ImageGeneration || settings.ReplyStyle != domain.ReplyStyleReply {
+		t.Errorf("チャンネルの設定が優先されていません: %+v", settings)
+	}
+	if settings.SystemPrompt != "ギルドのプロンプト" {
+		t.Errorf("チャンネルで未設定の項目はギルドの設定を使用するべきです: %s", settings.SystemPrompt)
+	}
+
+	// 他のチャンネルやギルド外では影響しない
+	if other := service.ResolveSettings(ctx, "guild1", "research"); other.Model != config.DefaultGeminiTextModel {
+		t.Errorf("他のチャンネルにチャンネル設定が適用されています: %+v", other)
+	}
+	if dm := service.ResolveSettings(ctx, "", ""); dm.SystemPrompt != "全体のプロンプト" || dm.Model != "" {
+		t.Errorf("ギルド外では全体の既定の設定を使用するべきです: %+v", dm)
+	}
+
+	if err := service.ResetChannelConfig(ctx, "help"); err != nil {
+		t.Fatalf("チャンネル設定の削除に失敗: %v", err)
+	}
+	if settings := service.ResolveSettings(ctx, "guild1", "help"); settings.Model != config.DefaultGeminiTextModel || !settings.ImageGeneration {
+		t.Errorf("削除後はギルドの設定に戻るべきです: %+v", settings)
+	}
+}
+
+func TestChannelConfigApplicationService_UpdateValidation(t *testing.T) {
+	service := NewChannelConfigApplicationService(discordInfra.NewChannelConfigStore(), nil, "")
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		update domain.ChannelConfig
+	}{
+		{name: "変更なし", update: domain.ChannelConfig{ChannelID: "c1", SystemPrompt: "   "}},
+		{name: "未対応のモデル", update: domain.ChannelConfig{ChannelID: "c1", Model: "unknown-model"}},
+		{name: "履歴の件数が上限超過", update: domain.ChannelConfig{ChannelID: "c1", HistoryLength: domain.MaxChannelHistoryLength + 1}},
+		{name: "不正な応答方法", update: domain.ChannelConfig{ChannelID: "c1", ReplyStyle: "dm"}},
+	}
+	for _, tt := range tests {
+		if _, err := service.UpdateChannelConfig(ctx, tt.update); err == nil {
+			t.Errorf("%s: エラーになるべきです", tt.name)
+		}
+	}
+
+	if _, err := NewChannelConfigApplicationService(nil, nil, "").UpdateChannelConfig(ctx, domain.ChannelConfig{ChannelID: "c1", HistoryLength: 5}); err == nil {
+		t.Error("保存先がない場合はエラーになるべきです")
+	}
+}
+
+func TestMentionApplicationService_HandleMention_UsesChannelSettings(t *testing.T) {
+	ctx := context.Background()
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "全体のプロンプト",
+	}
+
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	channelConfigService := NewChannelConfigApplicationService(discordInfra.NewChannelConfigStore(), apiKeyService, botConfig.SystemPrompt)
+	if _, err := channelConfigService.UpdateChannelConfig(ctx, domain.ChannelConfig{
+		ChannelID:     "research",
+		GuildID:       "guild1",
+		Model:         "gemini-2.0-flash",
+		SystemPrompt:  "調査用のプロンプト",
+		HistoryLength: 50,
+	}); err != nil {
+		t.Fatalf("チャンネル設定の変更に失敗: %v", err)
+	}
+
+	repo := &limitRecordingConversationRepository{}
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(repo, mockClient, botConfig, apiKeyService, &config.GeminiConfig{}, nil, nil, channelConfigService, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "質問",
+		ChannelID: "research",
+		GuildID:   "guild1",
+		MessageID: "testmessageid",
+	}
+	result, err := service.HandleMention(ctx, mention)
+	if err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+	if result.Model != "gemini-2.0-flash" || mockClient.lastSystemPrompt != "調査用のプロンプト" || repo.lastLimit != 50 {
+		t.Errorf("チャンネルの設定が使用されていません: モデル=%s, プロンプト=%s, 履歴=%d", result.Model, mockClient.lastSystemPrompt, repo.lastLimit)
+	}
+
+	// チャンネル設定のないチャンネルではギルドの設定と既定の履歴件数を使用する
+	mention.ChannelID = "general"
+	result, err = service.HandleMention(ctx, mention)
+	if err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+	if result.Model != config.DefaultGeminiTextModel || mockClient.lastSystemPrompt != "全体のプロンプト" || repo.lastLimit != domain.DefaultChannelHistoryLength {
+		t.Errorf("ギルドの設定が使用されていません: モデル=%s, プロンプト=%s, 履歴=%d", result.Model, mockClient.lastSystemPrompt, repo.lastLimit)
+	}
+
+	// スレッド内のメンションは親チャンネルの設定を使用する
+	thread := domain.BotMention{ThreadID: "thread1", ChannelID: "thread1", ParentChannelID: "research", GuildID: "guild1"}
+	if settings := service.ResolveSettings(ctx, thread); settings.Model != "gemini-2.0-flash" {
+		t.Errorf("スレッドに親チャンネルの設定が適用されていません: %+v", settings)
+	}
+}
+
+func TestMentionApplicationService_HandleMention_SearchGrounding(t *testing.T) {
+	ctx := context.Background()
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+	}
+
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	channelConfigService := NewChannelConfigApplicationService(discordInfra.NewChannelConfigStore(), apiKeyService, "")
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, mockClient, botConfig, apiKeyService, &config.GeminiConfig{}, nil, nil, channelConfigService, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "今日のニュースは？",
+		ChannelID: "news",
+		GuildID:   "guild1",
+		MessageID: "testmessageid",
+	}
+	searchGrounding := func(mention domain.BotMention) bool {
+		t.Helper()
+		if _, err := service.HandleMention(ctx, mention); err != nil {
+			t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+		}
+		return mockClient.lastOptions.SearchGrounding
+	}
+
+	if searchGrounding(mention) {
+		t.Error("既定ではGoogle 検索を使用するべきではありません")
+	}
+
+	// /ask の search オプションは、チャンネル・ギルドの設定より優先する
+	asked := mention
+	enabledSearch, disabledSearch := true, false
+	asked.Search = &enabledSearch
+	if !searchGrounding(asked) {
+		t.Error("search オプションが指定された場合はGoogle 検索を使用するべきです")
+	}
+
+	// ギルドで有効にした場合も、チャンネルで無効にしたチャンネルでは使用しない
+	if err := apiKeyService.SetGuildSearchGrounding(ctx, "guild1", true); err != nil {
+		t.Fatalf("グラウンディングの設定に失敗: %v", err)
+	}
+	if !searchGrounding(mention) {
+		t.Error("ギルドで有効にした場合はGoogle 検索を使用するべきです")
+	}
+	declined := mention
+	declined.Search = &disabledSearch
+	if searchGrounding(declined) {
+		t.Error("search:false を指定した場合はギルドで有効にしていてもGoogle 検索を使用するべきではありません")
+	}
+	disabled := false
+	if _, err := channelConfigService.UpdateChannelConfig(ctx, domain.ChannelConfig{ChannelID: "news", GuildID: "guild1", SearchGrounding: &disabled}); err != nil {
+		t.Fatalf("チャンネル設定の変更に失敗: %v", err)
+	}
+	if searchGrounding(mention) {
+		t.Error("チャンネルで無効にした場合はギルドの設定より優先するべきです")
+	}
+
+	// コード実行を有効にしたチャンネルでも、Google 検索を使用する場合はコードを実行しない
+	enabled := true
+	if _, err := channelConfigService.UpdateChannelConfig(ctx, domain.ChannelConfig{ChannelID: "news", GuildID: "guild1", CodeExecution: &enabled}); err != nil {
+		t.Fatalf("チャンネル設定の変更に失敗: %v", err)
+	}
+	if searchGrounding(mention) || !mockClient.lastOptions.CodeExecution {
+		t.Errorf("チャンネルで有効にした場合はコードを実行するべきです: %+v", mockClient.lastOptions)
+	}
+	if !searchGrounding(asked) || mockClient.lastOptions.CodeExecution {
+		t.Errorf("Google 検索とコード実行は同時に使用するべきではありません: %+v", mockClient.lastOptions)
+	}
+}
+
+func TestMentionApplicationService_HandleMention_Thinking(t *testing.T) {
+	ctx := context.Background()
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+	}
+
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	channelConfigService := NewChannelConfigApplicationService(discordInfra.NewChannelConfigStore(), apiKeyService, "")
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, mockClient, botConfig, apiKeyService, &config.GeminiConfig{}, nil, nil, channelConfigService, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "難しい質問",
+		ChannelID: "general",
+		GuildID:   "guild1",
+		MessageID: "testmessageid",
+	}
+	options := func(mention domain.BotMention) TextGenerationOptions {
+		t.Helper()
+		if _, err := service.HandleMention(ctx, mention); err != nil {
+			t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+		}
+		return mockClient.lastOptions
+	}
+
+	// 既定では全体の設定（クライアントの既定）を使用する
+	if got := options(mention); got.ThinkingBudget != nil || got.IncludeThoughts {
+		t.Errorf("既定では思考の設定を指定するべきではありません: %+v", got)
+	}
+
+	// ギルドの設定を使用する
+	guildBudget := 1024
+	if err := apiKeyService.SetGuildThinking(ctx, "guild1", domain.ThinkingSettings{Budget: &guildBudget}); err != nil {
+		t.Fatalf("思考の設定に失敗: %v", err)
+	}
+	if got := options(mention); got.ThinkingBudget == nil || *got.ThinkingBudget != 1024 || got.IncludeThoughts {
+		t.Errorf("ギルドの思考の設定を使用するべきです: %+v", got)
+	}
+
+	// /ask のオプションはギルドの設定より優先する
+	asked := mention
+	requestBudget := domain.ThinkingBudgetDynamic
+	asked.Thinking = domain.ThinkingSettings{Budget: &requestBudget, IncludeThoughts: true}
+	if got := options(asked); got.ThinkingBudget == nil || *got.ThinkingBudget != domain.ThinkingBudgetDynamic || !got.IncludeThoughts {
+		t.Errorf("リクエストの思考の設定を優先するべきです: %+v", got)
+	}
+
+	// 範囲外の思考予算は保存しない
+	invalid := domain.MaxThinkingBudget + 1
+	if err := apiKeyService.SetGuildThinking(ctx, "guild1", domain.ThinkingSettings{Budget: &invalid}); err == nil {
+		t.Error("範囲外の思考予算はエラーにするべきです")
+	}
+}
+
+func TestMentionApplicationService_HandleMention_FallbackModels(t *testing.T) {
+	ctx := context.Background()
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+	}
+
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	channelConfigService := NewChannelConfigApplicationService(discordInfra.NewChannelConfigStore(), apiKeyService, "")
+	usageService := NewUsageApplicationService(discordInfra.NewUsageStore())
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, mockClient, botConfig, apiKeyService, &config.GeminiConfig{}, nil, nil, channelConfigService, usageService, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "質問",
+		ChannelID: "general",
+		GuildID:   "guild1",
+		MessageID: "testmessageid",
+	}
+	options := func() TextGenerationOptions {
+		t.Helper()
+		if _, err := service.HandleMention(ctx, mention); err != nil {
+			t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+		}
+		return mockClient.lastOptions
+	}
+
+	// 既定ではクライアントの既定のフォールバックチェーンを使用する
+	if got := options(); len(got.FallbackModels) != 0 || got.DisableFallback {
+		t.Errorf("既定ではフォールバックチェーンを指定するべきではありません: %+v", got)
+	}
+
+	// ギルドのフォールバックチェーンを使用する
+	chain := []string{"gemini-2.5-flash", "gemini-2.0-flash"}
+	if err := apiKeyService.SetGuildFallbackModels(ctx, "guild1", chain); err != nil {
+		t.Fatalf("フォールバックチェーンの設定に失敗: %v", err)
+	}
+	if got := options(); !reflect.DeepEqual(got.FallbackModels, chain) || got.DisableFallback {
+		t.Errorf("ギルドのフォールバックチェーンを使用するべきです: %+v", got)
+	}
+
+	// 利用上限で代わりのモデルを使用する場合は、フォールバックしない
+	if err := usageService.SetUsageBudget(ctx, domain.UsageBudget{GuildID: "guild1", MonthlyTokenLimit: 1, FallbackModel: "gemini-2.5-flash-lite"}); err != nil {
+		t.Fatalf("利用上限の設定に失敗: %v", err)
+	}
+	usageService.RecordUsage(ctx, domain.UsageRecord{GuildID: "guild1", Model: "gemini-2.5-pro", Kind: domain.RequestKindText, Usage: domain.TokenUsage{PromptTokens: 10}})
+	if got := options(); got.Model != "gemini-2.5-flash-lite" || !got.DisableFallback {
+		t.Errorf("利用上限に達した場合はフォールバックするべきではありません: %+v", got)
+	}
+
+	// サポートされていないモデルや重複したモデルは保存しない
+	for _, models := range [][]string{
+		{"gemini-1.0-pro"},
+		{"gemini-2.5-flash", "gemini-2.5-flash"},
+		{"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.0-flash", "gemini-2.5-flash-lite"},
+	} {
+		if err := apiKeyService.SetGuildFallbackModels(ctx, "guild1", models); err == nil {
+			t.Errorf("不正なフォールバックチェーンはエラーにするべきです: %v", models)
+		}
+	}
+}
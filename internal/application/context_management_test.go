@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -10,7 +11,9 @@ import (
 )
 
 // ContextManagementMockGeminiClient は、テスト用のGeminiClientモックです
-type ContextManagementMockGeminiClient struct{}
+type ContextManagementMockGeminiClient struct {
+	lastHistory []domain.Message
+}
 
 func (m *ContextManagementMockGeminiClient) GenerateText(ctx context.Context, prompt domain.Prompt) (string, error) {
 	return "従来の方法での応答", nil
@@ -20,8 +23,27 @@ func (m *ContextManagementMockGeminiClient) GenerateTextWithOptions(ctx context.
 	return "オプション付きでの応答", nil
 }
 
-func (m *ContextManagementMockGeminiClient) GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string) (string, error) {
-	return "構造化コンテキストでの応答", nil
+func (m *ContextManagementMockGeminiClient) GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options TextGenerationOptions) (*TextGenerationResult, error) {
+	m.lastHistory = conversationHistory
+	return &TextGenerationResult{Content: "構造化コンテキストでの応答", Model: options.Model}, nil
+}
+
+func (m *ContextManagementMockGeminiClient) GenerateTextWithStructuredContextStream(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options TextGenerationOptions, onChunk StreamCallback) (*TextGenerationResult, error) {
+	result, err := m.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options)
+	if err != nil {
+		return nil, err
+	}
+	onChunk(result.Content)
+	return result, nil
+}
+
+func (m *ContextManagementMockGeminiClient) GenerateJSON(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, instruction string, schema map[string]any, options TextGenerationOptions) (*JSONGenerationResult, error) {
+	m.lastHistory = conversationHistory
+	return &JSONGenerationResult{JSON: []byte("{}"), Model: options.Model, Attempts: 1}, nil
+}
+
+func (m *ContextManagementMockGeminiClient) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	return domain.EstimateTokens(text), nil
 }
 
 func (m *ContextManagementMockGeminiClient) GenerateImage(ctx context.Context, request domain.ImageGenerationRequest) (*domain.ImageGenerationResponse, error) {
@@ -65,6 +87,10 @@ func (m *ContextManagementMockConversationRepository) GetMessagesBefore(ctx cont
 	return m.GetRecentMessages(ctx, channelID, limit)
 }
 
+func (m *ContextManagementMockConversationRepository) GetReplyChain(ctx context.Context, channelID string, messageID string, depth int) ([]domain.Message, error) {
+	return nil, nil
+}
+
 func TestMentionApplicationService_ContextManagement(t *testing.T) {
 	// テスト用の設定
 	config := &config.BotConfig{
@@ -110,8 +136,8 @@ func TestMentionApplicationService_ContextManagement(t *testing.T) {
 		t.Errorf("メンション処理でエラーが発生しました: %v", err)
 	}
 
-	if response != "構造化コンテキストでの応答" {
-		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response)
+	if response.Content != "構造化コンテキストでの応答" {
+		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response.Content)
 	}
 }
 
@@ -151,7 +177,7 @@ func TestMentionApplicationService_GetConversationHistory(t *testing.T) {
 
 	// getConversationHistoryメソッドをテスト
 	ctx := context.Background()
-	history, err := service.getConversationHistory(ctx, mention)
+	history, err := service.getConversationHistory(ctx, mention, 0)
 
 	if err != nil {
 		t.Errorf("会話履歴の取得でエラーが発生しました: %v", err)
@@ -207,7 +233,110 @@ func TestMentionApplicationService_ContextTruncation(t *testing.T) {
 		t.Errorf("メンション処理でエラーが発生しました: %v", err)
 	}
 
-	if response != "構造化コンテキストでの応答" {
-		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response)
+	if response.Content != "構造化コンテキストでの応答" {
+		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response.Content)
+	}
+}
+
+// threadMockConversationRepository は、スレッド取得の呼び出しを記録するテスト用リポジトリです
+type threadMockConversationRepository struct {
+	requestedThreadID string
+	messages          []domain.Message
+}
+
+func (m *threadMockConversationRepository) GetRecentMessages(ctx context.Context, channelID string, limit int) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func (m *threadMockConversationRepository) GetThreadMessages(ctx context.Context, threadID string) ([]domain.Message, error) {
+	m.requestedThreadID = threadID
+	return m.messages, nil
+}
+
+func (m *threadMockConversationRepository) GetMessagesBefore(ctx context.Context, channelID string, messageID string, limit int) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func (m *threadMockConversationRepository) GetReplyChain(ctx context.Context, channelID string, messageID string, depth int) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func TestMentionApplicationService_ThreadMentionUsesTruncatedThreadHistory(t *testing.T) {
+	config := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 30,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "テストシステムプロンプト",
+	}
+
+	// スレッドの起点から時系列順に並んだメッセージ（合計は履歴制限を超える）
+	base := time.Now().Add(-time.Hour)
+	var threadMessages []domain.Message
+	for i := 0; i < 5; i++ {
+		threadMessages = append(threadMessages, domain.Message{
+			ID:        fmt.Sprintf("msg%d", i),
+			User:      domain.User{ID: "user1", DisplayName: "User"},
+			Content:   fmt.Sprintf("メッセージ%d", i),
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	mockClient := &ContextManagementMockGeminiClient{}
+	mockRepo := &threadMockConversationRepository{messages: threadMessages}
+
+	service := &MentionApplicationService{
+		conversationRepo: mockRepo,
+		promptGenerator:  domain.NewPromptGenerator(config.SystemPrompt),
+		geminiClient:     mockClient,
+		contextManager:   domain.NewContextManager(config.MaxContextLength, config.MaxHistoryLength),
+		config:           config,
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "スレッド内の質問",
+		ChannelID: "thread1",
+		ThreadID:  "thread1",
+		MessageID: "testmessageid",
+	}
+
+	if !mention.IsThread() {
+		t.Fatal("ThreadIDが設定されたメンションはスレッドとして判定されるべきです")
+	}
+
+	if _, err := service.HandleMention(context.Background(), mention); err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+
+	if mockRepo.requestedThreadID != "thread1" {
+		t.Errorf("スレッドの全メッセージが取得されていません: %s", mockRepo.requestedThreadID)
+	}
+
+	// 履歴制限内に収まるよう、新しいメッセージが優先して保持される
+	history := mockClient.lastHistory
+	if len(history) == 0 || len(history) >= len(threadMessages) {
+		t.Fatalf("履歴が切り詰められていません: %d件", len(history))
+	}
+	if history[len(history)-1].ID != "msg4" {
+		t.Errorf("最新のメッセージが保持されていません: %s", history[len(history)-1].ID)
+	}
+}
+
+func TestMessagesBeforeMention(t *testing.T) {
+	messages := []domain.Message{
+		{ID: "msg1", Content: "最初の質問"},
+		{ID: "msg2", Content: "Botの回答", FromSelf: true},
+		{ID: "mention", Content: "追加の質問"},
+		{ID: "msg3", Content: "メンション後の投稿"},
+	}
+
+	history := messagesBeforeMention(messages, "mention")
+	if len(history) != 2 || history[len(history)-1].ID != "msg2" {
+		t.Errorf("メンション以降のメッセージが除外されていません: %+v", history)
+	}
+
+	// メンションが含まれない場合はそのまま返す
+	if got := messagesBeforeMention(messages, "unknown"); len(got) != len(messages) {
+		t.Errorf("メンションが見つからない場合は履歴を変更しないべきです: %d件", len(got))
 	}
 }
@@ -0,0 +1,100 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+const (
+	// summaryMaxRunes は、会話の要約の長さの目安（文字数）です
+	summaryMaxRunes = 1000
+
+	// summarySystemPrompt は、会話の要約を作成・更新するときのシステムプロンプトです
+	summarySystemPrompt = "あなたはDiscordの会話を要約するアシスタントです。" +
+		"これまでの要約と新しいメッセージを統合し、話題・決定事項・未解決の質問・参加者ごとの主な主張が分かるよう、日本語で簡潔に要約してください。" +
+		"要約の本文のみを出力し、前置きや見出しは付けないでください。"
+)
+
+// ConversationSummaryService は、コンテキスト長の上限を超えて履歴から外れた古いメッセージを、安価なモデルでチャンネルまたはスレッドごとの要約にまとめるアプリケーションサービスです
+// 要約はストアに保存し、新しく外れたメッセージだけを前回の要約に取り込んで更新します
+type ConversationSummaryService struct {
+	store domain.ConversationSummaryStore
+	model string
+	now   func() time.Time
+}
+
+// NewConversationSummaryService は新しいConversationSummaryServiceインスタンスを作成します
+// model は要約に使用するモデルです
+func NewConversationSummaryService(store domain.ConversationSummaryStore, model string) *ConversationSummaryService {
+	return &ConversationSummaryService{
+		store: store,
+		model: model,
+		now:   time.Now,
+	}
+}
+
+// Model は、要約に使用するモデルを返します
+func (s *ConversationSummaryService) Model() string {
+	return s.model
+}
+
+// Summarize は、older のうちまだ要約に含めていないメッセージを前回の要約に取り込み、最新の要約を返します
+// 新しいメッセージがない場合は、モデルを呼び出さずに保存済みの要約を返します
+// 戻り値の usage は、要約の作成に消費したトークン数です
+func (s *ConversationSummaryService) Summarize(ctx context.Context, client GeminiClient, channelID string, older []domain.Message) (summary domain.ConversationSummary, usage domain.TokenUsage, err error) {
+	summary, err = s.store.GetConversationSummary(ctx, channelID)
+	if err != nil {
+		return domain.ConversationSummary{}, domain.TokenUsage{}, fmt.Errorf("会話の要約の取得に失敗: %w", err)
+	}
+
+	unsummarized := summary.UnsummarizedMessages(older)
+	if len(unsummarized) == 0 {
+		return summary, domain.TokenUsage{}, nil
+	}
+
+	result, err := client.GenerateTextWithStructuredContext(ctx, summarySystemPrompt, nil, buildSummaryRequest(summary, unsummarized), nil, TextGenerationOptions{Model: s.model, DisableTools: true})
+	if err != nil {
+		return summary, domain.TokenUsage{}, fmt.Errorf("会話の要約の作成に失敗: %w", err)
+	}
+	content := strings.TrimSpace(result.Content)
+	if content == "" {
+		return summary, result.Usage, fmt.Errorf("会話の要約が空でした")
+	}
+
+	summary = domain.ConversationSummary{
+		ChannelID:    channelID,
+		Content:      content,
+		CoveredUntil: unsummarized[len(unsummarized)-1].Timestamp,
+		UpdatedAt:    s.now(),
+	}
+	if err := s.store.SaveConversationSummary(ctx, summary); err != nil {
+		return summary, result.Usage, fmt.Errorf("会話の要約の保存に失敗: %w", err)
+	}
+	return summary, result.Usage, nil
+}
+
+// buildSummaryRequest は、前回の要約と新しいメッセージから、要約を更新する依頼文を作成します
+func buildSummaryRequest(previous domain.ConversationSummary, messages []domain.Message) string {
+	var b strings.Builder
+	if !previous.IsEmpty() {
+		b.WriteString("## これまでの要約\n")
+		b.WriteString(strings.TrimSpace(previous.Content))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString("## 新しいメッセージ\n")
+	for _, msg := range messages {
+		name := msg.User.DisplayName
+		if msg.FromSelf {
+			name = "Bot"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", name, msg.Content)
+	}
+
+	fmt.Fprintf(&b, "\n上記を統合した最新の要約を%d文字以内で作成してください。", summaryMaxRunes)
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+	discordInfra "geminibot/internal/infrastructure/discord"
+)
+
+func TestConversationSummaryService_SummarizeIncrementally(t *testing.T) {
+	ctx := context.Background()
+	service := NewConversationSummaryService(discordInfra.NewConversationSummaryStore(), "gemini-2.5-flash-lite")
+	client := &MockGeminiClient{}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	older := []domain.Message{
+		{ID: "m1", User: domain.User{DisplayName: "Alice"}, Content: "デプロイの手順を決めよう", Timestamp: base},
+		{ID: "m2", User: domain.User{DisplayName: "Bob"}, Content: "ステージングで先に確認する", Timestamp: base.Add(time.Minute)},
+	}
+
+	summary, _, err := service.Summarize(ctx, client, "thread1", older)
+	if err != nil {
+		t.Fatalf("要約の作成に失敗: %v", err)
+	}
+	if summary.IsEmpty() || !summary.CoveredUntil.Equal(base.Add(time.Minute)) {
+		t.Errorf("要約に含めたメッセージの日時が記録されていません: %+v", summary)
+	}
+	if client.lastOptions.Model != "gemini-2.5-flash-lite" || !client.lastOptions.DisableTools {
+		t.Errorf("要約には指定した安価なモデルをツールなしで使用するべきです: %+v", client.lastOptions)
+	}
+	if !strings.Contains(client.lastQuestion, "Alice: デプロイの手順を決めよう") || strings.Contains(client.lastQuestion, "これまでの要約") {
+		t.Errorf("最初の要約の依頼文が正しくありません: %s", client.lastQuestion)
+	}
+
+	// 新しく外れたメッセージがない場合はモデルを呼び出さない
+	client.lastQuestion = ""
+	if _, _, err := service.Summarize(ctx, client, "thread1", older); err != nil {
+		t.Fatalf("要約の取得に失敗: %v", err)
+	}
+	if client.lastQuestion != "" {
+		t.Errorf("要約済みのメッセージだけの場合はモデルを呼び出すべきではありません: %s", client.lastQuestion)
+	}
+
+	// 新しく外れたメッセージだけを前回の要約に取り込む
+	older = append(older, domain.Message{ID: "m3", User: domain.User{DisplayName: "Carol"}, Content: "本番は金曜に", Timestamp: base.Add(2 * time.Minute)})
+	summary, _, err = service.Summarize(ctx, client, "thread1", older)
+	if err != nil {
+		t.Fatalf("要約の更新に失敗: %v", err)
+	}
+	if !strings.Contains(client.lastQuestion, "これまでの要約") || strings.Contains(client.lastQuestion, "Alice:") || !strings.Contains(client.lastQuestion, "Carol: 本番は金曜に") {
+		t.Errorf("前回の要約と新しいメッセージだけで更新するべきです: %s", client.lastQuestion)
+	}
+	if !summary.CoveredUntil.Equal(base.Add(2 * time.Minute)) {
+		t.Errorf("更新した要約の日時が正しくありません: %+v", summary)
+	}
+
+	// 要約はチャンネル・スレッドごとに管理する
+	other, _, err := service.Summarize(ctx, client, "thread2", nil)
+	if err != nil || !other.IsEmpty() {
+		t.Errorf("他のスレッドの要約が返されました: %+v, %v", other, err)
+	}
+}
+
+// longHistoryRepository は、コンテキスト長の上限を超える履歴を返すテスト用のリポジトリです
+type longHistoryRepository struct {
+	MockConversationRepository
+	messages []domain.Message
+}
+
+func (r *longHistoryRepository) GetRecentMessages(ctx context.Context, channelID string, limit int) ([]domain.Message, error) {
+	return append([]domain.Message(nil), r.messages...), nil
+}
+
+func (r *longHistoryRepository) GetMessagesBefore(ctx context.Context, channelID string, messageID string, limit int) ([]domain.Message, error) {
+	return r.GetRecentMessages(ctx, channelID, limit)
+}
+
+func TestMentionApplicationService_HandleMention_InjectsHistorySummary(t *testing.T) {
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 30,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "全体のプロンプト",
+	}
+
+	now := time.Now()
+	repo := &longHistoryRepository{messages: []domain.Message{
+		{ID: "old", User: domain.User{DisplayName: "Alice"}, Content: strings.Repeat("古い話題", 10), Timestamp: now.Add(-time.Hour)},
+		{ID: "new", User: domain.User{DisplayName: "Bob"}, Content: "最近の話題", Timestamp: now.Add(-time.Minute)},
+	}}
+	mockClient := &MockGeminiClient{}
+	summaryService := NewConversationSummaryService(discordInfra.NewConversationSummaryStore(), "gemini-2.5-flash-lite")
+	service, err := NewMentionApplicationService(repo, mockClient, botConfig, nil, &config.GeminiConfig{}, nil, nil, nil, nil, nil, summaryService)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", DisplayName: "TestUser"},
+		Content:   "質問",
+		ChannelID: "testchannel",
+		MessageID: "testmessageid",
+	}
+	if _, err := service.HandleMention(context.Background(), mention); err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+
+	if len(mockClient.lastHistory) != 1 || mockClient.lastHistory[0].ID != "new" {
+		t.Errorf("直近のメッセージのみをそのまま履歴として渡すべきです: %+v", mockClient.lastHistory)
+	}
+	if !strings.HasPrefix(mockClient.lastSystemPrompt, "全体のプロンプト\n\n## これまでの会話の要約") {
+		t.Errorf("履歴から外れたメッセージの要約がシステムプロンプトに加えられていません: %s", mockClient.lastSystemPrompt)
+	}
+}
@@ -0,0 +1,101 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"geminibot/internal/domain"
+)
+
+// extractionSystemPrompt は、会話から構造化データを抽出するときのシステムプロンプトです
+const extractionSystemPrompt = "あなたはDiscordの会話から情報を抽出するアシスタントです。" +
+	"会話に書かれている内容だけを根拠に、推測で項目を補わず、指定されたJSONスキーマに従って抽出してください。" +
+	"該当するものがない場合は空の配列を返してください。"
+
+// Extract は、チャンネルまたはスレッドの直近の会話から、プリセットのスキーマに沿った構造化データを抽出します
+// 使用するモデルと利用上限はメンションと同じように解決し、抽出に消費したトークン数を使用量として記録します
+func (s *MentionApplicationService) Extract(ctx context.Context, request domain.ExtractionRequest) (*domain.ExtractionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.RequestTimeout)
+	defer cancel()
+
+	settings := s.channelConfigService.ResolveSettings(ctx, request.GuildID, request.SettingsChannelID())
+	budgetModel, err := s.usageService.CheckBudget(ctx, request.GuildID, domain.RequestKindText)
+	if err != nil {
+		return nil, err
+	}
+	if budgetModel != "" {
+		settings.Model = budgetModel
+	}
+
+	history, err := s.getExtractionMessages(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("抽出の対象のメッセージの取得に失敗: %w", err)
+	}
+
+	// コンテキスト長の上限を超える場合は、新しいメッセージを優先して抽出の対象とする
+	// サーバー別のAPIキーのクライアントは、トークン数の計測と抽出で共通して使用するため1回だけ解決する
+	client := s.guildClient(ctx, request.GuildID)
+	contextManager := s.contextManagerFor(ctx, settings.Model, request.GuildID, client)
+	history = contextManager.TruncateConversationHistory(history)
+	if len(history) == 0 {
+		return nil, domain.ErrEmptyConversationHistory
+	}
+
+	instruction := fmt.Sprintf("%s\n対象は、ここまでの会話（直近の%d件のメッセージ）です。", request.Preset.Instruction, len(history))
+	options := TextGenerationOptions{
+		Model:           settings.Model,
+		DisableTools:    true,
+		FallbackModels:  settings.FallbackModels,
+		DisableFallback: budgetModel != "",
+	}
+	result, err := client.GenerateJSON(ctx, extractionSystemPrompt, history, instruction, request.Preset.Schema(), options)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("会話からの抽出が%w: %w", domain.ErrTimeout, err)
+		}
+		return nil, fmt.Errorf("会話からの抽出に失敗: %w", err)
+	}
+
+	log.Printf("会話から%sを抽出: %dバイト（モデル: %s、生成回数: %d）", request.Preset.DisplayName, len(result.JSON), result.Model, result.Attempts)
+	s.usageService.RecordUsage(ctx, domain.UsageRecord{
+		GuildID:   request.GuildID,
+		UserID:    request.UserID,
+		ChannelID: request.SettingsChannelID(),
+		Model:     result.Model,
+		Kind:      domain.RequestKindText,
+		Usage:     result.Usage,
+	})
+	if request.GuildID != "" && s.apiKeyService != nil {
+		s.apiKeyService.RecordUsedModel(request.GuildID, result.Model)
+	}
+
+	items, err := request.Preset.ParseItems(result.JSON)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ExtractionResult{
+		Preset:       request.Preset,
+		JSON:         result.JSON,
+		Items:        items,
+		MessageCount: len(history),
+		Model:        result.Model,
+	}, nil
+}
+
+// getExtractionMessages は、抽出の対象とする直近のメッセージを時系列順に取得します
+func (s *MentionApplicationService) getExtractionMessages(ctx context.Context, request domain.ExtractionRequest) ([]domain.Message, error) {
+	limit := request.MessageCount
+	if limit <= 0 {
+		limit = domain.DefaultExtractionMessageCount
+	}
+
+	if request.IsThread() {
+		messages, err := s.conversationRepo.GetThreadMessages(ctx, request.ChannelID)
+		if err != nil {
+			return nil, err
+		}
+		return latestMessages(messages, limit), nil
+	}
+	return s.conversationRepo.GetMessagesBefore(ctx, request.ChannelID, "", limit)
+}
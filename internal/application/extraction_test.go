@@ -0,0 +1,68 @@
+package application
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+	discordInfra "geminibot/internal/infrastructure/discord"
+)
+
+func TestMentionApplicationService_Extract(t *testing.T) {
+	ctx := context.Background()
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+	}
+
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	mockClient := &MockGeminiClient{jsonOutput: `{"action_items":[{"task":"議事録を共有する","assignee":"TestUser"}]}`}
+	repo := &limitRecordingConversationRepository{}
+	service, err := NewMentionApplicationService(repo, mockClient, botConfig, apiKeyService, &config.GeminiConfig{}, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	result, err := service.Extract(ctx, domain.ExtractionRequest{
+		Preset:    domain.ExtractionActionItems,
+		GuildID:   "guild1",
+		ChannelID: "general",
+		UserID:    "testuser",
+	})
+	if err != nil {
+		t.Fatalf("抽出でエラーが発生しました: %v", err)
+	}
+
+	if repo.lastLimit != domain.DefaultExtractionMessageCount {
+		t.Errorf("取得したメッセージ数 = %d, 期待値: %d", repo.lastLimit, domain.DefaultExtractionMessageCount)
+	}
+	if !reflect.DeepEqual(mockClient.lastSchema, domain.ExtractionActionItems.Schema()) {
+		t.Errorf("プリセットのスキーマで生成するべきです: %v", mockClient.lastSchema)
+	}
+	if !mockClient.lastOptions.DisableTools {
+		t.Error("抽出ではツールを使用するべきではありません")
+	}
+	if !strings.Contains(mockClient.lastQuestion, domain.ExtractionActionItems.Instruction) {
+		t.Errorf("プリセットの抽出の指示が含まれていません: %q", mockClient.lastQuestion)
+	}
+	if len(result.Items) != 1 || result.Items[0]["task"] != "議事録を共有する" {
+		t.Errorf("抽出した項目 = %v", result.Items)
+	}
+	if result.MessageCount != len(mockClient.lastHistory) || result.Model != "mock-default-model" {
+		t.Errorf("抽出結果 = %+v", result)
+	}
+
+	// 取得するメッセージ数を指定できる
+	mockClient.jsonOutput = `{"decisions":[]}`
+	if _, err := service.Extract(ctx, domain.ExtractionRequest{Preset: domain.ExtractionDecisions, ChannelID: "general", MessageCount: 10}); err != nil {
+		t.Fatalf("抽出でエラーが発生しました: %v", err)
+	}
+	if repo.lastLimit != 10 {
+		t.Errorf("取得したメッセージ数 = %d, 期待値: 10", repo.lastLimit)
+	}
+}
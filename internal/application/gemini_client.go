@@ -15,11 +15,26 @@ type GeminiClient interface {
 	GenerateTextWithOptions(ctx context.Context, prompt domain.Prompt, options TextGenerationOptions) (string, error)
 
 	// GenerateTextWithStructuredContext は、構造化されたコンテキストを使用してテキストを生成します
-	GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string) (string, error)
+	// questionAttachments はユーザーの質問に添付されたファイル（ダウンロード済み）で、質問と共にインラインデータとして渡します
+	// options のゼロ値の項目は、クライアントの既定設定を使用します
+	GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options TextGenerationOptions) (*TextGenerationResult, error)
+
+	// GenerateTextWithStructuredContextStream は、GenerateTextWithStructuredContext のストリーミング版です
+	// 生成されたテキストを受信するたびに onChunk を呼び出し、完了後に全体の結果を返します
+	// 停止・失敗した場合も、それまでに消費したトークン数を Usage に含む結果をエラーとともに返すことがあります
+	GenerateTextWithStructuredContextStream(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options TextGenerationOptions, onChunk StreamCallback) (*TextGenerationResult, error)
+
+	// GenerateJSON は、応答のMIMEタイプを application/json、応答のスキーマを schema（JSON Schema）に設定してJSONを生成します
+	// 生成されたJSONは domain.ValidateJSON で検証し、スキーマに合わない場合は誤りを伝えて生成し直します
+	// 生成し直してもスキーマに合わない場合は domain.ErrInvalidJSONOutput を返します
+	GenerateJSON(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, instruction string, schema map[string]any, options TextGenerationOptions) (*JSONGenerationResult, error)
 
 	// GenerateImage は、プロンプトを受け取ってGemini APIから画像を生成します
 	// optionsが空の場合はデフォルト設定を使用します
 	GenerateImage(ctx context.Context, request domain.ImageGenerationRequest) (*domain.ImageGenerationResponse, error)
+
+	// CountTokens は、model でテキストを送信した場合のトークン数を数えます（domain.Tokenizer として使用できます）
+	CountTokens(ctx context.Context, model string, text string) (int, error)
 }
 
 // TextGenerationOptions は、テキスト生成時のオプションを定義します
@@ -29,6 +44,57 @@ type TextGenerationOptions struct {
 	TopP        float64 `json:"top_p,omitempty"`
 	TopK        int     `json:"top_k,omitempty"`
 	Model       string  `json:"model,omitempty"`
+
+	// DisableTools は、登録されたツール（関数呼び出し）を使用しないかどうかです（会話の要約など、内部の処理で使用します）
+	DisableTools bool `json:"disable_tools,omitempty"`
+
+	// SearchGrounding は、Google 検索によるグラウンディングを使用するかどうかです
+	SearchGrounding bool `json:"search_grounding,omitempty"`
+
+	// CodeExecution は、モデルがPythonのコードを実行して計算・データ処理を行えるようにするかどうかです
+	CodeExecution bool `json:"code_execution,omitempty"`
+
+	// ThinkingBudget は、思考に使用する最大のトークン数です（nil の場合はクライアントの既定、-1 で動的、0 で無効）
+	// 思考に対応していないモデルでは無視し、モデルの範囲外の値はモデルの範囲に収めます
+	ThinkingBudget *int `json:"thinking_budget,omitempty"`
+
+	// IncludeThoughts は、回答とは別に思考の要約を受け取るかどうかです（クライアントの既定で有効な場合も受け取ります）
+	IncludeThoughts bool `json:"include_thoughts,omitempty"`
+
+	// FallbackModels は、Model が利用制限・過負荷・安全フィルターなどで生成できなかった場合に順に使用するモデルです（空の場合はクライアントの既定）
+	FallbackModels []string `json:"fallback_models,omitempty"`
+
+	// DisableFallback は、Model で生成できなかった場合も他のモデルを使用しないかどうかです（利用上限で代わりのモデルを使用する場合など）
+	DisableFallback bool `json:"disable_fallback,omitempty"`
+}
+
+// StreamCallback は、ストリーミング生成中に新しく生成されたテキスト（差分）を受け取るコールバックです
+type StreamCallback func(chunk string)
+
+// TextGenerationResult は、テキスト生成の結果を表します
+type TextGenerationResult struct {
+	Content   string            // 生成されたテキスト
+	Model     string            // 実際に使用したモデル名
+	Truncated bool              // 最大トークン数に達して応答が途中で終了したかどうか
+	Usage     domain.TokenUsage // 生成に消費したトークン数
+	Grounding *domain.Grounding // Google 検索によるグラウンディングの結果（使用しなかった場合は nil）
+
+	// Attachments は、コード実行で作成された画像（グラフなど）です
+	Attachments []domain.Attachment
+
+	// Thoughts は、モデルの思考の要約です（IncludeThoughts を指定しなかった場合や思考しなかった場合は空）
+	Thoughts string
+
+	// FallbackFrom は、フォールバックチェーンの別のモデルで回答した場合の、最初に使用しようとしたモデルです（フォールバックしなかった場合は空）
+	FallbackFrom string
+}
+
+// JSONGenerationResult は、JSONの生成結果を表します
+type JSONGenerationResult struct {
+	JSON     []byte            // スキーマで検証済みのJSON
+	Model    string            // 実際に使用したモデル名
+	Usage    domain.TokenUsage // 生成し直した分を含む、生成に消費したトークン数
+	Attempts int               // 生成した回数
 }
 
 // DefaultTextGenerationOptions は、デフォルトのテキスト生成オプションを返します
@@ -3,6 +3,9 @@ package application
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"geminibot/internal/domain"
 	"geminibot/internal/infrastructure/config"
@@ -11,12 +14,17 @@ import (
 // APIKeyApplicationService は、APIキーの管理を行うアプリケーションサービスです
 type APIKeyApplicationService struct {
 	apiKeyRepo domain.GuildConfigManager
+
+	// lastUsedModels は、ギルドごとに直近の応答生成で実際に使用したモデルを保持します
+	lastUsedModels map[string]string
+	mutex          sync.RWMutex
 }
 
 // NewAPIKeyApplicationService は新しいAPIKeyApplicationServiceインスタンスを作成します
 func NewAPIKeyApplicationService(apiKeyRepo domain.GuildConfigManager) *APIKeyApplicationService {
 	return &APIKeyApplicationService{
-		apiKeyRepo: apiKeyRepo,
+		apiKeyRepo:     apiKeyRepo,
+		lastUsedModels: make(map[string]string),
 	}
 }
 
@@ -71,6 +79,89 @@ func (s *APIKeyApplicationService) GetGuildModel(ctx context.Context, guildID st
 	return s.apiKeyRepo.GetGuildModel(ctx, guildID)
 }
 
+// SetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを設定します
+func (s *APIKeyApplicationService) SetGuildSystemPrompt(ctx context.Context, guildID, prompt string) error {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return fmt.Errorf("システムプロンプトが空です")
+	}
+	if length := utf8.RuneCountInString(prompt); length > domain.MaxGuildSystemPromptLength {
+		return fmt.Errorf("システムプロンプトが長すぎます（%d文字、上限は%d文字）", length, domain.MaxGuildSystemPromptLength)
+	}
+
+	return s.apiKeyRepo.SetGuildSystemPrompt(ctx, guildID, prompt)
+}
+
+// ResetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを削除し、全体の既定のプロンプトに戻します
+func (s *APIKeyApplicationService) ResetGuildSystemPrompt(ctx context.Context, guildID string) error {
+	return s.apiKeyRepo.SetGuildSystemPrompt(ctx, guildID, "")
+}
+
+// GetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを取得します（未設定の場合は空文字）
+func (s *APIKeyApplicationService) GetGuildSystemPrompt(ctx context.Context, guildID string) (string, error) {
+	return s.apiKeyRepo.GetGuildSystemPrompt(ctx, guildID)
+}
+
+// SetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを設定します
+func (s *APIKeyApplicationService) SetGuildSearchGrounding(ctx context.Context, guildID string, enabled bool) error {
+	return s.apiKeyRepo.SetGuildSearchGrounding(ctx, guildID, enabled)
+}
+
+// GetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを取得します
+func (s *APIKeyApplicationService) GetGuildSearchGrounding(ctx context.Context, guildID string) (bool, error) {
+	return s.apiKeyRepo.GetGuildSearchGrounding(ctx, guildID)
+}
+
+// SetGuildThinking は、指定されたギルドの思考の設定を保存します
+func (s *APIKeyApplicationService) SetGuildThinking(ctx context.Context, guildID string, thinking domain.ThinkingSettings) error {
+	if thinking.Budget != nil {
+		if err := domain.ValidateThinkingBudget(*thinking.Budget); err != nil {
+			return err
+		}
+	}
+	return s.apiKeyRepo.SetGuildThinking(ctx, guildID, thinking)
+}
+
+// GetGuildThinking は、指定されたギルドの思考の設定を取得します
+func (s *APIKeyApplicationService) GetGuildThinking(ctx context.Context, guildID string) (domain.ThinkingSettings, error) {
+	return s.apiKeyRepo.GetGuildThinking(ctx, guildID)
+}
+
+// SetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを保存します（空の場合は全体の既定に戻します）
+// チェーンのモデルは、サポートするテキストモデルの中から重複なく指定する必要があります
+func (s *APIKeyApplicationService) SetGuildFallbackModels(ctx context.Context, guildID string, models []string) error {
+	if err := config.ValidateGeminiFallbackModels(models); err != nil {
+		return err
+	}
+	return s.apiKeyRepo.SetGuildFallbackModels(ctx, guildID, models)
+}
+
+// GetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを取得します
+func (s *APIKeyApplicationService) GetGuildFallbackModels(ctx context.Context, guildID string) ([]string, error) {
+	return s.apiKeyRepo.GetGuildFallbackModels(ctx, guildID)
+}
+
+// RecordUsedModel は、指定されたギルドで実際に使用したモデルを記録します
+func (s *APIKeyApplicationService) RecordUsedModel(guildID, model string) {
+	if guildID == "" || model == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastUsedModels[guildID] = model
+}
+
+// GetLastUsedModel は、指定されたギルドで直近に使用したモデルを取得します
+// まだ応答を生成していない場合は false を返します
+func (s *APIKeyApplicationService) GetLastUsedModel(guildID string) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	model, ok := s.lastUsedModels[guildID]
+	return model, ok
+}
+
 // isValidModel は、指定されたモデルが有効かどうかを検証します
 func (s *APIKeyApplicationService) isValidModel(model string) bool {
 	return config.IsSupportedGeminiTextModel(model)
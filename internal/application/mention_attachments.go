@@ -0,0 +1,101 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"geminibot/internal/domain"
+)
+
+// maxTotalAttachmentBytes は、1回のリクエストでGeminiにインラインデータとして渡す添付ファイルの合計サイズの上限です
+const maxTotalAttachmentBytes = 20 * 1024 * 1024
+
+// validateMentionAttachments は、メンションの添付ファイルがGeminiに渡せる形式・サイズであることを検証します
+func (s *MentionApplicationService) validateMentionAttachments(attachments []domain.Attachment) error {
+	var total int64
+	for _, attachment := range attachments {
+		if err := attachment.Validate(s.maxAttachmentBytes()); err != nil {
+			return err
+		}
+		total += attachment.Size
+	}
+
+	if total > maxTotalAttachmentBytes {
+		return fmt.Errorf("%w: 合計 %dバイト（上限 %dバイト）", domain.ErrAttachmentTooLarge, total, maxTotalAttachmentBytes)
+	}
+	return nil
+}
+
+// downloadMentionAttachments は、メンションの添付ファイルをダウンロードします
+// ユーザーが明示的に添付したファイルのため、1件でも失敗した場合はエラーを返します
+func (s *MentionApplicationService) downloadMentionAttachments(ctx context.Context, attachments []domain.Attachment) ([]domain.Attachment, int64, error) {
+	if len(attachments) == 0 {
+		return nil, 0, nil
+	}
+	if s.attachmentDownloader == nil {
+		return nil, 0, fmt.Errorf("添付ファイルのダウンロード機能が設定されていません")
+	}
+
+	downloaded := make([]domain.Attachment, 0, len(attachments))
+	var total int64
+	for _, attachment := range attachments {
+		data, err := s.attachmentDownloader.Download(ctx, attachment.URL, s.maxAttachmentBytes())
+		if err != nil {
+			return nil, 0, fmt.Errorf("添付ファイル %s のダウンロードに失敗: %w", attachment.Filename, err)
+		}
+		attachment.Data = data
+		attachment.Size = int64(len(data))
+		total += attachment.Size
+		downloaded = append(downloaded, attachment)
+	}
+
+	log.Printf("メンションの添付ファイルを取得: %d件, %dバイト", len(downloaded), total)
+	return downloaded, total, nil
+}
+
+// downloadHistoryAttachments は、会話履歴の添付ファイルを新しいメッセージから順に budget バイトの範囲でダウンロードします
+// 非対応の形式やサイズ超過、ダウンロードに失敗した添付ファイルは、ログを出力して履歴から除外します
+func (s *MentionApplicationService) downloadHistoryAttachments(ctx context.Context, history []domain.Message, budget int64) []domain.Message {
+	result := make([]domain.Message, len(history))
+	copy(result, history)
+
+	for i := len(result) - 1; i >= 0; i-- {
+		if len(result[i].Attachments) == 0 {
+			continue
+		}
+
+		var kept []domain.Attachment
+		for _, attachment := range result[i].Attachments {
+			if err := attachment.Validate(s.maxAttachmentBytes()); err != nil {
+				log.Printf("履歴の添付ファイルをスキップ: %v", err)
+				continue
+			}
+			if s.attachmentDownloader == nil || attachment.Size > budget {
+				log.Printf("履歴の添付ファイルをスキップ: %s（合計サイズの上限に達しました）", attachment.Filename)
+				continue
+			}
+
+			data, err := s.attachmentDownloader.Download(ctx, attachment.URL, min(s.maxAttachmentBytes(), budget))
+			if err != nil {
+				log.Printf("履歴の添付ファイル %s のダウンロードに失敗: %v", attachment.Filename, err)
+				continue
+			}
+			attachment.Data = data
+			attachment.Size = int64(len(data))
+			budget -= attachment.Size
+			kept = append(kept, attachment)
+		}
+		result[i].Attachments = kept
+	}
+
+	return result
+}
+
+// maxAttachmentBytes は、添付ファイル1件あたりのサイズ上限を返します（未設定の場合は合計サイズの上限を使用）
+func (s *MentionApplicationService) maxAttachmentBytes() int64 {
+	if s.config.MaxAttachmentBytes > 0 {
+		return s.config.MaxAttachmentBytes
+	}
+	return maxTotalAttachmentBytes
+}
@@ -0,0 +1,150 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+)
+
+// fakeAttachmentDownloader は、URLごとに用意したデータを返すテスト用の AttachmentDownloader です
+type fakeAttachmentDownloader struct {
+	files map[string][]byte
+	urls  []string
+}
+
+func (d *fakeAttachmentDownloader) Download(ctx context.Context, url string, maxBytes int64) ([]byte, error) {
+	d.urls = append(d.urls, url)
+	data, ok := d.files[url]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, domain.ErrAttachmentTooLarge
+	}
+	return data, nil
+}
+
+func newAttachmentTestService(t *testing.T, client GeminiClient, downloader AttachmentDownloader) *MentionApplicationService {
+	t.Helper()
+	botConfig := &config.BotConfig{
+		MaxContextLength:   8000,
+		MaxHistoryLength:   4000,
+		RequestTimeout:     30 * time.Second,
+		SystemPrompt:       "テストシステムプロンプト",
+		MaxAttachmentBytes: 1024,
+	}
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, client, botConfig, nil, &config.GeminiConfig{}, nil, downloader, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+	return service
+}
+
+func TestMentionApplicationService_HandleMention_PassesAttachments(t *testing.T) {
+	mockClient := &MockGeminiClient{}
+	downloader := &fakeAttachmentDownloader{files: map[string][]byte{"https://cdn.example/a.png": []byte("png-data")}}
+	service := newAttachmentTestService(t, mockClient, downloader)
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "このスクリーンショットは何？",
+		ChannelID: "testchannel",
+		MessageID: "testmessageid",
+		Attachments: []domain.Attachment{
+			{Filename: "a.png", MimeType: "image/png", Size: 8, URL: "https://cdn.example/a.png"},
+		},
+	}
+
+	if _, err := service.HandleMention(context.Background(), mention); err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+
+	if len(mockClient.lastQuestionAttachments) != 1 || string(mockClient.lastQuestionAttachments[0].Data) != "png-data" {
+		t.Errorf("ダウンロードした添付ファイルがクライアントに渡されていません: %+v", mockClient.lastQuestionAttachments)
+	}
+}
+
+func TestMentionApplicationService_HandleMention_RejectsUnsupportedAttachment(t *testing.T) {
+	downloader := &fakeAttachmentDownloader{}
+	service := newAttachmentTestService(t, &MockGeminiClient{}, downloader)
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "これを解凍して",
+		ChannelID: "testchannel",
+		MessageID: "testmessageid",
+		Attachments: []domain.Attachment{
+			{Filename: "archive.zip", MimeType: "application/zip", Size: 8, URL: "https://cdn.example/archive.zip"},
+		},
+	}
+
+	_, err := service.HandleMention(context.Background(), mention)
+	if !errors.Is(err, domain.ErrUnsupportedAttachment) {
+		t.Errorf("非対応形式のエラーが返されるべきです: %v", err)
+	}
+	if len(downloader.urls) != 0 {
+		t.Errorf("非対応形式の添付ファイルはダウンロードされるべきではありません: %v", downloader.urls)
+	}
+}
+
+func TestMentionApplicationService_DownloadHistoryAttachments(t *testing.T) {
+	downloader := &fakeAttachmentDownloader{files: map[string][]byte{
+		"https://cdn.example/old.pdf": []byte("old"),
+		"https://cdn.example/new.pdf": []byte("new"),
+	}}
+	service := newAttachmentTestService(t, &MockGeminiClient{}, downloader)
+
+	history := []domain.Message{
+		{ID: "1", Attachments: []domain.Attachment{{Filename: "old.pdf", MimeType: "application/pdf", Size: 3, URL: "https://cdn.example/old.pdf"}}},
+		{ID: "2", Attachments: []domain.Attachment{{Filename: "a.zip", MimeType: "application/zip", Size: 3, URL: "https://cdn.example/a.zip"}}},
+		{ID: "3", Attachments: []domain.Attachment{{Filename: "new.pdf", MimeType: "application/pdf", Size: 3, URL: "https://cdn.example/new.pdf"}}},
+	}
+
+	// 合計の上限は新しいメッセージの添付ファイル1件分のみ
+	result := service.downloadHistoryAttachments(context.Background(), history, 3)
+
+	if len(result[2].Attachments) != 1 || string(result[2].Attachments[0].Data) != "new" {
+		t.Errorf("新しいメッセージの添付ファイルが優先されるべきです: %+v", result[2].Attachments)
+	}
+	if len(result[0].Attachments) != 0 || len(result[1].Attachments) != 0 {
+		t.Errorf("上限を超える添付ファイルと非対応形式は除外されるべきです: %+v, %+v", result[0].Attachments, result[1].Attachments)
+	}
+	if len(history[2].Attachments[0].Data) != 0 {
+		t.Error("元の履歴を変更するべきではありません")
+	}
+}
+
+func TestLoadSourceImages(t *testing.T) {
+	downloader := &fakeAttachmentDownloader{files: map[string][]byte{"https://cdn.example/a.png": []byte("png-data")}}
+
+	images, err := LoadSourceImages(context.Background(), downloader, []domain.Attachment{
+		{Filename: "a.png", MimeType: "image/png", Size: 8, URL: "https://cdn.example/a.png"},
+		{Filename: "b.png", MimeType: "image/png", Data: []byte("already-loaded")},
+	}, 1024)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+
+	if string(images[0].Data) != "png-data" || string(images[1].Data) != "already-loaded" {
+		t.Errorf("元画像のデータが正しく設定されていません: %+v", images)
+	}
+	if len(downloader.urls) != 1 {
+		t.Errorf("取得済みの画像はダウンロードされるべきではありません: %v", downloader.urls)
+	}
+}
+
+func TestMentionApplicationService_GenerateImage_RejectsNonImageSource(t *testing.T) {
+	service := newAttachmentTestService(t, &MockGeminiClient{}, &fakeAttachmentDownloader{})
+
+	_, err := service.GenerateImage(context.Background(), domain.ImageGenerationRequest{
+		Prompt:       "背景を青にして",
+		SourceImages: []domain.Attachment{{Filename: "a.pdf", MimeType: "application/pdf", Size: 8}},
+	})
+	if !errors.Is(err, domain.ErrUnsupportedAttachment) {
+		t.Errorf("画像以外の編集元はエラーになるべきです: %v", err)
+	}
+}
@@ -12,17 +12,32 @@ import (
 
 // MentionApplicationService は、メンションイベントをトリガーに、一連の処理を制御するアプリケーションサービスです
 type MentionApplicationService struct {
-	conversationRepo    domain.ConversationRepository
-	promptGenerator     *domain.PromptGenerator
-	geminiClient        GeminiClient
-	contextManager      *domain.ContextManager
-	config              *appconfig.BotConfig
-	apiKeyService       *APIKeyApplicationService
-	defaultGeminiConfig *appconfig.GeminiConfig
-	geminiClientFactory func(apiKey string) (GeminiClient, error)
+	conversationRepo     domain.ConversationRepository
+	promptGenerator      *domain.PromptGenerator
+	geminiClient         GeminiClient
+	contextManager       *domain.ContextManager
+	config               *appconfig.BotConfig
+	apiKeyService        *APIKeyApplicationService
+	defaultGeminiConfig  *appconfig.GeminiConfig
+	geminiClientFactory  func(apiKey string) (GeminiClient, error)
+	attachmentDownloader AttachmentDownloader
+	channelConfigService *ChannelConfigApplicationService
+	usageService         *UsageApplicationService
+	summaryService       *ConversationSummaryService
+	tokenizer            domain.Tokenizer
+}
+
+// ClientTokenizer は、指定したGeminiクライアントでトークン数を数えるTokenizerを作成できるTokenizerです
+// ギルドのAPIキーでトークン数を数えるために使用します
+type ClientTokenizer interface {
+	domain.Tokenizer
+	WithCounter(guildID string, counter domain.Tokenizer) domain.Tokenizer
 }
 
 // NewMentionApplicationService は新しいMentionApplicationServiceインスタンスを作成します
+// usageService が nil の場合、使用量の記録と利用上限の確認は行いません
+// tokenizer は、コンテキスト長をトークン数で数える場合に使用します（nil の場合はトークン数を見積もります）
+// summaryService が nil の場合、履歴から外れた古いメッセージは要約せずに捨てます
 func NewMentionApplicationService(
 	conversationRepo domain.ConversationRepository,
 	geminiClient GeminiClient,
@@ -30,60 +45,232 @@ func NewMentionApplicationService(
 	apiKeyService *APIKeyApplicationService,
 	defaultGeminiConfig *appconfig.GeminiConfig,
 	geminiClientFactory func(apiKey string) (GeminiClient, error),
+	attachmentDownloader AttachmentDownloader,
+	channelConfigService *ChannelConfigApplicationService,
+	usageService *UsageApplicationService,
+	tokenizer domain.Tokenizer,
+	summaryService *ConversationSummaryService,
 ) (*MentionApplicationService, error) {
 	if botConfig == nil {
 		return nil, fmt.Errorf("BotConfigが指定されていません")
 	}
 
+	// チャンネル設定を使用しない場合も、ギルドまたは全体の既定の設定は解決する
+	if channelConfigService == nil {
+		channelConfigService = NewChannelConfigApplicationService(nil, apiKeyService, botConfig.SystemPrompt)
+	}
+
+	contextManager, err := newContextManager(botConfig, tokenizer)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MentionApplicationService{
-		conversationRepo:    conversationRepo,
-		promptGenerator:     domain.NewPromptGenerator(botConfig.SystemPrompt),
-		geminiClient:        geminiClient,
-		contextManager:      domain.NewContextManager(botConfig.MaxContextLength, botConfig.MaxHistoryLength),
-		config:              botConfig,
-		apiKeyService:       apiKeyService,
-		defaultGeminiConfig: defaultGeminiConfig,
-		geminiClientFactory: geminiClientFactory,
+		conversationRepo:     conversationRepo,
+		promptGenerator:      domain.NewPromptGenerator(botConfig.SystemPrompt),
+		geminiClient:         geminiClient,
+		contextManager:       contextManager,
+		config:               botConfig,
+		apiKeyService:        apiKeyService,
+		defaultGeminiConfig:  defaultGeminiConfig,
+		geminiClientFactory:  geminiClientFactory,
+		attachmentDownloader: attachmentDownloader,
+		channelConfigService: channelConfigService,
+		usageService:         usageService,
+		summaryService:       summaryService,
+		tokenizer:            tokenizer,
 	}, nil
 }
 
-// HandleMention は、Botへのメンションを処理します
-func (s *MentionApplicationService) HandleMention(ctx context.Context, mention domain.BotMention) (string, error) {
+// newContextManager は、BotConfigのコンテキスト長の単位に応じたContextManagerを作成します
+// トークン数で数える場合、履歴には MAX_HISTORY_LENGTH / MAX_CONTEXT_LENGTH の割合を使用します
+func newContextManager(botConfig *appconfig.BotConfig, tokenizer domain.Tokenizer) (*domain.ContextManager, error) {
+	mode, err := domain.ParseContextBudgetMode(botConfig.ContextBudgetMode)
+	if err != nil {
+		return nil, err
+	}
+	if mode != domain.ContextBudgetTokens {
+		return domain.NewContextManager(botConfig.MaxContextLength, botConfig.MaxHistoryLength), nil
+	}
+
+	historyRatio := float64(botConfig.MaxHistoryLength) / float64(botConfig.MaxContextLength)
+	return domain.NewTokenContextManager(tokenizer, botConfig.ContextWindowRatio, historyRatio), nil
+}
+
+// contextManagerFor は、model のコンテキスト長の上限を使用するContextManagerを返します
+// トークン数で数える場合は、client（guildID のギルドのAPIキーのクライアント）でトークン数を数えます
+func (s *MentionApplicationService) contextManagerFor(ctx context.Context, model, guildID string, client GeminiClient) *domain.ContextManager {
+	contextManager := s.contextManager.ForModel(ctx, model, appconfig.GeminiContextWindow(model))
+	if contextManager.Mode() != domain.ContextBudgetTokens {
+		return contextManager
+	}
+	if tokenizer, ok := s.tokenizer.(ClientTokenizer); ok {
+		contextManager = contextManager.WithTokenizer(tokenizer.WithCounter(guildID, client))
+	}
+	return contextManager
+}
+
+// HandleMention は、Botへのメンションを処理し、生成結果（使用したモデルを含む）を返します
+func (s *MentionApplicationService) HandleMention(ctx context.Context, mention domain.BotMention) (*TextGenerationResult, error) {
+	return s.handleMention(ctx, mention, "", nil)
+}
+
+// HandleMentionStream は、Botへのメンションをストリーミングで処理します
+// 生成されたテキストを受信するたびに onChunk を呼び出し、完了後に生成結果を返します
+func (s *MentionApplicationService) HandleMentionStream(ctx context.Context, mention domain.BotMention, onChunk StreamCallback) (*TextGenerationResult, error) {
+	return s.handleMention(ctx, mention, "", onChunk)
+}
+
+// ContinueMentionStream は、途中で終了した回答の続きをストリーミングで生成します
+// 元のメンションと同じ会話履歴に質問と途中までの回答を加え、続きのみを生成させます
+func (s *MentionApplicationService) ContinueMentionStream(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk StreamCallback) (*TextGenerationResult, error) {
+	if strings.TrimSpace(previousAnswer) == "" {
+		return nil, fmt.Errorf("続きを生成する回答がありません")
+	}
+	return s.handleMention(ctx, mention, previousAnswer, onChunk)
+}
+
+// handleMention は、メンション処理の共通部分です。onChunk が nil の場合は一括で生成します
+// previousAnswer が指定された場合は、その回答の続きを生成します
+func (s *MentionApplicationService) handleMention(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk StreamCallback) (*TextGenerationResult, error) {
 	log.Printf("構造化コンテキストでメンションを処理中: %s", mention.String())
 
 	// コンテキストにタイムアウトを設定
 	ctx, cancel := context.WithTimeout(ctx, s.config.RequestTimeout)
 	defer cancel()
 
-	// 1. チャット履歴を取得
-	history, err := s.getConversationHistory(ctx, mention)
+	// 添付ファイルの形式とサイズは、履歴の取得やAPI呼び出しの前に検証する
+	if err := s.validateMentionAttachments(mention.Attachments); err != nil {
+		return nil, err
+	}
+
+	// チャンネル → ギルド → 全体の既定の順に、モデル・システムプロンプト・履歴の件数を解決
+	settings := s.ResolveSettings(ctx, mention)
+
+	// ギルドの今月の利用上限に達している場合は、代わりのモデルを使用するか、リクエストを拒否する
+	budgetModel, err := s.usageService.CheckBudget(ctx, mention.GuildID, domain.RequestKindText)
+	if err != nil {
+		return nil, err
+	}
+	if budgetModel != "" {
+		settings.Model = budgetModel
+	}
+
+	// 1. チャット履歴を取得（返信でメンションされた場合は返信の連鎖を含める）
+	history, err := s.getConversationHistory(ctx, mention, settings.HistoryLength)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("チャット履歴の取得がタイムアウトしました: %w", err)
+			return nil, fmt.Errorf("チャット履歴の取得が%w: %w", domain.ErrTimeout, err)
 		}
-		return "", fmt.Errorf("チャット履歴の取得に失敗: %w", err)
+		return nil, fmt.Errorf("チャット履歴の取得に失敗: %w", err)
 	}
+	replyTarget, isReply := domain.FindReplyTarget(history)
 
-	// 2. コンテキスト長制限を適用
-	truncatedSystemPrompt := s.contextManager.TruncateSystemPrompt(s.config.SystemPrompt)
-	truncatedQuestion := s.contextManager.TruncateUserQuestion(mention.Content)
+	// サーバー別のAPIキーのクライアントは、トークン数の計測・要約・生成で共通して使用するため1回だけ解決する
+	client := s.guildClient(ctx, mention.GuildID)
+
+	// 2. コンテキスト長制限を適用（履歴は新しいメッセージを優先して保持、トークン数で数える場合はモデルごとの上限を使用）
+	contextManager := s.contextManagerFor(ctx, settings.Model, mention.GuildID, client)
+	history, olderHistory := contextManager.SplitConversationHistory(history)
+	truncatedSystemPrompt := contextManager.TruncateSystemPrompt(settings.SystemPrompt)
+	truncatedQuestion := contextManager.TruncateUserQuestion(mention.Content)
+	if isReply {
+		// どのメッセージへの返信かを質問に明示する
+		truncatedQuestion = domain.WithReplyTarget(truncatedQuestion, replyTarget)
+	}
+
+	// 履歴から外れた古いメッセージは要約に取り込み、直近の履歴より前の会話の要約としてシステムプロンプトに加える
+	truncatedSystemPrompt = domain.WithConversationSummary(truncatedSystemPrompt, s.summarizeOlderHistory(ctx, client, mention, history, olderHistory))
 
 	// 3. 統計情報をログ出力
-	stats := s.contextManager.GetContextStats(truncatedSystemPrompt, history, truncatedQuestion)
-	log.Printf("コンテキスト統計: システム=%d文字, 履歴=%d文字, 質問=%d文字, 合計=%d文字, 制限=%d文字, 切り詰め=%v",
-		stats.SystemPromptLength, stats.HistoryLength, stats.QuestionLength, stats.TotalLength, stats.MaxContextLength, stats.IsTruncated)
+	stats := contextManager.GetContextStats(truncatedSystemPrompt, history, truncatedQuestion)
+	unit := stats.Mode.UnitName()
+	log.Printf("コンテキスト統計: システム=%d%s, 履歴=%d%s, 質問=%d%s, 合計=%d%s, 制限=%d%s, 切り詰め=%v",
+		stats.SystemPromptLength, unit, stats.HistoryLength, unit, stats.QuestionLength, unit, stats.TotalLength, unit, stats.MaxContextLength, unit, stats.IsTruncated)
+
+	// 4. 添付ファイルをダウンロード（メンションの添付ファイルを優先し、残りの容量で履歴の添付ファイルを含める）
+	questionAttachments, used, err := s.downloadMentionAttachments(ctx, mention.Attachments)
+	if err != nil {
+		return nil, fmt.Errorf("添付ファイルの取得に失敗: %w", err)
+	}
+	history = s.downloadHistoryAttachments(ctx, history, maxTotalAttachmentBytes-used)
+
+	// 続きを生成する場合は、元の質問と途中までの回答を履歴に加え、続きを求める指示を質問とする
+	if previousAnswer != "" {
+		history = appendPreviousAnswer(history, mention, truncatedQuestion, questionAttachments, previousAnswer)
+		truncatedQuestion = continuationPrompt
+		questionAttachments = nil
+	}
 
-	// 4. サーバー別のAPIキーを使用してGemini APIにリクエストを送信
-	response, err := s.generateResponseWithGuildAPIKey(ctx, mention, truncatedSystemPrompt, history, truncatedQuestion)
+	// 5. サーバー別のAPIキーを使用してGemini APIにリクエストを送信
+	// サーバーやチャンネルを参照するツールは、このメンションのサーバーとチャンネルだけを参照する
+	ctx = domain.WithToolCallContext(ctx, domain.ToolCallContext{
+		GuildID:   mention.GuildID,
+		ChannelID: mention.ChannelID,
+		UserID:    mention.User.ID,
+	})
+	// /ask の search オプションが指定された場合は、有効・無効ともにチャンネル・ギルドの設定より優先する
+	// Google 検索とコード実行は併用できないため、Google 検索を使用する場合はコードを実行しない
+	// 思考予算は /ask の thinking-budget オプション → ギルドの設定 → 全体の既定の順に使用する
+	// 利用上限に達して代わりのモデルを使用する場合は、より高価なモデルにフォールバックしない
+	searchGrounding := settings.SearchGrounding
+	if mention.Search != nil {
+		searchGrounding = *mention.Search
+	}
+	options := TextGenerationOptions{
+		Model:           settings.Model,
+		SearchGrounding: searchGrounding,
+		CodeExecution:   settings.CodeExecution && !searchGrounding,
+		ThinkingBudget:  settings.Thinking.Budget,
+		IncludeThoughts: settings.Thinking.IncludeThoughts || mention.Thinking.IncludeThoughts,
+		FallbackModels:  settings.FallbackModels,
+		DisableFallback: budgetModel != "",
+	}
+	if mention.Thinking.Budget != nil {
+		options.ThinkingBudget = mention.Thinking.Budget
+	}
+	result, err := s.generateResponseWithGuildAPIKey(ctx, client, mention, truncatedSystemPrompt, history, truncatedQuestion, questionAttachments, options, onChunk)
 	if err != nil {
+		// 停止・失敗した場合も、それまでに消費したトークン数を利用上限に数える
+		s.recordTextUsage(ctx, mention, result)
 		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("Gemini APIからの応答取得がタイムアウトしました: %w", err)
+			return nil, fmt.Errorf("Gemini APIからの応答取得が%w: %w", domain.ErrTimeout, err)
 		}
-		return "", fmt.Errorf("Gemini APIからの応答取得に失敗: %w", err)
+		return nil, fmt.Errorf("Gemini APIからの応答取得に失敗: %w", err)
 	}
 
-	log.Printf("Gemini APIからの応答を取得: %d文字", len(response))
-	return response, nil
+	log.Printf("Gemini APIからの応答を取得: %d文字（モデル: %s、トークン: 入力=%d, 出力=%d, 思考=%d）",
+		len(result.Content), result.Model, result.Usage.PromptTokens, result.Usage.OutputTokens, result.Usage.ThinkingTokens)
+	if result.FallbackFrom != "" {
+		log.Printf("%s で生成できなかったため、%s で回答しました", result.FallbackFrom, result.Model)
+	}
+	s.recordTextUsage(ctx, mention, result)
+	return result, nil
+}
+
+// recordTextUsage は、メンションへの回答の生成に消費したトークン数を記録します（result が nil の場合は何もしません）
+// 停止やタイムアウトで ctx が終了していても記録できるよう、ctx のキャンセルを引き継ぎません
+func (s *MentionApplicationService) recordTextUsage(ctx context.Context, mention domain.BotMention, result *TextGenerationResult) {
+	if result == nil {
+		return
+	}
+	s.usageService.RecordUsage(context.WithoutCancel(ctx), domain.UsageRecord{
+		GuildID:   mention.GuildID,
+		UserID:    mention.User.ID,
+		ChannelID: mention.SettingsChannelID(),
+		Model:     result.Model,
+		Kind:      domain.RequestKindText,
+		Usage:     result.Usage,
+	})
+}
+
+// ResolveSettings は、メンションが発生したチャンネルに適用する設定を、チャンネル → ギルド → 全体の既定の順に解決します
+// スレッド内のメンションでは、スレッドの親チャンネルの設定を使用します
+func (s *MentionApplicationService) ResolveSettings(ctx context.Context, mention domain.BotMention) domain.ChannelSettings {
+	if s.channelConfigService == nil {
+		return domain.DefaultChannelSettings(s.config.SystemPrompt)
+	}
+	return s.channelConfigService.ResolveSettings(ctx, mention.GuildID, mention.SettingsChannelID())
 }
 
 // GenerateImage は、画像生成を実行します
@@ -95,6 +282,16 @@ func (s *MentionApplicationService) GenerateImage(ctx context.Context, request d
 		request.Options = s.defaultGeminiConfig.ImageGenerationDefaults()
 	}
 
+	// 編集モードの場合は元画像を取得
+	if request.IsEdit() {
+		sourceImages, err := LoadSourceImages(ctx, s.attachmentDownloader, request.SourceImages, s.maxAttachmentBytes())
+		if err != nil {
+			return nil, fmt.Errorf("編集元の画像の取得に失敗: %w", err)
+		}
+		request.SourceImages = sourceImages
+		log.Printf("画像編集モード: 元画像 %d枚", len(sourceImages))
+	}
+
 	// デフォルトのGeminiクライアントを使用して画像生成
 	result, err := s.geminiClient.GenerateImage(ctx, request)
 	if err != nil {
@@ -106,64 +303,118 @@ func (s *MentionApplicationService) GenerateImage(ctx context.Context, request d
 	return result, nil
 }
 
-// generateResponseWithGuildAPIKey は、サーバー別のAPIキーを使用してGemini APIにリクエストを送信します
+// summarizeOlderHistory は、履歴から外れた古いメッセージをチャンネルまたはスレッドごとの要約に取り込み、直近の履歴に加える要約を返します
+// 要約が直近の履歴と重なる場合や、要約に失敗して保存済みの要約もない場合は、空の要約を返します
+func (s *MentionApplicationService) summarizeOlderHistory(ctx context.Context, client GeminiClient, mention domain.BotMention, recent, older []domain.Message) domain.ConversationSummary {
+	if s.summaryService == nil {
+		return domain.ConversationSummary{}
+	}
+
+	summary, usage, err := s.summaryService.Summarize(ctx, client, mention.ChannelID, older)
+	if err != nil {
+		log.Printf("チャンネル %s の会話の要約に失敗: %v", mention.ChannelID, err)
+	}
+	s.usageService.RecordUsage(ctx, domain.UsageRecord{
+		GuildID:   mention.GuildID,
+		UserID:    mention.User.ID,
+		ChannelID: mention.SettingsChannelID(),
+		Model:     s.summaryService.Model(),
+		Kind:      domain.RequestKindText,
+		Usage:     usage,
+	})
+
+	// 返信の連鎖のメッセージは古くても保持されるため、重なりはそれ以外の最も古いメッセージで判定する
+	for _, msg := range recent {
+		if msg.ReplyChain {
+			continue
+		}
+		if !summary.CoveredUntil.Before(msg.Timestamp) {
+			return domain.ConversationSummary{}
+		}
+		break
+	}
+	return summary
+}
+
+// guildClient は、ギルド固有のAPIキーがあればそのクライアントを、なければデフォルトのクライアントを返します
+func (s *MentionApplicationService) guildClient(ctx context.Context, guildID string) GeminiClient {
+	if guildID == "" || s.apiKeyService == nil {
+		log.Printf("ギルドIDが取得できないため、デフォルトのAPIキーを使用")
+		return s.geminiClient
+	}
+	return s.resolveGuildClient(ctx, guildID)
+}
+
+// generateResponseWithGuildAPIKey は、サーバー別のAPIキーのクライアント（guildClient で解決済み）と解決済みのモデルを使用してGemini APIにリクエストを送信します
 func (s *MentionApplicationService) generateResponseWithGuildAPIKey(
 	ctx context.Context,
+	client GeminiClient,
 	mention domain.BotMention,
 	systemPrompt string,
 	conversationHistory []domain.Message,
 	userQuestion string,
-) (string, error) {
-	// ギルドIDを取得
-	guildID := mention.GuildID
+	questionAttachments []domain.Attachment,
+	options TextGenerationOptions,
+	onChunk StreamCallback,
+) (*TextGenerationResult, error) {
+	result, err := s.generate(ctx, client, systemPrompt, conversationHistory, userQuestion, questionAttachments, options, onChunk)
+	if err != nil {
+		return result, err
+	}
 
-	if guildID == "" {
-		log.Printf("ギルドIDが取得できないため、デフォルトのAPIキーとモデルを使用")
-		return s.geminiClient.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion)
+	if mention.GuildID != "" && s.apiKeyService != nil {
+		s.apiKeyService.RecordUsedModel(mention.GuildID, result.Model)
 	}
+	return result, nil
+}
 
-	// ギルド固有のモデル設定を取得
-	guildModel, err := s.apiKeyService.GetGuildModel(ctx, guildID)
-	if err != nil {
-		log.Printf("ギルド %s のモデル設定取得に失敗: %v, デフォルト設定を使用", guildID, err)
-		guildModel = appconfig.DefaultGeminiTextModel
+// generate は、onChunk の有無に応じて一括生成またはストリーミング生成を行います
+func (s *MentionApplicationService) generate(
+	ctx context.Context,
+	client GeminiClient,
+	systemPrompt string,
+	conversationHistory []domain.Message,
+	userQuestion string,
+	questionAttachments []domain.Attachment,
+	options TextGenerationOptions,
+	onChunk StreamCallback,
+) (*TextGenerationResult, error) {
+	if onChunk != nil {
+		return client.GenerateTextWithStructuredContextStream(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options, onChunk)
 	}
+	return client.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options)
+}
 
+// resolveGuildClient は、ギルド固有のAPIキーがあればそのクライアントを、なければデフォルトのクライアントを返します
+func (s *MentionApplicationService) resolveGuildClient(ctx context.Context, guildID string) GeminiClient {
 	// ギルド固有のAPIキーがあるかチェック
 	hasCustomAPIKey, err := s.apiKeyService.HasGuildAPIKey(ctx, guildID)
 	if err != nil {
 		log.Printf("ギルド %s のAPIキー確認に失敗: %v, デフォルトのAPIキーを使用", guildID, err)
-		return s.geminiClient.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion)
+		return s.geminiClient
 	}
 
-	if hasCustomAPIKey {
-		// カスタムAPIキーを使用
-		customAPIKey, err := s.apiKeyService.GetGuildAPIKey(ctx, guildID)
-		if err != nil {
-			log.Printf("ギルド %s のカスタムAPIキー取得に失敗: %v, デフォルトのAPIキーを使用", guildID, err)
-			return s.geminiClient.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion)
-		}
-
-		log.Printf("ギルド %s 用のカスタムAPIキーとモデル %s を使用", guildID, guildModel)
-
-		// カスタムAPIキーでGeminiクライアントを作成
-		customClient, err := s.createGeminiClientWithAPIKey(customAPIKey)
-		if err != nil {
-			log.Printf("カスタムAPIキーでのGeminiクライアント作成に失敗: %v, デフォルトのAPIキーを使用", err)
-			return s.geminiClient.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion)
-		}
+	if !hasCustomAPIKey {
+		log.Printf("ギルド %s はデフォルトAPIキーを使用", guildID)
+		return s.geminiClient
+	}
 
-		return customClient.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion)
+	// カスタムAPIキーを使用
+	customAPIKey, err := s.apiKeyService.GetGuildAPIKey(ctx, guildID)
+	if err != nil {
+		log.Printf("ギルド %s のカスタムAPIキー取得に失敗: %v, デフォルトのAPIキーを使用", guildID, err)
+		return s.geminiClient
 	}
 
-	// デフォルトのAPIキーを使用、ただしモデル設定がある場合はそれを使用
-	if guildModel != appconfig.DefaultGeminiTextModel && guildModel != "" {
-		log.Printf("デフォルトAPIキーとカスタムモデル %s を使用", guildModel)
-		// TODO: 将来的にモデル設定を反映したい場合は、ここでGeminiクライアントの設定を変更
-	} else {
-		log.Printf("デフォルトAPIキーを使用")
+	// カスタムAPIキーでGeminiクライアントを作成
+	customClient, err := s.createGeminiClientWithAPIKey(customAPIKey)
+	if err != nil {
+		log.Printf("カスタムAPIキーでのGeminiクライアント作成に失敗: %v, デフォルトのAPIキーを使用", err)
+		return s.geminiClient
 	}
-	return s.geminiClient.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion)
+
+	log.Printf("ギルド %s 用のカスタムAPIキーを使用", guildID)
+	return customClient
 }
 
 // createGeminiClientWithAPIKey は、指定されたAPIキーでGeminiクライアントを作成します
@@ -176,17 +427,86 @@ func (s *MentionApplicationService) createGeminiClientWithAPIKey(apiKey string)
 }
 
 // getConversationHistory は、メンションに基づいて会話履歴を取得します
-func (s *MentionApplicationService) getConversationHistory(ctx context.Context, mention domain.BotMention) ([]domain.Message, error) {
-	// スレッドかどうかを判定（簡易的な判定）
+// limit は取得するメッセージ数の上限です（0の場合、通常チャンネルは直近の DefaultChannelHistoryLength 件、スレッドは全件）
+// 返信でメンションされた場合は、返信元をたどったメッセージを上限とは別に加えます
+func (s *MentionApplicationService) getConversationHistory(ctx context.Context, mention domain.BotMention, limit int) ([]domain.Message, error) {
+	history, err := s.getRecentHistory(ctx, mention, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if mention.ReferencedMessageID == "" || s.config.ReplyChainDepth <= 0 {
+		return history, nil
+	}
+	chain, err := s.conversationRepo.GetReplyChain(ctx, mention.ChannelID, mention.ReferencedMessageID, s.config.ReplyChainDepth)
+	if err != nil {
+		// 返信先が削除されている場合なども、直近の履歴だけで回答する
+		log.Printf("返信の連鎖の取得に失敗: %v", err)
+		return history, nil
+	}
+	log.Printf("返信の連鎖を取得: %d件", len(chain))
+	return domain.MergeReplyChain(history, chain, mention.ReferencedMessageID), nil
+}
+
+// getRecentHistory は、メンションより前の直近の会話履歴を取得します
+func (s *MentionApplicationService) getRecentHistory(ctx context.Context, mention domain.BotMention, limit int) ([]domain.Message, error) {
+	// スレッドかどうかを判定
 	if mention.IsThread() {
-		log.Printf("スレッド内のメンションを検出: %s", mention.ChannelID)
-		// スレッドの場合は全メッセージを取得
-		return s.conversationRepo.GetThreadMessages(ctx, mention.ChannelID)
+		log.Printf("スレッド内のメンションを検出: %s", mention.ThreadID)
+		// スレッドの場合は起点メッセージからの全メッセージを取得（長さはContextManagerで制限）
+		messages, err := s.conversationRepo.GetThreadMessages(ctx, mention.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		return latestMessages(messagesBeforeMention(messages, mention.MessageID), limit), nil
 	} else {
 		log.Printf("通常チャンネル内のメンションを検出: %s", mention.ChannelID)
-		// 通常チャンネルの場合は直近のメッセージを取得
-		return s.conversationRepo.GetRecentMessages(ctx, mention.ChannelID, 10)
+		// 通常チャンネルの場合はメンションより前の直近のメッセージを取得
+		if limit <= 0 {
+			limit = domain.DefaultChannelHistoryLength
+		}
+		return s.conversationRepo.GetMessagesBefore(ctx, mention.ChannelID, mention.MessageID, limit)
+	}
+}
+
+// latestMessages は、時系列順の履歴から新しい順に limit 件のメッセージを返します（limit が0以下の場合はすべて返します）
+func latestMessages(messages []domain.Message, limit int) []domain.Message {
+	if limit <= 0 || len(messages) <= limit {
+		return messages
+	}
+	return messages[len(messages)-limit:]
+}
+
+// continuationPrompt は、途中で終了した回答の続きを生成させるための指示です
+const continuationPrompt = "直前のあなたの回答は途中で終了しました。すでに出力した内容を繰り返さず、途切れた位置から続きを出力してください。"
+
+// appendPreviousAnswer は、会話履歴の末尾に元の質問と途中までの回答を追加した新しいスライスを返します
+func appendPreviousAnswer(history []domain.Message, mention domain.BotMention, question string, questionAttachments []domain.Attachment, previousAnswer string) []domain.Message {
+	result := make([]domain.Message, 0, len(history)+2)
+	result = append(result, history...)
+	return append(result,
+		domain.Message{
+			ID:          mention.MessageID,
+			User:        mention.User,
+			Content:     question,
+			Attachments: questionAttachments,
+		},
+		domain.Message{
+			Content:  previousAnswer,
+			FromSelf: true,
+		},
+	)
+}
+
+// messagesBeforeMention は、時系列順の履歴からメンション自身とそれ以降のメッセージを除外します
+// メンションの内容はユーザーの質問として別途渡すため、履歴に重複して含めないようにします
+func messagesBeforeMention(messages []domain.Message, mentionMessageID string) []domain.Message {
+	for i, msg := range messages {
+		if msg.ID == mentionMessageID {
+			return messages[:i]
+		}
 	}
+	return messages
 }
 
 // truncateResponse は、Discordのメッセージ長制限に合わせて応答を切り詰めます
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// PermissionApplicationService は、ギルドの権限ルールの管理と権限の判定を行うアプリケーションサービスです
+type PermissionApplicationService struct {
+	store domain.PermissionStore
+}
+
+// NewPermissionApplicationService は新しいPermissionApplicationServiceインスタンスを作成します
+// store が nil の場合は権限ルールを使用せず、管理系の権限は管理者のみ、それ以外は全員に許可します
+func NewPermissionApplicationService(store domain.PermissionStore) *PermissionApplicationService {
+	return &PermissionApplicationService{store: store}
+}
+
+// GrantPermission は、ロールまたはチャンネルに対する権限の許可・拒否ルールを設定します
+func (s *PermissionApplicationService) GrantPermission(ctx context.Context, rule domain.PermissionRule) error {
+	if s.store == nil {
+		return fmt.Errorf("権限ルールの保存先が設定されていません")
+	}
+	if err := validatePermissionRule(rule); err != nil {
+		return err
+	}
+
+	rule.CreatedAt = time.Now()
+	return s.store.SavePermissionRule(ctx, rule)
+}
+
+// RevokePermission は、ロールまたはチャンネルに対する権限ルールを削除します
+func (s *PermissionApplicationService) RevokePermission(ctx context.Context, guildID string, permission domain.Permission, targetType domain.PermissionTargetType, targetID string) error {
+	if s.store == nil {
+		return fmt.Errorf("権限ルールの保存先が設定されていません")
+	}
+	if !permission.IsValid() {
+		return fmt.Errorf("無効な権限です: %s", permission)
+	}
+	return s.store.DeletePermissionRule(ctx, guildID, permission, targetType, targetID)
+}
+
+// ListPermissions は、指定されたギルドの権限ルールを取得します
+func (s *PermissionApplicationService) ListPermissions(ctx context.Context, guildID string) ([]domain.PermissionRule, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	return s.store.ListPermissionRules(ctx, guildID)
+}
+
+// HasPermission は、ギルドの権限ルールに基づいて subject が permission を持つかどうかを判定します
+// ギルド外（DM）では、管理系の権限は拒否し、それ以外は許可します
+// 権限ルールの取得に失敗した場合は、ルールがないものとして判定します
+func (s *PermissionApplicationService) HasPermission(ctx context.Context, guildID string, permission domain.Permission, subject domain.PermissionSubject) bool {
+	if guildID == "" {
+		return !permission.IsRestricted()
+	}
+
+	var rules []domain.PermissionRule
+	if s.store != nil {
+		var err error
+		rules, err = s.store.ListPermissionRules(ctx, guildID)
+		if err != nil {
+			log.Printf("ギルド %s の権限ルール取得に失敗: %v, 既定の権限で判定します", guildID, err)
+			rules = nil
+		}
+	}
+	return domain.EvaluatePermission(rules, permission, subject)
+}
+
+// validatePermissionRule は、権限ルールの内容を検証します
+func validatePermissionRule(rule domain.PermissionRule) error {
+	if !rule.Permission.IsValid() {
+		return fmt.Errorf("無効な権限です: %s", rule.Permission)
+	}
+	if rule.Effect != domain.PermissionAllow && rule.Effect != domain.PermissionDeny {
+		return fmt.Errorf("無効なルールの種類です: %s", rule.Effect)
+	}
+	if rule.TargetID == "" {
+		return fmt.Errorf("ルールの対象が指定されていません")
+	}
+
+	switch rule.TargetType {
+	case domain.PermissionTargetRole:
+		return nil
+	case domain.PermissionTargetChannel:
+		if !rule.Permission.SupportsChannelRules() {
+			return fmt.Errorf("「%s」の権限はチャンネルを対象に設定できません（ロールを指定してください）", rule.Permission.DisplayName())
+		}
+		return nil
+	default:
+		return fmt.Errorf("無効なルールの対象です: %s", rule.TargetType)
+	}
+}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"geminibot/internal/domain"
+	discordInfra "geminibot/internal/infrastructure/discord"
+)
+
+func TestPermissionApplicationService_GrantAndCheck(t *testing.T) {
+	ctx := context.Background()
+	service := NewPermissionApplicationService(discordInfra.NewPermissionStore())
+	moderator := domain.PermissionSubject{RoleIDs: []string{"guild1", "moderator"}, ChannelIDs: []string{"general"}}
+
+	if service.HasPermission(ctx, "guild1", domain.PermissionManageAPIKeys, moderator) {
+		t.Error("APIキーの管理は既定では管理者のみに許可されるべきです")
+	}
+
+	err := service.GrantPermission(ctx, domain.PermissionRule{
+		GuildID:    "guild1",
+		Permission: domain.PermissionManageAPIKeys,
+		TargetType: domain.PermissionTargetRole,
+		TargetID:   "moderator",
+		Effect:     domain.PermissionAllow,
+	})
+	if err != nil {
+		t.Fatalf("権限の付与に失敗: %v", err)
+	}
+	if !service.HasPermission(ctx, "guild1", domain.PermissionManageAPIKeys, moderator) {
+		t.Error("権限を付与したロールはAPIキーを管理できるべきです")
+	}
+	if service.HasPermission(ctx, "guild2", domain.PermissionManageAPIKeys, moderator) {
+		t.Error("他のギルドの権限ルールが適用されています")
+	}
+
+	if err := service.RevokePermission(ctx, "guild1", domain.PermissionManageAPIKeys, domain.PermissionTargetRole, "moderator"); err != nil {
+		t.Fatalf("権限の削除に失敗: %v", err)
+	}
+	if service.HasPermission(ctx, "guild1", domain.PermissionManageAPIKeys, moderator) {
+		t.Error("削除した権限が残っています")
+	}
+
+	// ギルド外（DM）では管理系の権限のみ拒否する
+	if service.HasPermission(ctx, "", domain.PermissionManageModels, domain.PermissionSubject{}) || !service.HasPermission(ctx, "", domain.PermissionUseBot, domain.PermissionSubject{}) {
+		t.Error("ギルド外の既定の権限が正しくありません")
+	}
+}
+
+func TestPermissionApplicationService_GrantValidation(t *testing.T) {
+	service := NewPermissionApplicationService(discordInfra.NewPermissionStore())
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		rule domain.PermissionRule
+	}{
+		{name: "未知の権限", rule: domain.PermissionRule{Permission: "unknown", TargetType: domain.PermissionTargetRole, TargetID: "r1", Effect: domain.PermissionAllow}},
+		{name: "対象なし", rule: domain.PermissionRule{Permission: domain.PermissionUseBot, TargetType: domain.PermissionTargetRole, Effect: domain.PermissionAllow}},
+		{name: "未知のルールの種類", rule: domain.PermissionRule{Permission: domain.PermissionUseBot, TargetType: domain.PermissionTargetRole, TargetID: "r1", Effect: "maybe"}},
+		{name: "管理系の権限をチャンネルに設定", rule: domain.PermissionRule{Permission: domain.PermissionManageModels, TargetType: domain.PermissionTargetChannel, TargetID: "c1", Effect: domain.PermissionAllow}},
+	}
+	for _, tt := range tests {
+		tt.rule.GuildID = "guild1"
+		if err := service.GrantPermission(ctx, tt.rule); err == nil {
+			t.Errorf("%s: エラーになるべきです", tt.name)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package application
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// rateLimitPruneInterval は、使われなくなったトークンバケットを破棄する間隔です
+const rateLimitPruneInterval = 10 * time.Minute
+
+// RateLimitRequest は、レート制限の判定対象となるリクエストです
+type RateLimitRequest struct {
+	Kind      domain.RequestKind
+	GuildID   string
+	ChannelID string
+	UserID    string
+}
+
+// rateLimitViolation は、ユーザーがレート制限を続けて超えた回数です
+type rateLimitViolation struct {
+	count  int
+	lastAt time.Time
+}
+
+// RateLimiter は、ユーザー・チャンネル・ギルドごとのトークンバケットでリクエスト数を制限します
+// バケットはメモリに保持し、ストアが指定された場合は再起動後も状態を引き継げるよう保存します
+type RateLimiter struct {
+	policy domain.RateLimitPolicy
+	store  domain.RateLimitStore
+	now    func() time.Time
+
+	mu         sync.Mutex
+	buckets    map[string]domain.TokenBucket
+	violations map[string]rateLimitViolation
+	lastPrune  time.Time
+}
+
+// NewRateLimiter は新しいRateLimiterインスタンスを作成します
+// store が nil の場合、バケットはメモリにのみ保持します
+func NewRateLimiter(policy domain.RateLimitPolicy, store domain.RateLimitStore) *RateLimiter {
+	return &RateLimiter{
+		policy:     policy,
+		store:      store,
+		now:        time.Now,
+		buckets:    make(map[string]domain.TokenBucket),
+		violations: make(map[string]rateLimitViolation),
+		lastPrune:  time.Now(),
+	}
+}
+
+// Allow は、リクエストを受け付けられるかを判定し、受け付ける場合はユーザー・チャンネル・ギルドの枠を1件ずつ消費します
+// いずれかの枠が残っていない場合は *domain.RateLimitError を返し、どの枠も消費しません
+func (l *RateLimiter) Allow(ctx context.Context, request RateLimitRequest) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.pruneIdle(ctx, now)
+
+	type target struct {
+		key    string
+		limit  domain.RateLimit
+		bucket domain.TokenBucket
+	}
+
+	var targets []target
+	var rejected *domain.RateLimitError
+	for _, scope := range []struct {
+		scope domain.RateLimitScope
+		id    string
+	}{
+		{domain.RateLimitScopeUser, request.UserID},
+		{domain.RateLimitScopeChannel, request.ChannelID},
+		{domain.RateLimitScopeGuild, request.GuildID},
+	} {
+		limit := l.policy.Limit(scope.scope, request.Kind)
+		if scope.id == "" || limit.IsUnlimited() {
+			continue
+		}
+
+		key := domain.RateLimitKey(scope.scope, request.Kind, scope.id)
+		bucket := l.bucket(ctx, key, limit, now).Refill(limit, now)
+		if bucket.Tokens < 1 {
+			// 複数の枠が不足している場合は、最も長く待つ必要がある枠を報告する
+			if retryAfter := bucket.RetryAfter(limit); rejected == nil || retryAfter > rejected.RetryAfter {
+				rejected = &domain.RateLimitError{Scope: scope.scope, Kind: request.Kind, RetryAfter: retryAfter}
+			}
+			continue
+		}
+		targets = append(targets, target{key: key, limit: limit, bucket: bucket})
+	}
+
+	if rejected != nil {
+		rejected.Spam = l.recordViolation(request.UserID, now)
+		return rejected
+	}
+
+	delete(l.violations, request.UserID)
+	for _, t := range targets {
+		t.bucket.Tokens--
+		l.buckets[t.key] = t.bucket
+		if l.store != nil {
+			if err := l.store.SaveTokenBucket(ctx, t.key, t.bucket); err != nil {
+				log.Printf("レート制限の状態の保存に失敗: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// bucket は、キーに対応するトークンバケットを返します
+// メモリにない場合はストアから読み込み、ストアにもない場合は上限まで枠が残っているバケットを作成します
+func (l *RateLimiter) bucket(ctx context.Context, key string, limit domain.RateLimit, now time.Time) domain.TokenBucket {
+	if bucket, ok := l.buckets[key]; ok {
+		return bucket
+	}
+
+	if l.store != nil {
+		bucket, found, err := l.store.GetTokenBucket(ctx, key)
+		if err != nil {
+			log.Printf("レート制限の状態の取得に失敗: %v", err)
+		} else if found {
+			return bucket
+		}
+	}
+	return domain.NewTokenBucket(limit, now)
+}
+
+// recordViolation は、ユーザーがレート制限を超えた回数を記録し、スパムとみなす回数に達したかどうかを返します
+func (l *RateLimiter) recordViolation(userID string, now time.Time) bool {
+	if userID == "" || l.policy.SpamThreshold <= 0 {
+		return false
+	}
+
+	violation := l.violations[userID]
+	violation.count++
+	violation.lastAt = now
+	l.violations[userID] = violation
+	return violation.count >= l.policy.SpamThreshold
+}
+
+// pruneIdle は、一定間隔で、満杯まで回復したトークンバケットと古い超過の記録を破棄します
+func (l *RateLimiter) pruneIdle(ctx context.Context, now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	before := now.Add(-l.policy.LongestPeriod())
+	for key, bucket := range l.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(l.buckets, key)
+		}
+	}
+	for userID, violation := range l.violations {
+		if violation.lastAt.Before(before) {
+			delete(l.violations, userID)
+		}
+	}
+
+	if l.store != nil {
+		if err := l.store.DeleteTokenBucketsBefore(ctx, before); err != nil {
+			log.Printf("古いレート制限の状態の削除に失敗: %v", err)
+		}
+	}
+}
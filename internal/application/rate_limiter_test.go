@@ -0,0 +1,84 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(domain.RateLimitPolicy{
+		User:    domain.RateLimitBudget{Text: domain.RateLimit{Requests: 2, Per: time.Minute}, Image: domain.RateLimit{Requests: 1, Per: 10 * time.Minute}},
+		Channel: domain.RateLimitBudget{Text: domain.RateLimit{Requests: 3, Per: time.Minute}},
+	}, nil)
+	limiter.now = func() time.Time { return now }
+
+	alice := RateLimitRequest{Kind: domain.RequestKindText, GuildID: "guild1", ChannelID: "general", UserID: "alice"}
+	for i := 0; i < 2; i++ {
+		if err := limiter.Allow(ctx, alice); err != nil {
+			t.Fatalf("%d件目のリクエストが拒否されました: %v", i+1, err)
+		}
+	}
+
+	err := limiter.Allow(ctx, alice)
+	var rateLimitErr *domain.RateLimitError
+	if !errors.Is(err, domain.ErrRateLimited) || !errors.As(err, &rateLimitErr) {
+		t.Fatalf("ユーザーの上限を超えたリクエストはレート制限のエラーになるべきです: %v", err)
+	}
+	if rateLimitErr.Scope != domain.RateLimitScopeUser || rateLimitErr.RetryAfter != 30*time.Second {
+		t.Errorf("拒否した枠の情報が正しくありません: %+v", rateLimitErr)
+	}
+
+	// テキストと画像の枠は別々に数える
+	if err := limiter.Allow(ctx, RateLimitRequest{Kind: domain.RequestKindImage, GuildID: "guild1", ChannelID: "general", UserID: "alice"}); err != nil {
+		t.Errorf("画像生成の枠はテキスト生成と別に数えるべきです: %v", err)
+	}
+
+	// チャンネルの枠は他のユーザーとも共有する
+	bob := RateLimitRequest{Kind: domain.RequestKindText, GuildID: "guild1", ChannelID: "general", UserID: "bob"}
+	if err := limiter.Allow(ctx, bob); err != nil {
+		t.Fatalf("チャンネルの枠が残っているのに拒否されました: %v", err)
+	}
+	if err := limiter.Allow(ctx, bob); !errors.As(err, &rateLimitErr) || rateLimitErr.Scope != domain.RateLimitScopeChannel {
+		t.Errorf("チャンネルの上限を超えたリクエストはチャンネルの枠で拒否されるべきです: %v", err)
+	}
+
+	// 時間の経過で枠が回復する
+	now = now.Add(time.Minute)
+	if err := limiter.Allow(ctx, alice); err != nil {
+		t.Errorf("枠が回復した後のリクエストが拒否されました: %v", err)
+	}
+}
+
+func TestRateLimiter_SpamDetection(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRateLimiter(domain.RateLimitPolicy{
+		User:          domain.RateLimitBudget{Text: domain.RateLimit{Requests: 1, Per: time.Hour}},
+		SpamThreshold: 3,
+	}, nil)
+	request := RateLimitRequest{Kind: domain.RequestKindText, UserID: "alice"}
+
+	if err := limiter.Allow(ctx, request); err != nil {
+		t.Fatalf("最初のリクエストが拒否されました: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := limiter.Allow(ctx, request); errors.Is(err, domain.ErrSpamDetected) || !errors.Is(err, domain.ErrRateLimited) {
+			t.Fatalf("%d回目の超過はレート制限のエラーになるべきです: %v", i+1, err)
+		}
+	}
+	if err := limiter.Allow(ctx, request); !errors.Is(err, domain.ErrSpamDetected) {
+		t.Errorf("上限を超えてリクエストを繰り返した場合はスパムとみなすべきです: %v", err)
+	}
+}
+
+func TestRateLimiter_NilAllowsEverything(t *testing.T) {
+	var limiter *RateLimiter
+	if err := limiter.Allow(context.Background(), RateLimitRequest{Kind: domain.RequestKindText, UserID: "alice"}); err != nil {
+		t.Errorf("レート制限が設定されていない場合はすべて受け付けるべきです: %v", err)
+	}
+}
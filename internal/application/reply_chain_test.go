@@ -0,0 +1,105 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+)
+
+// replyChainRepository は、直近の履歴とは別に返信の連鎖を返すテスト用のリポジトリです
+type replyChainRepository struct {
+	MockConversationRepository
+	recent     []domain.Message
+	chain      []domain.Message
+	chainDepth int
+}
+
+func (r *replyChainRepository) GetMessagesBefore(ctx context.Context, channelID string, messageID string, limit int) ([]domain.Message, error) {
+	return append([]domain.Message(nil), r.recent...), nil
+}
+
+func (r *replyChainRepository) GetReplyChain(ctx context.Context, channelID string, messageID string, depth int) ([]domain.Message, error) {
+	r.chainDepth = depth
+	return append([]domain.Message(nil), r.chain...), nil
+}
+
+func TestMentionApplicationService_HandleMention_FollowsReplyChain(t *testing.T) {
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		ReplyChainDepth:  3,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "全体のプロンプト",
+	}
+
+	now := time.Now()
+	repo := &replyChainRepository{
+		recent: []domain.Message{
+			{ID: "recent", User: domain.User{DisplayName: "Bob"}, Content: "関係のない最近の話", Timestamp: now.Add(-time.Minute)},
+		},
+		chain: []domain.Message{
+			{ID: "question", User: domain.User{DisplayName: "Carol"}, Content: "地球の形は？", Timestamp: now.Add(-4 * time.Hour)},
+			{ID: "target", User: domain.User{DisplayName: "Alice"}, Content: "地球は平らです", Timestamp: now.Add(-3 * time.Hour)},
+		},
+	}
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(repo, mockClient, botConfig, nil, &config.GeminiConfig{}, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:                domain.User{ID: "testuser", DisplayName: "TestUser"},
+		Content:             "これは正しい？",
+		ChannelID:           "testchannel",
+		MessageID:           "testmessageid",
+		ReferencedMessageID: "target",
+	}
+	if _, err := service.HandleMention(context.Background(), mention); err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+
+	if repo.chainDepth != 3 {
+		t.Errorf("設定した深さで返信元をたどるべきです: %d", repo.chainDepth)
+	}
+	history := mockClient.lastHistory
+	if len(history) != 3 || history[0].ID != "question" || history[1].ID != "target" || history[2].ID != "recent" {
+		t.Fatalf("返信の連鎖と直近の履歴を古い順に含めるべきです: %+v", history)
+	}
+	if !history[1].ReplyTarget || !history[0].ReplyChain || history[2].ReplyChain {
+		t.Errorf("返信の連鎖と返信先のメッセージが記録されていません: %+v", history)
+	}
+	if !strings.HasPrefix(mockClient.lastQuestion, domain.ReplyTargetLabel) || !strings.Contains(mockClient.lastQuestion, "地球は平らです") || !strings.HasSuffix(mockClient.lastQuestion, "これは正しい？") {
+		t.Errorf("質問に返信先のメッセージを明示するべきです: %s", mockClient.lastQuestion)
+	}
+}
+
+func TestMentionApplicationService_HandleMention_ReplyChainDisabled(t *testing.T) {
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "全体のプロンプト",
+	}
+
+	repo := &replyChainRepository{
+		chain: []domain.Message{{ID: "target", User: domain.User{DisplayName: "Alice"}, Content: "返信先", Timestamp: time.Now()}},
+	}
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(repo, mockClient, botConfig, nil, &config.GeminiConfig{}, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{Content: "質問", ChannelID: "testchannel", MessageID: "testmessageid", ReferencedMessageID: "target"}
+	if _, err := service.HandleMention(context.Background(), mention); err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+	if repo.chainDepth != 0 || mockClient.lastQuestion != "質問" {
+		t.Errorf("REPLY_CHAIN_DEPTH が0の場合は返信元をたどらないべきです: depth=%d, question=%s", repo.chainDepth, mockClient.lastQuestion)
+	}
+}
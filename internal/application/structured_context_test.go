@@ -7,11 +7,20 @@ import (
 
 	"geminibot/internal/domain"
 	"geminibot/internal/infrastructure/config"
+	discordInfra "geminibot/internal/infrastructure/discord"
 )
 
 // MockGeminiClient は、テスト用のGeminiClientモックです
 type MockGeminiClient struct {
 	shouldUseStructuredContext bool
+	lastOptions                TextGenerationOptions
+	lastQuestionAttachments    []domain.Attachment
+	lastHistory                []domain.Message
+	lastQuestion               string
+	lastSystemPrompt           string
+	lastToolCallContext        domain.ToolCallContext
+	lastSchema                 map[string]any
+	jsonOutput                 string // GenerateJSON が返すJSON（空の場合は空のオブジェクト）
 }
 
 func (m *MockGeminiClient) GenerateText(ctx context.Context, prompt domain.Prompt) (string, error) {
@@ -22,8 +31,49 @@ func (m *MockGeminiClient) GenerateTextWithOptions(ctx context.Context, prompt d
 	return "オプション付きでの応答", nil
 }
 
-func (m *MockGeminiClient) GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string) (string, error) {
-	return "構造化コンテキストでの応答", nil
+func (m *MockGeminiClient) GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options TextGenerationOptions) (*TextGenerationResult, error) {
+	m.lastOptions = options
+	m.lastQuestionAttachments = questionAttachments
+	m.lastHistory = conversationHistory
+	m.lastQuestion = userQuestion
+	m.lastSystemPrompt = systemPrompt
+	m.lastToolCallContext = domain.ToolCallContextFrom(ctx)
+
+	model := options.Model
+	if model == "" {
+		model = "mock-default-model"
+	}
+	return &TextGenerationResult{Content: "構造化コンテキストでの応答", Model: model}, nil
+}
+
+func (m *MockGeminiClient) GenerateTextWithStructuredContextStream(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options TextGenerationOptions, onChunk StreamCallback) (*TextGenerationResult, error) {
+	result, err := m.GenerateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options)
+	if err != nil {
+		return nil, err
+	}
+	onChunk(result.Content)
+	return result, nil
+}
+
+func (m *MockGeminiClient) GenerateJSON(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, instruction string, schema map[string]any, options TextGenerationOptions) (*JSONGenerationResult, error) {
+	m.lastOptions = options
+	m.lastHistory = conversationHistory
+	m.lastQuestion = instruction
+	m.lastSystemPrompt = systemPrompt
+	m.lastSchema = schema
+
+	output := m.jsonOutput
+	if output == "" {
+		output = "{}"
+	}
+	if err := domain.ValidateJSON(schema, []byte(output)); err != nil {
+		return nil, err
+	}
+	return &JSONGenerationResult{JSON: []byte(output), Model: "mock-default-model", Attempts: 1}, nil
+}
+
+func (m *MockGeminiClient) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	return domain.EstimateTokens(text), nil
 }
 
 func (m *MockGeminiClient) GenerateImage(ctx context.Context, request domain.ImageGenerationRequest) (*domain.ImageGenerationResponse, error) {
@@ -70,6 +120,10 @@ func (m *MockConversationRepository) GetMessagesBefore(ctx context.Context, chan
 	return m.GetRecentMessages(ctx, channelID, limit)
 }
 
+func (m *MockConversationRepository) GetReplyChain(ctx context.Context, channelID string, messageID string, depth int) ([]domain.Message, error) {
+	return nil, nil
+}
+
 func TestMentionApplicationService_HandleMentionWithStructuredContext(t *testing.T) {
 	// テスト用の設定
 	config := &config.BotConfig{
@@ -115,8 +169,13 @@ func TestMentionApplicationService_HandleMentionWithStructuredContext(t *testing
 		t.Errorf("メンション処理でエラーが発生しました: %v", err)
 	}
 
-	if response != "構造化コンテキストでの応答" {
-		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response)
+	if response.Content != "構造化コンテキストでの応答" {
+		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response.Content)
+	}
+
+	// サーバーやチャンネルを参照するツールには、メンションのチャンネルとユーザーを渡す
+	if want := (domain.ToolCallContext{ChannelID: "testchannel", UserID: "testuser"}); mockClient.lastToolCallContext != want {
+		t.Errorf("ツールを呼び出したメンションの情報が正しくありません: %+v", mockClient.lastToolCallContext)
 	}
 }
 
@@ -162,8 +221,8 @@ func TestMentionApplicationService_HandleMention_WithStructuredContext(t *testin
 		t.Errorf("メンション処理でエラーが発生しました: %v", err)
 	}
 
-	if response != "構造化コンテキストでの応答" {
-		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response)
+	if response.Content != "構造化コンテキストでの応答" {
+		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response.Content)
 	}
 }
 
@@ -209,7 +268,181 @@ func TestMentionApplicationService_HandleMention_WithoutStructuredContext(t *tes
 		t.Errorf("メンション処理でエラーが発生しました: %v", err)
 	}
 
-	if response != "構造化コンテキストでの応答" {
-		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response)
+	if response.Content != "構造化コンテキストでの応答" {
+		t.Errorf("期待される応答: '構造化コンテキストでの応答', 実際の応答: %s", response.Content)
+	}
+}
+
+func TestMentionApplicationService_HandleMention_UsesGuildModel(t *testing.T) {
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "テストシステムプロンプト",
+	}
+
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	ctx := context.Background()
+	if err := apiKeyService.SetGuildModel(ctx, "guild1", "gemini-2.0-flash"); err != nil {
+		t.Fatalf("モデルの設定に失敗: %v", err)
+	}
+
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, mockClient, botConfig, apiKeyService, &config.GeminiConfig{}, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "テストメッセージ",
+		ChannelID: "testchannel",
+		GuildID:   "guild1",
+		MessageID: "testmessageid",
+	}
+
+	result, err := service.HandleMention(ctx, mention)
+	if err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+
+	if mockClient.lastOptions.Model != "gemini-2.0-flash" {
+		t.Errorf("ギルドのモデルがクライアントに渡されていません: %s", mockClient.lastOptions.Model)
+	}
+	if result.Model != "gemini-2.0-flash" {
+		t.Errorf("期待される使用モデル: gemini-2.0-flash, 実際: %s", result.Model)
+	}
+
+	lastModel, ok := apiKeyService.GetLastUsedModel("guild1")
+	if !ok || lastModel != "gemini-2.0-flash" {
+		t.Errorf("直近の使用モデルが記録されていません: model=%s, ok=%v", lastModel, ok)
+	}
+}
+
+func TestMentionApplicationService_HandleMentionStream(t *testing.T) {
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "テストシステムプロンプト",
+	}
+
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, &MockGeminiClient{}, botConfig, nil, &config.GeminiConfig{}, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "テストメッセージ",
+		ChannelID: "testchannel",
+		MessageID: "testmessageid",
+	}
+
+	var chunks []string
+	result, err := service.HandleMentionStream(context.Background(), mention, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+
+	if len(chunks) != 1 || chunks[0] != result.Content {
+		t.Errorf("ストリーミングのチャンクが正しく渡されていません: %v", chunks)
+	}
+	if result.Model != "mock-default-model" {
+		t.Errorf("期待される使用モデル: mock-default-model, 実際: %s", result.Model)
+	}
+}
+
+func TestMentionApplicationService_ContinueMentionStream(t *testing.T) {
+	botConfig := &config.BotConfig{
+		MaxContextLength: 8000,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "テストシステムプロンプト",
+	}
+
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, mockClient, botConfig, nil, &config.GeminiConfig{}, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "長い説明をしてください",
+		ChannelID: "testchannel",
+		MessageID: "testmessageid",
+	}
+
+	if _, err := service.ContinueMentionStream(context.Background(), mention, "", func(string) {}); err == nil {
+		t.Errorf("途中までの回答がない場合はエラーを返すべきです")
+	}
+
+	if _, err := service.ContinueMentionStream(context.Background(), mention, "途中までの回答", func(string) {}); err != nil {
+		t.Fatalf("続きの生成でエラーが発生しました: %v", err)
+	}
+
+	history := mockClient.lastHistory
+	if len(history) < 2 {
+		t.Fatalf("元の質問と途中までの回答が履歴に追加されていません: %d件", len(history))
+	}
+	question, answer := history[len(history)-2], history[len(history)-1]
+	if question.FromSelf || question.Content != mention.Content {
+		t.Errorf("元の質問がユーザーの発言として追加されていません: %+v", question)
+	}
+	if !answer.FromSelf || answer.Content != "途中までの回答" {
+		t.Errorf("途中までの回答がBotの発言として追加されていません: %+v", answer)
+	}
+	if mockClient.lastQuestion != continuationPrompt {
+		t.Errorf("続きを求める指示が質問として渡されていません: %s", mockClient.lastQuestion)
+	}
+}
+
+func TestMentionApplicationService_HandleMention_UsesGuildSystemPrompt(t *testing.T) {
+	botConfig := &config.BotConfig{
+		MaxContextLength: 20,
+		MaxHistoryLength: 4000,
+		RequestTimeout:   30 * time.Second,
+		SystemPrompt:     "全体のプロンプト",
+	}
+
+	apiKeyService := NewAPIKeyApplicationService(discordInfra.NewGuildConfigManager(config.DefaultGeminiTextModel))
+	ctx := context.Background()
+	guildPrompt := "あなたは厳格なコードレビュアーです。指摘は具体的に行ってください。"
+	if err := apiKeyService.SetGuildSystemPrompt(ctx, "guild1", guildPrompt); err != nil {
+		t.Fatalf("システムプロンプトの設定に失敗: %v", err)
+	}
+
+	mockClient := &MockGeminiClient{}
+	service, err := NewMentionApplicationService(&MockConversationRepository{}, mockClient, botConfig, apiKeyService, &config.GeminiConfig{}, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("サービスの作成に失敗: %v", err)
+	}
+
+	mention := domain.BotMention{
+		User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+		Content:   "質問",
+		ChannelID: "testchannel",
+		GuildID:   "guild1",
+		MessageID: "testmessageid",
+	}
+	if _, err := service.HandleMention(ctx, mention); err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+	if expected := string([]rune(guildPrompt)[:botConfig.MaxContextLength]); mockClient.lastSystemPrompt != expected {
+		t.Errorf("ギルドのシステムプロンプトが切り詰めて使用されていません: %s", mockClient.lastSystemPrompt)
+	}
+
+	// プロンプトを削除したギルドや未設定のギルドは全体のプロンプトを使用する
+	if err := apiKeyService.ResetGuildSystemPrompt(ctx, "guild1"); err != nil {
+		t.Fatalf("システムプロンプトの削除に失敗: %v", err)
+	}
+	if _, err := service.HandleMention(ctx, mention); err != nil {
+		t.Fatalf("メンション処理でエラーが発生しました: %v", err)
+	}
+	if mockClient.lastSystemPrompt != "全体のプロンプト" {
+		t.Errorf("全体のシステムプロンプトが使用されていません: %s", mockClient.lastSystemPrompt)
 	}
 }
@@ -0,0 +1,165 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"geminibot/internal/domain"
+	appconfig "geminibot/internal/infrastructure/config"
+)
+
+// UsageSummary は、期間内の使用量の合計と推定コストです
+type UsageSummary struct {
+	Requests      int
+	Usage         domain.TokenUsage
+	EstimatedCost float64             // 推定コスト（米ドル）
+	Models        []domain.ModelUsage // モデルごとの内訳
+	UnpricedModel bool                // 料金が不明なため推定コストに含めていないモデルがあるかどうか
+}
+
+// UsageReport は、ギルドの今日と今月の使用量、および利用上限です
+type UsageReport struct {
+	Daily   UsageSummary
+	Monthly UsageSummary
+	Budget  domain.UsageBudget
+}
+
+// UsageApplicationService は、トークンの使用量の記録・集計と、ギルドの利用上限を管理するアプリケーションサービスです
+type UsageApplicationService struct {
+	store domain.UsageStore
+	now   func() time.Time
+}
+
+// NewUsageApplicationService は新しいUsageApplicationServiceインスタンスを作成します
+func NewUsageApplicationService(store domain.UsageStore) *UsageApplicationService {
+	return &UsageApplicationService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// RecordUsage は、1回のリクエストで消費したトークン数を記録します
+// 記録に失敗しても応答は返せるため、エラーはログに出力するのみとします
+func (s *UsageApplicationService) RecordUsage(ctx context.Context, record domain.UsageRecord) {
+	if s == nil || s.store == nil || record.Usage.IsZero() {
+		return
+	}
+
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = s.now()
+	}
+	if err := s.store.RecordUsage(ctx, record); err != nil {
+		log.Printf("使用量の記録に失敗: %v", err)
+	}
+}
+
+// GetUsageReport は、ギルドの今日と今月の使用量、および利用上限を取得します
+// userID を指定した場合は、そのユーザーの使用量のみを集計します
+func (s *UsageApplicationService) GetUsageReport(ctx context.Context, guildID, userID string) (UsageReport, error) {
+	now := s.now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	daily, err := s.summarize(ctx, domain.UsageQuery{GuildID: guildID, UserID: userID, Since: dayStart})
+	if err != nil {
+		return UsageReport{}, err
+	}
+	monthly, err := s.summarize(ctx, domain.UsageQuery{GuildID: guildID, UserID: userID, Since: monthStart(now)})
+	if err != nil {
+		return UsageReport{}, err
+	}
+	budget, err := s.store.GetUsageBudget(ctx, guildID)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("利用上限の取得に失敗: %w", err)
+	}
+
+	return UsageReport{Daily: daily, Monthly: monthly, Budget: budget}, nil
+}
+
+// SetUsageBudget は、ギルドの1か月あたりの利用上限を設定します
+func (s *UsageApplicationService) SetUsageBudget(ctx context.Context, budget domain.UsageBudget) error {
+	if budget.MonthlyTokenLimit < 0 || budget.MonthlyCostLimit < 0 {
+		return fmt.Errorf("利用上限は0以上である必要があります")
+	}
+	if budget.IsEmpty() {
+		return fmt.Errorf("トークン数または推定コストの上限を指定してください")
+	}
+	if budget.FallbackModel != "" && !appconfig.IsSupportedGeminiTextModel(budget.FallbackModel) {
+		return fmt.Errorf("サポートされていないモデルです: %s", budget.FallbackModel)
+	}
+
+	if budget.UpdatedAt.IsZero() {
+		budget.UpdatedAt = s.now()
+	}
+	if err := s.store.SaveUsageBudget(ctx, budget); err != nil {
+		return fmt.Errorf("利用上限の保存に失敗: %w", err)
+	}
+	return nil
+}
+
+// ResetUsageBudget は、ギルドの利用上限を削除します
+func (s *UsageApplicationService) ResetUsageBudget(ctx context.Context, guildID string) error {
+	if err := s.store.DeleteUsageBudget(ctx, guildID); err != nil {
+		return fmt.Errorf("利用上限の削除に失敗: %w", err)
+	}
+	return nil
+}
+
+// CheckBudget は、ギルドの今月の使用量が利用上限に達していないかを確認します
+// 上限に達している場合、テキスト生成で代わりのモデルが設定されていればそのモデル名を返し、それ以外は ErrUsageBudgetExceeded を返します
+// 使用量を取得できない場合は、リクエストを止めないよう上限に達していないものとして扱います
+func (s *UsageApplicationService) CheckBudget(ctx context.Context, guildID string, kind domain.RequestKind) (fallbackModel string, err error) {
+	if s == nil || s.store == nil || guildID == "" {
+		return "", nil
+	}
+
+	budget, err := s.store.GetUsageBudget(ctx, guildID)
+	if err != nil {
+		log.Printf("ギルド %s の利用上限の取得に失敗: %v", guildID, err)
+		return "", nil
+	}
+	if budget.IsEmpty() {
+		return "", nil
+	}
+
+	monthly, err := s.summarize(ctx, domain.UsageQuery{GuildID: guildID, Since: monthStart(s.now())})
+	if err != nil {
+		log.Printf("ギルド %s の使用量の集計に失敗: %v", guildID, err)
+		return "", nil
+	}
+	if !budget.IsExceeded(monthly.Usage.TotalTokens(), monthly.EstimatedCost) {
+		return "", nil
+	}
+
+	if kind == domain.RequestKindText && budget.FallbackModel != "" {
+		log.Printf("ギルド %s は今月の利用上限に達したため、%s を使用します", guildID, budget.FallbackModel)
+		return budget.FallbackModel, nil
+	}
+	return "", fmt.Errorf("%w（今月の使用量: %dトークン、推定 $%.2f）", domain.ErrUsageBudgetExceeded, monthly.Usage.TotalTokens(), monthly.EstimatedCost)
+}
+
+// summarize は、条件に一致する使用量を集計し、モデルの料金から推定コストを計算します
+func (s *UsageApplicationService) summarize(ctx context.Context, query domain.UsageQuery) (UsageSummary, error) {
+	models, err := s.store.SummarizeUsage(ctx, query)
+	if err != nil {
+		return UsageSummary{}, fmt.Errorf("使用量の集計に失敗: %w", err)
+	}
+
+	summary := UsageSummary{Models: models}
+	for _, model := range models {
+		summary.Requests += model.Requests
+		summary.Usage = summary.Usage.Add(model.Usage)
+		if pricing, ok := appconfig.GeminiModelPricing(model.Model); ok {
+			summary.EstimatedCost += pricing.Cost(model.Usage)
+		} else {
+			summary.UnpricedModel = true
+		}
+	}
+	return summary, nil
+}
+
+// monthStart は、指定された時刻が属する月の初めの時刻を返します
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,157 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+	discordInfra "geminibot/internal/infrastructure/discord"
+)
+
+func TestUsageApplicationService_GetUsageReport(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	service := NewUsageApplicationService(discordInfra.NewUsageStore())
+	service.now = func() time.Time { return now }
+
+	service.RecordUsage(ctx, domain.UsageRecord{GuildID: "guild1", UserID: "alice", Model: "gemini-2.5-flash", Kind: domain.RequestKindText, Usage: domain.TokenUsage{PromptTokens: 1_000_000}})
+	service.RecordUsage(ctx, domain.UsageRecord{GuildID: "guild1", UserID: "bob", Model: "gemini-2.5-pro", Kind: domain.RequestKindText, Usage: domain.TokenUsage{OutputTokens: 100_000}})
+	service.RecordUsage(ctx, domain.UsageRecord{GuildID: "guild2", UserID: "alice", Model: "gemini-2.5-pro", Kind: domain.RequestKindText, Usage: domain.TokenUsage{PromptTokens: 10}})
+	// トークンを消費していないリクエストは記録しない
+	service.RecordUsage(ctx, domain.UsageRecord{GuildID: "guild1", UserID: "alice", Model: "gemini-2.5-pro", Kind: domain.RequestKindText})
+
+	report, err := service.GetUsageReport(ctx, "guild1", "")
+	if err != nil {
+		t.Fatalf("使用量の取得に失敗: %v", err)
+	}
+	if report.Monthly.Requests != 2 || report.Monthly.Usage.TotalTokens() != 1_100_000 {
+		t.Errorf("ギルドの今月の使用量が正しくありません: %+v", report.Monthly)
+	}
+	if report.Daily.Requests != 2 {
+		t.Errorf("ギルドの今日の使用量が正しくありません: %+v", report.Daily)
+	}
+	// gemini-2.5-flash の入力 $0.30 + gemini-2.5-pro の出力 $1.00
+	if report.Monthly.EstimatedCost < 1.2999 || report.Monthly.EstimatedCost > 1.3001 {
+		t.Errorf("推定コスト = %f, 期待値: 1.30", report.Monthly.EstimatedCost)
+	}
+
+	report, err = service.GetUsageReport(ctx, "guild1", "alice")
+	if err != nil {
+		t.Fatalf("使用量の取得に失敗: %v", err)
+	}
+	if report.Monthly.Requests != 1 || report.Monthly.Models[0].Model != "gemini-2.5-flash" {
+		t.Errorf("ユーザーを指定した場合はそのユーザーの使用量のみを集計するべきです: %+v", report.Monthly)
+	}
+}
+
+func TestUsageApplicationService_CheckBudget(t *testing.T) {
+	ctx := context.Background()
+	service := NewUsageApplicationService(discordInfra.NewUsageStore())
+
+	if _, err := service.CheckBudget(ctx, "guild1", domain.RequestKindText); err != nil {
+		t.Fatalf("利用上限が設定されていない場合は受け付けるべきです: %v", err)
+	}
+
+	if err := service.SetUsageBudget(ctx, domain.UsageBudget{GuildID: "guild1", MonthlyTokenLimit: 1000}); err != nil {
+		t.Fatalf("利用上限の設定に失敗: %v", err)
+	}
+	if _, err := service.CheckBudget(ctx, "guild1", domain.RequestKindText); err != nil {
+		t.Fatalf("上限に達していない場合は受け付けるべきです: %v", err)
+	}
+
+	service.RecordUsage(ctx, domain.UsageRecord{GuildID: "guild1", Model: "gemini-2.5-pro", Kind: domain.RequestKindText, Usage: domain.TokenUsage{PromptTokens: 800, OutputTokens: 200}})
+	if _, err := service.CheckBudget(ctx, "guild1", domain.RequestKindText); !errors.Is(err, domain.ErrUsageBudgetExceeded) {
+		t.Errorf("上限に達した場合は ErrUsageBudgetExceeded を返すべきです: %v", err)
+	}
+	if _, err := service.CheckBudget(ctx, "guild2", domain.RequestKindText); err != nil {
+		t.Errorf("他のギルドの使用量が上限の判定に含まれています: %v", err)
+	}
+
+	// 代わりのモデルが設定されている場合、テキスト生成はそのモデルで受け付け、画像生成は拒否する
+	if err := service.SetUsageBudget(ctx, domain.UsageBudget{GuildID: "guild1", MonthlyTokenLimit: 1000, FallbackModel: "gemini-2.5-flash-lite"}); err != nil {
+		t.Fatalf("利用上限の設定に失敗: %v", err)
+	}
+	fallback, err := service.CheckBudget(ctx, "guild1", domain.RequestKindText)
+	if err != nil || fallback != "gemini-2.5-flash-lite" {
+		t.Errorf("CheckBudget() = %q, %v, 期待値: gemini-2.5-flash-lite", fallback, err)
+	}
+	if _, err := service.CheckBudget(ctx, "guild1", domain.RequestKindImage); !errors.Is(err, domain.ErrUsageBudgetExceeded) {
+		t.Errorf("画像生成は代わりのモデルに切り替えずに拒否するべきです: %v", err)
+	}
+
+	if err := service.ResetUsageBudget(ctx, "guild1"); err != nil {
+		t.Fatalf("利用上限の削除に失敗: %v", err)
+	}
+	if _, err := service.CheckBudget(ctx, "guild1", domain.RequestKindImage); err != nil {
+		t.Errorf("利用上限を削除した後は受け付けるべきです: %v", err)
+	}
+}
+
+func TestUsageApplicationService_SetUsageBudgetValidation(t *testing.T) {
+	service := NewUsageApplicationService(discordInfra.NewUsageStore())
+
+	for _, budget := range []domain.UsageBudget{
+		{GuildID: "guild1"},
+		{GuildID: "guild1", MonthlyTokenLimit: -1, MonthlyCostLimit: 10},
+		{GuildID: "guild1", MonthlyCostLimit: 10, FallbackModel: "unknown-model"},
+	} {
+		if err := service.SetUsageBudget(context.Background(), budget); err == nil {
+			t.Errorf("不正な利用上限が保存されました: %+v", budget)
+		}
+	}
+}
+
+func TestUsageApplicationService_NilIsNoop(t *testing.T) {
+	var service *UsageApplicationService
+	service.RecordUsage(context.Background(), domain.UsageRecord{GuildID: "guild1", Usage: domain.TokenUsage{PromptTokens: 1}})
+	if _, err := service.CheckBudget(context.Background(), "guild1", domain.RequestKindText); err != nil {
+		t.Errorf("使用量を管理しない場合はすべて受け付けるべきです: %v", err)
+	}
+}
+
+// failingStreamGeminiClient は、途中までの使用量とともにストリーミング生成の失敗を返すモックです
+type failingStreamGeminiClient struct {
+	MockGeminiClient
+	err error
+}
+
+func (m *failingStreamGeminiClient) GenerateTextWithStructuredContextStream(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options TextGenerationOptions, onChunk StreamCallback) (*TextGenerationResult, error) {
+	onChunk("途中まで")
+	return &TextGenerationResult{Model: "gemini-2.5-pro", Usage: domain.TokenUsage{PromptTokens: 500, OutputTokens: 20}}, m.err
+}
+
+func TestMentionApplicationService_RecordsUsageOfFailedStream(t *testing.T) {
+	for _, streamErr := range []error{context.Canceled, errors.New("stream error")} {
+		ctx := context.Background()
+		botConfig := &config.BotConfig{MaxContextLength: 8000, MaxHistoryLength: 4000, RequestTimeout: 30 * time.Second}
+		usageService := NewUsageApplicationService(discordInfra.NewUsageStore())
+		client := &failingStreamGeminiClient{err: streamErr}
+		service, err := NewMentionApplicationService(&MockConversationRepository{}, client, botConfig, nil, &config.GeminiConfig{}, nil, nil, nil, usageService, nil, nil)
+		if err != nil {
+			t.Fatalf("サービスの作成に失敗: %v", err)
+		}
+
+		mention := domain.BotMention{
+			User:      domain.User{ID: "testuser", Username: "testuser", DisplayName: "TestUser"},
+			Content:   "質問",
+			ChannelID: "general",
+			GuildID:   "guild1",
+			MessageID: "testmessageid",
+		}
+		if _, err := service.HandleMentionStream(ctx, mention, func(string) {}); !errors.Is(err, streamErr) {
+			t.Fatalf("生成のエラーが返されていません: %v", err)
+		}
+
+		// 停止・失敗した回答の入力と途中までの出力も、利用上限に数える
+		report, err := usageService.GetUsageReport(ctx, "guild1", "")
+		if err != nil {
+			t.Fatalf("使用量の取得に失敗: %v", err)
+		}
+		if report.Monthly.Requests != 1 || report.Monthly.Usage.TotalTokens() != 520 {
+			t.Errorf("%v: 停止・失敗した回答の使用量が記録されていません: %+v", streamErr, report.Monthly)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrAnswerNotFound は、指定されたメッセージの回答記録が見つからない場合のエラーです
+var ErrAnswerNotFound = errors.New("回答の記録が見つかりません")
+
+// AnswerRecord は、Botの回答メッセージと、その回答を生成した元のリクエストを表します
+// 回答に付けたボタン（再生成・続きを生成）の操作時に、元のリクエストを復元するために使用します
+type AnswerRecord struct {
+	MessageID string     // ボタンを付けた回答メッセージのID
+	ChannelID string     // 回答メッセージを送信したチャンネル（スレッド）のID
+	Mention   BotMention // 回答の元になったメンション
+	Content   string     // 回答の本文（続きを生成した場合はそれまでの回答全体）
+	Truncated bool       // 最大トークン数や停止操作により、回答が途中で終了したかどうか
+	CreatedAt time.Time
+}
+
+// AnswerStore は、回答の記録をメッセージID単位で保存するインターフェースです
+type AnswerStore interface {
+	// SaveAnswer は、回答の記録を保存します（同じメッセージIDの記録は上書きします）
+	SaveAnswer(ctx context.Context, record AnswerRecord) error
+
+	// GetAnswer は、指定されたメッセージIDの回答の記録を取得します
+	// 記録がない場合は ErrAnswerNotFound を返します
+	GetAnswer(ctx context.Context, messageID string) (AnswerRecord, error)
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// supportedAttachmentMimeTypes は、Geminiにインラインデータとして渡せる添付ファイルのMIMEタイプです
+var supportedAttachmentMimeTypes = map[string]bool{
+	// 画像
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+	"image/heic": true,
+	"image/heif": true,
+	// ドキュメント
+	"application/pdf": true,
+	// テキスト
+	"text/plain":       true,
+	"text/markdown":    true,
+	"text/csv":         true,
+	"text/html":        true,
+	"text/xml":         true,
+	"application/json": true,
+	// 音声
+	"audio/wav":   true,
+	"audio/x-wav": true,
+	"audio/mpeg":  true,
+	"audio/mp3":   true,
+	"audio/aiff":  true,
+	"audio/aac":   true,
+	"audio/ogg":   true,
+	"audio/flac":  true,
+}
+
+// sourceImageMimeTypes は、画像編集の元画像として使用できるMIMEタイプです
+var sourceImageMimeTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+	"image/heic": true,
+	"image/heif": true,
+}
+
+// NormalizeAttachmentMimeType は、添付ファイルのContent-TypeからMIMEタイプを取り出します
+// Content-Typeが空の場合は、ファイルの拡張子から推定します
+func NormalizeAttachmentMimeType(contentType, filename string) string {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(strings.ToLower(filepath.Ext(filename)))
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// IsSupportedAttachmentMimeType は、指定されたMIMEタイプをGeminiに渡せるかどうかを判定します
+func IsSupportedAttachmentMimeType(mimeType string) bool {
+	return supportedAttachmentMimeTypes[mimeType]
+}
+
+// Validate は、添付ファイルがGeminiに渡せる形式であり、サイズ上限以内であることを検証します
+// maxBytes が0以下の場合はサイズを検証しません
+func (a Attachment) Validate(maxBytes int64) error {
+	if !IsSupportedAttachmentMimeType(a.MimeType) {
+		mimeType := a.MimeType
+		if mimeType == "" {
+			mimeType = "不明"
+		}
+		return fmt.Errorf("%w: %s (%s)。画像・PDF・テキスト・音声ファイルを添付してください", ErrUnsupportedAttachment, a.Filename, mimeType)
+	}
+	if maxBytes > 0 && a.Size > maxBytes {
+		return fmt.Errorf("%w: %s (%dバイト、上限 %dバイト)", ErrAttachmentTooLarge, a.Filename, a.Size, maxBytes)
+	}
+	return nil
+}
+
+// IsSourceImage は、添付ファイルを画像編集の元画像として使用できるかどうかを判定します
+func (a Attachment) IsSourceImage() bool {
+	return sourceImageMimeTypes[a.MimeType]
+}
+
+// ValidateSourceImage は、添付ファイルが画像編集の元画像として使用できる形式・サイズであることを検証します
+// maxBytes が0以下の場合はサイズを検証しません
+func (a Attachment) ValidateSourceImage(maxBytes int64) error {
+	if !a.IsSourceImage() {
+		return fmt.Errorf("%w: %s (%s)。編集元にはPNG・JPEG・WebP・HEIC形式の画像を指定してください", ErrUnsupportedAttachment, a.Filename, a.MimeType)
+	}
+	return a.Validate(maxBytes)
+}
+
+// HasData は、添付ファイルのデータがダウンロード済みかどうかを返します
+func (a Attachment) HasData() bool {
+	return len(a.Data) > 0
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeAttachmentMimeType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		filename    string
+		expected    string
+	}{
+		{"image/png", "a.png", "image/png"},
+		{"text/plain; charset=utf-8", "a.txt", "text/plain"},
+		{"", "document.pdf", "application/pdf"},
+		{"", "unknown", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAttachmentMimeType(tt.contentType, tt.filename); got != tt.expected {
+			t.Errorf("NormalizeAttachmentMimeType(%q, %q) = %q, 期待値 %q", tt.contentType, tt.filename, got, tt.expected)
+		}
+	}
+}
+
+func TestAttachment_Validate(t *testing.T) {
+	supported := Attachment{Filename: "a.png", MimeType: "image/png", Size: 100}
+	if err := supported.Validate(1000); err != nil {
+		t.Errorf("対応形式の添付ファイルでエラーが発生しました: %v", err)
+	}
+
+	unsupported := Attachment{Filename: "a.zip", MimeType: "application/zip", Size: 100}
+	if err := unsupported.Validate(1000); !errors.Is(err, ErrUnsupportedAttachment) {
+		t.Errorf("非対応形式のエラーが返されるべきです: %v", err)
+	}
+
+	tooLarge := Attachment{Filename: "a.pdf", MimeType: "application/pdf", Size: 2000}
+	if err := tooLarge.Validate(1000); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("サイズ超過のエラーが返されるべきです: %v", err)
+	}
+}
+
+func TestAttachment_ValidateSourceImage(t *testing.T) {
+	image := Attachment{Filename: "a.webp", MimeType: "image/webp", Size: 100}
+	if err := image.ValidateSourceImage(1000); err != nil {
+		t.Errorf("画像の添付ファイルでエラーが発生しました: %v", err)
+	}
+
+	pdf := Attachment{Filename: "a.pdf", MimeType: "application/pdf", Size: 100}
+	if err := pdf.ValidateSourceImage(1000); !errors.Is(err, ErrUnsupportedAttachment) {
+		t.Errorf("画像以外は編集元として使用できないべきです: %v", err)
+	}
+}
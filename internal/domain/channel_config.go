@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MaxChannelHistoryLength は、チャンネル設定で指定できる会話履歴の最大件数です（Discord APIで一度に取得できる上限）
+const MaxChannelHistoryLength = 100
+
+// DefaultChannelHistoryLength は、通常チャンネルで会話履歴として取得する既定の件数です
+const DefaultChannelHistoryLength = 10
+
+// ReplyStyle は、スレッド外のメンションへの応答方法を表します
+type ReplyStyle string
+
+const (
+	// ReplyStyleThread は、メンションを起点にスレッドを作成して応答します
+	ReplyStyleThread ReplyStyle = "thread"
+	// ReplyStyleReply は、メンションへのリプライとして応答します
+	ReplyStyleReply ReplyStyle = "reply"
+)
+
+// IsValid は、有効な応答方法かどうかを返します
+func (s ReplyStyle) IsValid() bool {
+	return s == ReplyStyleThread || s == ReplyStyleReply
+}
+
+// DisplayName は、応答方法の日本語名を返します
+func (s ReplyStyle) DisplayName() string {
+	switch s {
+	case ReplyStyleThread:
+		return "スレッド"
+	case ReplyStyleReply:
+		return "リプライ"
+	default:
+		return string(s)
+	}
+}
+
+// ChannelConfig は、チャンネル単位で上書きする設定を表します
+// ゼロ値（空文字・0・nil）の項目は上書きせず、ギルドまたは全体の既定の設定を使用します
+type ChannelConfig struct {
+	ChannelID       string
+	GuildID         string
+	Model           string
+	SystemPrompt    string
+	HistoryLength   int        // 会話履歴として取得するメッセージ数
+	ImageGeneration *bool      // 画像生成・編集を許可するかどうか
+	ReplyStyle      ReplyStyle // スレッド外のメンションへの応答方法
+	SearchGrounding *bool      // Google 検索によるグラウンディングを使用するかどうか
+	CodeExecution   *bool      // モデルがコードを実行して計算・データ処理を行えるようにするかどうか
+	UpdatedBy       string
+	UpdatedAt       time.Time
+}
+
+// IsEmpty は、上書きする設定が1つもないかどうかを返します
+func (c ChannelConfig) IsEmpty() bool {
+	return c.Model == "" && c.SystemPrompt == "" && c.HistoryLength == 0 && c.ImageGeneration == nil && c.ReplyStyle == "" && c.SearchGrounding == nil && c.CodeExecution == nil
+}
+
+// Merge は、update で指定された項目のみを上書きした設定を返します
+func (c ChannelConfig) Merge(update ChannelConfig) ChannelConfig {
+	merged := c
+	if update.Model != "" {
+		merged.Model = update.Model
+	}
+	if update.SystemPrompt != "" {
+		merged.SystemPrompt = update.SystemPrompt
+	}
+	if update.HistoryLength != 0 {
+		merged.HistoryLength = update.HistoryLength
+	}
+	if update.ImageGeneration != nil {
+		merged.ImageGeneration = update.ImageGeneration
+	}
+	if update.ReplyStyle != "" {
+		merged.ReplyStyle = update.ReplyStyle
+	}
+	if update.SearchGrounding != nil {
+		merged.SearchGrounding = update.SearchGrounding
+	}
+	if update.CodeExecution != nil {
+		merged.CodeExecution = update.CodeExecution
+	}
+	return merged
+}
+
+// ChannelSettings は、チャンネル → ギルド → 全体の既定の順に解決した、応答生成に使用する設定です
+type ChannelSettings struct {
+	Model           string // 空の場合はクライアントの既定のモデルを使用します
+	SystemPrompt    string
+	HistoryLength   int // 0の場合は既定の件数（通常チャンネルは DefaultChannelHistoryLength、スレッドは全件）を使用します
+	ImageGeneration bool
+	ReplyStyle      ReplyStyle
+	SearchGrounding bool             // Google 検索によるグラウンディングを使用するかどうか
+	CodeExecution   bool             // コード実行ツールを使用するかどうか（チャンネル単位でのみ有効にできます）
+	Thinking        ThinkingSettings // 思考の設定（ギルド単位で設定します）
+	FallbackModels  []string         // モデルで生成できなかった場合に順に使用するモデル（ギルド単位で設定し、空の場合はクライアントの既定を使用します）
+}
+
+// DefaultChannelSettings は、全体の既定の設定を返します
+func DefaultChannelSettings(systemPrompt string) ChannelSettings {
+	return ChannelSettings{
+		SystemPrompt:    systemPrompt,
+		ImageGeneration: true,
+		ReplyStyle:      ReplyStyleThread,
+	}
+}
+
+// ApplyTo は、チャンネル設定で上書きされた項目を inherited（ギルドまたは全体の既定の設定）に適用した設定を返します
+func (c ChannelConfig) ApplyTo(inherited ChannelSettings) ChannelSettings {
+	settings := inherited
+	if c.Model != "" {
+		settings.Model = c.Model
+	}
+	if c.SystemPrompt != "" {
+		settings.SystemPrompt = c.SystemPrompt
+	}
+	if c.HistoryLength > 0 {
+		settings.HistoryLength = c.HistoryLength
+	}
+	if c.ImageGeneration != nil {
+		settings.ImageGeneration = *c.ImageGeneration
+	}
+	if c.ReplyStyle != "" {
+		settings.ReplyStyle = c.ReplyStyle
+	}
+	if c.SearchGrounding != nil {
+		settings.SearchGrounding = *c.SearchGrounding
+	}
+	if c.CodeExecution != nil {
+		settings.CodeExecution = *c.CodeExecution
+	}
+	return settings
+}
+
+// ChannelConfigStore は、チャンネル設定の永続化を行うインターフェースです
+type ChannelConfigStore interface {
+	// GetChannelConfig は、指定されたチャンネルの設定を取得します（未設定の場合は項目が空の設定を返します）
+	GetChannelConfig(ctx context.Context, channelID string) (ChannelConfig, error)
+
+	// SaveChannelConfig は、チャンネルの設定を保存します（既存の設定は置き換えます）
+	SaveChannelConfig(ctx context.Context, config ChannelConfig) error
+
+	// DeleteChannelConfig は、指定されたチャンネルの設定を削除します
+	DeleteChannelConfig(ctx context.Context, channelID string) error
+}
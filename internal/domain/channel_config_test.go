@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChannelConfig_ApplyTo(t *testing.T) {
+	inherited := ChannelSettings{
+		Model:           "gemini-2.5-pro",
+		SystemPrompt:    "ギルドのプロンプト",
+		ImageGeneration: true,
+		ReplyStyle:      ReplyStyleThread,
+	}
+
+	if got := (ChannelConfig{}).ApplyTo(inherited); !reflect.DeepEqual(got, inherited) {
+		t.Errorf("空のチャンネル設定は継承した設定を変更するべきではありません: %+v", got)
+	}
+
+	disabled := false
+	got := ChannelConfig{Model: "gemini-2.0-flash", HistoryLength: 50, ImageGeneration: &disabled}.ApplyTo(inherited)
+	want := ChannelSettings{
+		Model:           "gemini-2.0-flash",
+		SystemPrompt:    "ギルドのプロンプト",
+		HistoryLength:   50,
+		ImageGeneration: false,
+		ReplyStyle:      ReplyStyleThread,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ApplyTo() = %+v, 期待値: %+v", got, want)
+	}
+}
+
+func TestChannelConfig_Merge(t *testing.T) {
+	enabled := true
+	current := ChannelConfig{ChannelID: "c1", Model: "gemini-2.5-pro", ReplyStyle: ReplyStyleReply}
+
+	merged := current.Merge(ChannelConfig{HistoryLength: 20, ImageGeneration: &enabled})
+	if merged.Model != "gemini-2.5-pro" || merged.ReplyStyle != ReplyStyleReply {
+		t.Errorf("指定していない項目は保持されるべきです: %+v", merged)
+	}
+	if merged.HistoryLength != 20 || merged.ImageGeneration == nil || !*merged.ImageGeneration {
+		t.Errorf("指定した項目が反映されていません: %+v", merged)
+	}
+}
+
+func TestChannelConfig_ApplyTo_SearchGrounding(t *testing.T) {
+	inherited := ChannelSettings{SearchGrounding: true}
+
+	if got := (ChannelConfig{}).ApplyTo(inherited); !got.SearchGrounding {
+		t.Errorf("チャンネルで未設定の場合はギルドのグラウンディングの設定を継承するべきです: %+v", got)
+	}
+
+	disabled := false
+	if got := (ChannelConfig{SearchGrounding: &disabled}).ApplyTo(inherited); got.SearchGrounding {
+		t.Errorf("チャンネルでグラウンディングを無効にした場合はギルドの設定より優先するべきです: %+v", got)
+	}
+}
+
+func TestChannelConfig_Merge_CodeExecution(t *testing.T) {
+	enabled := true
+	update := ChannelConfig{CodeExecution: &enabled}
+	if update.IsEmpty() {
+		t.Error("コード実行のみを指定した場合も空ではないべきです")
+	}
+
+	merged := (ChannelConfig{Model: "gemini-2.5-pro"}).Merge(update)
+	if merged.Model != "gemini-2.5-pro" || merged.CodeExecution == nil || !*merged.CodeExecution {
+		t.Errorf("コード実行の設定が反映されていません: %+v", merged)
+	}
+	if got := merged.ApplyTo(DefaultChannelSettings("")); !got.CodeExecution {
+		t.Errorf("チャンネルで有効にした場合はコードを実行するべきです: %+v", got)
+	}
+	if got := (ChannelConfig{}).ApplyTo(DefaultChannelSettings("")); got.CodeExecution {
+		t.Errorf("既定ではコードを実行しないべきです: %+v", got)
+	}
+}
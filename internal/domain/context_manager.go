@@ -1,34 +1,172 @@
 package domain
 
 import (
+	"context"
+	"math"
 	"sort"
 	"strings"
 	"unicode/utf8"
 )
 
+// maxTruncateAttempts は、トークン数の単位でテキストを上限に収めるために切り詰め直す最大回数です
+const maxTruncateAttempts = 4
+
 // ContextManager は、コンテキストの長さを管理するドメインサービスです
+// 長さは文字数、またはTokenizerで数えたトークン数で管理します
 type ContextManager struct {
-	maxContextLength int // 最大コンテキスト長（文字数）
-	maxHistoryLength int // 最大履歴長（文字数）
+	maxContextLength int // 最大コンテキスト長（文字数、トークン数の単位ではトークン数）
+	maxHistoryLength int // 最大履歴長（文字数、トークン数の単位ではトークン数）
+
+	mode         ContextBudgetMode
+	tokenizer    Tokenizer
+	windowRatio  float64 // モデルのコンテキストウィンドウのうち、システムプロンプト・履歴・質問に使用する割合
+	historyRatio float64 // コンテキスト長の上限のうち、履歴に使用する割合
+
+	// ForModel で設定される、トークン数を数えるリクエストのコンテキストとモデル
+	ctx   context.Context
+	model string
+
+	// estimateScale は、トークン数の見積もり（EstimateTokens）を実際のトークン数に補正する比率です（0 の場合は補正しません）
+	estimateScale float64
 }
 
-// NewContextManager は新しいContextManagerインスタンスを作成します
+// NewContextManager は、文字数で長さを管理する新しいContextManagerインスタンスを作成します
 func NewContextManager(maxContextLength, maxHistoryLength int) *ContextManager {
 	return &ContextManager{
 		maxContextLength: maxContextLength,
 		maxHistoryLength: maxHistoryLength,
+		mode:             ContextBudgetChars,
+	}
+}
+
+// NewTokenContextManager は、トークン数で長さを管理する新しいContextManagerインスタンスを作成します
+// 上限はモデルごとに決まるため、ForModel で使用するモデルを指定してから使用します
+// tokenizer が nil の場合は、EstimateTokens の見積もりで数えます
+func NewTokenContextManager(tokenizer Tokenizer, windowRatio, historyRatio float64) *ContextManager {
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
 	}
+	return &ContextManager{
+		mode:         ContextBudgetTokens,
+		tokenizer:    tokenizer,
+		windowRatio:  windowRatio,
+		historyRatio: historyRatio,
+	}
+}
+
+// Mode は、長さを数える単位を返します
+func (cm *ContextManager) Mode() ContextBudgetMode {
+	return cm.mode
+}
+
+// ForModel は、指定されたモデルのコンテキストウィンドウ（入力トークン数の上限）に合わせて上限を決めたContextManagerを返します
+// 文字数で長さを管理する場合は、モデルによらず同じ上限を使用するため自身を返します
+func (cm *ContextManager) ForModel(ctx context.Context, model string, contextWindow int) *ContextManager {
+	if cm.mode != ContextBudgetTokens {
+		return cm
+	}
+
+	scoped := *cm
+	scoped.ctx = ctx
+	scoped.model = model
+	scoped.maxContextLength = int(float64(contextWindow) * cm.windowRatio)
+	scoped.maxHistoryLength = int(float64(scoped.maxContextLength) * cm.historyRatio)
+	return &scoped
+}
+
+// WithTokenizer は、トークン数を tokenizer（ギルドのAPIキーのクライアントなど）で数えるContextManagerを返します
+// 文字数で長さを管理する場合や tokenizer が nil の場合は、自身を返します
+func (cm *ContextManager) WithTokenizer(tokenizer Tokenizer) *ContextManager {
+	if cm.mode != ContextBudgetTokens || tokenizer == nil {
+		return cm
+	}
+
+	scoped := *cm
+	scoped.tokenizer = tokenizer
+	return &scoped
+}
+
+// measure は、テキストの長さを文字数またはトークン数で返します
+// トークン数を数えられなかった場合は、EstimateTokens の見積もりを使用します
+func (cm *ContextManager) measure(text string) int {
+	if cm.mode != ContextBudgetTokens {
+		return utf8.RuneCountInString(text)
+	}
+
+	ctx := cm.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tokens, err := cm.tokenizer.CountTokens(ctx, cm.model, text)
+	if err != nil {
+		return EstimateTokens(text)
+	}
+	return tokens
+}
+
+// estimate は、テキストの長さを、APIを呼び出さずに文字数または補正したトークン数の見積もりで返します
+func (cm *ContextManager) estimate(text string) int {
+	if cm.mode != ContextBudgetTokens {
+		return utf8.RuneCountInString(text)
+	}
+	if cm.estimateScale <= 0 {
+		return EstimateTokens(text)
+	}
+	return int(math.Ceil(float64(EstimateTokens(text)) * cm.estimateScale))
+}
+
+// calibrated は、text を数えた長さ length と見積もりの比率で、見積もりを補正するContextManagerを返します
+// 同じ種類のテキストの一部の長さを、APIを呼び出し直さずに見積もるために使用します
+func (cm *ContextManager) calibrated(text string, length int) *ContextManager {
+	if cm.mode != ContextBudgetTokens {
+		return cm
+	}
+	estimated := EstimateTokens(text)
+	if estimated == 0 {
+		return cm
+	}
+
+	scoped := *cm
+	scoped.estimateScale = float64(length) / float64(estimated)
+	return &scoped
+}
+
+// messageText は、履歴に含める1件のメッセージをモデルに送信する形式（ユーザー名 + ": " + メッセージ内容 + 改行）で返します
+func messageText(msg Message) string {
+	return msg.User.DisplayName + ": " + msg.Content + "\n"
+}
+
+// historyText は、履歴に含めるメッセージを messageText の形式でつなげて返します
+func historyText(messages []Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		b.WriteString(messageText(msg))
+	}
+	return b.String()
+}
+
+// messageLength は、履歴に含める1件のメッセージの長さを返します
+// トークン数の単位では、メッセージごとにAPIを呼び出すと件数に比例して時間がかかるため、補正した見積もりを使用します
+func (cm *ContextManager) messageLength(msg Message) int {
+	return cm.estimate(messageText(msg))
 }
 
 // TruncateConversationHistory は、会話履歴を指定された長さに制限します
 func (cm *ContextManager) TruncateConversationHistory(history []Message) []Message {
+	recent, _ := cm.SplitConversationHistory(history)
+	return recent
+}
+
+// SplitConversationHistory は、会話履歴を、指定された長さに収まる直近のメッセージと、収まらずに外れる古いメッセージに分けます
+// いずれも古い順に並べて返します
+func (cm *ContextManager) SplitConversationHistory(history []Message) (recent, older []Message) {
 	if len(history) == 0 {
-		return history
+		return history, nil
 	}
 
 	messages := history
 	if len(messages) == 0 {
-		return history
+		return history, nil
 	}
 
 	// 現在の履歴の総文字数を計算
@@ -36,87 +174,109 @@ func (cm *ContextManager) TruncateConversationHistory(history []Message) []Messa
 
 	// 制限内に収まっている場合はそのまま返す
 	if totalLength <= cm.maxHistoryLength {
-		return history
+		return history, nil
 	}
 
-	// 制限を超えている場合、新しいメッセージから優先的に保持
-	truncatedMessages := cm.truncateMessagesFromNewest(messages)
-
-	return truncatedMessages
+	// 制限を超えている場合、新しいメッセージから優先的に保持（1件ごとの長さは履歴全体の長さで補正した見積もりを使用）
+	return cm.calibrated(historyText(messages), totalLength).truncateMessagesFromNewest(messages)
 }
 
 // TruncateSystemPrompt は、システムプロンプトを指定された長さに制限します
 func (cm *ContextManager) TruncateSystemPrompt(systemPrompt string) string {
-	if utf8.RuneCountInString(systemPrompt) <= cm.maxContextLength {
-		return systemPrompt
-	}
-
-	// 文字数制限を超えている場合、末尾を切り詰める
-	runes := []rune(systemPrompt)
-	if len(runes) > cm.maxContextLength {
-		runes = runes[:cm.maxContextLength]
-		// 完全な文で終わるように調整
-		lastPeriod := strings.LastIndex(string(runes), "。")
-		if lastPeriod > 0 && lastPeriod < len(runes)-50 {
-			runes = runes[:lastPeriod+1]
-		}
-	}
-
-	return string(runes)
+	return cm.truncateText(systemPrompt, 50)
 }
 
 // TruncateUserQuestion は、ユーザーの質問を指定された長さに制限します
 func (cm *ContextManager) TruncateUserQuestion(userQuestion string) string {
-	if utf8.RuneCountInString(userQuestion) <= cm.maxContextLength {
-		return userQuestion
-	}
-
-	// 文字数制限を超えている場合、末尾を切り詰める
-	runes := []rune(userQuestion)
-	if len(runes) > cm.maxContextLength {
-		runes = runes[:cm.maxContextLength]
-		// 完全な文で終わるように調整
-		lastPeriod := strings.LastIndex(string(runes), "。")
-		if lastPeriod > 0 && lastPeriod < len(runes)-30 {
-			runes = runes[:lastPeriod+1]
+	return cm.truncateText(userQuestion, 30)
+}
+
+// truncateText は、テキストの末尾を切り詰めて最大コンテキスト長に収めます
+// 切り詰めた位置から sentenceMargin 文字より前に「。」がある場合は、完全な文で終わるように調整します
+func (cm *ContextManager) truncateText(text string, sentenceMargin int) string {
+	length := cm.measure(text)
+	if length <= cm.maxContextLength {
+		return text
+	}
+
+	// 長さの比率で末尾を切り詰める（文字数では1回で収まり、トークン数では収まるまで数回切り詰め直す）
+	// 切り詰めた後の長さは、APIを呼び出し直さずに元のテキストの長さで補正した見積もりで数える
+	estimator := cm.calibrated(text, length)
+	runes := []rune(text)
+	keep := len(runes)
+	for i := 0; i < maxTruncateAttempts && length > cm.maxContextLength; i++ {
+		next := keep * cm.maxContextLength / length
+		if next >= keep {
+			next = keep - 1
+		}
+		if next <= 0 {
+			keep = 0
+			break
 		}
+		keep = next
+		length = estimator.estimate(string(runes[:keep]))
+	}
+	runes = runes[:keep]
+
+	// 完全な文で終わるように調整
+	lastPeriod := strings.LastIndex(string(runes), "。")
+	if lastPeriod > 0 && lastPeriod < len(runes)-sentenceMargin {
+		runes = runes[:lastPeriod+1]
 	}
 
 	return string(runes)
 }
 
-// calculateHistoryLength は、会話履歴の総文字数を計算します
+// calculateHistoryLength は、会話履歴の総文字数（トークン数の単位ではトークン数）を計算します
+// トークン数の単位では、履歴全体をまとめて1回で数えます
 func (cm *ContextManager) calculateHistoryLength(messages []Message) int {
-	totalLength := 0
-	for _, msg := range messages {
-		// ユーザー名 + ": " + メッセージ内容 + 改行
-		displayName := msg.User.DisplayName
-		totalLength += utf8.RuneCountInString(displayName) + 2 + utf8.RuneCountInString(msg.Content) + 1
+	if len(messages) == 0 {
+		return 0
 	}
-	return totalLength
+	return cm.measure(historyText(messages))
 }
 
-// truncateMessagesFromNewest は、新しいメッセージから優先的に保持して履歴を切り詰めます
-func (cm *ContextManager) truncateMessagesFromNewest(messages []Message) []Message {
+// truncateMessagesFromNewest は、新しいメッセージから優先的に保持して履歴を切り詰め、保持したメッセージと外れたメッセージを返します
+// 返信の連鎖のメッセージ（ReplyChain）は古くても先に保持し、残りの長さに新しいメッセージから順に詰めます
+func (cm *ContextManager) truncateMessagesFromNewest(messages []Message) (truncatedMessages, droppedMessages []Message) {
 	// メッセージを時系列順にソート（新しい順）
 	sort.Slice(messages, func(i, j int) bool {
 		return messages[i].Timestamp.After(messages[j].Timestamp)
 	})
 
-	var truncatedMessages []Message
 	currentLength := 0
+	kept := make([]bool, len(messages))
 
-	// 新しいメッセージから順に追加
-	for _, msg := range messages {
-		messageLength := utf8.RuneCountInString(msg.User.DisplayName) + 2 + utf8.RuneCountInString(msg.Content) + 1
+	// 返信の連鎖のメッセージを新しいものから順に保持
+	for i, msg := range messages {
+		if !msg.ReplyChain {
+			continue
+		}
+		if messageLength := cm.messageLength(msg); currentLength+messageLength <= cm.maxHistoryLength {
+			kept[i] = true
+			currentLength += messageLength
+		}
+	}
 
-		// このメッセージを追加しても制限内に収まる場合
-		if currentLength+messageLength <= cm.maxHistoryLength {
-			truncatedMessages = append(truncatedMessages, msg)
+	// 残りのメッセージを新しいものから順に追加し、制限を超えたらそれより古いメッセージはすべて外す
+	full := false
+	for i, msg := range messages {
+		if msg.ReplyChain || full {
+			continue
+		}
+		if messageLength := cm.messageLength(msg); currentLength+messageLength <= cm.maxHistoryLength {
+			kept[i] = true
 			currentLength += messageLength
 		} else {
-			// 制限を超える場合は終了
-			break
+			full = true
+		}
+	}
+
+	for i, msg := range messages {
+		if kept[i] {
+			truncatedMessages = append(truncatedMessages, msg)
+		} else {
+			droppedMessages = append(droppedMessages, msg)
 		}
 	}
 
@@ -124,15 +284,18 @@ func (cm *ContextManager) truncateMessagesFromNewest(messages []Message) []Messa
 	sort.Slice(truncatedMessages, func(i, j int) bool {
 		return truncatedMessages[i].Timestamp.Before(truncatedMessages[j].Timestamp)
 	})
+	sort.Slice(droppedMessages, func(i, j int) bool {
+		return droppedMessages[i].Timestamp.Before(droppedMessages[j].Timestamp)
+	})
 
-	return truncatedMessages
+	return truncatedMessages, droppedMessages
 }
 
 // GetContextStats は、コンテキストの統計情報を返します
 func (cm *ContextManager) GetContextStats(systemPrompt string, history []Message, userQuestion string) ContextStats {
-	systemLength := utf8.RuneCountInString(systemPrompt)
+	systemLength := cm.measure(systemPrompt)
 	historyLength := cm.calculateHistoryLength(history)
-	questionLength := utf8.RuneCountInString(userQuestion)
+	questionLength := cm.measure(userQuestion)
 	totalLength := systemLength + historyLength + questionLength
 
 	return ContextStats{
@@ -142,6 +305,7 @@ func (cm *ContextManager) GetContextStats(systemPrompt string, history []Message
 		TotalLength:        totalLength,
 		MaxContextLength:   cm.maxContextLength,
 		MaxHistoryLength:   cm.maxHistoryLength,
+		Mode:               cm.mode,
 		IsTruncated:        totalLength > cm.maxContextLength || historyLength > cm.maxHistoryLength,
 	}
 }
@@ -154,5 +318,6 @@ type ContextStats struct {
 	TotalLength        int
 	MaxContextLength   int
 	MaxHistoryLength   int
+	Mode               ContextBudgetMode // 長さの単位（文字数またはトークン数）
 	IsTruncated        bool
 }
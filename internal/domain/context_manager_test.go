@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -212,3 +214,134 @@ func TestContextManager_calculateHistoryLength(t *testing.T) {
 		t.Errorf("期待される履歴長: %d, 実際の履歴長: %d", expectedLength, length)
 	}
 }
+
+// fixedTokenizer は、1文字を perRune トークンとして数えるテスト用のTokenizerです
+type fixedTokenizer struct {
+	perRune int
+	models  []string
+}
+
+func (f *fixedTokenizer) CountTokens(_ context.Context, model string, text string) (int, error) {
+	f.models = append(f.models, model)
+	return utf8.RuneCountInString(text) * f.perRune, nil
+}
+
+func TestContextManager_ForModel_ScalesBudgetWithContextWindow(t *testing.T) {
+	tokenizer := &fixedTokenizer{perRune: 2}
+	manager := NewTokenContextManager(tokenizer, 0.1, 0.5)
+
+	small := manager.ForModel(context.Background(), "small-model", 1000)
+	large := manager.ForModel(context.Background(), "large-model", 10000)
+
+	if stats := small.GetContextStats("", nil, ""); stats.MaxContextLength != 100 || stats.MaxHistoryLength != 50 || stats.Mode != ContextBudgetTokens {
+		t.Errorf("小さいモデルの上限が正しくありません: %+v", stats)
+	}
+	if stats := large.GetContextStats("", nil, ""); stats.MaxContextLength != 1000 || stats.MaxHistoryLength != 500 {
+		t.Errorf("大きいモデルの上限が正しくありません: %+v", stats)
+	}
+
+	// 60文字は120トークンなので、小さいモデルでは上限の100トークン以内に切り詰める
+	question := strings.Repeat("あ", 60)
+	if got := small.TruncateUserQuestion(question); utf8.RuneCountInString(got)*2 > 100 {
+		t.Errorf("トークン数の上限に収まるよう切り詰められていません: %d文字", utf8.RuneCountInString(got))
+	}
+	if got := large.TruncateUserQuestion(question); got != question {
+		t.Error("上限に収まる質問は変更されるべきではありません")
+	}
+	if tokenizer.models[len(tokenizer.models)-1] != "large-model" {
+		t.Errorf("ForModel で指定したモデルでトークン数を数えるべきです: %v", tokenizer.models)
+	}
+}
+
+func TestContextManager_ForModel_CharsModeIgnoresModel(t *testing.T) {
+	manager := NewContextManager(8000, 4000)
+	if scoped := manager.ForModel(context.Background(), "gemini-2.5-pro", 1_000_000); scoped != manager {
+		t.Error("文字数で数える場合はモデルによらず同じ上限を使用するべきです")
+	}
+}
+
+func TestContextManager_TokenMode_TruncatesHistoryByTokens(t *testing.T) {
+	manager := NewTokenContextManager(&fixedTokenizer{perRune: 1}, 1, 0.5).ForModel(context.Background(), "model", 60)
+
+	now := time.Now()
+	history := []Message{
+		{ID: "old", User: User{DisplayName: "A"}, Content: strings.Repeat("古", 20), Timestamp: now.Add(-time.Minute)},
+		{ID: "new", User: User{DisplayName: "B"}, Content: strings.Repeat("新", 20), Timestamp: now},
+	}
+
+	// 1件あたり「B: 」+ 20文字 + 改行 = 24トークン、履歴の上限は30トークン
+	result := manager.TruncateConversationHistory(history)
+	if len(result) != 1 || result[0].ID != "new" {
+		t.Errorf("履歴の上限を超えた場合は新しいメッセージを優先して保持するべきです: %+v", result)
+	}
+}
+
+func TestContextManager_TokenMode_CountsHistoryInOneCall(t *testing.T) {
+	defaultTokenizer := &fixedTokenizer{perRune: 1}
+	guildTokenizer := &fixedTokenizer{perRune: 1}
+	manager := NewTokenContextManager(defaultTokenizer, 1, 0.5).ForModel(context.Background(), "model", 600).WithTokenizer(guildTokenizer)
+
+	now := time.Now()
+	var history []Message
+	for i := 0; i < 50; i++ {
+		history = append(history, Message{ID: fmt.Sprintf("m%d", i), User: User{DisplayName: "A"}, Content: strings.Repeat("あ", 20), Timestamp: now.Add(time.Duration(i) * time.Second)})
+	}
+
+	// 履歴が上限を超えて切り詰める場合も、トークン数を数えるのは履歴全体の1回のみ
+	recent, older := manager.SplitConversationHistory(history)
+	if len(guildTokenizer.models) != 1 {
+		t.Errorf("履歴はまとめて1回で数えるべきです: %d回", len(guildTokenizer.models))
+	}
+	if len(defaultTokenizer.models) != 0 {
+		t.Errorf("WithTokenizer で指定したTokenizerで数えるべきです: 既定のTokenizerの呼び出し %d回", len(defaultTokenizer.models))
+	}
+
+	// 1件あたり「A: 」+ 20文字 + 改行 = 24トークン、履歴の上限は300トークン
+	if len(recent) != 12 || len(older) != 38 || recent[len(recent)-1].ID != "m49" {
+		t.Errorf("履歴の上限に収まる新しいメッセージを保持するべきです: 保持 %d件, 外れた %d件", len(recent), len(older))
+	}
+}
+
+func TestContextManager_SplitConversationHistory(t *testing.T) {
+	manager := NewContextManager(8000, 20)
+
+	now := time.Now()
+	history := []Message{
+		{ID: "new", User: User{DisplayName: "B"}, Content: "新しい", Timestamp: now},
+		{ID: "oldest", User: User{DisplayName: "A"}, Content: strings.Repeat("古", 30), Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "old", User: User{DisplayName: "A"}, Content: strings.Repeat("古", 30), Timestamp: now.Add(-time.Hour)},
+	}
+
+	recent, older := manager.SplitConversationHistory(history)
+	if len(recent) != 1 || recent[0].ID != "new" {
+		t.Errorf("直近のメッセージが正しくありません: %+v", recent)
+	}
+	if len(older) != 2 || older[0].ID != "oldest" || older[1].ID != "old" {
+		t.Errorf("外れたメッセージは古い順に返すべきです: %+v", older)
+	}
+
+	if recent, older := manager.SplitConversationHistory(recent); len(recent) != 1 || older != nil {
+		t.Errorf("上限に収まる履歴は外れるメッセージがないべきです: %+v, %+v", recent, older)
+	}
+}
+
+func TestContextManager_SplitConversationHistory_PrioritizesReplyChain(t *testing.T) {
+	// 1件あたり "A: " + 5文字 + 改行 = 9文字
+	manager := NewContextManager(8000, 27)
+
+	now := time.Now()
+	history := []Message{
+		{ID: "target", User: User{DisplayName: "A"}, Content: "返信先です", Timestamp: now.Add(-3 * time.Hour), ReplyChain: true, ReplyTarget: true},
+		{ID: "old", User: User{DisplayName: "A"}, Content: "古いです。", Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "recent1", User: User{DisplayName: "A"}, Content: "最近です。", Timestamp: now.Add(-time.Hour)},
+		{ID: "recent2", User: User{DisplayName: "A"}, Content: "最新です。", Timestamp: now},
+	}
+
+	recent, older := manager.SplitConversationHistory(history)
+	if len(recent) != 3 || recent[0].ID != "target" || recent[1].ID != "recent1" || recent[2].ID != "recent2" {
+		t.Errorf("返信の連鎖のメッセージを優先し、残りを新しいメッセージで埋めるべきです: %+v", recent)
+	}
+	if len(older) != 1 || older[0].ID != "old" {
+		t.Errorf("外れたメッセージが正しくありません: %+v", older)
+	}
+}
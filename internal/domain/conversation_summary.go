@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// ConversationSummary は、コンテキスト長の上限を超えて履歴から外れた古いメッセージを、チャンネルまたはスレッドごとに要約したものです
+// 新しく履歴から外れたメッセージがあるたびに、前回の要約に取り込んで更新します
+type ConversationSummary struct {
+	ChannelID    string    // 要約の対象のチャンネルまたはスレッドのID
+	Content      string    // これまでの会話の要約
+	CoveredUntil time.Time // 要約に含めた最も新しいメッセージの投稿日時
+	UpdatedAt    time.Time
+}
+
+// IsEmpty は、要約がまだ作成されていないかどうかを返します
+func (s ConversationSummary) IsEmpty() bool {
+	return strings.TrimSpace(s.Content) == ""
+}
+
+// UnsummarizedMessages は、messages のうち、まだ要約に含めていないメッセージを返します
+func (s ConversationSummary) UnsummarizedMessages(messages []Message) []Message {
+	var unsummarized []Message
+	for _, msg := range messages {
+		if msg.Timestamp.After(s.CoveredUntil) {
+			unsummarized = append(unsummarized, msg)
+		}
+	}
+	return unsummarized
+}
+
+// WithConversationSummary は、システムプロンプトの後ろに、直近の履歴より前の会話の要約のセクションを加えます
+func WithConversationSummary(systemPrompt string, summary ConversationSummary) string {
+	if summary.IsEmpty() {
+		return systemPrompt
+	}
+
+	section := "## これまでの会話の要約\n以下は、この後に続く直近の会話より前のやり取りの要約です。必要に応じて参考にしてください。\n" + strings.TrimSpace(summary.Content)
+	if systemPrompt == "" {
+		return section
+	}
+	return systemPrompt + "\n\n" + section
+}
+
+// ConversationSummaryStore は、チャンネルまたはスレッドごとの会話の要約を保存するストアのインターフェースです
+type ConversationSummaryStore interface {
+	// GetConversationSummary は、チャンネルの会話の要約を取得します（要約がない場合は空の要約を返します）
+	GetConversationSummary(ctx context.Context, channelID string) (ConversationSummary, error)
+	// SaveConversationSummary は、チャンネルの会話の要約を保存します
+	SaveConversationSummary(ctx context.Context, summary ConversationSummary) error
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConversationSummary_UnsummarizedMessages(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	summary := ConversationSummary{Content: "要約", CoveredUntil: base.Add(time.Minute)}
+	messages := []Message{
+		{ID: "m1", Timestamp: base},
+		{ID: "m2", Timestamp: base.Add(time.Minute)},
+		{ID: "m3", Timestamp: base.Add(2 * time.Minute)},
+	}
+
+	got := summary.UnsummarizedMessages(messages)
+	if len(got) != 1 || got[0].ID != "m3" {
+		t.Errorf("要約に含めた日時より後のメッセージのみを返すべきです: %+v", got)
+	}
+}
+
+func TestWithConversationSummary(t *testing.T) {
+	if got := WithConversationSummary("プロンプト", ConversationSummary{}); got != "プロンプト" {
+		t.Errorf("要約がない場合はシステムプロンプトを変更するべきではありません: %s", got)
+	}
+
+	got := WithConversationSummary("プロンプト", ConversationSummary{Content: "デプロイの手順を決めた"})
+	want := "プロンプト\n\n## これまでの会話の要約\n以下は、この後に続く直近の会話より前のやり取りの要約です。必要に応じて参考にしてください。\nデプロイの手順を決めた"
+	if got != want {
+		t.Errorf("WithConversationSummary() = %q, 期待値: %q", got, want)
+	}
+}
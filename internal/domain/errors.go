@@ -1,6 +1,10 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // ドメイン固有のエラー型を定義
 var (
@@ -18,4 +22,71 @@ var (
 
 	// ErrInvalidUserID は、無効なユーザーIDの場合のエラーです
 	ErrInvalidUserID = errors.New("無効なユーザーIDです")
+
+	// ErrUnsupportedAttachment は、Geminiに渡せない形式の添付ファイルの場合のエラーです
+	ErrUnsupportedAttachment = errors.New("対応していない形式の添付ファイルです")
+
+	// ErrAttachmentTooLarge は、添付ファイルがサイズ上限を超えている場合のエラーです
+	ErrAttachmentTooLarge = errors.New("添付ファイルのサイズが上限を超えています")
+
+	// ErrRateLimited は、ユーザー・チャンネル・ギルドごとのリクエスト数の上限を超えた場合のエラーです
+	ErrRateLimited = errors.New("レート制限を超過しました")
+
+	// ErrSpamDetected は、レート制限を超えた後も短時間にリクエストを繰り返した場合のエラーです
+	ErrSpamDetected = errors.New("スパムが検出されました")
+
+	// ErrInappropriateContent は、禁止ワードを含むメッセージの場合のエラーです
+	ErrInappropriateContent = errors.New("不適切なコンテンツが検出されました")
+
+	// ErrMessageTooLong は、メッセージが長すぎる場合のエラーです
+	ErrMessageTooLong = errors.New("メッセージが長すぎます")
+
+	// ErrDuplicateMessage は、同じ内容のメッセージが連続で送信された場合のエラーです
+	ErrDuplicateMessage = errors.New("重複メッセージが検出されました")
+
+	// ErrInvalidJSONOutput は、モデルの出力が指定したJSONスキーマに合わない場合のエラーです
+	ErrInvalidJSONOutput = errors.New("モデルの出力が指定したJSONスキーマに合いません")
+
+	// ErrQuotaExceeded は、Gemini APIの利用制限（クォータ）に達した場合のエラーです
+	ErrQuotaExceeded = errors.New("Gemini APIの利用制限に達しました")
+
+	// ErrUnauthorized は、APIキーが無効、またはモデルへのアクセス権限がない場合のエラーです
+	ErrUnauthorized = errors.New("Gemini APIへのアクセス権限がありません")
+
+	// ErrSafetyBlocked は、安全フィルターによって応答がブロックされた場合のエラーです
+	// ブロックされたカテゴリは SafetyBlockedError で受け取れます
+	ErrSafetyBlocked = errors.New("Gemini APIの安全フィルターによって応答がブロックされました")
+
+	// ErrRecitation は、著作権で保護された内容の引用を検出して応答が停止された場合のエラーです
+	ErrRecitation = errors.New("Gemini APIが著作権保護された内容を検出しました")
+
+	// ErrMaxTokens は、応答が最大トークン数に達して本文を生成できなかった場合のエラーです
+	ErrMaxTokens = errors.New("Gemini APIの応答が最大トークン数に達しました")
+
+	// ErrTimeout は、外部APIへのリクエストがタイムアウトした場合のエラーです
+	// メッセージは「〜が%w」の形で処理名に続けて使用します
+	ErrTimeout = errors.New("タイムアウトしました")
+
+	// ErrUpstreamUnavailable は、Gemini APIの過負荷や一時的な障害、ネットワークエラーで応答を得られなかった場合のエラーです
+	ErrUpstreamUnavailable = errors.New("Gemini APIが一時的に利用できません")
 )
+
+// SafetyBlockedError は、安全フィルターによって応答がブロックされた場合のエラーです
+// errors.Is で ErrSafetyBlocked と一致します
+type SafetyBlockedError struct {
+	// Categories は、ブロックの原因となったカテゴリの表示名です（不明な場合は空）
+	Categories []string
+}
+
+// Error は、エラーメッセージを返します
+func (e *SafetyBlockedError) Error() string {
+	if len(e.Categories) == 0 {
+		return ErrSafetyBlocked.Error()
+	}
+	return fmt.Sprintf("%s（%s）", ErrSafetyBlocked, strings.Join(e.Categories, "、"))
+}
+
+// Is は、errors.Is で安全フィルターのエラーと判定できるようにします
+func (e *SafetyBlockedError) Is(target error) bool {
+	return target == ErrSafetyBlocked
+}
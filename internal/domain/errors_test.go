@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSafetyBlockedError_Is(t *testing.T) {
+	var err error = fmt.Errorf("応答の生成に失敗: %w", &SafetyBlockedError{Categories: []string{"ハラスメント", "危険なコンテンツ"}})
+	if !errors.Is(err, ErrSafetyBlocked) {
+		t.Errorf("安全フィルターのエラーとして判定されていません: %v", err)
+	}
+
+	var safetyErr *SafetyBlockedError
+	if !errors.As(err, &safetyErr) || len(safetyErr.Categories) != 2 {
+		t.Fatalf("ブロックされたカテゴリを取り出せません: %v", err)
+	}
+	if want := "Gemini APIの安全フィルターによって応答がブロックされました（ハラスメント、危険なコンテンツ）"; safetyErr.Error() != want {
+		t.Errorf("Error() = %q, 期待値: %q", safetyErr.Error(), want)
+	}
+
+	if got := (&SafetyBlockedError{}).Error(); got != ErrSafetyBlocked.Error() {
+		t.Errorf("カテゴリが不明な場合のError() = %q, 期待値: %q", got, ErrSafetyBlocked.Error())
+	}
+}
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultExtractionMessageCount は、/extract で抽出の対象とする既定のメッセージ数です
+const DefaultExtractionMessageCount = 50
+
+// ExtractionField は、抽出する項目の1つのフィールドです
+type ExtractionField struct {
+	Key         string // JSON のプロパティ名
+	Label       string // 表示名
+	Description string // モデルに渡すフィールドの説明
+	Required    bool
+}
+
+// ExtractionPreset は、会話から構造化データを抽出するときのスキーマのプリセットです
+// 抽出結果は、ListKey のプロパティに項目の配列を持つ JSON オブジェクトです
+type ExtractionPreset struct {
+	ID          string
+	DisplayName string
+	Emoji       string
+	Instruction string            // モデルへの抽出の指示
+	ListKey     string            // 項目の配列を持つプロパティ名
+	Fields      []ExtractionField // 項目のフィールド（先頭のフィールドを項目の見出しとして表示します）
+}
+
+// 抽出のプリセット
+var (
+	// ExtractionActionItems は、会話からアクションアイテム（やるべきこと）を抽出するプリセットです
+	ExtractionActionItems = ExtractionPreset{
+		ID:          "action-items",
+		DisplayName: "アクションアイテム",
+		Emoji:       "✅",
+		Instruction: "会話から、誰かがやるべきこととして合意・依頼・宣言されたアクションアイテムをすべて抽出してください。",
+		ListKey:     "action_items",
+		Fields: []ExtractionField{
+			{Key: "task", Label: "タスク", Description: "やるべきことの内容", Required: true},
+			{Key: "assignee", Label: "担当者", Description: "担当者の表示名（不明な場合は空文字）"},
+			{Key: "due", Label: "期限", Description: "期限（会話で言及された表現のまま。不明な場合は空文字）"},
+		},
+	}
+
+	// ExtractionDecisions は、会話から決定事項を抽出するプリセットです
+	ExtractionDecisions = ExtractionPreset{
+		ID:          "decisions",
+		DisplayName: "決定事項",
+		Emoji:       "📌",
+		Instruction: "会話の中で合意・決定された事項をすべて抽出してください。検討中のものや却下されたものは含めないでください。",
+		ListKey:     "decisions",
+		Fields: []ExtractionField{
+			{Key: "decision", Label: "決定事項", Description: "決定した内容", Required: true},
+			{Key: "rationale", Label: "理由", Description: "決定の理由（会話で述べられていない場合は空文字）"},
+			{Key: "decided_by", Label: "決定者", Description: "決定した人の表示名（不明な場合は空文字）"},
+		},
+	}
+
+	// ExtractionQAPairs は、会話から質問と回答の組を抽出するプリセットです
+	ExtractionQAPairs = ExtractionPreset{
+		ID:          "qa-pairs",
+		DisplayName: "Q&A",
+		Emoji:       "❓",
+		Instruction: "会話の中の質問と、それに対する回答の組をすべて抽出してください。回答されていない質問は answer を空文字にしてください。",
+		ListKey:     "qa_pairs",
+		Fields: []ExtractionField{
+			{Key: "question", Label: "質問", Description: "質問の内容", Required: true},
+			{Key: "answer", Label: "回答", Description: "回答の内容（回答されていない場合は空文字）", Required: true},
+			{Key: "asked_by", Label: "質問者", Description: "質問した人の表示名"},
+		},
+	}
+)
+
+// ExtractionPresets は、利用できる抽出のプリセットを返します
+func ExtractionPresets() []ExtractionPreset {
+	return []ExtractionPreset{ExtractionActionItems, ExtractionDecisions, ExtractionQAPairs}
+}
+
+// FindExtractionPreset は、IDで抽出のプリセットを探します
+func FindExtractionPreset(id string) (ExtractionPreset, bool) {
+	for _, preset := range ExtractionPresets() {
+		if preset.ID == id {
+			return preset, true
+		}
+	}
+	return ExtractionPreset{}, false
+}
+
+// Schema は、抽出結果の JSON Schema を返します
+func (p ExtractionPreset) Schema() map[string]any {
+	properties := make(map[string]any, len(p.Fields))
+	var required []any
+	for _, field := range p.Fields {
+		properties[field.Key] = map[string]any{
+			"type":        "string",
+			"description": field.Description,
+		}
+		if field.Required {
+			required = append(required, field.Key)
+		}
+	}
+
+	item := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		item["required"] = required
+	}
+
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			p.ListKey: map[string]any{
+				"type":  "array",
+				"items": item,
+			},
+		},
+		"required": []any{p.ListKey},
+	}
+}
+
+// ParseItems は、スキーマで検証済みの抽出結果の JSON から項目を取り出します（文字列でないフィールドは無視します）
+func (p ExtractionPreset) ParseItems(data []byte) ([]map[string]string, error) {
+	var result map[string][]map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("抽出結果の解析に失敗: %w", err)
+	}
+
+	items := make([]map[string]string, 0, len(result[p.ListKey]))
+	for _, raw := range result[p.ListKey] {
+		item := make(map[string]string, len(p.Fields))
+		for _, field := range p.Fields {
+			if value, ok := raw[field.Key].(string); ok {
+				item[field.Key] = value
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ExtractionRequest は、会話から構造化データを抽出するリクエストです
+type ExtractionRequest struct {
+	Preset          ExtractionPreset
+	GuildID         string
+	ChannelID       string // 抽出の対象のチャンネルまたはスレッドのID
+	ParentChannelID string // スレッドの場合の親チャンネルのID（通常チャンネルでは空）
+	UserID          string
+	MessageCount    int // 抽出の対象とする直近のメッセージ数（0の場合は DefaultExtractionMessageCount）
+}
+
+// IsThread は、抽出の対象がスレッドかどうかを返します
+func (r ExtractionRequest) IsThread() bool {
+	return r.ParentChannelID != ""
+}
+
+// SettingsChannelID は、チャンネル設定の解決に使用するチャンネルIDを返します（スレッドでは親チャンネル）
+func (r ExtractionRequest) SettingsChannelID() string {
+	if r.ParentChannelID != "" {
+		return r.ParentChannelID
+	}
+	return r.ChannelID
+}
+
+// ExtractionResult は、会話から抽出した構造化データです
+type ExtractionResult struct {
+	Preset       ExtractionPreset
+	JSON         []byte              // スキーマで検証済みの JSON
+	Items        []map[string]string // 抽出した項目（表示用）
+	MessageCount int                 // 抽出の対象としたメッセージ数
+	Model        string
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestFindExtractionPreset(t *testing.T) {
+	for _, preset := range ExtractionPresets() {
+		found, ok := FindExtractionPreset(preset.ID)
+		if !ok || found.ID != preset.ID {
+			t.Errorf("FindExtractionPreset(%q) = %+v, %v", preset.ID, found, ok)
+		}
+	}
+	if _, ok := FindExtractionPreset("unknown"); ok {
+		t.Error("存在しないプリセットは見つからないべきです")
+	}
+}
+
+func TestExtractionPreset_SchemaAndParseItems(t *testing.T) {
+	preset := ExtractionActionItems
+	schema := preset.Schema()
+
+	data := []byte(`{"action_items":[{"task":"議事録を共有する","assignee":"Alice","due":"金曜"},{"task":"見積もりを出す"}]}`)
+	if err := ValidateJSON(schema, data); err != nil {
+		t.Fatalf("スキーマに合う抽出結果の検証に失敗: %v", err)
+	}
+	if err := ValidateJSON(schema, []byte(`{"action_items":[{"assignee":"Alice"}]}`)); err == nil {
+		t.Error("task のない項目はスキーマに合わないべきです")
+	}
+	if err := ValidateJSON(schema, []byte(`{"decisions":[]}`)); err == nil {
+		t.Error("プリセットの配列のプロパティがない場合はスキーマに合わないべきです")
+	}
+
+	items, err := preset.ParseItems(data)
+	if err != nil {
+		t.Fatalf("ParseItems() でエラーが発生しました: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("項目数 = %d, 期待値: 2", len(items))
+	}
+	if items[0]["task"] != "議事録を共有する" || items[0]["assignee"] != "Alice" || items[0]["due"] != "金曜" {
+		t.Errorf("1件目の項目 = %v", items[0])
+	}
+	if _, ok := items[1]["assignee"]; ok {
+		t.Errorf("含まれていないフィールドは設定しないべきです: %v", items[1])
+	}
+}
+
+func TestExtractionRequest_SettingsChannelID(t *testing.T) {
+	channel := ExtractionRequest{ChannelID: "general"}
+	if channel.IsThread() || channel.SettingsChannelID() != "general" {
+		t.Errorf("通常チャンネルでは自身のIDを使用するべきです: %+v", channel)
+	}
+
+	thread := ExtractionRequest{ChannelID: "thread1", ParentChannelID: "general"}
+	if !thread.IsThread() || thread.SettingsChannelID() != "general" {
+		t.Errorf("スレッドでは親チャンネルのIDを使用するべきです: %+v", thread)
+	}
+}
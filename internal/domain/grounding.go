@@ -0,0 +1,18 @@
+package domain
+
+// GroundingSource は、Google 検索によるグラウンディングで回答の根拠として参照したWebページです
+type GroundingSource struct {
+	Title string // ページのタイトル（取得できない場合はドメイン名など）
+	URI   string
+}
+
+// Grounding は、Google 検索によるグラウンディングの結果（実行した検索クエリと参照したWebページ）です
+type Grounding struct {
+	SearchQueries []string
+	Sources       []GroundingSource // 引用番号の順（1番目が [1]）
+}
+
+// HasSources は、回答の根拠として参照したWebページがあるかどうかを返します
+func (g *Grounding) HasSources() bool {
+	return g != nil && len(g.Sources) > 0
+}
@@ -5,6 +5,12 @@ import (
 	"time"
 )
 
+// apiKeyFingerprintChars は、フィンガープリントとして表示するAPIキー先頭・末尾の文字数です
+const apiKeyFingerprintChars = 4
+
+// MaxGuildSystemPromptLength は、ギルド固有のシステムプロンプトの最大文字数です（Discordのモーダル入力の上限に合わせています）
+const MaxGuildSystemPromptLength = 4000
+
 // GuildAPIKey は、Discordサーバー（ギルド）固有のAPIキーを表します
 type GuildConfig struct {
 	GuildID string
@@ -12,6 +18,39 @@ type GuildConfig struct {
 	SetBy   string
 	SetAt   time.Time
 	Model   string
+
+	// SystemPrompt は、ギルド固有のシステムプロンプトです（空の場合は全体の既定のプロンプトを使用します）
+	SystemPrompt string
+
+	// SearchGrounding は、Google 検索によるグラウンディングをギルド全体で使用するかどうかです
+	SearchGrounding bool
+
+	// Thinking は、ギルド全体の思考の設定です（思考予算が nil の場合は全体の既定を使用します）
+	Thinking ThinkingSettings
+
+	// FallbackModels は、ギルドのモデルで生成できなかった場合に順に使用するモデルです（空の場合は全体の既定を使用します）
+	FallbackModels []string
+
+	// APIKeyFingerprint は、APIキーをマスクした識別用の文字列です（例: AIza…3f9c）
+	APIKeyFingerprint string
+}
+
+// FingerprintAPIKey は、APIキーの先頭と末尾のみを残したマスク文字列を返します
+// キーが短く先頭・末尾が重なる場合は、末尾のみを表示します
+func FingerprintAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+
+	runes := []rune(apiKey)
+	if len(runes) <= apiKeyFingerprintChars*2 {
+		if len(runes) <= apiKeyFingerprintChars {
+			return "…"
+		}
+		return "…" + string(runes[len(runes)-apiKeyFingerprintChars:])
+	}
+
+	return string(runes[:apiKeyFingerprintChars]) + "…" + string(runes[len(runes)-apiKeyFingerprintChars:])
 }
 
 // GuildConfigManager は、ギルド固有のAPIキーの永続化を行うインターフェースです
@@ -36,4 +75,29 @@ type GuildConfigManager interface {
 
 	// GetGuildModel は、指定されたギルドのAIモデルを取得します
 	GetGuildModel(ctx context.Context, guildID string) (string, error)
+
+	// SetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを設定します
+	// 空文字を指定すると、全体の既定のプロンプトを使用する状態に戻ります
+	SetGuildSystemPrompt(ctx context.Context, guildID string, prompt string) error
+
+	// GetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを取得します（未設定の場合は空文字）
+	GetGuildSystemPrompt(ctx context.Context, guildID string) (string, error)
+
+	// SetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを設定します
+	SetGuildSearchGrounding(ctx context.Context, guildID string, enabled bool) error
+
+	// GetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを取得します（未設定の場合は false）
+	GetGuildSearchGrounding(ctx context.Context, guildID string) (bool, error)
+
+	// SetGuildThinking は、指定されたギルドの思考の設定を保存します
+	SetGuildThinking(ctx context.Context, guildID string, thinking ThinkingSettings) error
+
+	// GetGuildThinking は、指定されたギルドの思考の設定を取得します（未設定の場合はゼロ値）
+	GetGuildThinking(ctx context.Context, guildID string) (ThinkingSettings, error)
+
+	// SetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを保存します（空の場合は全体の既定に戻します）
+	SetGuildFallbackModels(ctx context.Context, guildID string, models []string) error
+
+	// GetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを取得します（未設定の場合は空）
+	GetGuildFallbackModels(ctx context.Context, guildID string) ([]string, error)
 }
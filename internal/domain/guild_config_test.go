@@ -0,0 +1,21 @@
+package domain
+
+import "testing"
+
+func TestFingerprintAPIKey(t *testing.T) {
+	tests := []struct {
+		apiKey string
+		want   string
+	}{
+		{apiKey: "AIzaSyD-example-key-3f9c", want: "AIza…3f9c"},
+		{apiKey: "abcdefgh", want: "…efgh"},
+		{apiKey: "abc", want: "…"},
+		{apiKey: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := FingerprintAPIKey(tt.apiKey); got != tt.want {
+			t.Errorf("FingerprintAPIKey(%q) = %q, 期待値: %q", tt.apiKey, got, tt.want)
+		}
+	}
+}
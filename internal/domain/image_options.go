@@ -32,6 +32,9 @@ const (
 	ImageSize768x1024
 )
 
+// MaxImageCount は、1回のリクエストで生成できる画像の最大枚数です
+const MaxImageCount = 4
+
 // discordOptionData はImageStyle, ImageQuality, ImageSizeのデータを保持します
 type discordOptionData struct {
 	Value       string
@@ -64,6 +67,15 @@ var imageSizes = []discordOptionData{
 	{"768x1024", "768x1024"},
 }
 
+// imageAspectRatios は各ImageSizeに対応する、画像モデルが対応しているアスペクト比です
+// 画像モデルは出力解像度を指定できないため、サイズは縦横比としてのみ反映されます
+var imageAspectRatios = []string{
+	"1:1",
+	"1:1",
+	"4:3",
+	"3:4",
+}
+
 // String はImageStyleの英語名を返します
 func (s ImageStyle) String() string {
 	if int(s) >= 0 && int(s) < len(imageStyles) {
@@ -112,6 +124,14 @@ func (s ImageSize) DisplayName() string {
 	return "512x512"
 }
 
+// AspectRatio はImageSizeに対応するアスペクト比（"4:3" など）を返します
+func (s ImageSize) AspectRatio() string {
+	if int(s) >= 0 && int(s) < len(imageAspectRatios) {
+		return imageAspectRatios[s]
+	}
+	return "1:1"
+}
+
 // AllImageStyles はすべてのImageStyleを返します
 func AllImageStyles() []ImageStyle {
 	return []ImageStyle{
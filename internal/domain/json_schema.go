@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ValidateJSON は、data が JSON として正しく、schema（JSON Schema）に合っているかどうかを検証します
+// 検証するのは type・properties・required・items・enum のみで、それ以外のキーワードは無視します
+// 合わない場合は、最初に見つかった誤りの位置を含む ErrInvalidJSONOutput を返します
+func ValidateJSON(schema map[string]any, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%w: JSONとして解析できません: %v", ErrInvalidJSONOutput, err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("%w: JSONの後ろに余分な内容があります", ErrInvalidJSONOutput)
+	}
+
+	if err := validateJSONValue(schema, value, "$"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSONOutput, err)
+	}
+	return nil
+}
+
+// validateJSONValue は、path の位置にある値を schema で検証します
+func validateJSONValue(schema map[string]any, value any, path string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if schemaType, ok := schema["type"].(string); ok && !matchesJSONType(schemaType, value) {
+		return fmt.Errorf("%s は %s であるべきです（実際: %s）", path, schemaType, jsonTypeName(value))
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 && !containsJSONValue(enum, value) {
+		return fmt.Errorf("%s は %v のいずれかであるべきです", path, enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range requiredProperties(schema) {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s に必須のプロパティ %s がありません", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertySchema, _ := properties[name].(map[string]any)
+			if err := validateJSONValue(propertySchema, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range v {
+			if err := validateJSONValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// requiredProperties は、スキーマの required に指定されたプロパティ名を返します
+func requiredProperties(schema map[string]any) []string {
+	switch required := schema["required"].(type) {
+	case []string:
+		return required
+	case []any:
+		names := make([]string, 0, len(required))
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	default:
+		return nil
+	}
+}
+
+// matchesJSONType は、値が JSON Schema の type に合っているかどうかを返します
+func matchesJSONType(schemaType string, value any) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	default:
+		return true
+	}
+}
+
+// jsonTypeName は、値の JSON での型名を返します
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// containsJSONValue は、enum に値が含まれているかどうかを返します（値は文字列の表現で比較します）
+func containsJSONValue(enum []any, value any) bool {
+	for _, candidate := range enum {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateJSON(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"items": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"task":     map[string]any{"type": "string"},
+						"priority": map[string]any{"type": "string", "enum": []any{"high", "low"}},
+						"count":    map[string]any{"type": "integer"},
+					},
+					"required": []any{"task"},
+				},
+			},
+		},
+		"required": []any{"items"},
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		wantPath string // 誤りの位置（空の場合は検証に成功することを期待します）
+	}{
+		{name: "スキーマに合う", data: `{"items":[{"task":"資料作成","priority":"high","count":2}]}`},
+		{name: "空の配列", data: `{"items":[]}`},
+		{name: "必須のプロパティがない", data: `{}`, wantPath: "$"},
+		{name: "項目の必須のプロパティがない", data: `{"items":[{"priority":"low"}]}`, wantPath: "$.items[0]"},
+		{name: "型が異なる", data: `{"items":[{"task":1}]}`, wantPath: "$.items[0].task"},
+		{name: "整数でない", data: `{"items":[{"task":"a","count":1.5}]}`, wantPath: "$.items[0].count"},
+		{name: "enumにない値", data: `{"items":[{"task":"a","priority":"medium"}]}`, wantPath: "$.items[0].priority"},
+		{name: "JSONでない", data: `items: []`, wantPath: "JSON"},
+		{name: "余分な内容がある", data: `{"items":[]} {}`, wantPath: "余分"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSON(schema, []byte(tt.data))
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Errorf("ValidateJSON() = %v, 期待値: nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidJSONOutput) {
+				t.Fatalf("ValidateJSON() = %v, ErrInvalidJSONOutput を返すべきです", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantPath) {
+				t.Errorf("エラーに %q が含まれていません: %v", tt.wantPath, err)
+			}
+		})
+	}
+}
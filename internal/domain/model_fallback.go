@@ -0,0 +1,45 @@
+package domain
+
+import "strings"
+
+// MaxFallbackModels は、フォールバックチェーンに指定できるモデルの最大数です
+const MaxFallbackModels = 3
+
+// ModelChain は、primary で生成できなかった場合に fallbacks を順に試す、使用するモデルの一覧を返します
+// 空のモデル名と、すでに一覧に含まれるモデルは除きます
+func ModelChain(primary string, fallbacks []string) []string {
+	var chain []string
+	for _, model := range append([]string{primary}, fallbacks...) {
+		if model == "" || containsString(chain, model) {
+			continue
+		}
+		chain = append(chain, model)
+	}
+	return chain
+}
+
+// ParseModelList は、カンマ区切りのモデル名の一覧を、前後の空白と空の要素を除いて返します
+func ParseModelList(value string) []string {
+	var models []string
+	for _, model := range strings.Split(value, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// FormatModelChain は、フォールバックチェーンを「a → b → c」の形式で返します
+func FormatModelChain(models []string) string {
+	return strings.Join(models, " → ")
+}
+
+// containsString は、values に value が含まれるかどうかを返します
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModelChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   string
+		fallbacks []string
+		want      []string
+	}{
+		{name: "フォールバックなし", primary: "gemini-2.5-pro", want: []string{"gemini-2.5-pro"}},
+		{
+			name:      "順に試す",
+			primary:   "gemini-2.5-pro",
+			fallbacks: []string{"gemini-2.5-flash", "gemini-2.0-flash"},
+			want:      []string{"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.0-flash"},
+		},
+		{
+			name:      "重複と空文字を除く",
+			primary:   "gemini-2.5-flash",
+			fallbacks: []string{"gemini-2.5-flash", "", "gemini-2.0-flash", "gemini-2.0-flash"},
+			want:      []string{"gemini-2.5-flash", "gemini-2.0-flash"},
+		},
+		{name: "既定のモデル", primary: "", fallbacks: []string{"gemini-2.0-flash"}, want: []string{"gemini-2.0-flash"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ModelChain(tt.primary, tt.fallbacks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ModelChain() = %v, 期待値: %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	got := ParseModelList(" gemini-2.5-flash, ,gemini-2.0-flash ,")
+	want := []string{"gemini-2.5-flash", "gemini-2.0-flash"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseModelList() = %v, 期待値: %v", got, want)
+	}
+	if got := ParseModelList(""); got != nil {
+		t.Errorf("空文字の場合は nil を返すべきです: %v", got)
+	}
+	if got := FormatModelChain(want); got != "gemini-2.5-flash → gemini-2.0-flash" {
+		t.Errorf("FormatModelChain() = %q", got)
+	}
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPermissionRuleNotFound は、削除する権限ルールが見つからない場合のエラーです
+var ErrPermissionRuleNotFound = errors.New("権限ルールが見つかりません")
+
+// Permission は、ギルド内でロールやチャンネルに付与できる権限を表します
+type Permission string
+
+const (
+	// PermissionUseBot は、メンションやスラッシュコマンドでBotを利用する権限です（既定では全員に許可）
+	PermissionUseBot Permission = "use-bot"
+	// PermissionGenerateImages は、画像生成・編集を行う権限です（既定では全員に許可）
+	PermissionGenerateImages Permission = "generate-images"
+	// PermissionManageAPIKeys は、ギルドのAPIキーを設定・削除する権限です（既定では管理者のみ）
+	PermissionManageAPIKeys Permission = "manage-keys"
+	// PermissionManageModels は、モデル・システムプロンプト・チャンネル設定を変更する権限です（既定では管理者のみ）
+	PermissionManageModels Permission = "manage-models"
+)
+
+// AllPermissions は、すべての権限を返します
+func AllPermissions() []Permission {
+	return []Permission{PermissionUseBot, PermissionGenerateImages, PermissionManageAPIKeys, PermissionManageModels}
+}
+
+// IsValid は、有効な権限かどうかを返します
+func (p Permission) IsValid() bool {
+	for _, permission := range AllPermissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayName は、権限の日本語名を返します
+func (p Permission) DisplayName() string {
+	switch p {
+	case PermissionUseBot:
+		return "Botの利用"
+	case PermissionGenerateImages:
+		return "画像生成"
+	case PermissionManageAPIKeys:
+		return "APIキーの管理"
+	case PermissionManageModels:
+		return "モデル・プロンプトの変更"
+	default:
+		return string(p)
+	}
+}
+
+// IsRestricted は、既定では管理者のみに許可される権限かどうかを返します
+// 制限された権限は、許可ルールで付与されたロールと管理者のみが使用できます
+func (p Permission) IsRestricted() bool {
+	return p == PermissionManageAPIKeys || p == PermissionManageModels
+}
+
+// SupportsChannelRules は、チャンネルを対象とするルールを設定できる権限かどうかを返します
+func (p Permission) SupportsChannelRules() bool {
+	return !p.IsRestricted()
+}
+
+// PermissionTargetType は、権限ルールの対象の種類を表します
+type PermissionTargetType string
+
+const (
+	// PermissionTargetRole は、ロールを対象とするルールです
+	PermissionTargetRole PermissionTargetType = "role"
+	// PermissionTargetChannel は、チャンネルを対象とするルールです
+	PermissionTargetChannel PermissionTargetType = "channel"
+)
+
+// PermissionEffect は、権限ルールが許可と拒否のどちらであるかを表します
+type PermissionEffect string
+
+const (
+	// PermissionAllow は、対象に権限を許可するルールです（許可ルールがある場合、対象以外は使用できなくなります）
+	PermissionAllow PermissionEffect = "allow"
+	// PermissionDeny は、対象の権限を拒否するルールです（許可ルールより優先されます）
+	PermissionDeny PermissionEffect = "deny"
+)
+
+// PermissionRule は、ギルド内のロールまたはチャンネルに対する権限の許可・拒否を表します
+type PermissionRule struct {
+	GuildID    string
+	Permission Permission
+	TargetType PermissionTargetType
+	TargetID   string
+	Effect     PermissionEffect
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+// PermissionSubject は、権限を判定する操作の実行者と実行場所を表します
+type PermissionSubject struct {
+	IsAdministrator bool
+	RoleIDs         []string
+	ChannelIDs      []string // 実行したチャンネル（スレッドの場合はスレッドと親チャンネル）
+}
+
+// EvaluatePermission は、ギルドの権限ルールに基づいて subject が permission を持つかどうかを判定します
+// 管理者は常に許可します。拒否ルールに該当する場合は拒否し、許可ルールがある種類の対象（ロール・チャンネル）は
+// いずれかの許可ルールに該当する必要があります。制限された権限はロールの許可ルールが必須です
+func EvaluatePermission(rules []PermissionRule, permission Permission, subject PermissionSubject) bool {
+	if subject.IsAdministrator {
+		return true
+	}
+
+	var allowedRoles, allowedChannels []string
+	for _, rule := range rules {
+		if rule.Permission != permission {
+			continue
+		}
+		matched := subject.matches(rule)
+		if rule.Effect == PermissionDeny {
+			if matched {
+				return false
+			}
+			continue
+		}
+
+		switch rule.TargetType {
+		case PermissionTargetRole:
+			allowedRoles = append(allowedRoles, rule.TargetID)
+		case PermissionTargetChannel:
+			allowedChannels = append(allowedChannels, rule.TargetID)
+		}
+	}
+
+	if (len(allowedRoles) > 0 || permission.IsRestricted()) && !containsAny(subject.RoleIDs, allowedRoles) {
+		return false
+	}
+	if len(allowedChannels) > 0 && !containsAny(subject.ChannelIDs, allowedChannels) {
+		return false
+	}
+	return true
+}
+
+// matches は、権限ルールの対象が subject に該当するかどうかを返します
+func (s PermissionSubject) matches(rule PermissionRule) bool {
+	switch rule.TargetType {
+	case PermissionTargetRole:
+		return containsAny(s.RoleIDs, []string{rule.TargetID})
+	case PermissionTargetChannel:
+		return containsAny(s.ChannelIDs, []string{rule.TargetID})
+	default:
+		return false
+	}
+}
+
+// containsAny は、values のいずれかが candidates に含まれるかどうかを返します
+func containsAny(values, candidates []string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PermissionStore は、ギルドの権限ルールの永続化を行うインターフェースです
+type PermissionStore interface {
+	// SavePermissionRule は、権限ルールを保存します（同じ権限・対象のルールは置き換えます）
+	SavePermissionRule(ctx context.Context, rule PermissionRule) error
+
+	// DeletePermissionRule は、指定された権限・対象のルールを削除します（見つからない場合は ErrPermissionRuleNotFound）
+	DeletePermissionRule(ctx context.Context, guildID string, permission Permission, targetType PermissionTargetType, targetID string) error
+
+	// ListPermissionRules は、指定されたギルドの権限ルールを取得します
+	ListPermissionRules(ctx context.Context, guildID string) ([]PermissionRule, error)
+}
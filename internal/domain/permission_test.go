@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestEvaluatePermission(t *testing.T) {
+	rules := []PermissionRule{
+		{Permission: PermissionManageAPIKeys, TargetType: PermissionTargetRole, TargetID: "moderator", Effect: PermissionAllow},
+		{Permission: PermissionGenerateImages, TargetType: PermissionTargetRole, TargetID: "artist", Effect: PermissionAllow},
+		{Permission: PermissionUseBot, TargetType: PermissionTargetRole, TargetID: "muted", Effect: PermissionDeny},
+		{Permission: PermissionUseBot, TargetType: PermissionTargetChannel, TargetID: "help", Effect: PermissionAllow},
+		{Permission: PermissionUseBot, TargetType: PermissionTargetChannel, TargetID: "research", Effect: PermissionAllow},
+	}
+
+	tests := []struct {
+		name       string
+		permission Permission
+		subject    PermissionSubject
+		want       bool
+	}{
+		{name: "管理者は常に許可", permission: PermissionManageModels, subject: PermissionSubject{IsAdministrator: true}, want: true},
+		{name: "制限された権限は既定で拒否", permission: PermissionManageModels, subject: PermissionSubject{RoleIDs: []string{"moderator"}}, want: false},
+		{name: "制限された権限を付与されたロール", permission: PermissionManageAPIKeys, subject: PermissionSubject{RoleIDs: []string{"member", "moderator"}}, want: true},
+		{name: "ロールの許可リストに含まれない", permission: PermissionGenerateImages, subject: PermissionSubject{RoleIDs: []string{"member"}}, want: false},
+		{name: "ロールの許可リストに含まれる", permission: PermissionGenerateImages, subject: PermissionSubject{RoleIDs: []string{"artist"}}, want: true},
+		{name: "チャンネルの許可リストに含まれる", permission: PermissionUseBot, subject: PermissionSubject{ChannelIDs: []string{"thread1", "help"}}, want: true},
+		{name: "チャンネルの許可リストに含まれない", permission: PermissionUseBot, subject: PermissionSubject{ChannelIDs: []string{"general"}}, want: false},
+		{name: "拒否ルールは許可ルールより優先", permission: PermissionUseBot, subject: PermissionSubject{RoleIDs: []string{"muted"}, ChannelIDs: []string{"help"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := EvaluatePermission(rules, tt.permission, tt.subject); got != tt.want {
+			t.Errorf("%s: EvaluatePermission() = %v, 期待値: %v", tt.name, got, tt.want)
+		}
+	}
+
+	if !EvaluatePermission(nil, PermissionUseBot, PermissionSubject{}) {
+		t.Error("ルールがない場合、Botの利用は全員に許可されるべきです")
+	}
+}
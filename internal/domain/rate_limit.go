@@ -0,0 +1,223 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestKind は、レート制限の予算を分けるリクエストの種類です
+type RequestKind string
+
+const (
+	// RequestKindText は、メンションへの応答などのテキスト生成リクエストです
+	RequestKindText RequestKind = "text"
+	// RequestKindImage は、画像生成・編集のリクエストです
+	RequestKindImage RequestKind = "image"
+)
+
+// DisplayName は、リクエストの種類の日本語名を返します
+func (k RequestKind) DisplayName() string {
+	switch k {
+	case RequestKindText:
+		return "テキスト生成"
+	case RequestKindImage:
+		return "画像生成"
+	default:
+		return string(k)
+	}
+}
+
+// RateLimitScope は、レート制限を数える単位です
+type RateLimitScope string
+
+const (
+	// RateLimitScopeUser は、ユーザーごとのレート制限です
+	RateLimitScopeUser RateLimitScope = "user"
+	// RateLimitScopeChannel は、チャンネルごとのレート制限です
+	RateLimitScopeChannel RateLimitScope = "channel"
+	// RateLimitScopeGuild は、ギルドごとのレート制限です
+	RateLimitScopeGuild RateLimitScope = "guild"
+)
+
+// DisplayName は、レート制限の単位の日本語名を返します
+func (s RateLimitScope) DisplayName() string {
+	switch s {
+	case RateLimitScopeUser:
+		return "ユーザー"
+	case RateLimitScopeChannel:
+		return "チャンネル"
+	case RateLimitScopeGuild:
+		return "サーバー"
+	default:
+		return string(s)
+	}
+}
+
+// RateLimit は、期間あたりのリクエスト数の上限です
+// Requests 件までは連続で受け付け、Per の間に Requests 件分の枠が回復します
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseRateLimit は、「件数/期間」形式（例: 10/1m）の文字列からレート制限を作成します
+// 空文字・0・off は無制限として扱います
+func ParseRateLimit(value string) (RateLimit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" || strings.EqualFold(value, "off") {
+		return RateLimit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("「件数/期間」の形式で指定してください（例: 10/1m）: %s", value)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("件数は正の整数である必要があります: %s", value)
+	}
+
+	per, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("期間は正の時間（例: 30s, 1m, 1h）である必要があります: %s", value)
+	}
+
+	return RateLimit{Requests: n, Per: per}, nil
+}
+
+// IsUnlimited は、上限が設定されていないかどうかを返します
+func (l RateLimit) IsUnlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// String は、レート制限を「件数/期間」形式で返します
+func (l RateLimit) String() string {
+	if l.IsUnlimited() {
+		return "無制限"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// RateLimitBudget は、リクエストの種類ごとのレート制限です
+type RateLimitBudget struct {
+	Text  RateLimit
+	Image RateLimit
+}
+
+// RateLimitPolicy は、ユーザー・チャンネル・ギルドごとのレート制限の設定です
+type RateLimitPolicy struct {
+	User    RateLimitBudget
+	Channel RateLimitBudget
+	Guild   RateLimitBudget
+
+	// SpamThreshold は、レート制限を超えた後にリクエストを続けてスパムとみなすまでの回数です（0で無効）
+	SpamThreshold int
+}
+
+// Limit は、指定された単位とリクエストの種類のレート制限を返します
+func (p RateLimitPolicy) Limit(scope RateLimitScope, kind RequestKind) RateLimit {
+	var budget RateLimitBudget
+	switch scope {
+	case RateLimitScopeUser:
+		budget = p.User
+	case RateLimitScopeChannel:
+		budget = p.Channel
+	case RateLimitScopeGuild:
+		budget = p.Guild
+	}
+
+	if kind == RequestKindImage {
+		return budget.Image
+	}
+	return budget.Text
+}
+
+// LongestPeriod は、設定されたレート制限のうち最も長い期間を返します
+// これより長く使われていない枠は満杯まで回復しているため、破棄しても結果は変わりません
+func (p RateLimitPolicy) LongestPeriod() time.Duration {
+	var longest time.Duration
+	for _, budget := range []RateLimitBudget{p.User, p.Channel, p.Guild} {
+		for _, limit := range []RateLimit{budget.Text, budget.Image} {
+			if !limit.IsUnlimited() && limit.Per > longest {
+				longest = limit.Per
+			}
+		}
+	}
+	return longest
+}
+
+// TokenBucket は、トークンバケット方式のレート制限の状態です
+type TokenBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewTokenBucket は、上限まで枠が残っているトークンバケットを作成します
+func NewTokenBucket(limit RateLimit, now time.Time) TokenBucket {
+	return TokenBucket{Tokens: float64(limit.Requests), UpdatedAt: now}
+}
+
+// Refill は、前回の更新からの経過時間に応じて枠を回復したバケットを返します
+func (b TokenBucket) Refill(limit RateLimit, now time.Time) TokenBucket {
+	if limit.IsUnlimited() || !now.After(b.UpdatedAt) {
+		return b
+	}
+
+	elapsed := now.Sub(b.UpdatedAt)
+	b.Tokens = math.Min(float64(limit.Requests), b.Tokens+float64(limit.Requests)*elapsed.Seconds()/limit.Per.Seconds())
+	b.UpdatedAt = now
+	return b
+}
+
+// RetryAfter は、次のリクエストを受け付けられるようになるまでの時間を返します
+func (b TokenBucket) RetryAfter(limit RateLimit) time.Duration {
+	if b.Tokens >= 1 || limit.IsUnlimited() {
+		return 0
+	}
+	missing := 1 - b.Tokens
+	return time.Duration(math.Ceil(missing * float64(limit.Per) / float64(limit.Requests)))
+}
+
+// RateLimitKey は、トークンバケットを識別するキーを返します
+func RateLimitKey(scope RateLimitScope, kind RequestKind, id string) string {
+	return fmt.Sprintf("%s:%s:%s", scope, kind, id)
+}
+
+// RateLimitError は、レート制限によってリクエストを拒否した場合のエラーです
+// errors.Is で ErrRateLimited（スパムとみなした場合は ErrSpamDetected も）と一致します
+type RateLimitError struct {
+	Scope      RateLimitScope
+	Kind       RequestKind
+	RetryAfter time.Duration
+	Spam       bool
+}
+
+// Error は、エラーメッセージを返します
+func (e *RateLimitError) Error() string {
+	if e.Spam {
+		return fmt.Sprintf("%s（%sごとの%sの上限を超えた後もリクエストが繰り返されました）", ErrSpamDetected, e.Scope.DisplayName(), e.Kind.DisplayName())
+	}
+	// 待ち時間は秒単位に切り上げて表示する
+	retryAfter := (e.RetryAfter + time.Second - 1).Truncate(time.Second)
+	return fmt.Sprintf("%s（%sごとの%sの上限、%s後に再試行できます）", ErrRateLimited, e.Scope.DisplayName(), e.Kind.DisplayName(), retryAfter)
+}
+
+// Is は、errors.Is でレート制限・スパムのエラーと判定できるようにします
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited || (e.Spam && target == ErrSpamDetected)
+}
+
+// RateLimitStore は、トークンバケットの状態を永続化するストアのインターフェースです
+type RateLimitStore interface {
+	// GetTokenBucket は、キーに対応するトークンバケットを取得します（保存されていない場合は false を返します）
+	GetTokenBucket(ctx context.Context, key string) (TokenBucket, bool, error)
+	// SaveTokenBucket は、トークンバケットの状態を保存します
+	SaveTokenBucket(ctx context.Context, key string, bucket TokenBucket) error
+	// DeleteTokenBucketsBefore は、指定された時刻より前から更新されていないトークンバケットを削除します
+	DeleteTokenBucketsBefore(ctx context.Context, before time.Time) error
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RateLimit
+		wantErr bool
+	}{
+		{value: "10/1m", want: RateLimit{Requests: 10, Per: time.Minute}},
+		{value: " 3 / 10m ", want: RateLimit{Requests: 3, Per: 10 * time.Minute}},
+		{value: "", want: RateLimit{}},
+		{value: "0", want: RateLimit{}},
+		{value: "off", want: RateLimit{}},
+		{value: "10", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "10/forever", wantErr: true},
+		{value: "10/0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRateLimit(%q) のエラー = %v, エラーを期待: %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, 期待値: %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTokenBucket_Refill(t *testing.T) {
+	limit := RateLimit{Requests: 6, Per: time.Minute}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	bucket := TokenBucket{Tokens: 0, UpdatedAt: start}
+	if got := bucket.RetryAfter(limit); got != 10*time.Second {
+		t.Errorf("RetryAfter() = %v, 期待値: 10s", got)
+	}
+
+	// 1分あたり6件の枠は10秒で1件回復する
+	bucket = bucket.Refill(limit, start.Add(25*time.Second))
+	if bucket.Tokens != 2.5 {
+		t.Errorf("25秒後の残り枠 = %v, 期待値: 2.5", bucket.Tokens)
+	}
+
+	// 上限を超えて回復しない
+	bucket = bucket.Refill(limit, start.Add(time.Hour))
+	if bucket.Tokens != 6 || bucket.RetryAfter(limit) != 0 {
+		t.Errorf("枠が上限まで回復していません: %+v", bucket)
+	}
+}
+
+func TestRateLimitError_Is(t *testing.T) {
+	var err error = &RateLimitError{Scope: RateLimitScopeUser, Kind: RequestKindText, RetryAfter: 1500 * time.Millisecond}
+	if !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrSpamDetected) {
+		t.Errorf("レート制限のエラーとして判定されていません: %v", err)
+	}
+	if want := "レート制限を超過しました（ユーザーごとのテキスト生成の上限、2s後に再試行できます）"; err.Error() != want {
+		t.Errorf("Error() = %q, 期待値: %q", err.Error(), want)
+	}
+
+	err = &RateLimitError{Scope: RateLimitScopeUser, Kind: RequestKindImage, Spam: true}
+	if !errors.Is(err, ErrRateLimited) || !errors.Is(err, ErrSpamDetected) {
+		t.Errorf("スパムのエラーとして判定されていません: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// ReplyTargetLabel は、メンションが返信したメッセージであることをプロンプト上で示すラベルです
+	ReplyTargetLabel = "[返信先のメッセージ]"
+
+	// replyTargetExcerptRunes は、質問に添える返信先のメッセージの抜粋の最大文字数です
+	replyTargetExcerptRunes = 200
+)
+
+// MergeReplyChain は、会話履歴に返信の連鎖のメッセージを加え、重複を除いて古い順に並べた新しいスライスを返します
+// 連鎖のメッセージには ReplyChain を、メンションが直接返信したメッセージ（targetID）には ReplyTarget を設定します
+func MergeReplyChain(history, chain []Message, targetID string) []Message {
+	if len(chain) == 0 {
+		return history
+	}
+
+	inChain := make(map[string]bool, len(chain))
+	for _, msg := range chain {
+		inChain[msg.ID] = true
+	}
+
+	merged := make([]Message, 0, len(history)+len(chain))
+	seen := make(map[string]bool, len(history)+len(chain))
+	for _, msg := range append(append([]Message{}, history...), chain...) {
+		if seen[msg.ID] {
+			continue
+		}
+		seen[msg.ID] = true
+		msg.ReplyChain = inChain[msg.ID]
+		msg.ReplyTarget = msg.ID == targetID
+		merged = append(merged, msg)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+	return merged
+}
+
+// FindReplyTarget は、会話履歴からメンションが直接返信したメッセージを探します
+func FindReplyTarget(history []Message) (Message, bool) {
+	for _, msg := range history {
+		if msg.ReplyTarget {
+			return msg, true
+		}
+	}
+	return Message{}, false
+}
+
+// WithReplyTarget は、ユーザーの質問の前に、どのメッセージへの返信かを示す一文を加えます
+// 「これは正しい？」のような返信だけの質問でも、返信先のメッセージについて答えられるようにします
+func WithReplyTarget(question string, target Message) string {
+	speaker := target.User.DisplayName
+	if target.FromSelf {
+		speaker = "あなた（このBot）"
+	}
+
+	excerpt := strings.TrimSpace(target.Content)
+	if runes := []rune(excerpt); len(runes) > replyTargetExcerptRunes {
+		excerpt = string(runes[:replyTargetExcerptRunes]) + "…"
+	}
+	if excerpt == "" {
+		excerpt = "（本文なし）"
+	}
+
+	return fmt.Sprintf("%s この質問は、%s の次のメッセージへの返信です:「%s」\n\n%s", ReplyTargetLabel, speaker, excerpt, question)
+}
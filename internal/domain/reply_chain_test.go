@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeReplyChain(t *testing.T) {
+	now := time.Now()
+	history := []Message{
+		{ID: "2", Content: "直近1", Timestamp: now.Add(-2 * time.Minute)},
+		{ID: "3", Content: "直近2", Timestamp: now.Add(-time.Minute)},
+	}
+	chain := []Message{
+		{ID: "1", Content: "数時間前の質問", Timestamp: now.Add(-3 * time.Hour)},
+		{ID: "2", Content: "直近1", Timestamp: now.Add(-2 * time.Minute)},
+	}
+
+	merged := MergeReplyChain(history, chain, "2")
+	if len(merged) != 3 {
+		t.Fatalf("重複を除いて3件になるべきです: %+v", merged)
+	}
+	if merged[0].ID != "1" || merged[1].ID != "2" || merged[2].ID != "3" {
+		t.Errorf("古い順に並べるべきです: %+v", merged)
+	}
+	if !merged[0].ReplyChain || !merged[1].ReplyChain || merged[2].ReplyChain {
+		t.Errorf("連鎖のメッセージにだけ ReplyChain を設定するべきです: %+v", merged)
+	}
+
+	target, ok := FindReplyTarget(merged)
+	if !ok || target.ID != "2" {
+		t.Errorf("返信先のメッセージが見つかりません: %+v", target)
+	}
+
+	if got := MergeReplyChain(history, nil, ""); len(got) != 2 {
+		t.Errorf("連鎖がない場合は履歴をそのまま返すべきです: %+v", got)
+	}
+}
+
+func TestWithReplyTarget(t *testing.T) {
+	target := Message{User: User{DisplayName: "Alice"}, Content: "地球は平らです"}
+	got := WithReplyTarget("これは正しい？", target)
+	if !strings.HasPrefix(got, ReplyTargetLabel) || !strings.Contains(got, "Alice") || !strings.Contains(got, "地球は平らです") || !strings.HasSuffix(got, "これは正しい？") {
+		t.Errorf("返信先を示す一文が正しくありません: %q", got)
+	}
+
+	self := WithReplyTarget("本当？", Message{FromSelf: true, Content: strings.Repeat("長", 300)})
+	if !strings.Contains(self, "このBot") || !strings.Contains(self, "…") {
+		t.Errorf("Bot自身の長い回答は抜粋して示すべきです: %q", self)
+	}
+}
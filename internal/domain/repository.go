@@ -12,4 +12,7 @@ type ConversationRepository interface {
 
 	// GetMessagesBefore は、指定されたメッセージIDより前のメッセージを取得します
 	GetMessagesBefore(ctx context.Context, channelID string, messageID string, limit int) ([]Message, error)
+
+	// GetReplyChain は、指定されたメッセージから返信元をたどり、最大 depth 件のメッセージを古い順に取得します
+	GetReplyChain(ctx context.Context, channelID string, messageID string, depth int) ([]Message, error)
 }
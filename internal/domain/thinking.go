@@ -0,0 +1,42 @@
+package domain
+
+import "fmt"
+
+const (
+	// ThinkingBudgetDynamic は、思考に使うトークン数を質問に応じてモデルが決めることを表す思考予算です
+	ThinkingBudgetDynamic = -1
+	// ThinkingBudgetOff は、思考を行わないことを表す思考予算です（思考を無効にできないモデルでは最小の予算を使用します）
+	ThinkingBudgetOff = 0
+	// MaxThinkingBudget は、思考予算に指定できる最大のトークン数です（モデルごとの上限を超える場合は上限に合わせます）
+	MaxThinkingBudget = 32768
+)
+
+// ThinkingSettings は、Gemini 2.5 系のモデルの思考（推論）の設定です
+type ThinkingSettings struct {
+	// Budget は、思考に使用する最大のトークン数です（nil の場合は上位の設定またはモデルの既定を使用します）
+	Budget *int
+	// IncludeThoughts は、回答とは別に思考の要約を表示するかどうかです
+	IncludeThoughts bool
+}
+
+// ValidateThinkingBudget は、思考予算が ThinkingBudgetDynamic または0以上 MaxThinkingBudget 以下であるかどうかを検証します
+func ValidateThinkingBudget(budget int) error {
+	if budget != ThinkingBudgetDynamic && (budget < 0 || budget > MaxThinkingBudget) {
+		return fmt.Errorf("思考予算は %d（動的）または0以上%d以下である必要があります: %d", ThinkingBudgetDynamic, MaxThinkingBudget, budget)
+	}
+	return nil
+}
+
+// FormatThinkingBudget は、思考予算の表示用の文字列を返します
+func FormatThinkingBudget(budget *int) string {
+	switch {
+	case budget == nil:
+		return "既定"
+	case *budget == ThinkingBudgetDynamic:
+		return "動的"
+	case *budget == ThinkingBudgetOff:
+		return "無効"
+	default:
+		return fmt.Sprintf("%dトークン", *budget)
+	}
+}
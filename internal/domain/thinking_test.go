@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestValidateThinkingBudget(t *testing.T) {
+	for _, budget := range []int{ThinkingBudgetDynamic, ThinkingBudgetOff, 1024, MaxThinkingBudget} {
+		if err := ValidateThinkingBudget(budget); err != nil {
+			t.Errorf("ValidateThinkingBudget(%d) = %v, 期待値: nil", budget, err)
+		}
+	}
+	for _, budget := range []int{-2, MaxThinkingBudget + 1} {
+		if err := ValidateThinkingBudget(budget); err == nil {
+			t.Errorf("ValidateThinkingBudget(%d) はエラーを返すべきです", budget)
+		}
+	}
+}
+
+func TestFormatThinkingBudget(t *testing.T) {
+	budget := func(v int) *int { return &v }
+	tests := []struct {
+		budget *int
+		want   string
+	}{
+		{budget: nil, want: "既定"},
+		{budget: budget(ThinkingBudgetDynamic), want: "動的"},
+		{budget: budget(ThinkingBudgetOff), want: "無効"},
+		{budget: budget(2048), want: "2048トークン"},
+	}
+	for _, tt := range tests {
+		if got := FormatThinkingBudget(tt.budget); got != tt.want {
+			t.Errorf("FormatThinkingBudget() = %q, 期待値: %q", got, tt.want)
+		}
+	}
+}
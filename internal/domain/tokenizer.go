@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Tokenizer は、モデルに送信するテキストのトークン数を数えるインターフェースです
+type Tokenizer interface {
+	CountTokens(ctx context.Context, model string, text string) (int, error)
+}
+
+// HeuristicTokenizer は、APIを呼び出さずに文字の種類からトークン数を見積もるTokenizerです
+type HeuristicTokenizer struct{}
+
+// CountTokens は、EstimateTokens でテキストのトークン数を見積もります
+func (HeuristicTokenizer) CountTokens(_ context.Context, _ string, text string) (int, error) {
+	return EstimateTokens(text), nil
+}
+
+// EstimateTokens は、テキストのトークン数を見積もります
+// 英数字や記号などのASCII文字はおよそ4文字で1トークン、日本語などそれ以外の文字は1文字で1トークンとして数えます
+func EstimateTokens(text string) int {
+	ascii, others := 0, 0
+	for _, r := range text {
+		if r < 0x80 {
+			ascii++
+		} else {
+			others++
+		}
+	}
+	return (ascii+3)/4 + others
+}
+
+// ContextBudgetMode は、コンテキスト長の上限を数える単位です
+type ContextBudgetMode string
+
+const (
+	ContextBudgetChars  ContextBudgetMode = "chars"  // 文字数で数える（MAX_CONTEXT_LENGTH・MAX_HISTORY_LENGTH をそのまま上限とする）
+	ContextBudgetTokens ContextBudgetMode = "tokens" // トークン数で数える（モデルのコンテキストウィンドウから上限を決める）
+)
+
+// ParseContextBudgetMode は、文字列からContextBudgetModeを作成します（空文字は文字数として扱います）
+func ParseContextBudgetMode(s string) (ContextBudgetMode, error) {
+	switch mode := ContextBudgetMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", ContextBudgetChars:
+		return ContextBudgetChars, nil
+	case ContextBudgetTokens:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("不明なコンテキスト長の単位です: %s（chars または tokens を指定してください）", s)
+	}
+}
+
+// UnitName は、上限の単位の表示名を返します
+func (m ContextBudgetMode) UnitName() string {
+	if m == ContextBudgetTokens {
+		return "トークン"
+	}
+	return "文字"
+}
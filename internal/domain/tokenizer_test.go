@@ -0,0 +1,33 @@
+package domain
+
+import "testing"
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "abcd", want: 1},
+		{text: "Hello", want: 2},
+		{text: "こんにちは", want: 5},
+		{text: "Go言語", want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, 期待値: %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseContextBudgetMode(t *testing.T) {
+	for input, want := range map[string]ContextBudgetMode{"": ContextBudgetChars, "chars": ContextBudgetChars, "Tokens": ContextBudgetTokens} {
+		if got, err := ParseContextBudgetMode(input); err != nil || got != want {
+			t.Errorf("ParseContextBudgetMode(%q) = %q, %v, 期待値: %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseContextBudgetMode("words"); err == nil {
+		t.Error("不明な単位はエラーになるべきです")
+	}
+}
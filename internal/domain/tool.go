@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Tool は、Geminiの関数呼び出し（Function Calling）でモデルが使用できるツールのインターフェースです
+type Tool interface {
+	// Name は、モデルがツールを呼び出すときの名前を返します（英数字とアンダースコア）
+	Name() string
+	// Description は、モデルがツールを使うかどうかを判断するための説明を返します
+	Description() string
+	// Parameters は、引数のJSON Schema（type が object のスキーマ）を返します
+	Parameters() map[string]any
+	// Execute は、モデルが指定した引数でツールを実行し、モデルに返す結果を返します
+	Execute(ctx context.Context, args map[string]any) (map[string]any, error)
+}
+
+// ToolRegistry は、起動時に登録したツールを名前で管理するレジストリです
+// nil のレジストリは、ツールが登録されていないものとして扱います
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+	order []string // 登録順（モデルに渡す関数宣言の順序を安定させるため）
+}
+
+// NewToolRegistry は、指定されたツールを登録した新しいToolRegistryインスタンスを作成します
+func NewToolRegistry(tools ...Tool) (*ToolRegistry, error) {
+	registry := &ToolRegistry{tools: make(map[string]Tool)}
+	for _, tool := range tools {
+		if err := registry.Register(tool); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register は、ツールを登録します（同じ名前のツールは登録できません）
+func (r *ToolRegistry) Register(tool Tool) error {
+	if tool == nil {
+		return fmt.Errorf("ツールが指定されていません")
+	}
+	name := tool.Name()
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("ツールの名前が空です")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[name]; exists {
+		return fmt.Errorf("同じ名前のツールがすでに登録されています: %s", name)
+	}
+	r.tools[name] = tool
+	r.order = append(r.order, name)
+	return nil
+}
+
+// Get は、名前でツールを取得します
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Tools は、登録されているツールを登録順に返します
+func (r *ToolRegistry) Tools() []Tool {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name])
+	}
+	return tools
+}
+
+// IsEmpty は、ツールが1つも登録されていないかどうかを返します
+func (r *ToolRegistry) IsEmpty() bool {
+	if r == nil {
+		return true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.order) == 0
+}
+
+// ToolCallContext は、ツールを呼び出したメンションの情報です
+// サーバーやチャンネルを参照するツールは、モデルが指定した引数ではなくこの情報を使用し、呼び出し元のサーバーの外を参照しないようにします
+type ToolCallContext struct {
+	GuildID   string
+	ChannelID string
+	UserID    string
+}
+
+// toolCallContextKey は、context.Context に ToolCallContext を格納するキーです
+type toolCallContextKey struct{}
+
+// WithToolCallContext は、ツールを呼び出したメンションの情報を持つコンテキストを返します
+func WithToolCallContext(ctx context.Context, toolCtx ToolCallContext) context.Context {
+	return context.WithValue(ctx, toolCallContextKey{}, toolCtx)
+}
+
+// ToolCallContextFrom は、コンテキストからツールを呼び出したメンションの情報を取り出します（ない場合はゼロ値を返します）
+func ToolCallContextFrom(ctx context.Context) ToolCallContext {
+	toolCtx, _ := ctx.Value(toolCallContextKey{}).(ToolCallContext)
+	return toolCtx
+}
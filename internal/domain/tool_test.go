@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"context"
+	"testing"
+)
+
+type stubTool struct {
+	name string
+}
+
+func (t stubTool) Name() string               { return t.name }
+func (t stubTool) Description() string        { return "テスト用のツール" }
+func (t stubTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (t stubTool) Execute(ctx context.Context, args map[string]any) (map[string]any, error) {
+	return map[string]any{"name": t.name}, nil
+}
+
+func TestToolRegistry(t *testing.T) {
+	registry, err := NewToolRegistry(stubTool{name: "b"}, stubTool{name: "a"})
+	if err != nil {
+		t.Fatalf("ツールの登録に失敗: %v", err)
+	}
+
+	tools := registry.Tools()
+	if len(tools) != 2 || tools[0].Name() != "b" || tools[1].Name() != "a" {
+		t.Errorf("ツールは登録順に返すべきです: %+v", tools)
+	}
+	if tool, ok := registry.Get("a"); !ok || tool.Name() != "a" {
+		t.Errorf("名前でツールを取得できません: %v, %v", tool, ok)
+	}
+	if _, ok := registry.Get("unknown"); ok {
+		t.Error("登録されていないツールは取得できないべきです")
+	}
+
+	if err := registry.Register(stubTool{name: "a"}); err == nil {
+		t.Error("同じ名前のツールは登録できないべきです")
+	}
+	if err := registry.Register(stubTool{name: " "}); err == nil {
+		t.Error("名前が空のツールは登録できないべきです")
+	}
+
+	var empty *ToolRegistry
+	if !empty.IsEmpty() || empty.Tools() != nil {
+		t.Error("nil のレジストリはツールがないものとして扱うべきです")
+	}
+	if _, ok := empty.Get("a"); ok {
+		t.Error("nil のレジストリからはツールを取得できないべきです")
+	}
+}
+
+func TestToolCallContext(t *testing.T) {
+	if got := ToolCallContextFrom(context.Background()); got != (ToolCallContext{}) {
+		t.Errorf("情報がない場合はゼロ値を返すべきです: %+v", got)
+	}
+
+	want := ToolCallContext{GuildID: "guild1", ChannelID: "channel1", UserID: "user1"}
+	if got := ToolCallContextFrom(WithToolCallContext(context.Background(), want)); got != want {
+		t.Errorf("ToolCallContextFrom() = %+v, 期待値: %+v", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUsageBudgetExceeded は、ギルドの今月のトークン数または推定コストが上限に達している場合のエラーです
+var ErrUsageBudgetExceeded = errors.New("今月の利用上限に達しました")
+
+// TokenUsage は、1回以上のリクエストで消費したトークン数です
+type TokenUsage struct {
+	PromptTokens   int64 // 入力（プロンプト・会話履歴・添付ファイル）のトークン数
+	OutputTokens   int64 // 出力（応答・画像）のトークン数
+	ThinkingTokens int64 // 思考に使用したトークン数
+}
+
+// TotalTokens は、入力・出力・思考の合計トークン数を返します
+func (u TokenUsage) TotalTokens() int64 {
+	return u.PromptTokens + u.OutputTokens + u.ThinkingTokens
+}
+
+// Add は、2つの使用量を合算した使用量を返します
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		PromptTokens:   u.PromptTokens + other.PromptTokens,
+		OutputTokens:   u.OutputTokens + other.OutputTokens,
+		ThinkingTokens: u.ThinkingTokens + other.ThinkingTokens,
+	}
+}
+
+// IsZero は、トークンを消費していないかどうかを返します
+func (u TokenUsage) IsZero() bool {
+	return u == TokenUsage{}
+}
+
+// ModelPricing は、モデルの100万トークンあたりの料金（米ドル）です
+type ModelPricing struct {
+	InputPerMillion  float64
+	OutputPerMillion float64 // 思考のトークンも出力として課金されます
+}
+
+// Cost は、使用量の推定コスト（米ドル）を返します
+func (p ModelPricing) Cost(usage TokenUsage) float64 {
+	input := float64(usage.PromptTokens) * p.InputPerMillion
+	output := float64(usage.OutputTokens+usage.ThinkingTokens) * p.OutputPerMillion
+	return (input + output) / 1_000_000
+}
+
+// UsageRecord は、1回のリクエストで消費したトークン数の記録です
+type UsageRecord struct {
+	GuildID   string
+	UserID    string
+	ChannelID string
+	Model     string
+	Kind      RequestKind
+	Usage     TokenUsage
+	CreatedAt time.Time
+}
+
+// UsageQuery は、使用量を集計する条件です（空の項目では絞り込みません）
+type UsageQuery struct {
+	GuildID   string
+	UserID    string
+	ChannelID string
+	Since     time.Time // この時刻以降の記録を集計します
+	Until     time.Time // この時刻より前の記録を集計します（ゼロ値の場合は現在まで）
+}
+
+// Matches は、記録が集計の条件に一致するかどうかを返します
+func (q UsageQuery) Matches(record UsageRecord) bool {
+	if q.GuildID != "" && record.GuildID != q.GuildID {
+		return false
+	}
+	if q.UserID != "" && record.UserID != q.UserID {
+		return false
+	}
+	if q.ChannelID != "" && record.ChannelID != q.ChannelID {
+		return false
+	}
+	if record.CreatedAt.Before(q.Since) {
+		return false
+	}
+	return q.Until.IsZero() || record.CreatedAt.Before(q.Until)
+}
+
+// ModelUsage は、モデルごとに集計した使用量です
+type ModelUsage struct {
+	Model    string
+	Requests int
+	Usage    TokenUsage
+}
+
+// UsageBudget は、ギルドの1か月あたりの利用上限です
+// 上限に達した後は、FallbackModel が設定されていればテキスト生成をそのモデルで行い、設定されていなければリクエストを拒否します
+type UsageBudget struct {
+	GuildID           string
+	MonthlyTokenLimit int64   // 1か月あたりのトークン数の上限（0で無制限）
+	MonthlyCostLimit  float64 // 1か月あたりの推定コストの上限（米ドル、0で無制限）
+	FallbackModel     string  // 上限に達した後に使用する安価なモデル（空の場合は拒否）
+	UpdatedBy         string
+	UpdatedAt         time.Time
+}
+
+// IsEmpty は、上限が設定されていないかどうかを返します
+func (b UsageBudget) IsEmpty() bool {
+	return b.MonthlyTokenLimit <= 0 && b.MonthlyCostLimit <= 0
+}
+
+// IsExceeded は、今月のトークン数または推定コストが上限に達しているかどうかを返します
+func (b UsageBudget) IsExceeded(tokens int64, cost float64) bool {
+	if b.MonthlyTokenLimit > 0 && tokens >= b.MonthlyTokenLimit {
+		return true
+	}
+	return b.MonthlyCostLimit > 0 && cost >= b.MonthlyCostLimit
+}
+
+// UsageStore は、トークンの使用量の記録とギルドの利用上限を保存するストアのインターフェースです
+type UsageStore interface {
+	// RecordUsage は、使用量の記録を追加します
+	RecordUsage(ctx context.Context, record UsageRecord) error
+	// SummarizeUsage は、条件に一致する使用量をモデルごとに集計して返します
+	SummarizeUsage(ctx context.Context, query UsageQuery) ([]ModelUsage, error)
+	// GetUsageBudget は、ギルドの利用上限を取得します（設定されていない場合は空の上限を返します）
+	GetUsageBudget(ctx context.Context, guildID string) (UsageBudget, error)
+	// SaveUsageBudget は、ギルドの利用上限を保存します
+	SaveUsageBudget(ctx context.Context, budget UsageBudget) error
+	// DeleteUsageBudget は、ギルドの利用上限を削除します
+	DeleteUsageBudget(ctx context.Context, guildID string) error
+}
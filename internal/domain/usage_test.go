@@ -0,0 +1,36 @@
+package domain
+
+import "testing"
+
+func TestModelPricing_Cost(t *testing.T) {
+	pricing := ModelPricing{InputPerMillion: 1.25, OutputPerMillion: 10}
+	usage := TokenUsage{PromptTokens: 2_000_000, OutputTokens: 100_000, ThinkingTokens: 100_000}
+
+	// 思考のトークンは出力として課金される
+	if got := pricing.Cost(usage); got < 4.4999 || got > 4.5001 {
+		t.Errorf("Cost() = %f, 期待値: 4.50", got)
+	}
+}
+
+func TestUsageBudget_IsExceeded(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget UsageBudget
+		tokens int64
+		cost   float64
+		want   bool
+	}{
+		{name: "上限なし", budget: UsageBudget{}, tokens: 1_000_000, cost: 100, want: false},
+		{name: "トークン数が上限未満", budget: UsageBudget{MonthlyTokenLimit: 1000}, tokens: 999, want: false},
+		{name: "トークン数が上限に到達", budget: UsageBudget{MonthlyTokenLimit: 1000}, tokens: 1000, want: true},
+		{name: "推定コストが上限に到達", budget: UsageBudget{MonthlyTokenLimit: 1000, MonthlyCostLimit: 5}, tokens: 10, cost: 5, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.IsExceeded(tt.tokens, tt.cost); got != tt.want {
+				t.Errorf("IsExceeded(%d, %f) = %v, 期待値: %v", tt.tokens, tt.cost, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,10 +7,14 @@ import (
 
 // Message は、Discordのメッセージを表現する値オブジェクトです
 type Message struct {
-	ID        string
-	User      User
-	Content   string
-	Timestamp time.Time
+	ID          string
+	User        User
+	Content     string
+	Timestamp   time.Time
+	FromSelf    bool         // このBot自身が送信したメッセージかどうか（Geminiへはモデルの発言として渡します）
+	Attachments []Attachment // メッセージに添付されたファイル（Dataはダウンロード後に設定されます）
+	ReplyChain  bool         // メンションの返信先をたどって取得したメッセージかどうか（履歴を切り詰めるときに優先して保持します）
+	ReplyTarget bool         // メンションが直接返信したメッセージかどうか
 }
 
 // User は、Discordのユーザー情報を表現する値オブジェクトです
@@ -30,31 +34,57 @@ type Prompt struct {
 
 // BotMention は、Botへのメンション情報を表現する値オブジェクトです
 type BotMention struct {
-	ChannelID string
-	GuildID   string
-	User      User
-	Content   string
-	MessageID string
+	ChannelID       string
+	GuildID         string
+	User            User
+	Content         string
+	MessageID       string
+	ThreadID        string       // スレッド内のメンションの場合のスレッドID（通常チャンネルでは空）
+	ParentChannelID string       // スレッド内のメンションの場合のスレッドの親チャンネルID（通常チャンネルでは空）
+	Attachments     []Attachment // メンションに添付されたファイル（Dataはダウンロード後に設定されます）
+
+	// ReferencedMessageID は、メンションが返信したメッセージのID（返信でない場合は空）
+	ReferencedMessageID string
+
+	// Search は、チャンネル・ギルドの設定より優先して、Google 検索によるグラウンディングを使用するかどうかです（/ask の search オプション）
+	// nil の場合はチャンネル・ギルドの設定に従います
+	Search *bool
+
+	// Thinking は、このリクエストだけに適用する思考の設定です（/ask の thinking-budget・show-thinking オプション）
+	Thinking ThinkingSettings
 }
 
 // IsThread は、このメンションがスレッド内で発生したかどうかを判定します
-// この判定は、チャンネルIDの形式に基づいて行われます
+// スレッドIDはメンション作成時にDiscordのチャンネル種別から設定されます
 func (bm BotMention) IsThread() bool {
-	// DiscordのスレッドチャンネルIDは通常のチャンネルIDと異なる形式を持つ場合があります
-	// 実際の実装では、Discord APIの仕様に基づいて判定ロジックを調整する必要があります
-	return false // 仮の実装
+	return bm.ThreadID != ""
+}
+
+// SettingsChannelID は、チャンネル設定の解決に使用するチャンネルIDを返します
+// スレッド内のメンションでは、スレッドの親チャンネルの設定を使用します
+func (bm BotMention) SettingsChannelID() string {
+	if bm.ParentChannelID != "" {
+		return bm.ParentChannelID
+	}
+	return bm.ChannelID
 }
 
 // String はBotMentionの文字列表現を返します
 func (bm BotMention) String() string {
-	return fmt.Sprintf("BotMention{ChannelID: %s, GuildID: %s, User: %s, Content: %s, MessageID: %s}",
-		bm.ChannelID, bm.GuildID, bm.User.Username, bm.Content, bm.MessageID)
+	return fmt.Sprintf("BotMention{ChannelID: %s, GuildID: %s, ThreadID: %s, User: %s, Content: %s, MessageID: %s}",
+		bm.ChannelID, bm.GuildID, bm.ThreadID, bm.User.Username, bm.Content, bm.MessageID)
 }
 
 // ImageGenerationRequest は、画像生成リクエストを表現する値オブジェクトです
 type ImageGenerationRequest struct {
-	Prompt  string
-	Options ImageGenerationOptions
+	Prompt       string
+	Options      ImageGenerationOptions
+	SourceImages []Attachment // 編集元の画像（指定した場合はプロンプトに従って画像を編集します）
+}
+
+// IsEdit は、このリクエストが既存の画像を編集する編集モードかどうかを判定します
+func (r ImageGenerationRequest) IsEdit() bool {
+	return len(r.SourceImages) > 0
 }
 
 // ImageGenerationResponse は、画像生成レスポンスを表現する値オブジェクトです
@@ -63,6 +93,7 @@ type ImageGenerationResponse struct {
 	Prompt      string
 	Model       string
 	GeneratedAt time.Time
+	Usage       TokenUsage // 生成に消費したトークン数（複数枚の場合は合計）
 }
 
 // GeneratedImage は、生成された画像の情報を表現する値オブジェクトです
@@ -87,11 +118,24 @@ type ImageGenerationOptions struct {
 	TopK        int32        `json:"top_k,omitempty"`
 }
 
+// ImageCount は、生成する画像の枚数を1〜MaxImageCountの範囲に収めて返します
+func (o ImageGenerationOptions) ImageCount() int {
+	switch {
+	case o.Count < 1:
+		return 1
+	case o.Count > MaxImageCount:
+		return MaxImageCount
+	default:
+		return o.Count
+	}
+}
+
 // ImageGenerationResult は、画像生成の結果を表現する値オブジェクトです
 type ImageGenerationResult struct {
 	Response *ImageGenerationResponse // 画像生成レスポンスを内包
 	Success  bool                     // 成功/失敗の状態
 	Error    string                   // エラーメッセージ
+	Err      error                    // 失敗の原因となったエラー（errors.Is でエラーの種類を判定するために使用）
 	ImageURL string                   // 画像URL（必要に応じて設定）
 }
 
@@ -108,6 +152,7 @@ type Attachment struct {
 	Size        int64     // ファイルサイズ
 	IsImage     bool      // 画像かどうか
 	GeneratedAt time.Time // 生成時刻
+	URL         string    // ダウンロード元のURL（Discordの添付ファイルの場合）
 }
 
 // ResponseMetadata は、レスポンスのメタデータを表現する値オブジェクトです
@@ -125,7 +170,10 @@ type UnifiedResponse struct {
 	Metadata    ResponseMetadata // メタデータ（プロンプト、モデルなど）
 	Success     bool             // 成功/失敗
 	Error       string           // エラーメッセージ
+	Err         error            // 失敗の原因となったエラー（errors.Is でエラーの種類を判定するために使用）
 	ThreadID    string           // スレッドID（空の場合はリプライで送信）
+	Grounding   *Grounding       // Google 検索によるグラウンディングの結果（使用しなかった場合は nil）
+	Thoughts    string           // モデルの思考の要約（表示しない場合は空）
 }
 
 // NewTextResponse は、テキストレスポンスを作成します
@@ -187,6 +235,7 @@ func NewErrorResponse(err error, responseType string) *UnifiedResponse {
 		},
 		Success:  false,
 		Error:    err.Error(),
+		Err:      err,
 		ThreadID: "",
 	}
 }
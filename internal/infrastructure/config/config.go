@@ -14,6 +14,17 @@ type GeminiConfig struct {
 	MaxRetries     int  // 最大リトライ回数
 	EnableImageGen bool // 画像生成機能の有効/無効
 
+	// MaxToolIterations は、1回の生成で関数呼び出しとその結果の受け渡しを繰り返す最大回数です（0 でツールを使用しません）
+	MaxToolIterations int
+
+	// ThinkingBudget は、Gemini 2.5 系のモデルが思考に使用する最大のトークン数の既定値です（nil の場合はモデルの既定、-1 で動的、0 で無効）
+	ThinkingBudget *int
+	// IncludeThoughts は、既定で回答とは別に思考の要約を表示するかどうかです
+	IncludeThoughts bool
+
+	// FallbackModels は、利用制限・過負荷・安全フィルターなどで生成できなかった場合に順に使用するモデルの既定値です
+	FallbackModels []string
+
 	// 画像生成関連の設定
 	ImageStyle   string // デフォルト画像スタイル
 	ImageQuality string // デフォルト画像品質
@@ -25,8 +36,24 @@ type GeminiConfig struct {
 type BotConfig struct {
 	MaxContextLength int // 最大コンテキスト長（文字数）
 	MaxHistoryLength int // 最大履歴長（文字数）
+
+	// ContextBudgetMode は、コンテキスト長を数える単位です（"chars" または "tokens"）
+	// "tokens" の場合は、モデルのコンテキストウィンドウに ContextWindowRatio を掛けたトークン数を上限とし、
+	// そのうち MaxHistoryLength / MaxContextLength の割合を履歴に使用します
+	ContextBudgetMode  string
+	ContextWindowRatio float64 // モデルのコンテキストウィンドウのうち、システムプロンプト・履歴・質問に使用する割合
+
+	// HistorySummaryModel は、コンテキスト長の上限を超えて履歴から外れた古いメッセージを要約するモデルです（"off" で要約しません）
+	HistorySummaryModel string
+
+	// ReplyChainDepth は、返信でメンションされたときに返信元をたどって履歴に含めるメッセージの最大件数です（0 でたどりません）
+	ReplyChainDepth int
+
 	RequestTimeout   time.Duration
 	SystemPrompt     string
+	IncludeOtherBots bool // 会話履歴に他のBotのメッセージを含めるかどうか
+
+	MaxAttachmentBytes int64 // Geminiに渡す添付ファイル1件あたりの最大サイズ（バイト）
 }
 
 // DiscordConfig は、Discord関連の設定を定義します
@@ -34,9 +61,39 @@ type DiscordConfig struct {
 	BotToken string
 }
 
+// StorageConfig は、永続化ストア関連の設定を定義します
+type StorageConfig struct {
+	GuildConfigStore string // ギルド設定の保存先（"memory" または "sqlite:///path/to/bot.db"）
+	MasterKey        string // APIキー暗号化用のマスターキー（Base64エンコードした32バイト）
+	MasterKeyFile    string // マスターキーを記載したファイルのパス（MasterKey未指定時に使用）
+}
+
+// HistorySummaryOff は、履歴の要約を無効にする HistorySummaryModel の値です
+const HistorySummaryOff = "off"
+
+// HistorySummaryEnabled は、履歴から外れた古いメッセージを要約するかどうかを返します
+func (c BotConfig) HistorySummaryEnabled() bool {
+	return c.HistorySummaryModel != "" && c.HistorySummaryModel != HistorySummaryOff
+}
+
+// RateLimitConfig は、レート制限関連の設定を定義します
+// 各上限は「件数/期間」（例: 10/1m）で指定し、空文字・0・off は無制限になります
+type RateLimitConfig struct {
+	UserText     string // ユーザーごとのテキスト生成の上限
+	UserImage    string // ユーザーごとの画像生成の上限
+	ChannelText  string // チャンネルごとのテキスト生成の上限
+	ChannelImage string // チャンネルごとの画像生成の上限
+	GuildText    string // ギルドごとのテキスト生成の上限
+	GuildImage   string // ギルドごとの画像生成の上限
+
+	SpamThreshold int // レート制限を超えた後、リクエストを続けてスパムとみなすまでの回数（0で無効）
+}
+
 // AppConfig は、アプリケーション全体の設定を定義します
 type AppConfig struct {
-	Discord DiscordConfig
-	Gemini  GeminiConfig
-	Bot     BotConfig
+	Discord   DiscordConfig
+	Gemini    GeminiConfig
+	Bot       BotConfig
+	Storage   StorageConfig
+	RateLimit RateLimitConfig
 }
@@ -1,5 +1,11 @@
 package config
 
+import (
+	"fmt"
+
+	"geminibot/internal/domain"
+)
+
 // DefaultGeminiTextModel は環境変数未指定時の既定テキスト生成モデルです（GEMINI_MODEL_NAME のデフォルトと一致させること）。
 const DefaultGeminiTextModel = "gemini-2.5-pro"
 
@@ -13,6 +19,7 @@ type GeminiTextModelChoice struct {
 func GeminiTextModelChoices() []GeminiTextModelChoice {
 	return []GeminiTextModelChoice{
 		{DisplayName: "Gemini 2.5 Pro", ModelID: DefaultGeminiTextModel},
+		{DisplayName: "Gemini 2.5 Flash", ModelID: "gemini-2.5-flash"},
 		{DisplayName: "Gemini 2.0 Flash", ModelID: "gemini-2.0-flash"},
 		{DisplayName: "Gemini 2.5 Flash Lite", ModelID: "gemini-2.5-flash-lite"},
 	}
@@ -27,3 +34,101 @@ func IsSupportedGeminiTextModel(model string) bool {
 	}
 	return false
 }
+
+// ValidateGeminiFallbackModels は、フォールバックチェーンのモデルが許可リストに含まれ、重複がなく、最大数以内かを検証します。
+func ValidateGeminiFallbackModels(models []string) error {
+	if len(models) > domain.MaxFallbackModels {
+		return fmt.Errorf("フォールバックのモデルは%d個まで指定できます", domain.MaxFallbackModels)
+	}
+	for i, model := range models {
+		if !IsSupportedGeminiTextModel(model) {
+			return fmt.Errorf("サポートされていないモデルです: %s", model)
+		}
+		for _, previous := range models[:i] {
+			if previous == model {
+				return fmt.Errorf("フォールバックのモデルが重複しています: %s", model)
+			}
+		}
+	}
+	return nil
+}
+
+// geminiModelPricing は、使用量の推定コストの計算に使うモデルごとの料金（100万トークンあたりの米ドル、目安）です。
+var geminiModelPricing = map[string]domain.ModelPricing{
+	"gemini-2.5-pro":                 {InputPerMillion: 1.25, OutputPerMillion: 10.00},
+	"gemini-2.5-flash":               {InputPerMillion: 0.30, OutputPerMillion: 2.50},
+	"gemini-2.5-flash-lite":          {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gemini-2.0-flash":               {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gemini-2.5-flash-image-preview": {InputPerMillion: 0.30, OutputPerMillion: 30.00},
+}
+
+// GeminiModelPricing は model の料金の目安を返します。料金が不明なモデルの場合は false を返します。
+func GeminiModelPricing(model string) (domain.ModelPricing, bool) {
+	pricing, ok := geminiModelPricing[model]
+	return pricing, ok
+}
+
+// DefaultGeminiContextWindow は、コンテキストウィンドウが不明なモデルに使用する入力トークン数の上限です。
+const DefaultGeminiContextWindow = 32_768
+
+// geminiContextWindows は、モデルごとのコンテキストウィンドウ（入力トークン数の上限）です。
+var geminiContextWindows = map[string]int{
+	"gemini-2.5-pro":                 1_048_576,
+	"gemini-2.5-flash":               1_048_576,
+	"gemini-2.5-flash-lite":          1_048_576,
+	"gemini-2.0-flash":               1_048_576,
+	"gemini-2.5-flash-image-preview": 32_768,
+}
+
+// GeminiContextWindow は model のコンテキストウィンドウ（入力トークン数の上限）を返します。不明なモデルの場合は DefaultGeminiContextWindow を返します。
+func GeminiContextWindow(model string) int {
+	if window, ok := geminiContextWindows[model]; ok {
+		return window
+	}
+	return DefaultGeminiContextWindow
+}
+
+// GeminiThinkingBudgetRange は、モデルの思考予算に指定できる範囲です。
+type GeminiThinkingBudgetRange struct {
+	Min        int  // 思考を有効にする場合の最小のトークン数
+	Max        int  // 最大のトークン数
+	CanDisable bool // 思考予算を0にして思考を無効にできるかどうか
+}
+
+// geminiThinkingBudgets は、思考に対応したモデルごとの思考予算の範囲です（含まれないモデルは思考に対応していません）。
+var geminiThinkingBudgets = map[string]GeminiThinkingBudgetRange{
+	"gemini-2.5-pro":        {Min: 128, Max: 32_768, CanDisable: false},
+	"gemini-2.5-flash":      {Min: 1, Max: 24_576, CanDisable: true},
+	"gemini-2.5-flash-lite": {Min: 512, Max: 24_576, CanDisable: true},
+}
+
+// SupportsGeminiThinking は model が思考（思考予算の指定と思考の要約）に対応しているかを返します。
+func SupportsGeminiThinking(model string) bool {
+	_, ok := geminiThinkingBudgets[model]
+	return ok
+}
+
+// GeminiThinkingBudget は budget を model に指定できる思考予算に合わせて返します。思考に対応していないモデルの場合は false を返します。
+// 思考を無効にできないモデルで0が指定された場合は最小の予算を、範囲外の予算はモデルの範囲に収めた値を返します（-1 の動的はそのまま返します）。
+func GeminiThinkingBudget(model string, budget int) (int, bool) {
+	budgetRange, ok := geminiThinkingBudgets[model]
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case budget == domain.ThinkingBudgetDynamic:
+		return budget, true
+	case budget <= domain.ThinkingBudgetOff:
+		if budgetRange.CanDisable {
+			return domain.ThinkingBudgetOff, true
+		}
+		return budgetRange.Min, true
+	case budget < budgetRange.Min:
+		return budgetRange.Min, true
+	case budget > budgetRange.Max:
+		return budgetRange.Max, true
+	default:
+		return budget, true
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+
+	"geminibot/internal/domain"
+)
+
+// Policy は、レート制限の設定を解析し、ユーザー・チャンネル・ギルドごとのレート制限を返します
+func (c RateLimitConfig) Policy() (domain.RateLimitPolicy, error) {
+	policy := domain.RateLimitPolicy{SpamThreshold: c.SpamThreshold}
+	if c.SpamThreshold < 0 {
+		return domain.RateLimitPolicy{}, fmt.Errorf("RATE_LIMIT_SPAM_THRESHOLD は0以上の整数である必要があります")
+	}
+
+	limits := []struct {
+		env    string
+		value  string
+		target *domain.RateLimit
+	}{
+		{"RATE_LIMIT_USER_TEXT", c.UserText, &policy.User.Text},
+		{"RATE_LIMIT_USER_IMAGE", c.UserImage, &policy.User.Image},
+		{"RATE_LIMIT_CHANNEL_TEXT", c.ChannelText, &policy.Channel.Text},
+		{"RATE_LIMIT_CHANNEL_IMAGE", c.ChannelImage, &policy.Channel.Image},
+		{"RATE_LIMIT_GUILD_TEXT", c.GuildText, &policy.Guild.Text},
+		{"RATE_LIMIT_GUILD_IMAGE", c.GuildImage, &policy.Guild.Image},
+	}
+	for _, limit := range limits {
+		parsed, err := domain.ParseRateLimit(limit.value)
+		if err != nil {
+			return domain.RateLimitPolicy{}, fmt.Errorf("%s の値が正しくありません: %w", limit.env, err)
+		}
+		*limit.target = parsed
+	}
+
+	return policy, nil
+}
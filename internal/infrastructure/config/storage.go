@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// StoreKindMemory はプロセス内メモリに保存するストア種別です（再起動で消えます）。
+	StoreKindMemory = "memory"
+	// StoreKindSQLite は SQLite ファイルに永続化するストア種別です。
+	StoreKindSQLite = "sqlite"
+
+	sqliteStorePrefix = StoreKindSQLite + "://"
+)
+
+// ParseGuildConfigStore は GUILD_CONFIG_STORE の値を解析し、ストア種別と SQLite の場合はファイルパスを返します。
+// 空文字は "memory" として扱います。"sqlite:///app/data/bot.db" は絶対パス、"sqlite://data/bot.db" は相対パスになります。
+func (s StorageConfig) ParseGuildConfigStore() (kind string, path string, err error) {
+	store := strings.TrimSpace(s.GuildConfigStore)
+	switch {
+	case store == "" || store == StoreKindMemory:
+		return StoreKindMemory, "", nil
+	case strings.HasPrefix(store, sqliteStorePrefix):
+		path = strings.TrimPrefix(store, sqliteStorePrefix)
+		if path == "" {
+			return "", "", fmt.Errorf("GUILD_CONFIG_STORE に SQLite のファイルパスが指定されていません: %s", store)
+		}
+		return StoreKindSQLite, path, nil
+	default:
+		return "", "", fmt.Errorf("GUILD_CONFIG_STORE は \"memory\" または \"sqlite://<path>\" である必要があります: %s", store)
+	}
+}
@@ -1,6 +1,10 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+
+	"geminibot/internal/domain"
+)
 
 // Validate は、アプリケーション設定の妥当性を検証します。
 func (c *AppConfig) Validate() error {
@@ -40,13 +44,61 @@ func (c *AppConfig) Validate() error {
 		return fmt.Errorf("MAX_HISTORY_LENGTH は MAX_CONTEXT_LENGTH 以下である必要があります")
 	}
 
+	mode, err := domain.ParseContextBudgetMode(c.Bot.ContextBudgetMode)
+	if err != nil {
+		return fmt.Errorf("CONTEXT_BUDGET_MODE が不正です: %w", err)
+	}
+
+	if mode == domain.ContextBudgetTokens && (c.Bot.ContextWindowRatio <= 0 || c.Bot.ContextWindowRatio > 1) {
+		return fmt.Errorf("CONTEXT_WINDOW_RATIO は0より大きく1以下の値である必要があります")
+	}
+
+	if c.Bot.HistorySummaryEnabled() && !IsSupportedGeminiTextModel(c.Bot.HistorySummaryModel) {
+		return fmt.Errorf("HISTORY_SUMMARY_MODEL はサポートされているモデルまたは off である必要があります: %s", c.Bot.HistorySummaryModel)
+	}
+
+	if c.Bot.ReplyChainDepth < 0 {
+		return fmt.Errorf("REPLY_CHAIN_DEPTH は0以上の整数である必要があります")
+	}
+
 	if c.Bot.RequestTimeout <= 0 {
 		return fmt.Errorf("REQUEST_TIMEOUT は正の値である必要があります")
 	}
 
+	if c.Bot.MaxAttachmentBytes <= 0 {
+		return fmt.Errorf("MAX_ATTACHMENT_BYTES は正の整数である必要があります")
+	}
+
 	if c.Gemini.MaxRetries < 0 {
 		return fmt.Errorf("GEMINI_MAX_RETRIES は0以上の整数である必要があります")
 	}
 
+	if c.Gemini.MaxToolIterations < 0 {
+		return fmt.Errorf("GEMINI_MAX_TOOL_ITERATIONS は0以上の整数である必要があります")
+	}
+
+	if c.Gemini.ThinkingBudget != nil {
+		if err := domain.ValidateThinkingBudget(*c.Gemini.ThinkingBudget); err != nil {
+			return fmt.Errorf("GEMINI_THINKING_BUDGET が不正です: %w", err)
+		}
+	}
+
+	if err := ValidateGeminiFallbackModels(c.Gemini.FallbackModels); err != nil {
+		return fmt.Errorf("GEMINI_FALLBACK_MODELS が不正です: %w", err)
+	}
+
+	if _, err := c.RateLimit.Policy(); err != nil {
+		return err
+	}
+
+	kind, _, err := c.Storage.ParseGuildConfigStore()
+	if err != nil {
+		return err
+	}
+
+	if kind == StoreKindSQLite && c.Storage.MasterKey == "" && c.Storage.MasterKeyFile == "" {
+		return fmt.Errorf("GUILD_CONFIG_STORE に sqlite を指定する場合は GUILD_CONFIG_MASTER_KEY または GUILD_CONFIG_MASTER_KEY_FILE が必要です")
+	}
+
 	return nil
 }
@@ -0,0 +1,70 @@
+package discord
+
+import (
+	"context"
+	"sync"
+
+	"geminibot/internal/domain"
+)
+
+// DefaultAnswerStoreCapacity は、インメモリの回答ストアが保持する記録数の既定の上限です
+const DefaultAnswerStoreCapacity = 1000
+
+// AnswerStore は、回答の記録のインメモリ実装です。
+// 上限を超えた場合は古い記録から削除し、プロセス再起動で記録は失われます。
+// 再起動後もボタン操作を受け付ける場合は sqlite.AnswerStore を使用してください。
+type AnswerStore struct {
+	records  map[string]domain.AnswerRecord
+	order    []string // 保存順のメッセージID（古い記録の削除に使用）
+	capacity int
+	mutex    sync.RWMutex
+}
+
+// NewAnswerStore は新しい AnswerStore を作成します。
+// capacity が0以下の場合は DefaultAnswerStoreCapacity を使用します。
+func NewAnswerStore(capacity int) *AnswerStore {
+	if capacity <= 0 {
+		capacity = DefaultAnswerStoreCapacity
+	}
+	return &AnswerStore{
+		records:  make(map[string]domain.AnswerRecord),
+		capacity: capacity,
+	}
+}
+
+// SaveAnswer は、回答の記録を保存します
+func (s *AnswerStore) SaveAnswer(ctx context.Context, record domain.AnswerRecord) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.records[record.MessageID]; !exists {
+		s.order = append(s.order, record.MessageID)
+	}
+	s.records[record.MessageID] = record
+
+	for len(s.order) > s.capacity {
+		delete(s.records, s.order[0])
+		s.order = s.order[1:]
+	}
+	return nil
+}
+
+// GetAnswer は、指定されたメッセージIDの回答の記録を取得します
+func (s *AnswerStore) GetAnswer(ctx context.Context, messageID string) (domain.AnswerRecord, error) {
+	if ctx.Err() != nil {
+		return domain.AnswerRecord{}, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, exists := s.records[messageID]
+	if !exists {
+		return domain.AnswerRecord{}, domain.ErrAnswerNotFound
+	}
+	return record, nil
+}
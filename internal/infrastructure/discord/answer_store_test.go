@@ -0,0 +1,55 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geminibot/internal/domain"
+)
+
+func TestAnswerStore_SaveAndGet(t *testing.T) {
+	store := NewAnswerStore(0)
+	ctx := context.Background()
+
+	record := domain.AnswerRecord{
+		MessageID: "answer1",
+		ChannelID: "channel1",
+		Mention:   domain.BotMention{MessageID: "mention1", Content: "質問"},
+		Content:   "回答",
+		Truncated: true,
+	}
+	if err := store.SaveAnswer(ctx, record); err != nil {
+		t.Fatalf("回答の保存に失敗: %v", err)
+	}
+
+	got, err := store.GetAnswer(ctx, "answer1")
+	if err != nil {
+		t.Fatalf("回答の取得に失敗: %v", err)
+	}
+	if got.Mention.Content != "質問" || got.Content != "回答" || !got.Truncated {
+		t.Errorf("保存した記録と一致しません: %+v", got)
+	}
+
+	if _, err := store.GetAnswer(ctx, "unknown"); !errors.Is(err, domain.ErrAnswerNotFound) {
+		t.Errorf("未保存のメッセージは ErrAnswerNotFound を返すべきです: %v", err)
+	}
+}
+
+func TestAnswerStore_EvictsOldestRecords(t *testing.T) {
+	store := NewAnswerStore(2)
+	ctx := context.Background()
+
+	for _, id := range []string{"answer1", "answer2", "answer3"} {
+		if err := store.SaveAnswer(ctx, domain.AnswerRecord{MessageID: id}); err != nil {
+			t.Fatalf("回答の保存に失敗: %v", err)
+		}
+	}
+
+	if _, err := store.GetAnswer(ctx, "answer1"); !errors.Is(err, domain.ErrAnswerNotFound) {
+		t.Errorf("上限を超えた場合は古い記録から削除されるべきです: %v", err)
+	}
+	if _, err := store.GetAnswer(ctx, "answer3"); err != nil {
+		t.Errorf("新しい記録は保持されるべきです: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// attachmentDownloadTimeout は、添付ファイル1件のダウンロードに許容する最大時間です
+const attachmentDownloadTimeout = 30 * time.Second
+
+// ToDomainAttachments は、Discordのメッセージ添付ファイルをドメインの Attachment に変換します
+// ファイルのデータは含まれないため、必要に応じて AttachmentDownloader でダウンロードします
+func ToDomainAttachments(attachments []*discordgo.MessageAttachment) []domain.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	result := make([]domain.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		if attachment == nil {
+			continue
+		}
+		mimeType := domain.NormalizeAttachmentMimeType(attachment.ContentType, attachment.Filename)
+		result = append(result, domain.Attachment{
+			MimeType: mimeType,
+			Filename: attachment.Filename,
+			Size:     int64(attachment.Size),
+			IsImage:  attachment.Width > 0 && attachment.Height > 0,
+			URL:      attachment.URL,
+		})
+	}
+	return result
+}
+
+// HTTPAttachmentDownloader は、Discord CDNから添付ファイルをダウンロードします
+type HTTPAttachmentDownloader struct {
+	client *http.Client
+}
+
+// NewHTTPAttachmentDownloader は新しいHTTPAttachmentDownloaderインスタンスを作成します
+// client が nil の場合は、タイムアウトを設定した既定のクライアントを使用します
+func NewHTTPAttachmentDownloader(client *http.Client) *HTTPAttachmentDownloader {
+	if client == nil {
+		client = &http.Client{Timeout: attachmentDownloadTimeout}
+	}
+	return &HTTPAttachmentDownloader{client: client}
+}
+
+// Download は、指定されたURLのファイルを maxBytes を上限としてダウンロードします
+func (d *HTTPAttachmentDownloader) Download(ctx context.Context, url string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの作成に失敗: %w", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("添付ファイルの取得に失敗: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("添付ファイルの取得に失敗: HTTPステータス %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("%w: %dバイト（上限 %dバイト）", domain.ErrAttachmentTooLarge, resp.ContentLength, maxBytes)
+	}
+
+	// Content-Lengthが不正確な場合に備え、上限+1バイトまで読み込んで超過を検出する
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("添付ファイルの読み込みに失敗: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: 上限 %dバイト", domain.ErrAttachmentTooLarge, maxBytes)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,62 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestToDomainAttachments(t *testing.T) {
+	attachments := ToDomainAttachments([]*discordgo.MessageAttachment{
+		{Filename: "screenshot.png", ContentType: "image/png", Size: 1024, URL: "https://cdn.example/a.png", Width: 100, Height: 50},
+		{Filename: "notes.txt", ContentType: "text/plain; charset=utf-8", Size: 10, URL: "https://cdn.example/notes.txt"},
+	})
+
+	if len(attachments) != 2 {
+		t.Fatalf("期待される件数: 2, 実際: %d", len(attachments))
+	}
+	if !attachments[0].IsImage || attachments[0].URL != "https://cdn.example/a.png" || attachments[0].Size != 1024 {
+		t.Errorf("画像の添付ファイルが正しく変換されていません: %+v", attachments[0])
+	}
+	if attachments[1].MimeType != "text/plain" {
+		t.Errorf("Content-Typeのパラメータが除去されていません: %s", attachments[1].MimeType)
+	}
+}
+
+func TestHTTPAttachmentDownloader_Download(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	downloader := NewHTTPAttachmentDownloader(server.Client())
+
+	data, err := downloader.Download(context.Background(), server.URL, 100)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if len(data) != 100 {
+		t.Errorf("期待されるサイズ: 100, 実際: %d", len(data))
+	}
+
+	if _, err := downloader.Download(context.Background(), server.URL, 99); !errors.Is(err, domain.ErrAttachmentTooLarge) {
+		t.Errorf("サイズ超過のエラーが返されるべきです: %v", err)
+	}
+}
+
+func TestHTTPAttachmentDownloader_DownloadNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	downloader := NewHTTPAttachmentDownloader(server.Client())
+	if _, err := downloader.Download(context.Background(), server.URL, 100); err == nil {
+		t.Error("HTTPエラーの場合はエラーが返されるべきです")
+	}
+}
@@ -0,0 +1,64 @@
+package discord
+
+import (
+	"context"
+	"sync"
+
+	"geminibot/internal/domain"
+)
+
+// ChannelConfigStore は、チャンネル設定のインメモリ実装です。
+// プロセス再起動で設定は失われます。永続化が必要な場合は sqlite.ChannelConfigStore を使用してください。
+type ChannelConfigStore struct {
+	configs map[string]domain.ChannelConfig
+	mutex   sync.RWMutex
+}
+
+// NewChannelConfigStore は新しい ChannelConfigStore を作成します
+func NewChannelConfigStore() *ChannelConfigStore {
+	return &ChannelConfigStore{
+		configs: make(map[string]domain.ChannelConfig),
+	}
+}
+
+// GetChannelConfig は、指定されたチャンネルの設定を取得します
+func (s *ChannelConfigStore) GetChannelConfig(ctx context.Context, channelID string) (domain.ChannelConfig, error) {
+	if ctx.Err() != nil {
+		return domain.ChannelConfig{}, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	config, exists := s.configs[channelID]
+	if !exists {
+		return domain.ChannelConfig{ChannelID: channelID}, nil
+	}
+	return config, nil
+}
+
+// SaveChannelConfig は、チャンネルの設定を保存します
+func (s *ChannelConfigStore) SaveChannelConfig(ctx context.Context, config domain.ChannelConfig) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.configs[config.ChannelID] = config
+	return nil
+}
+
+// DeleteChannelConfig は、指定されたチャンネルの設定を削除します
+func (s *ChannelConfigStore) DeleteChannelConfig(ctx context.Context, channelID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.configs, channelID)
+	return nil
+}
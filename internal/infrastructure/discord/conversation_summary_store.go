@@ -0,0 +1,51 @@
+package discord
+
+import (
+	"context"
+	"sync"
+
+	"geminibot/internal/domain"
+)
+
+// ConversationSummaryStore は、チャンネルまたはスレッドごとの会話の要約のインメモリ実装です。
+// プロセス再起動で要約は失われます。永続化が必要な場合は sqlite.ConversationSummaryStore を使用してください。
+type ConversationSummaryStore struct {
+	summaries map[string]domain.ConversationSummary
+	mutex     sync.RWMutex
+}
+
+// NewConversationSummaryStore は新しい ConversationSummaryStore を作成します
+func NewConversationSummaryStore() *ConversationSummaryStore {
+	return &ConversationSummaryStore{
+		summaries: make(map[string]domain.ConversationSummary),
+	}
+}
+
+// GetConversationSummary は、チャンネルの会話の要約を取得します
+func (s *ConversationSummaryStore) GetConversationSummary(ctx context.Context, channelID string) (domain.ConversationSummary, error) {
+	if ctx.Err() != nil {
+		return domain.ConversationSummary{}, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	summary, ok := s.summaries[channelID]
+	if !ok {
+		return domain.ConversationSummary{ChannelID: channelID}, nil
+	}
+	return summary, nil
+}
+
+// SaveConversationSummary は、チャンネルの会話の要約を保存します
+func (s *ConversationSummaryStore) SaveConversationSummary(ctx context.Context, summary domain.ConversationSummary) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.summaries[summary.ChannelID] = summary
+	return nil
+}
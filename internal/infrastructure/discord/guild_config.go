@@ -10,7 +10,7 @@ import (
 )
 
 // GuildConfigManager は、Discord用のギルド別 API キー／モデル設定のインメモリ実装です。
-// 現在はメモリベースですが、将来的にはデータベースやKVストアに変更可能です。
+// プロセス再起動で設定は失われます。永続化が必要な場合は sqlite.GuildConfigManager を使用してください。
 type GuildConfigManager struct {
 	apiKeys          map[string]domain.GuildConfig
 	mutex            sync.RWMutex
@@ -49,13 +49,13 @@ func (r *GuildConfigManager) SetAPIKey(ctx context.Context, guildID, apiKey, set
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	// 既存の設定がある場合は、モデル設定を保持
-	model := ""
-	if existing, exists := r.apiKeys[guildID]; exists {
-		model = existing.Model
-	}
-
-	guildAPIKey := r.makeGuildConfig(guildID, apiKey, setBy, model)
+	// 既存の設定がある場合は、モデル・システムプロンプト・グラウンディング・思考の設定・フォールバックチェーンを保持
+	existing := r.apiKeys[guildID]
+	guildAPIKey := r.makeGuildConfig(guildID, apiKey, setBy, existing.Model)
+	guildAPIKey.SystemPrompt = existing.SystemPrompt
+	guildAPIKey.SearchGrounding = existing.SearchGrounding
+	guildAPIKey.Thinking = existing.Thinking
+	guildAPIKey.FallbackModels = existing.FallbackModels
 	r.apiKeys[guildID] = guildAPIKey
 
 	return nil
@@ -71,14 +71,14 @@ func (r *GuildConfigManager) GetAPIKey(ctx context.Context, guildID string) (str
 	defer r.mutex.RUnlock()
 
 	guildAPIKey, exists := r.apiKeys[guildID]
-	if !exists {
+	if !exists || guildAPIKey.APIKey == "" {
 		return "", fmt.Errorf("ギルド %s のAPIキーが設定されていません", guildID)
 	}
 
 	return guildAPIKey.APIKey, nil
 }
 
-// DeleteAPIKey は、指定されたギルドのAPIキーを削除します
+// DeleteAPIKey は、指定されたギルドのAPIキーを削除します（モデル設定は保持されます）
 func (r *GuildConfigManager) DeleteAPIKey(ctx context.Context, guildID string) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -87,11 +87,15 @@ func (r *GuildConfigManager) DeleteAPIKey(ctx context.Context, guildID string) e
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, exists := r.apiKeys[guildID]; !exists {
+	existing, exists := r.apiKeys[guildID]
+	if !exists || existing.APIKey == "" {
 		return fmt.Errorf("ギルド %s のAPIキーが設定されていません", guildID)
 	}
 
-	delete(r.apiKeys, guildID)
+	existing.APIKey = ""
+	existing.SetBy = ""
+	existing.SetAt = time.Time{}
+	r.apiKeys[guildID] = existing
 	return nil
 }
 
@@ -104,8 +108,8 @@ func (r *GuildConfigManager) HasAPIKey(ctx context.Context, guildID string) (boo
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	_, exists := r.apiKeys[guildID]
-	return exists, nil
+	guildAPIKey, exists := r.apiKeys[guildID]
+	return exists && guildAPIKey.APIKey != "", nil
 }
 
 // GetGuildAPIKeyInfo は、指定されたギルドのAPIキー情報を取得します（APIキーは含まれません）
@@ -118,12 +122,13 @@ func (r *GuildConfigManager) GetGuildAPIKeyInfo(ctx context.Context, guildID str
 	defer r.mutex.RUnlock()
 
 	guildAPIKey, exists := r.apiKeys[guildID]
-	if !exists {
+	if !exists || guildAPIKey.APIKey == "" {
 		return domain.GuildConfig{}, fmt.Errorf("ギルド %s のAPIキーが設定されていません", guildID)
 	}
 
-	// APIキーを空文字にして返す（セキュリティのため）
+	// APIキーを空文字にし、代わりにフィンガープリントを返す（セキュリティのため）
 	info := guildAPIKey
+	info.APIKeyFingerprint = domain.FingerprintAPIKey(guildAPIKey.APIKey)
 	info.APIKey = ""
 	return info, nil
 }
@@ -168,3 +173,133 @@ func (r *GuildConfigManager) GetGuildModel(ctx context.Context, guildID string)
 
 	return guildAPIKey.Model, nil
 }
+
+// SetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを設定します
+func (r *GuildConfigManager) SetGuildSystemPrompt(ctx context.Context, guildID, prompt string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// 既存の設定がある場合は更新、ない場合は新規作成
+	if existing, exists := r.apiKeys[guildID]; exists {
+		existing.SystemPrompt = prompt
+		r.apiKeys[guildID] = existing
+	} else {
+		guildConfig := r.makeGuildConfig(guildID, "", "", "")
+		guildConfig.SystemPrompt = prompt
+		r.apiKeys[guildID] = guildConfig
+	}
+
+	return nil
+}
+
+// GetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを取得します
+func (r *GuildConfigManager) GetGuildSystemPrompt(ctx context.Context, guildID string) (string, error) {
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.apiKeys[guildID].SystemPrompt, nil
+}
+
+// SetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを設定します
+func (r *GuildConfigManager) SetGuildSearchGrounding(ctx context.Context, guildID string, enabled bool) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// 既存の設定がある場合は更新、ない場合は新規作成
+	guildConfig, exists := r.apiKeys[guildID]
+	if !exists {
+		guildConfig = r.makeGuildConfig(guildID, "", "", "")
+	}
+	guildConfig.SearchGrounding = enabled
+	r.apiKeys[guildID] = guildConfig
+
+	return nil
+}
+
+// GetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを取得します
+func (r *GuildConfigManager) GetGuildSearchGrounding(ctx context.Context, guildID string) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.apiKeys[guildID].SearchGrounding, nil
+}
+
+// SetGuildThinking は、指定されたギルドの思考の設定を保存します
+func (r *GuildConfigManager) SetGuildThinking(ctx context.Context, guildID string, thinking domain.ThinkingSettings) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// 既存の設定がある場合は更新、ない場合は新規作成
+	guildConfig, exists := r.apiKeys[guildID]
+	if !exists {
+		guildConfig = r.makeGuildConfig(guildID, "", "", "")
+	}
+	guildConfig.Thinking = thinking
+	r.apiKeys[guildID] = guildConfig
+
+	return nil
+}
+
+// GetGuildThinking は、指定されたギルドの思考の設定を取得します
+func (r *GuildConfigManager) GetGuildThinking(ctx context.Context, guildID string) (domain.ThinkingSettings, error) {
+	if ctx.Err() != nil {
+		return domain.ThinkingSettings{}, ctx.Err()
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.apiKeys[guildID].Thinking, nil
+}
+
+// SetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを保存します（空の場合は全体の既定に戻します）
+func (r *GuildConfigManager) SetGuildFallbackModels(ctx context.Context, guildID string, models []string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// 既存の設定がある場合は更新、ない場合は新規作成（呼び出し元のスライスを共有しないようコピーする）
+	guildConfig, exists := r.apiKeys[guildID]
+	if !exists {
+		guildConfig = r.makeGuildConfig(guildID, "", "", "")
+	}
+	guildConfig.FallbackModels = append([]string(nil), models...)
+	r.apiKeys[guildID] = guildConfig
+
+	return nil
+}
+
+// GetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを取得します
+func (r *GuildConfigManager) GetGuildFallbackModels(ctx context.Context, guildID string) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return append([]string(nil), r.apiKeys[guildID].FallbackModels...), nil
+}
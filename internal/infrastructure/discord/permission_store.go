@@ -0,0 +1,78 @@
+package discord
+
+import (
+	"context"
+	"sync"
+
+	"geminibot/internal/domain"
+)
+
+// PermissionStore は、ギルドの権限ルールのインメモリ実装です。
+// プロセス再起動でルールは失われます。永続化が必要な場合は sqlite.PermissionStore を使用してください。
+type PermissionStore struct {
+	rules map[string][]domain.PermissionRule // ギルドIDごとの権限ルール（登録順）
+	mutex sync.RWMutex
+}
+
+// NewPermissionStore は新しい PermissionStore を作成します
+func NewPermissionStore() *PermissionStore {
+	return &PermissionStore{
+		rules: make(map[string][]domain.PermissionRule),
+	}
+}
+
+// SavePermissionRule は、権限ルールを保存します
+func (s *PermissionStore) SavePermissionRule(ctx context.Context, rule domain.PermissionRule) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rules := s.rules[rule.GuildID]
+	for i, existing := range rules {
+		if sameRuleTarget(existing, rule.Permission, rule.TargetType, rule.TargetID) {
+			rules[i] = rule
+			return nil
+		}
+	}
+	s.rules[rule.GuildID] = append(rules, rule)
+	return nil
+}
+
+// DeletePermissionRule は、指定された権限・対象のルールを削除します
+func (s *PermissionStore) DeletePermissionRule(ctx context.Context, guildID string, permission domain.Permission, targetType domain.PermissionTargetType, targetID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rules := s.rules[guildID]
+	for i, existing := range rules {
+		if sameRuleTarget(existing, permission, targetType, targetID) {
+			s.rules[guildID] = append(rules[:i:i], rules[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrPermissionRuleNotFound
+}
+
+// ListPermissionRules は、指定されたギルドの権限ルールを取得します
+func (s *PermissionStore) ListPermissionRules(ctx context.Context, guildID string) ([]domain.PermissionRule, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]domain.PermissionRule(nil), s.rules[guildID]...), nil
+}
+
+// sameRuleTarget は、権限ルールが指定された権限・対象のものかどうかを返します
+func sameRuleTarget(rule domain.PermissionRule, permission domain.Permission, targetType domain.PermissionTargetType, targetID string) bool {
+	return rule.Permission == permission && rule.TargetType == targetType && rule.TargetID == targetID
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 
 	"geminibot/internal/domain"
 
@@ -12,16 +13,27 @@ import (
 
 // DiscordConversationRepository は、Discord APIを使用してConversationRepositoryインターフェースを実装します
 type DiscordConversationRepository struct {
-	session *discordgo.Session
+	session          *discordgo.Session
+	includeOtherBots bool
 }
 
 // NewDiscordConversationRepository は新しいDiscordConversationRepositoryインスタンスを作成します
-func NewDiscordConversationRepository(session *discordgo.Session) *DiscordConversationRepository {
+// Bot自身のメッセージは常に履歴に含まれ、includeOtherBots が true の場合は他のBotのメッセージも含めます
+func NewDiscordConversationRepository(session *discordgo.Session, includeOtherBots bool) *DiscordConversationRepository {
 	return &DiscordConversationRepository{
-		session: session,
+		session:          session,
+		includeOtherBots: includeOtherBots,
 	}
 }
 
+const (
+	// messagePageSize は、Discord APIで一度に取得できるメッセージの最大件数です
+	messagePageSize = 100
+
+	// maxThreadMessages は、スレッドから取得するメッセージ数の上限です（ContextManagerで更に切り詰められます）
+	maxThreadMessages = 500
+)
+
 // GetRecentMessages は、指定されたチャンネルの直近のメッセージを取得します
 func (r *DiscordConversationRepository) GetRecentMessages(ctx context.Context, channelID string, limit int) ([]domain.Message, error) {
 	log.Printf("Discordから直近%d件のメッセージを取得中: %s", limit, channelID)
@@ -29,49 +41,80 @@ func (r *DiscordConversationRepository) GetRecentMessages(ctx context.Context, c
 	messages, err := r.session.ChannelMessages(channelID, limit, "", "", "")
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return nil, fmt.Errorf("Discord APIからのメッセージ取得がタイムアウトしました: %w", err)
+			return nil, fmt.Errorf("Discord APIからのメッセージ取得が%w: %w", domain.ErrTimeout, err)
 		}
 		return nil, fmt.Errorf("Discord APIからメッセージ取得に失敗: %w", err)
 	}
 
-	domainMessages := make([]domain.Message, 0, len(messages))
-	for _, msg := range messages {
-		// Botのメッセージは除外
-		if msg.Author.Bot {
-			continue
-		}
+	return r.toDomainMessages(messages), nil
+}
 
-		timestamp := msg.Timestamp
+// GetThreadMessages は、指定されたスレッドの全メッセージを起点メッセージから時系列順に取得します
+func (r *DiscordConversationRepository) GetThreadMessages(ctx context.Context, threadID string) ([]domain.Message, error) {
+	log.Printf("Discordからスレッドの全メッセージを取得中: %s", threadID)
 
-		// ユーザー情報を作成
-		user := domain.User{
-			ID:            msg.Author.ID,
-			Username:      msg.Author.Username,
-			DisplayName:   r.getDisplayName(msg),
-			Avatar:        msg.Author.Avatar,
-			Discriminator: msg.Author.Discriminator,
-			IsBot:         msg.Author.Bot,
+	// 新しい順に返されるため、最も古いメッセージIDを起点にページングする
+	var messages []*discordgo.Message
+	before := ""
+	for len(messages) < maxThreadMessages {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("スレッドのメッセージ取得が中断されました: %w", ctx.Err())
 		}
 
-		domainMessage := domain.Message{
-			ID:        msg.ID,
-			User:      user,
-			Content:   msg.Content,
-			Timestamp: timestamp,
+		page, err := r.session.ChannelMessages(threadID, messagePageSize, before, "", "")
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, fmt.Errorf("Discord APIからのメッセージ取得が%w: %w", domain.ErrTimeout, err)
+			}
+			return nil, fmt.Errorf("Discord APIからスレッドのメッセージ取得に失敗: %w", err)
 		}
-		domainMessages = append(domainMessages, domainMessage)
+
+		messages = append(messages, page...)
+		if len(page) < messagePageSize {
+			break
+		}
+		before = page[len(page)-1].ID
+	}
+
+	// スレッドの起点メッセージ（親チャンネル側のメッセージ）を追加
+	if starter := r.getThreadStarterMessage(threadID, messages); starter != nil {
+		messages = append(messages, starter)
 	}
 
+	domainMessages := r.toDomainMessages(messages)
+	log.Printf("スレッドのメッセージを取得: %d件", len(domainMessages))
 	return domainMessages, nil
 }
 
-// GetThreadMessages は、指定されたスレッドの全メッセージを取得します
-func (r *DiscordConversationRepository) GetThreadMessages(ctx context.Context, threadID string) ([]domain.Message, error) {
-	log.Printf("Discordからスレッドの全メッセージを取得中: %s", threadID)
+// getThreadStarterMessage は、スレッドの起点となった親チャンネルのメッセージを取得します
+// フォーラムのスレッドなど、起点メッセージがスレッド内にある場合や取得できない場合は nil を返します
+func (r *DiscordConversationRepository) getThreadStarterMessage(threadID string, threadMessages []*discordgo.Message) *discordgo.Message {
+	for _, msg := range threadMessages {
+		if msg.ID == threadID {
+			return nil
+		}
+	}
+
+	// メッセージから作成されたスレッドは、スレッドIDと起点メッセージIDが一致する
+	thread, err := r.session.State.Channel(threadID)
+	if err != nil {
+		thread, err = r.session.Channel(threadID)
+		if err != nil {
+			log.Printf("スレッド情報の取得に失敗: %v", err)
+			return nil
+		}
+	}
+	if thread.ParentID == "" {
+		return nil
+	}
 
-	// スレッドの場合は十分な数のメッセージを取得（コンテキスト長制限で調整される）
-	const threadMessageLimit = 200
-	return r.GetRecentMessages(ctx, threadID, threadMessageLimit)
+	starter, err := r.session.ChannelMessage(thread.ParentID, threadID)
+	if err != nil {
+		// メッセージを起点としないスレッドでは存在しないため、ログのみ出力
+		log.Printf("スレッドの起点メッセージを取得できませんでした: %v", err)
+		return nil
+	}
+	return starter
 }
 
 // GetMessagesBefore は、指定されたメッセージIDより前のメッセージを取得します
@@ -81,40 +124,124 @@ func (r *DiscordConversationRepository) GetMessagesBefore(ctx context.Context, c
 	messages, err := r.session.ChannelMessages(channelID, limit, messageID, "", "")
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return nil, fmt.Errorf("Discord APIからのメッセージ取得がタイムアウトしました: %w", err)
+			return nil, fmt.Errorf("Discord APIからのメッセージ取得が%w: %w", domain.ErrTimeout, err)
 		}
 		return nil, fmt.Errorf("Discord APIからメッセージ取得に失敗: %w", err)
 	}
 
+	return r.toDomainMessages(messages), nil
+}
+
+// GetReplyChain は、指定されたメッセージから返信元をたどり、最大 depth 件のメッセージを古い順に取得します
+// 返信元が削除されている場合などは、そこまでにたどったメッセージを返します
+func (r *DiscordConversationRepository) GetReplyChain(ctx context.Context, channelID string, messageID string, depth int) ([]domain.Message, error) {
+	log.Printf("Discordから返信の連鎖を取得中: %s/%s（最大%d件）", channelID, messageID, depth)
+
+	var chain []*discordgo.Message
+	seen := make(map[string]bool)
+	for len(chain) < depth && messageID != "" && !seen[messageID] {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("返信の連鎖の取得が中断されました: %w", ctx.Err())
+		}
+		seen[messageID] = true
+
+		msg, err := r.session.ChannelMessage(channelID, messageID)
+		if err != nil {
+			if len(chain) == 0 {
+				return nil, fmt.Errorf("Discord APIから返信先のメッセージ取得に失敗: %w", err)
+			}
+			log.Printf("返信元のメッセージを取得できませんでした: %v", err)
+			break
+		}
+		chain = append(chain, msg)
+
+		messageID = ""
+		if ref := replyReference(msg); ref != nil {
+			messageID = ref.MessageID
+			if ref.ChannelID != "" {
+				channelID = ref.ChannelID
+			}
+		}
+	}
+
+	return r.toDomainMessages(chain), nil
+}
+
+// replyReference は、メッセージが返信の場合に返信先の参照を返します（転送などの参照は nil を返します）
+func replyReference(msg *discordgo.Message) *discordgo.MessageReference {
+	if msg.MessageReference == nil || msg.MessageReference.Type != discordgo.MessageReferenceTypeDefault {
+		return nil
+	}
+	return msg.MessageReference
+}
+
+// toDomainMessages は、DiscordのメッセージをドメインのMessageに変換し、時系列順（古い順）に並べます
+func (r *DiscordConversationRepository) toDomainMessages(messages []*discordgo.Message) []domain.Message {
 	domainMessages := make([]domain.Message, 0, len(messages))
+	selfID := r.selfID()
+
+	// 長いスレッドでメンバー情報の取得が繰り返されないよう、表示名を投稿者ごとにキャッシュ
+	displayNames := make(map[string]string)
+
 	for _, msg := range messages {
-		// Botのメッセージは除外
-		if msg.Author.Bot {
+		if msg.Author == nil {
+			continue
+		}
+
+		// Bot自身のメッセージはモデルの発言として含め、他のBotは設定に応じて除外
+		fromSelf := selfID != "" && msg.Author.ID == selfID
+		if msg.Author.Bot && !fromSelf && !r.includeOtherBots {
+			continue
+		}
+
+		// スレッド開始を示すシステムメッセージは本文を持たないため除外
+		if msg.Type == discordgo.MessageTypeThreadStarterMessage {
 			continue
 		}
 
 		timestamp := msg.Timestamp
 
+		displayName, ok := displayNames[msg.Author.ID]
+		if !ok {
+			displayName = r.getDisplayName(msg)
+			displayNames[msg.Author.ID] = displayName
+		}
+
 		// ユーザー情報を作成
 		user := domain.User{
 			ID:            msg.Author.ID,
 			Username:      msg.Author.Username,
-			DisplayName:   r.getDisplayName(msg),
+			DisplayName:   displayName,
 			Avatar:        msg.Author.Avatar,
 			Discriminator: msg.Author.Discriminator,
 			IsBot:         msg.Author.Bot,
 		}
 
 		domainMessage := domain.Message{
-			ID:        msg.ID,
-			User:      user,
-			Content:   msg.Content,
-			Timestamp: timestamp,
+			ID:          msg.ID,
+			User:        user,
+			Content:     msg.Content,
+			Timestamp:   timestamp,
+			FromSelf:    fromSelf,
+			Attachments: ToDomainAttachments(msg.Attachments),
 		}
 		domainMessages = append(domainMessages, domainMessage)
 	}
 
-	return domainMessages, nil
+	// Discord APIは新しい順に返すため、会話の流れに合わせて古い順に並べ替える
+	sort.Slice(domainMessages, func(i, j int) bool {
+		return domainMessages[i].Timestamp.Before(domainMessages[j].Timestamp)
+	})
+
+	return domainMessages
+}
+
+// selfID は、このBot自身のユーザーIDを返します（Ready受信前は空文字）
+func (r *DiscordConversationRepository) selfID() string {
+	if r.session.State == nil || r.session.State.User == nil {
+		return ""
+	}
+	return r.session.State.User.ID
 }
 
 // getDisplayName は、Discordメッセージから表示名を取得します
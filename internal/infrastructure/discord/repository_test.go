@@ -8,7 +8,7 @@ import (
 
 func TestNewDiscordConversationRepository(t *testing.T) {
 	session := &discordgo.Session{}
-	repo := NewDiscordConversationRepository(session)
+	repo := NewDiscordConversationRepository(session, false)
 
 	if repo.session != session {
 		t.Error("セッションが正しく設定されていません")
@@ -0,0 +1,181 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// defaultPinnedMessageLimit は、ピン留めされたメッセージを返す既定の件数です
+	defaultPinnedMessageLimit = 5
+	// maxPinnedMessageLimit は、ピン留めされたメッセージを返す最大件数です
+	maxPinnedMessageLimit = 20
+	// maxPinnedMessageRunes は、ピン留めされたメッセージ1件あたりの本文の最大文字数です
+	maxPinnedMessageRunes = 500
+)
+
+// ServerInfoTool は、メンションされたサーバーの情報を返すツールです
+// 参照するサーバーはモデルの引数ではなく、ツールを呼び出したメンションのサーバーに限ります
+type ServerInfoTool struct {
+	session *discordgo.Session
+}
+
+// NewServerInfoTool は新しいServerInfoToolインスタンスを作成します
+func NewServerInfoTool(session *discordgo.Session) *ServerInfoTool {
+	return &ServerInfoTool{session: session}
+}
+
+// Name は、ツールの名前を返します
+func (t *ServerInfoTool) Name() string {
+	return "get_server_info"
+}
+
+// Description は、ツールの説明を返します
+func (t *ServerInfoTool) Description() string {
+	return "このDiscordサーバーの名前・説明・メンバー数・チャンネル数・ロール数・作成日時を取得します。"
+}
+
+// Parameters は、引数のJSON Schemaを返します
+func (t *ServerInfoTool) Parameters() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	}
+}
+
+// Execute は、メンションされたサーバーの情報を返します
+func (t *ServerInfoTool) Execute(ctx context.Context, _ map[string]any) (map[string]any, error) {
+	guildID := domain.ToolCallContextFrom(ctx).GuildID
+	if guildID == "" {
+		return nil, fmt.Errorf("サーバー外（DM）ではサーバーの情報を取得できません")
+	}
+
+	guild, err := t.lookupGuild(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("サーバーの情報の取得に失敗: %w", err)
+	}
+
+	info := map[string]any{
+		"name":         guild.Name,
+		"description":  guild.Description,
+		"member_count": guildMemberCount(guild),
+		"role_count":   len(guild.Roles),
+		"boost_level":  int(guild.PremiumTier),
+	}
+	if len(guild.Channels) > 0 {
+		info["channel_count"] = len(guild.Channels)
+	}
+	if createdAt, err := discordgo.SnowflakeTimestamp(guild.ID); err == nil {
+		info["created_at"] = createdAt.Format(time.RFC3339)
+	}
+	return info, nil
+}
+
+// lookupGuild は、キャッシュ済みのサーバー情報を優先し、なければDiscord APIからメンバー数を含めて取得します
+func (t *ServerInfoTool) lookupGuild(guildID string) (*discordgo.Guild, error) {
+	if t.session.State != nil {
+		if guild, err := t.session.State.Guild(guildID); err == nil {
+			return guild, nil
+		}
+	}
+	return t.session.GuildWithCounts(guildID)
+}
+
+// guildMemberCount は、サーバーのメンバー数を返します（キャッシュにない場合は概数を使用します）
+func guildMemberCount(guild *discordgo.Guild) int {
+	if guild.MemberCount > 0 {
+		return guild.MemberCount
+	}
+	return guild.ApproximateMemberCount
+}
+
+// PinnedMessagesTool は、メンションされたチャンネルにピン留めされたメッセージを返すツールです
+// 参照するチャンネルはモデルの引数ではなく、ツールを呼び出したメンションのチャンネルに限ります
+type PinnedMessagesTool struct {
+	session *discordgo.Session
+}
+
+// NewPinnedMessagesTool は新しいPinnedMessagesToolインスタンスを作成します
+func NewPinnedMessagesTool(session *discordgo.Session) *PinnedMessagesTool {
+	return &PinnedMessagesTool{session: session}
+}
+
+// Name は、ツールの名前を返します
+func (t *PinnedMessagesTool) Name() string {
+	return "get_pinned_messages"
+}
+
+// Description は、ツールの説明を返します
+func (t *PinnedMessagesTool) Description() string {
+	return "このチャンネルにピン留めされたメッセージを新しい順に取得します。ルールやお知らせなど、チャンネルの重要な情報の確認に使用します。"
+}
+
+// Parameters は、引数のJSON Schemaを返します
+func (t *PinnedMessagesTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("取得する件数（1〜%d、既定は%d）", maxPinnedMessageLimit, defaultPinnedMessageLimit),
+			},
+		},
+	}
+}
+
+// Execute は、メンションされたチャンネルにピン留めされたメッセージを返します
+func (t *PinnedMessagesTool) Execute(ctx context.Context, args map[string]any) (map[string]any, error) {
+	channelID := domain.ToolCallContextFrom(ctx).ChannelID
+	if channelID == "" {
+		return nil, fmt.Errorf("チャンネルが特定できないため、ピン留めされたメッセージを取得できません")
+	}
+
+	limit := defaultPinnedMessageLimit
+	if value, ok := args["limit"].(float64); ok && value >= 1 {
+		limit = min(int(value), maxPinnedMessageLimit)
+	}
+
+	pinned, err := t.session.ChannelMessagesPinned(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("ピン留めされたメッセージの取得に失敗: %w", err)
+	}
+
+	return map[string]any{
+		"messages": pinnedMessagesResult(pinned, limit),
+		"total":    len(pinned),
+	}, nil
+}
+
+// pinnedMessagesResult は、ピン留めされたメッセージを新しい順に limit 件まで、モデルに返す形式に変換します
+func pinnedMessagesResult(pinned []*discordgo.Message, limit int) []map[string]any {
+	messages := make([]map[string]any, 0, min(len(pinned), limit))
+	for _, msg := range pinned {
+		if len(messages) >= limit {
+			break
+		}
+		author := ""
+		if msg.Author != nil {
+			author = msg.Author.Username
+			if msg.Member != nil && msg.Member.Nick != "" {
+				author = msg.Member.Nick
+			}
+		}
+
+		content := []rune(msg.Content)
+		if len(content) > maxPinnedMessageRunes {
+			content = append(content[:maxPinnedMessageRunes], '…')
+		}
+		messages = append(messages, map[string]any{
+			"author":      author,
+			"content":     string(content),
+			"posted_at":   msg.Timestamp.Format(time.RFC3339),
+			"attachments": len(msg.Attachments),
+		})
+	}
+	return messages
+}
@@ -0,0 +1,63 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestServerInfoTool_UsesCallerGuild(t *testing.T) {
+	session := &discordgo.Session{State: discordgo.NewState()}
+	guild := &discordgo.Guild{
+		ID:          "175928847299117063",
+		Name:        "テストサーバー",
+		MemberCount: 42,
+		Roles:       []*discordgo.Role{{ID: "r1"}, {ID: "r2"}},
+		Channels:    []*discordgo.Channel{{ID: "c1"}},
+	}
+	if err := session.State.GuildAdd(guild); err != nil {
+		t.Fatalf("サーバーの追加に失敗: %v", err)
+	}
+	tool := NewServerInfoTool(session)
+
+	ctx := domain.WithToolCallContext(context.Background(), domain.ToolCallContext{GuildID: guild.ID})
+	info, err := tool.Execute(ctx, map[string]any{"guild_id": "other"})
+	if err != nil {
+		t.Fatalf("サーバーの情報の取得に失敗: %v", err)
+	}
+	if info["name"] != "テストサーバー" || info["member_count"] != 42 || info["role_count"] != 2 || info["channel_count"] != 1 {
+		t.Errorf("呼び出し元のサーバーの情報を返すべきです: %+v", info)
+	}
+	if _, ok := info["created_at"]; !ok {
+		t.Errorf("サーバーの作成日時を含めるべきです: %+v", info)
+	}
+
+	if _, err := tool.Execute(context.Background(), nil); err == nil {
+		t.Error("サーバー外ではエラーを返すべきです")
+	}
+}
+
+func TestPinnedMessagesResult(t *testing.T) {
+	now := time.Now()
+	pinned := []*discordgo.Message{
+		{Author: &discordgo.User{Username: "alice"}, Member: &discordgo.Member{Nick: "Alice"}, Content: "ルール", Timestamp: now},
+		{Author: &discordgo.User{Username: "bob"}, Content: strings.Repeat("長", 600), Timestamp: now.Add(-time.Hour)},
+		{Author: &discordgo.User{Username: "carol"}, Content: "古いお知らせ", Timestamp: now.Add(-2 * time.Hour)},
+	}
+
+	messages := pinnedMessagesResult(pinned, 2)
+	if len(messages) != 2 {
+		t.Fatalf("指定した件数までに制限するべきです: %d件", len(messages))
+	}
+	if messages[0]["author"] != "Alice" || messages[0]["content"] != "ルール" {
+		t.Errorf("ニックネームと本文を返すべきです: %+v", messages[0])
+	}
+	if content := messages[1]["content"].(string); len([]rune(content)) != maxPinnedMessageRunes+1 || !strings.HasSuffix(content, "…") {
+		t.Errorf("長い本文は切り詰めるべきです: %d文字", len([]rune(content)))
+	}
+}
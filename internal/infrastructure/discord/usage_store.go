@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// usageRecordRetention は、メモリに保持する使用量の記録の期間です（前月分まで集計できる長さ）
+const usageRecordRetention = 62 * 24 * time.Hour
+
+// UsageStore は、トークンの使用量の記録とギルドの利用上限のインメモリ実装です。
+// プロセス再起動で記録は失われます。永続化が必要な場合は sqlite.UsageStore を使用してください。
+type UsageStore struct {
+	records []domain.UsageRecord // 記録順の使用量
+	budgets map[string]domain.UsageBudget
+	mutex   sync.RWMutex
+}
+
+// NewUsageStore は新しい UsageStore を作成します
+func NewUsageStore() *UsageStore {
+	return &UsageStore{
+		budgets: make(map[string]domain.UsageBudget),
+	}
+}
+
+// RecordUsage は、使用量の記録を追加し、保持期間を過ぎた記録を削除します
+func (s *UsageStore) RecordUsage(ctx context.Context, record domain.UsageRecord) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expired := 0
+	cutoff := record.CreatedAt.Add(-usageRecordRetention)
+	for expired < len(s.records) && s.records[expired].CreatedAt.Before(cutoff) {
+		expired++
+	}
+	s.records = append(s.records[expired:], record)
+	return nil
+}
+
+// SummarizeUsage は、条件に一致する使用量をモデルごとに集計して返します（モデル名順）
+func (s *UsageStore) SummarizeUsage(ctx context.Context, query domain.UsageQuery) ([]domain.ModelUsage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	byModel := make(map[string]*domain.ModelUsage)
+	for _, record := range s.records {
+		if !query.Matches(record) {
+			continue
+		}
+		usage, ok := byModel[record.Model]
+		if !ok {
+			usage = &domain.ModelUsage{Model: record.Model}
+			byModel[record.Model] = usage
+		}
+		usage.Requests++
+		usage.Usage = usage.Usage.Add(record.Usage)
+	}
+
+	result := make([]domain.ModelUsage, 0, len(byModel))
+	for _, usage := range byModel {
+		result = append(result, *usage)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Model < result[j].Model })
+	return result, nil
+}
+
+// GetUsageBudget は、ギルドの利用上限を取得します（設定されていない場合は空の上限を返します）
+func (s *UsageStore) GetUsageBudget(ctx context.Context, guildID string) (domain.UsageBudget, error) {
+	if ctx.Err() != nil {
+		return domain.UsageBudget{}, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if budget, ok := s.budgets[guildID]; ok {
+		return budget, nil
+	}
+	return domain.UsageBudget{GuildID: guildID}, nil
+}
+
+// SaveUsageBudget は、ギルドの利用上限を保存します
+func (s *UsageStore) SaveUsageBudget(ctx context.Context, budget domain.UsageBudget) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.budgets[budget.GuildID] = budget
+	return nil
+}
+
+// DeleteUsageBudget は、ギルドの利用上限を削除します
+func (s *UsageStore) DeleteUsageBudget(ctx context.Context, guildID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.budgets, guildID)
+	return nil
+}
@@ -2,6 +2,7 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -18,6 +19,7 @@ import (
 type GeminiAPIClient struct {
 	client *genai.Client
 	config *config.GeminiConfig
+	tools  *domain.ToolRegistry // 関数呼び出しでモデルが使用できるツール（nil の場合は使用しません）
 }
 
 // NewGeminiAPIClient は新しいGeminiAPIClientインスタンスを作成します
@@ -46,6 +48,11 @@ func NewGeminiAPIClient(geminiConfig *config.GeminiConfig) (*GeminiAPIClient, er
 	}, nil
 }
 
+// SetTools は、関数呼び出しでモデルが使用できるツールを設定します
+func (g *GeminiAPIClient) SetTools(tools *domain.ToolRegistry) {
+	g.tools = tools
+}
+
 // createSafetySettings は、安全フィルター設定を作成します
 func (g *GeminiAPIClient) createSafetySettings() []*genai.SafetySetting {
 	return []*genai.SafetySetting{
@@ -90,25 +97,9 @@ func (g *GeminiAPIClient) createGenerateConfigWithOptions(options application.Te
 	}
 }
 
-// handleAPIError は、APIエラーを統一して処理します
+// handleAPIError は、APIエラーを種類ごとのドメインのエラーに分類して返します
 func (g *GeminiAPIClient) handleAPIError(err error, ctx context.Context) error {
-	if ctx.Err() == context.DeadlineExceeded {
-		return fmt.Errorf("Gemini APIへのリクエストがタイムアウトしました。時間を置いて再度お試しください: %w", err)
-	}
-
-	// エラーメッセージをより詳細に
-	errStr := err.Error()
-	if strings.Contains(errStr, "quota") || strings.Contains(errStr, "limit") {
-		return fmt.Errorf("Gemini APIの利用制限に達しました。しばらく時間を置いてから再度お試しください: %w", err)
-	}
-	if strings.Contains(errStr, "permission") || strings.Contains(errStr, "unauthorized") {
-		return fmt.Errorf("Gemini APIへのアクセス権限がありません。APIキーを確認してください: %w", err)
-	}
-	if strings.Contains(errStr, "network") || strings.Contains(errStr, "connection") {
-		return fmt.Errorf("ネットワークエラーが発生しました。接続を確認して再度お試しください: %w", err)
-	}
-
-	return fmt.Errorf("Gemini APIからの応答取得に失敗しました: %w", err)
+	return classifyAPIError(ctx, err)
 }
 
 // logRequestDetails は、リクエスト詳細をログ出力します
@@ -137,15 +128,10 @@ func (g *GeminiAPIClient) logResponseDetails(resp *genai.GenerateContentResponse
 }
 
 // shouldRetry は、エラーがリトライ可能かどうかを判定します
+// 利用制限や過負荷のエラーは同じモデルでリトライせず、フォールバックチェーンに任せます
 func (g *GeminiAPIClient) shouldRetry(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	errStr := err.Error()
-	// Contentがnilの場合やコンテンツが含まれていない場合はリトライ対象
-	return strings.Contains(errStr, "Contentが含まれていません") ||
-		strings.Contains(errStr, "コンテンツが含まれていません")
+	// 応答にコンテンツが含まれていない場合は、一時的なものとしてリトライ対象
+	return errors.Is(err, errEmptyResponse)
 }
 
 // retryWithBackoff は、指数バックオフでリトライを実行します
@@ -246,35 +232,43 @@ func (g *GeminiAPIClient) GenerateTextWithOptions(ctx context.Context, prompt do
 }
 
 // GenerateTextWithStructuredContext は、構造化されたコンテキストを使用してテキストを生成します
-func (g *GeminiAPIClient) GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string) (string, error) {
+// 利用制限・過負荷・安全フィルターなどで生成できなかった場合は、フォールバックチェーンの次のモデルで生成し直します
+func (g *GeminiAPIClient) GenerateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
 	// 統一されたログ出力メソッドを使用
 	g.logRequestDetails(len(userQuestion), userQuestion)
 	log.Printf("構造化コンテキストでGemini APIにテキスト生成をリクエスト中")
 	log.Printf("システムプロンプト: %d文字", len(systemPrompt))
 	log.Printf("会話履歴: %d件", len(conversationHistory))
 
-	// リトライ機能付きでテキスト生成を実行
-	return g.retryWithBackoff(ctx, func() (string, error) {
-		// 構造化されたコンテンツを作成
-		var allContents []*genai.Content
-
-		// システムプロンプトを追加
-		allContents = append(allContents, genai.Text(systemPrompt)...)
+	return generateTextWithFallback(ctx, g.config, options, nil, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		return g.generateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options)
+	})
+}
 
-		// ユーザーの質問を最初に追加（最優先）
-		userQuestionText := fmt.Sprintf("## ユーザーの現在の質問\n%s", userQuestion)
-		allContents = append(allContents, genai.Text(userQuestionText)...)
+// generateTextWithStructuredContext は、options のモデルで、構造化されたコンテキストを使用してテキストを生成します
+func (g *GeminiAPIClient) generateTextWithStructuredContext(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+	// 生成設定を作成し、リクエスト単位のオプション（ギルド別モデルなど）を適用
+	config := g.createGenerateConfig()
+	modelName := applyTextGenerationOptions(config, g.config, options)
+	log.Printf("使用モデル: %s", modelName)
 
-		// 会話履歴を最後に追加（参考情報として）
-		if len(conversationHistory) > 0 {
-			historyText := g.formatConversationHistory(conversationHistory)
-			allContents = append(allContents, genai.Text(historyText)...)
-		}
+	// システムプロンプトはシステム指示として、会話履歴と質問は user / model のマルチターンとして渡す
+	config.SystemInstruction = buildSystemInstruction(systemPrompt)
+	allContents := buildConversationContents(conversationHistory, userQuestion, questionAttachments)
 
-		// 生成設定を作成
-		config := g.createGenerateConfig()
-
-		resp, err := g.client.Models.GenerateContent(ctx, g.config.ModelName, allContents, config)
+	// リトライ機能付きでテキスト生成を実行
+	truncated := false
+	var usage domain.TokenUsage
+	var grounding *domain.Grounding
+	var images []domain.Attachment
+	var thoughts string
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		return g.client.Models.GenerateContent(ctx, modelName, contents, config)
+	}
+	content, err := g.retryWithBackoff(ctx, func() (string, error) {
+		// ツールが設定されている場合は、モデルが最終的な回答を返すまで関数呼び出しを繰り返す
+		resp, callUsage, err := generateWithTools(ctx, generate, allContents, config, g.tools, maxToolIterations(g.config, options))
+		usage = usage.Add(callUsage)
 		if err != nil {
 			return "", g.handleAPIError(err, ctx)
 		}
@@ -282,23 +276,137 @@ func (g *GeminiAPIClient) GenerateTextWithStructuredContext(ctx context.Context,
 		// レスポンス詳細をログ出力
 		g.logResponseDetails(resp)
 
-		// レスポンス処理
-		return g.processResponse(resp)
+		// レスポンス処理（グラウンディングを使用した場合は、根拠の箇所に引用番号を付ける）
+		truncated = reachedMaxTokens(resp)
+		content, err := g.processResponse(resp)
+		if err != nil {
+			return "", err
+		}
+		content, grounding = groundedContent(resp, content)
+		images = codeExecutionImages(resp)
+		thoughts = responseThoughts(resp)
+		return content, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &application.TextGenerationResult{
+		Content:     content,
+		Model:       modelName,
+		Truncated:   truncated,
+		Usage:       usage,
+		Grounding:   grounding,
+		Attachments: images,
+		Thoughts:    thoughts,
+	}, nil
+}
+
+// GenerateTextWithStructuredContextStream は、構造化されたコンテキストを使用してテキストをストリーミング生成します
+// 受信済みのテキストを取り消せないため、ストリーミングではリトライを行わず、
+// フォールバックチェーンの次のモデルで生成し直すのもテキストを受信する前に失敗した場合に限ります
+func (g *GeminiAPIClient) GenerateTextWithStructuredContextStream(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options application.TextGenerationOptions, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+	g.logRequestDetails(len(userQuestion), userQuestion)
+	log.Printf("構造化コンテキストでGemini APIにストリーミング生成をリクエスト中")
+	log.Printf("システムプロンプト: %d文字", len(systemPrompt))
+	log.Printf("会話履歴: %d件", len(conversationHistory))
+
+	onChunk, beforeFirstChunk := trackFirstChunk(onChunk)
+	return generateTextWithFallback(ctx, g.config, options, beforeFirstChunk, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		return g.generateTextWithStructuredContextStream(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options, onChunk)
 	})
 }
 
-// formatConversationHistory は、会話履歴を構造化された形式にフォーマットします
-func (g *GeminiAPIClient) formatConversationHistory(messages []domain.Message) string {
-	var builder strings.Builder
-	builder.WriteString("## 参考情報：過去の会話履歴\n")
-	builder.WriteString("※ 以下の会話履歴は参考情報です。ユーザーの現在の質問に直接答えてください。\n\n")
+// generateTextWithStructuredContextStream は、options のモデルで、構造化されたコンテキストを使用してテキストをストリーミング生成します
+func (g *GeminiAPIClient) generateTextWithStructuredContextStream(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment, options application.TextGenerationOptions, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+	// 生成設定を作成し、リクエスト単位のオプション（ギルド別モデルなど）を適用
+	config := g.createGenerateConfig()
+	modelName := applyTextGenerationOptions(config, g.config, options)
+	log.Printf("使用モデル: %s", modelName)
 
-	for _, msg := range messages {
-		displayName := msg.User.DisplayName
-		builder.WriteString(fmt.Sprintf("%s: %s\n", displayName, msg.Content))
+	config.SystemInstruction = buildSystemInstruction(systemPrompt)
+	allContents := buildConversationContents(conversationHistory, userQuestion, questionAttachments)
+
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		return collectStream(g.client.Models.GenerateContentStream(ctx, modelName, contents, config), onChunk)
+	}
+	resp, usage, err := generateWithTools(ctx, generate, allContents, config, g.tools, maxToolIterations(g.config, options))
+	if err != nil {
+		// 停止・失敗した場合も、それまでに消費したトークン数を記録できるよう使用量を含む結果を返す
+		return &application.TextGenerationResult{Model: modelName, Usage: usage}, g.handleAPIError(err, ctx)
 	}
 
-	return builder.String()
+	// レスポンス詳細をログ出力
+	g.logResponseDetails(resp)
+
+	// グラウンディングを使用した場合は、根拠の箇所に引用番号を付ける
+	content, err := g.processResponse(resp)
+	if err != nil {
+		return &application.TextGenerationResult{Model: modelName, Usage: usage}, err
+	}
+	content, grounding := groundedContent(resp, content)
+
+	return &application.TextGenerationResult{
+		Content:     content,
+		Model:       modelName,
+		Truncated:   reachedMaxTokens(resp),
+		Usage:       usage,
+		Grounding:   grounding,
+		Attachments: codeExecutionImages(resp),
+		Thoughts:    responseThoughts(resp),
+	}, nil
+}
+
+// GenerateJSON は、構造化されたコンテキストを使用して、schema に沿ったJSONを生成します
+// 生成したJSONがスキーマに合わない場合は誤りを伝えて生成し直し、応答が空の場合などはバックオフしてリトライします
+// 利用制限・過負荷・安全フィルターなどで生成できなかった場合は、フォールバックチェーンの次のモデルで生成し直します
+func (g *GeminiAPIClient) GenerateJSON(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, instruction string, schema map[string]any, options application.TextGenerationOptions) (*application.JSONGenerationResult, error) {
+	g.logRequestDetails(len(instruction), instruction)
+	log.Printf("構造化コンテキストでGemini APIにJSONの生成をリクエスト中")
+	log.Printf("会話履歴: %d件", len(conversationHistory))
+
+	return generateWithFallback(ctx, fallbackModelChain(g.config, options), options, nil, func(options application.TextGenerationOptions) (*application.JSONGenerationResult, error) {
+		return g.generateJSONWithModel(ctx, systemPrompt, conversationHistory, instruction, schema, options)
+	})
+}
+
+// generateJSONWithModel は、options のモデルで、schema に沿ったJSONを生成します
+func (g *GeminiAPIClient) generateJSONWithModel(ctx context.Context, systemPrompt string, conversationHistory []domain.Message, instruction string, schema map[string]any, options application.TextGenerationOptions) (*application.JSONGenerationResult, error) {
+	// 生成設定を作成し、リクエスト単位のオプションと応答のスキーマを適用
+	config := g.createGenerateConfig()
+	modelName := applyTextGenerationOptions(config, g.config, options)
+	log.Printf("使用モデル: %s", modelName)
+	applyJSONResponse(config, schema)
+
+	config.SystemInstruction = buildSystemInstruction(systemPrompt)
+	allContents := buildConversationContents(conversationHistory, instruction, nil)
+
+	var usage domain.TokenUsage
+	attempts := 0
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		resp, err := g.client.Models.GenerateContent(ctx, modelName, contents, config)
+		if err != nil {
+			return nil, g.handleAPIError(err, ctx)
+		}
+		g.logResponseDetails(resp)
+		return resp, nil
+	}
+	output, err := g.retryWithBackoff(ctx, func() (string, error) {
+		data, callUsage, callAttempts, err := generateJSON(ctx, generate, allContents, config, schema, g.processResponse)
+		usage = usage.Add(callUsage)
+		attempts += callAttempts
+		return string(data), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("JSONの生成に失敗: %w", err)
+	}
+
+	return &application.JSONGenerationResult{
+		JSON:     []byte(output),
+		Model:    modelName,
+		Usage:    usage,
+		Attempts: attempts,
+	}, nil
 }
 
 // formatSafetyRatings は、SafetyRatingsの詳細情報をフォーマットします
@@ -325,18 +433,7 @@ func (g *GeminiAPIClient) formatSafetyRatings(ratings []*genai.SafetyRating) str
 
 // translateSafetyCategory は、SafetyCategoryを日本語に翻訳します
 func (g *GeminiAPIClient) translateSafetyCategory(category genai.HarmCategory) string {
-	switch category {
-	case genai.HarmCategoryHarassment:
-		return "ハラスメント"
-	case genai.HarmCategoryHateSpeech:
-		return "ヘイトスピーチ"
-	case genai.HarmCategorySexuallyExplicit:
-		return "性的表現"
-	case genai.HarmCategoryDangerousContent:
-		return "危険なコンテンツ"
-	default:
-		return string(category)
-	}
+	return translateSafetyCategory(category)
 }
 
 // translateSafetyProbability は、SafetyProbabilityを日本語に翻訳します
@@ -358,51 +455,91 @@ func (g *GeminiAPIClient) translateSafetyProbability(probability genai.HarmProba
 // processResponse は、Gemini APIのレスポンスを処理します
 func (g *GeminiAPIClient) processResponse(resp *genai.GenerateContentResponse) (string, error) {
 	if len(resp.Candidates) == 0 {
+		// プロンプト自体が安全フィルターでブロックされた場合は、候補が返されない
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			log.Printf("プロンプトがブロックされました: %s（%s）", resp.PromptFeedback.BlockReason, g.formatSafetyRatings(resp.PromptFeedback.SafetyRatings))
+			return "", safetyBlockedError(resp.PromptFeedback.SafetyRatings)
+		}
 		return "", fmt.Errorf("Gemini APIから有効な応答が得られませんでした")
 	}
 
 	candidate := resp.Candidates[0]
 
 	// FinishReasonをチェックして安全フィルターによるブロックを検出
-	if candidate.FinishReason == "SAFETY" {
-		safetyDetails := g.formatSafetyRatings(candidate.SafetyRatings)
-		return "", fmt.Errorf("Gemini APIの安全フィルターによって応答がブロックされました。詳細: %s", safetyDetails)
+	if candidate.FinishReason == genai.FinishReasonSafety {
+		log.Printf("安全フィルターによってブロックされました: %s", g.formatSafetyRatings(candidate.SafetyRatings))
+		return "", safetyBlockedError(candidate.SafetyRatings)
 	}
 
-	if candidate.FinishReason == "RECITATION" {
-		return "", fmt.Errorf("Gemini APIが著作権保護された内容を検出しました。著作権で保護されたコンテンツが含まれている可能性があります")
+	if candidate.FinishReason == genai.FinishReasonRecitation {
+		return "", fmt.Errorf("%w。著作権で保護されたコンテンツが含まれている可能性があります", domain.ErrRecitation)
 	}
 
-	if candidate.FinishReason == "MAX_TOKENS" {
-		return "", fmt.Errorf("Gemini APIの応答が最大トークン数に達しました。より短い質問を試してください")
-	}
-
-	if candidate.FinishReason == "STOP" {
+	// 最大トークン数に達した場合は、途中までの応答を返す（続きの生成は呼び出し側で行う）
+	if candidate.FinishReason == genai.FinishReasonMaxTokens {
+		if candidateText(candidate) == "" {
+			return "", fmt.Errorf("%w。より短い質問を試してください", domain.ErrMaxTokens)
+		}
+		log.Printf("Gemini APIの応答が最大トークン数に達したため、途中までの応答を返します")
+	} else if candidate.FinishReason == genai.FinishReasonStop {
 		// STOPは正常な終了なので、そのまま処理を続行
-	} else if candidate.FinishReason != "" && candidate.FinishReason != "STOP" {
+	} else if candidate.FinishReason != "" {
 		return "", fmt.Errorf("Gemini APIで予期しない終了理由が発生しました: %s", candidate.FinishReason)
 	}
 
-	// Contentがnilの場合のチェックを追加
-	if candidate.Content == nil {
-		return "", fmt.Errorf("Gemini APIの応答にContentが含まれていません。FinishReason: %s", candidate.FinishReason)
+	// Contentがnil、または空の場合はリトライ対象のエラーを返す
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return "", fmt.Errorf("%w。FinishReason: %s", errEmptyResponse, candidate.FinishReason)
 	}
 
-	if len(candidate.Content.Parts) == 0 {
-		return "", fmt.Errorf("Gemini APIの応答にコンテンツが含まれていません。FinishReason: %s", candidate.FinishReason)
+	// テキスト部分を抽出
+	result := candidateText(candidate)
+
+	log.Printf("Gemini APIから応答を取得: %d文字", len(result))
+	return result, nil
+}
+
+// candidateText は、候補に含まれるテキスト部分を連結して返します（思考の要約は含めません）
+// コード実行を使用した場合は、実行したコードと実行結果も出現した位置に含めます
+func candidateText(candidate *genai.Candidate) string {
+	if candidate == nil || candidate.Content == nil {
+		return ""
 	}
 
-	// テキスト部分を抽出
 	var result string
 	for _, part := range candidate.Content.Parts {
-		if part != nil && part.Text != "" {
+		// 思考の要約は回答に含めず、responseThoughts で別に取り出す
+		if part == nil || part.Thought {
+			continue
+		}
+		if part.Text != "" {
 			result += part.Text
+			continue
 		}
+		result += renderCodeExecutionPart(part)
+	}
+	return result
+}
 
+// responseThoughts は、レスポンスに含まれるモデルの思考の要約を返します（思考の要約を受け取らなかった場合は空文字）
+func responseThoughts(resp *genai.GenerateContentResponse) string {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0] == nil || resp.Candidates[0].Content == nil {
+		return ""
 	}
 
-	log.Printf("Gemini APIから応答を取得: %d文字", len(result))
-	return result, nil
+	var thoughts strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if part != nil && part.Thought && part.Text != "" {
+			thoughts.WriteString(part.Text)
+		}
+	}
+	return strings.TrimSpace(thoughts.String())
+}
+
+// reachedMaxTokens は、応答が最大トークン数に達して途中で終了したかどうかを返します
+func reachedMaxTokens(resp *genai.GenerateContentResponse) bool {
+	return resp != nil && len(resp.Candidates) > 0 && resp.Candidates[0] != nil &&
+		resp.Candidates[0].FinishReason == genai.FinishReasonMaxTokens
 }
 
 // GenerateImage は、プロンプトを受け取ってGemini APIから画像を生成します
@@ -412,10 +549,17 @@ func (g *GeminiAPIClient) GenerateImage(ctx context.Context, request domain.Imag
 	log.Printf("プロンプト内容: %s", request.Prompt)
 	log.Printf("オプション: %+v", request.Options)
 
-	// リトライ機能付きで画像生成を実行
+	// 指定枚数分のリクエストを並列に送信し、それぞれリトライ機能付きで画像生成を実行
+	return generateImages(ctx, request.Options.ImageCount(), func(ctx context.Context) (*domain.ImageGenerationResponse, error) {
+		return g.generateSingleImage(ctx, request)
+	})
+}
+
+// generateSingleImage は、リトライ機能付きで1回分の画像生成リクエストを実行します
+func (g *GeminiAPIClient) generateSingleImage(ctx context.Context, request domain.ImageGenerationRequest) (*domain.ImageGenerationResponse, error) {
 	return g.retryWithBackoffForImage(ctx, func() (*domain.ImageGenerationResponse, error) {
-		// 画像生成用のコンテンツを作成
-		contents := genai.Text(request.Prompt)
+		// 画像生成用のコンテンツを作成（編集モードでは元画像を含める）
+		contents := buildImageContents(request)
 
 		// オプションに基づいて画像生成設定を作成
 		config := g.createImageConfig(request.Options)
@@ -3,6 +3,7 @@ package gemini
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -227,23 +228,28 @@ func TestShouldRetry(t *testing.T) {
 		expected bool
 	}{
 		{
-			name:     "Contentがnilのエラー",
-			err:      errors.New("Gemini APIの応答にContentが含まれていません"),
+			name:     "コンテンツが含まれていないエラー",
+			err:      fmt.Errorf("%w。FinishReason: STOP", errEmptyResponse),
 			expected: true,
 		},
 		{
-			name:     "コンテンツが含まれていないエラー",
+			name:     "メッセージだけが同じエラー",
 			err:      errors.New("Gemini APIの応答にコンテンツが含まれていません"),
-			expected: true,
+			expected: false,
 		},
 		{
 			name:     "安全フィルターによるブロック",
-			err:      errors.New("Gemini APIの安全フィルターによって応答がブロックされました"),
+			err:      &domain.SafetyBlockedError{},
 			expected: false,
 		},
 		{
 			name:     "著作権保護エラー",
-			err:      errors.New("Gemini APIが著作権保護された内容を検出しました"),
+			err:      domain.ErrRecitation,
+			expected: false,
+		},
+		{
+			name:     "利用制限",
+			err:      fmt.Errorf("%w: 429", domain.ErrQuotaExceeded),
 			expected: false,
 		},
 		{
@@ -296,7 +302,7 @@ func TestRetryWithBackoff(t *testing.T) {
 				return func() (string, error) {
 					callCount++
 					if callCount == 1 {
-						return "", errors.New("Gemini APIの応答にContentが含まれていません")
+						return "", errEmptyResponse
 					}
 					return "success", nil
 				}
@@ -312,7 +318,7 @@ func TestRetryWithBackoff(t *testing.T) {
 				callCount := 0
 				return func() (string, error) {
 					callCount++
-					return "", errors.New("Gemini APIの応答にContentが含まれていません")
+					return "", errEmptyResponse
 				}
 			}(),
 			expectedResult: "",
@@ -323,7 +329,7 @@ func TestRetryWithBackoff(t *testing.T) {
 			name:       "リトライ不可能なエラー",
 			maxRetries: 3,
 			operation: func() (string, error) {
-				return "", errors.New("安全フィルターによって応答がブロックされました")
+				return "", &domain.SafetyBlockedError{}
 			},
 			expectedResult: "",
 			expectedError:  true,
@@ -374,7 +380,7 @@ func TestRetryWithBackoff_ContextCancellation(t *testing.T) {
 	cancel()
 
 	operation := func() (string, error) {
-		return "", errors.New("Gemini APIの応答にContentが含まれていません")
+		return "", errEmptyResponse
 	}
 
 	_, err := client.retryWithBackoff(ctx, operation)
@@ -524,3 +530,41 @@ func TestTranslateSafetyProbability(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessResponse_MaxTokens(t *testing.T) {
+	client := &GeminiAPIClient{}
+
+	partial := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			FinishReason: genai.FinishReasonMaxTokens,
+			Content:      genai.NewContentFromText("途中までの応答", genai.RoleModel),
+		}},
+	}
+	content, err := client.processResponse(partial)
+	if err != nil {
+		t.Fatalf("最大トークン数に達した場合は途中までの応答を返すべきです: %v", err)
+	}
+	if content != "途中までの応答" {
+		t.Errorf("期待される応答: 途中までの応答, 実際: %s", content)
+	}
+	if !reachedMaxTokens(partial) {
+		t.Errorf("最大トークン数に達した応答として判定されるべきです")
+	}
+
+	empty := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			FinishReason: genai.FinishReasonMaxTokens,
+			Content:      &genai.Content{Role: genai.RoleModel},
+		}},
+	}
+	if _, err := client.processResponse(empty); err == nil {
+		t.Errorf("テキストがない場合はエラーを返すべきです")
+	}
+
+	completed := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonStop}},
+	}
+	if reachedMaxTokens(completed) {
+		t.Errorf("正常に終了した応答は途中で終了したと判定されるべきではありません")
+	}
+}
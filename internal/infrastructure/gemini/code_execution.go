@@ -0,0 +1,88 @@
+package gemini
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// maxCodeBlockRunes は、実行したコードと実行結果をそれぞれ表示する最大文字数です（超えた分は省略します）
+const maxCodeBlockRunes = 1200
+
+// renderCodeExecutionPart は、コード実行の部分（実行したコードまたは実行結果）を、
+// Discordでクリックして展開できるスポイラー付きのコードブロックに変換します
+// コード実行の部分でない場合は空文字を返します
+func renderCodeExecutionPart(part *genai.Part) string {
+	switch {
+	case part == nil:
+		return ""
+	case part.ExecutableCode != nil:
+		language := strings.ToLower(string(part.ExecutableCode.Language))
+		if part.ExecutableCode.Language == "" || part.ExecutableCode.Language == genai.LanguageUnspecified {
+			language = "python"
+		}
+		return fmt.Sprintf("\n-# 🧮 実行したコード\n||```%s\n%s\n```||\n", language, codeBlockBody(part.ExecutableCode.Code))
+	case part.CodeExecutionResult != nil:
+		output := codeBlockBody(part.CodeExecutionResult.Output)
+		if output == "" {
+			output = "（出力なし）"
+		}
+		return fmt.Sprintf("-# %s\n||```\n%s\n```||\n", codeExecutionOutcomeLabel(part.CodeExecutionResult.Outcome), output)
+	default:
+		return ""
+	}
+}
+
+// codeExecutionOutcomeLabel は、コードの実行結果の見出しを返します
+func codeExecutionOutcomeLabel(outcome genai.Outcome) string {
+	switch outcome {
+	case genai.OutcomeFailed:
+		return "⚠️ 実行エラー"
+	case genai.OutcomeDeadlineExceeded:
+		return "⏱️ 実行がタイムアウトしました"
+	default:
+		return "📤 実行結果"
+	}
+}
+
+// codeBlockBody は、コードブロック内に表示するテキストを返します
+// 長いテキストは省略し、コードブロックを閉じてしまうバッククォートの連続は間にゼロ幅スペースを挟みます
+func codeBlockBody(text string) string {
+	text = strings.TrimRight(text, "\n")
+	if runes := []rune(text); len(runes) > maxCodeBlockRunes {
+		text = string(runes[:maxCodeBlockRunes]) + "\n…（省略）"
+	}
+	return strings.ReplaceAll(text, "```", "`\u200b`\u200b`")
+}
+
+// isCodeExecutionImage は、コード実行で作成された画像（グラフなど）の部分かどうかを返します
+func isCodeExecutionImage(part *genai.Part) bool {
+	return part != nil && part.InlineData != nil && len(part.InlineData.Data) > 0 &&
+		strings.HasPrefix(part.InlineData.MIMEType, "image/")
+}
+
+// codeExecutionImages は、レスポンスに含まれる、コード実行で作成された画像を添付ファイルとして返します
+func codeExecutionImages(resp *genai.GenerateContentResponse) []domain.Attachment {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0] == nil || resp.Candidates[0].Content == nil {
+		return nil
+	}
+
+	var images []domain.Attachment
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if !isCodeExecutionImage(part) {
+			continue
+		}
+		images = append(images, domain.Attachment{
+			Data:        part.InlineData.Data,
+			MimeType:    part.InlineData.MIMEType,
+			Size:        int64(len(part.InlineData.Data)),
+			IsImage:     true,
+			GeneratedAt: time.Now(),
+		})
+	}
+	return images
+}
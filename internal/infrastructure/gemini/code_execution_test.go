@@ -0,0 +1,113 @@
+package gemini
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"geminibot/internal/application"
+	"geminibot/internal/infrastructure/config"
+
+	"google.golang.org/genai"
+)
+
+// loadCodeExecutionResponse は、コード実行を使用したときのレスポンスの記録を読み込みます
+func loadCodeExecutionResponse(t *testing.T) *genai.GenerateContentResponse {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/code_execution_response.json")
+	if err != nil {
+		t.Fatalf("レスポンスの記録の読み込みに失敗: %v", err)
+	}
+	var resp genai.GenerateContentResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("レスポンスの記録の解析に失敗: %v", err)
+	}
+	return &resp
+}
+
+// codeExecutionWant は、記録したレスポンスを表示用のテキストに変換した結果です
+const codeExecutionWant = "1から100までの素数の和を計算します。\n" +
+	"\n-# 🧮 実行したコード\n||```python\nprimes = [n for n in range(2, 101) if all(n % d for d in range(2, int(n ** 0.5) + 1))]\nprint(sum(primes))\n```||\n" +
+	"-# 📤 実行結果\n||```\n1060\n```||\n" +
+	"1から100までの素数の和は **1060** です。"
+
+func TestCandidateText_RendersCodeExecution(t *testing.T) {
+	resp := loadCodeExecutionResponse(t)
+
+	if got := candidateText(resp.Candidates[0]); got != codeExecutionWant {
+		t.Errorf("コード実行の表示が一致しません:\n実際: %q\n期待値: %q", got, codeExecutionWant)
+	}
+
+	images := codeExecutionImages(resp)
+	if len(images) != 1 || !images[0].IsImage || images[0].MimeType != "image/png" || images[0].Size != int64(len(images[0].Data)) {
+		t.Errorf("作成された画像が添付ファイルとして取り出されていません: %+v", images)
+	}
+}
+
+func TestCollectStream_RendersCodeExecution(t *testing.T) {
+	recorded := loadCodeExecutionResponse(t)
+
+	// 記録したレスポンスの部分を1つずつ受信する
+	var responses []*genai.GenerateContentResponse
+	for _, part := range recorded.Candidates[0].Content.Parts {
+		responses = append(responses, &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{part}}}},
+		})
+	}
+	responses[len(responses)-1].Candidates[0].FinishReason = genai.FinishReasonStop
+
+	var streamed strings.Builder
+	resp, err := collectStream(fakeStream(responses, nil), func(chunk string) {
+		streamed.WriteString(chunk)
+	})
+	if err != nil {
+		t.Fatalf("collectStream() エラー: %v", err)
+	}
+
+	if streamed.String() != codeExecutionWant {
+		t.Errorf("実行したコードと実行結果は出現した位置でストリーミングするべきです:\n実際: %q", streamed.String())
+	}
+	if got := candidateText(resp.Candidates[0]); got != codeExecutionWant {
+		t.Errorf("まとめたレスポンスのテキストが一致しません:\n実際: %q", got)
+	}
+	if images := codeExecutionImages(resp); len(images) != 1 {
+		t.Errorf("作成された画像がレスポンスに含まれていません: %d件", len(images))
+	}
+}
+
+func TestRenderCodeExecutionPart(t *testing.T) {
+	failed := renderCodeExecutionPart(&genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeFailed}})
+	if !strings.Contains(failed, "実行エラー") || !strings.Contains(failed, "（出力なし）") {
+		t.Errorf("実行に失敗した結果の表示が正しくありません: %q", failed)
+	}
+
+	// コードブロックを閉じるバッククォートは、表示を壊さないよう置き換える
+	code := renderCodeExecutionPart(&genai.Part{ExecutableCode: &genai.ExecutableCode{Code: "print('```')"}})
+	if strings.Count(code, "```") != 2 {
+		t.Errorf("コード中のバッククォートがコードブロックを閉じています: %q", code)
+	}
+
+	long := renderCodeExecutionPart(&genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: strings.Repeat("あ", maxCodeBlockRunes+10)}})
+	if !strings.Contains(long, "…（省略）") {
+		t.Errorf("長い実行結果は省略するべきです")
+	}
+
+	if renderCodeExecutionPart(&genai.Part{Text: "テキスト"}) != "" {
+		t.Error("コード実行以外の部分は変換するべきではありません")
+	}
+}
+
+func TestCodeExecutionOptions(t *testing.T) {
+	generateConfig := &genai.GenerateContentConfig{}
+	applyTextGenerationOptions(generateConfig, &config.GeminiConfig{ModelName: "gemini-2.5-flash"}, application.TextGenerationOptions{CodeExecution: true})
+	if len(generateConfig.Tools) != 1 || generateConfig.Tools[0].CodeExecution == nil {
+		t.Errorf("コード実行を使用する場合はコード実行のツールを加えるべきです: %+v", generateConfig.Tools)
+	}
+
+	geminiConfig := &config.GeminiConfig{MaxToolIterations: 5}
+	if got := maxToolIterations(geminiConfig, application.TextGenerationOptions{CodeExecution: true}); got != 0 {
+		t.Errorf("maxToolIterations() = %d, 期待値: 0", got)
+	}
+}
@@ -0,0 +1,78 @@
+package gemini
+
+import (
+	"fmt"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// buildSystemInstruction は、システムプロンプトをGeminiのシステム指示に変換します
+func buildSystemInstruction(systemPrompt string) *genai.Content {
+	if systemPrompt == "" {
+		return nil
+	}
+	return &genai.Content{
+		Parts: []*genai.Part{{Text: systemPrompt}},
+	}
+}
+
+// buildConversationContents は、会話履歴とユーザーの質問を user / model のロールを持つマルチターンのコンテンツに変換します
+// Bot自身のメッセージは model、それ以外は表示名を付けた user の発言として扱い、
+// 同じロールが連続する場合は1つのターンにまとめます
+// メンションが返信したユーザーのメッセージには、返信先であることを示すラベルを付けます
+// ダウンロード済みの添付ファイルは、発言のテキストに続くインラインデータとして渡します
+func buildConversationContents(conversationHistory []domain.Message, userQuestion string, questionAttachments []domain.Attachment) []*genai.Content {
+	var contents []*genai.Content
+
+	appendTurn := func(role string, parts []*genai.Part) {
+		if len(parts) == 0 {
+			return
+		}
+		if len(contents) > 0 && contents[len(contents)-1].Role == role {
+			last := contents[len(contents)-1]
+			last.Parts = append(last.Parts, parts...)
+			return
+		}
+		contents = append(contents, &genai.Content{
+			Role:  role,
+			Parts: parts,
+		})
+	}
+
+	for _, msg := range conversationHistory {
+		if msg.FromSelf {
+			appendTurn(genai.RoleModel, messageParts(msg.Content, msg.Attachments))
+			continue
+		}
+		text := ""
+		if msg.Content != "" {
+			text = fmt.Sprintf("%s: %s", msg.User.DisplayName, msg.Content)
+			if msg.ReplyTarget {
+				text = domain.ReplyTargetLabel + " " + text
+			}
+		}
+		appendTurn(genai.RoleUser, messageParts(text, msg.Attachments))
+	}
+
+	// ユーザーの現在の質問は最後の user ターンとして追加
+	appendTurn(genai.RoleUser, messageParts(userQuestion, questionAttachments))
+
+	return contents
+}
+
+// messageParts は、発言のテキストとダウンロード済みの添付ファイルをGeminiのパートに変換します
+func messageParts(text string, attachments []domain.Attachment) []*genai.Part {
+	var parts []*genai.Part
+	if text != "" {
+		parts = append(parts, &genai.Part{Text: text})
+	}
+	for _, attachment := range attachments {
+		if !attachment.HasData() {
+			continue
+		}
+		parts = append(parts, genai.NewPartFromBytes(attachment.Data, attachment.MimeType))
+	}
+	return parts
+}
@@ -0,0 +1,146 @@
+package gemini
+
+import (
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+func TestBuildConversationContents(t *testing.T) {
+	now := time.Now()
+	conversationHistory := []domain.Message{
+		{
+			ID:        "msg1",
+			User:      domain.User{ID: "user1", Username: "testuser1", DisplayName: "TestUser1"},
+			Content:   "おすすめの本を3つ教えて",
+			Timestamp: now,
+		},
+		{
+			ID:        "msg2",
+			User:      domain.User{ID: "bot", Username: "geminibot", DisplayName: "GeminiBot", IsBot: true},
+			Content:   "1. 本A\n2. 本B\n3. 本C",
+			Timestamp: now.Add(time.Second),
+			FromSelf:  true,
+		},
+		{
+			ID:        "msg3",
+			User:      domain.User{ID: "user2", Username: "testuser2", DisplayName: "TestUser2"},
+			Content:   "私も気になります",
+			Timestamp: now.Add(2 * time.Second),
+		},
+	}
+	userQuestion := "2つ目について詳しく説明して"
+
+	contents := buildConversationContents(conversationHistory, userQuestion, nil)
+
+	// user → model → user（履歴の発言と質問はまとめられる）の3ターンになる
+	if len(contents) != 3 {
+		t.Fatalf("期待されるターン数: 3, 実際: %d", len(contents))
+	}
+
+	expectedRoles := []string{genai.RoleUser, genai.RoleModel, genai.RoleUser}
+	for i, role := range expectedRoles {
+		if contents[i].Role != role {
+			t.Errorf("ターン%dのロール: 期待値 %s, 実際 %s", i, role, contents[i].Role)
+		}
+	}
+
+	if got := contents[0].Parts[0].Text; got != "TestUser1: おすすめの本を3つ教えて" {
+		t.Errorf("ユーザーの発言に表示名が付与されていません: %s", got)
+	}
+
+	// Bot自身の発言は表示名を付けずにそのまま model ターンとして渡す
+	if got := contents[1].Parts[0].Text; got != "1. 本A\n2. 本B\n3. 本C" {
+		t.Errorf("Botの発言が正しく変換されていません: %s", got)
+	}
+
+	lastTurn := contents[2]
+	if len(lastTurn.Parts) != 2 {
+		t.Fatalf("連続する user の発言は1ターンにまとめられるべきです: Parts数=%d", len(lastTurn.Parts))
+	}
+	if lastTurn.Parts[0].Text != "TestUser2: 私も気になります" {
+		t.Errorf("期待される発言: TestUser2: 私も気になります, 実際: %s", lastTurn.Parts[0].Text)
+	}
+	if lastTurn.Parts[1].Text != userQuestion {
+		t.Errorf("最後のパートがユーザーの質問ではありません: %s", lastTurn.Parts[1].Text)
+	}
+}
+
+func TestBuildConversationContents_EmptyHistory(t *testing.T) {
+	contents := buildConversationContents(nil, "今日の天気は？", nil)
+
+	if len(contents) != 1 {
+		t.Fatalf("期待されるターン数: 1, 実際: %d", len(contents))
+	}
+	if contents[0].Role != genai.RoleUser || contents[0].Parts[0].Text != "今日の天気は？" {
+		t.Errorf("質問が user ターンとして追加されていません: %+v", contents[0])
+	}
+}
+
+func TestBuildConversationContents_ReplyTarget(t *testing.T) {
+	conversationHistory := []domain.Message{
+		{ID: "msg1", User: domain.User{DisplayName: "TestUser1"}, Content: "地球は平らです", ReplyChain: true, ReplyTarget: true},
+		{ID: "msg2", User: domain.User{DisplayName: "TestUser2"}, Content: "関係のない話"},
+	}
+
+	contents := buildConversationContents(conversationHistory, "これは正しい？", nil)
+
+	if len(contents) != 1 || len(contents[0].Parts) != 3 {
+		t.Fatalf("1つの user ターンにまとめられるべきです: %+v", contents)
+	}
+	if got, want := contents[0].Parts[0].Text, domain.ReplyTargetLabel+" TestUser1: 地球は平らです"; got != want {
+		t.Errorf("返信先のメッセージにはラベルを付けるべきです: got %q, want %q", got, want)
+	}
+	if got := contents[0].Parts[1].Text; got != "TestUser2: 関係のない話" {
+		t.Errorf("返信先以外のメッセージにはラベルを付けないべきです: %q", got)
+	}
+}
+
+func TestBuildConversationContents_Attachments(t *testing.T) {
+	conversationHistory := []domain.Message{
+		{
+			User:    domain.User{DisplayName: "TestUser1"},
+			Content: "",
+			Attachments: []domain.Attachment{
+				{Filename: "history.pdf", MimeType: "application/pdf", Data: []byte("%PDF")},
+				{Filename: "skipped.png", MimeType: "image/png"}, // 未ダウンロードの添付ファイルは含めない
+			},
+		},
+	}
+	questionAttachments := []domain.Attachment{
+		{Filename: "screenshot.png", MimeType: "image/png", Data: []byte("png-data")},
+	}
+
+	contents := buildConversationContents(conversationHistory, "このスクリーンショットは何？", questionAttachments)
+
+	if len(contents) != 1 {
+		t.Fatalf("期待されるターン数: 1, 実際: %d", len(contents))
+	}
+	parts := contents[0].Parts
+	if len(parts) != 3 {
+		t.Fatalf("期待されるパート数: 3, 実際: %d", len(parts))
+	}
+	if parts[0].InlineData == nil || parts[0].InlineData.MIMEType != "application/pdf" {
+		t.Errorf("履歴の添付ファイルがインラインデータとして渡されていません: %+v", parts[0])
+	}
+	if parts[1].Text != "このスクリーンショットは何？" {
+		t.Errorf("質問のテキストが添付ファイルより前にありません: %+v", parts[1])
+	}
+	if parts[2].InlineData == nil || string(parts[2].InlineData.Data) != "png-data" {
+		t.Errorf("質問の添付ファイルがインラインデータとして渡されていません: %+v", parts[2])
+	}
+}
+
+func TestBuildSystemInstruction(t *testing.T) {
+	if buildSystemInstruction("") != nil {
+		t.Error("空のシステムプロンプトではシステム指示を設定しないべきです")
+	}
+
+	instruction := buildSystemInstruction("あなたは優秀なアシスタントです。")
+	if instruction == nil || instruction.Parts[0].Text != "あなたは優秀なアシスタントです。" {
+		t.Errorf("システム指示が正しく作成されていません: %+v", instruction)
+	}
+}
@@ -0,0 +1,116 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// errEmptyResponse は、応答の候補にコンテンツが含まれていないことを表すエラーです
+// 一時的に発生することがあるため、リトライの対象にします
+var errEmptyResponse = errors.New("Gemini APIの応答にコンテンツが含まれていません")
+
+// classifyAPIError は、Gemini APIの呼び出しで発生したエラーを、HTTPステータスコードなどから種類を判定してドメインのエラーでラップします
+// 呼び出し側は errors.Is でエラーの種類を判定でき、元のエラーも errors.As で取り出せます
+func classifyAPIError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("Gemini APIへのリクエストが%w。時間を置いて再度お試しください: %w", domain.ErrTimeout, err)
+	}
+
+	if apiErr, ok := asAPIError(err); ok {
+		switch {
+		case apiErr.Code == http.StatusTooManyRequests:
+			return fmt.Errorf("%w。しばらく時間を置いてから再度お試しください: %w", domain.ErrQuotaExceeded, err)
+		case apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden || isInvalidAPIKey(apiErr):
+			return fmt.Errorf("%w。APIキーを確認してください: %w", domain.ErrUnauthorized, err)
+		case apiErr.Code == http.StatusGatewayTimeout:
+			return fmt.Errorf("Gemini APIでの処理が%w。時間を置いて再度お試しください: %w", domain.ErrTimeout, err)
+		case apiErr.Code >= http.StatusInternalServerError:
+			return fmt.Errorf("%w。時間を置いて再度お試しください: %w", domain.ErrUpstreamUnavailable, err)
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return fmt.Errorf("Gemini APIへの接続が%w。時間を置いて再度お試しください: %w", domain.ErrTimeout, err)
+		}
+		return fmt.Errorf("%w（ネットワークエラー）。接続を確認して再度お試しください: %w", domain.ErrUpstreamUnavailable, err)
+	}
+
+	return fmt.Errorf("Gemini APIからの応答取得に失敗しました: %w", err)
+}
+
+// asAPIError は、エラーに含まれるGemini APIのエラーを返します
+// genai は APIError を値で返すため、値とポインタの両方を確認します
+func asAPIError(err error) (genai.APIError, bool) {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) && apiErrPtr != nil {
+		return *apiErrPtr, true
+	}
+	return genai.APIError{}, false
+}
+
+// isInvalidAPIKey は、APIキーが無効であることを表すエラーかどうかを返します
+// 無効なAPIキーは 400 INVALID_ARGUMENT で返され、詳細の reason に API_KEY_INVALID が入ります
+func isInvalidAPIKey(apiErr genai.APIError) bool {
+	for _, detail := range apiErr.Details {
+		if reason, ok := detail["reason"].(string); ok && reason == "API_KEY_INVALID" {
+			return true
+		}
+	}
+	return false
+}
+
+// safetyBlockedError は、安全フィルターでブロックされた応答のエラーを、原因のカテゴリとともに返します
+func safetyBlockedError(ratings []*genai.SafetyRating) error {
+	return &domain.SafetyBlockedError{Categories: blockedSafetyCategories(ratings)}
+}
+
+// blockedSafetyCategories は、ブロックの原因となったカテゴリの表示名を返します
+// ブロックされたと明示されたカテゴリがない場合は、可能性が中レベル以上のカテゴリを返します
+func blockedSafetyCategories(ratings []*genai.SafetyRating) []string {
+	var blocked, likely []string
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		category := translateSafetyCategory(rating.Category)
+		if rating.Blocked {
+			blocked = append(blocked, category)
+		}
+		if rating.Probability == genai.HarmProbabilityMedium || rating.Probability == genai.HarmProbabilityHigh {
+			likely = append(likely, category)
+		}
+	}
+	if len(blocked) > 0 {
+		return blocked
+	}
+	return likely
+}
+
+// translateSafetyCategory は、SafetyCategoryを日本語に翻訳します
+func translateSafetyCategory(category genai.HarmCategory) string {
+	switch category {
+	case genai.HarmCategoryHarassment:
+		return "ハラスメント"
+	case genai.HarmCategoryHateSpeech:
+		return "ヘイトスピーチ"
+	case genai.HarmCategorySexuallyExplicit:
+		return "性的表現"
+	case genai.HarmCategoryDangerousContent:
+		return "危険なコンテンツ"
+	default:
+		return string(category)
+	}
+}
@@ -0,0 +1,111 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// timeoutNetError は、タイムアウトしたネットワークエラーを模したエラーです
+type timeoutNetError struct{ timeout bool }
+
+func (e timeoutNetError) Error() string   { return "dial tcp: i/o timeout" }
+func (e timeoutNetError) Timeout() bool   { return e.timeout }
+func (e timeoutNetError) Temporary() bool { return false }
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "利用制限", err: genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}, want: domain.ErrQuotaExceeded},
+		{name: "ポインタの利用制限", err: fmt.Errorf("呼び出しに失敗: %w", &genai.APIError{Code: 429}), want: domain.ErrQuotaExceeded},
+		{name: "権限なし", err: genai.APIError{Code: 403, Status: "PERMISSION_DENIED"}, want: domain.ErrUnauthorized},
+		{
+			name: "無効なAPIキー",
+			err:  genai.APIError{Code: 400, Status: "INVALID_ARGUMENT", Details: []map[string]any{{"reason": "API_KEY_INVALID"}}},
+			want: domain.ErrUnauthorized,
+		},
+		{name: "過負荷", err: genai.APIError{Code: 503, Status: "UNAVAILABLE"}, want: domain.ErrUpstreamUnavailable},
+		{name: "サーバーエラー", err: genai.APIError{Code: 500, Status: "INTERNAL"}, want: domain.ErrUpstreamUnavailable},
+		{name: "サーバー側のタイムアウト", err: genai.APIError{Code: 504, Status: "DEADLINE_EXCEEDED"}, want: domain.ErrTimeout},
+		{name: "コンテキストのタイムアウト", err: fmt.Errorf("送信に失敗: %w", context.DeadlineExceeded), want: domain.ErrTimeout},
+		{name: "接続のタイムアウト", err: timeoutNetError{timeout: true}, want: domain.ErrTimeout},
+		{name: "ネットワークエラー", err: timeoutNetError{}, want: domain.ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyAPIError(context.Background(), tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Errorf("classifyAPIError() = %v, 期待するエラー: %v", got, tt.want)
+			}
+			// genai.APIError は比較できないため、errors.As で元のエラーを確認する
+			if _, ok := asAPIError(tt.err); ok {
+				if _, ok := asAPIError(got); !ok {
+					t.Errorf("元のAPIエラーを取り出せません: %v", got)
+				}
+			} else if !errors.Is(got, tt.err) {
+				t.Errorf("元のエラーを取り出せません: %v", got)
+			}
+		})
+	}
+
+	// 種類を判定できないエラーは、どのドメインのエラーとも一致しない
+	got := classifyAPIError(context.Background(), genai.APIError{Code: 400, Status: "INVALID_ARGUMENT"})
+	for _, target := range []error{domain.ErrQuotaExceeded, domain.ErrUnauthorized, domain.ErrUpstreamUnavailable, domain.ErrTimeout} {
+		if errors.Is(got, target) {
+			t.Errorf("不正なリクエストを %v と判定しました: %v", target, got)
+		}
+	}
+
+	// リクエストのコンテキストが期限切れの場合は、エラーの内容によらずタイムアウトとする
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if got := classifyAPIError(ctx, errors.New("stream closed")); !errors.Is(got, domain.ErrTimeout) {
+		t.Errorf("期限切れのリクエストをタイムアウトと判定しませんでした: %v", got)
+	}
+}
+
+func TestProcessResponse_TypedErrors(t *testing.T) {
+	client := &GeminiAPIClient{}
+
+	_, err := client.processResponse(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		FinishReason: genai.FinishReasonSafety,
+		SafetyRatings: []*genai.SafetyRating{
+			{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityLow},
+			{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityHigh},
+		},
+	}}})
+	var safetyErr *domain.SafetyBlockedError
+	if !errors.As(err, &safetyErr) || !reflect.DeepEqual(safetyErr.Categories, []string{"危険なコンテンツ"}) {
+		t.Errorf("安全フィルターのエラーにブロックされたカテゴリが含まれていません: %v", err)
+	}
+
+	_, err = client.processResponse(&genai.GenerateContentResponse{PromptFeedback: &genai.GenerateContentResponsePromptFeedback{
+		BlockReason:   genai.BlockedReasonSafety,
+		SafetyRatings: []*genai.SafetyRating{{Category: genai.HarmCategoryHateSpeech, Blocked: true}},
+	}})
+	if !errors.As(err, &safetyErr) || !reflect.DeepEqual(safetyErr.Categories, []string{"ヘイトスピーチ"}) {
+		t.Errorf("プロンプトのブロックが安全フィルターのエラーになっていません: %v", err)
+	}
+
+	_, err = client.processResponse(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonRecitation}}})
+	if !errors.Is(err, domain.ErrRecitation) {
+		t.Errorf("著作権保護のエラーになっていません: %v", err)
+	}
+
+	_, err = client.processResponse(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonStop}}})
+	if !errors.Is(err, errEmptyResponse) || !client.shouldRetry(err) {
+		t.Errorf("空の応答がリトライ対象のエラーになっていません: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+)
+
+// shouldFallback は、エラーがフォールバックチェーンの次のモデルで生成し直す対象かどうかを返します
+// 利用制限やサーバーの過負荷・一時的な障害・タイムアウト、安全フィルターによるブロックは、
+// モデルごとに発生するため別のモデルでは成功する場合があります
+func shouldFallback(err error) bool {
+	return errors.Is(err, domain.ErrQuotaExceeded) ||
+		errors.Is(err, domain.ErrUpstreamUnavailable) ||
+		errors.Is(err, domain.ErrTimeout) ||
+		errors.Is(err, domain.ErrSafetyBlocked)
+}
+
+// fallbackModelChain は、options のモデル（指定がない場合は既定のモデル）と、生成できなかった場合に順に使用するモデルの一覧を返します
+// options にフォールバックのモデルが指定されていない場合は、geminiConfig の既定のフォールバックチェーンを使用します
+func fallbackModelChain(geminiConfig *config.GeminiConfig, options application.TextGenerationOptions) []string {
+	model := geminiConfig.ModelName
+	if options.Model != "" {
+		model = options.Model
+	}
+	if options.DisableFallback {
+		return []string{model}
+	}
+
+	fallbacks := options.FallbackModels
+	if len(fallbacks) == 0 {
+		fallbacks = geminiConfig.FallbackModels
+	}
+	// モデル名が決まらない場合は、フォールバックせずにそのまま生成する
+	if chain := domain.ModelChain(model, fallbacks); len(chain) > 0 && chain[0] == model {
+		return chain
+	}
+	return []string{model}
+}
+
+// generateWithFallback は、フォールバックチェーンのモデルを順に options.Model に指定して generate を実行します
+// フォールバックの対象のエラーで失敗した場合は次のモデルで実行し直し、それ以外のエラーやチェーンの最後のモデルのエラーはそのまま返します
+// canFallback が false を返す場合（ストリーミングで回答の一部を送信済みの場合など）は、次のモデルを使用しません
+func generateWithFallback[T any](ctx context.Context, chain []string, options application.TextGenerationOptions, canFallback func() bool, generate func(options application.TextGenerationOptions) (T, error)) (T, error) {
+	var (
+		result T
+		err    error
+	)
+	for i, model := range chain {
+		options.Model = model
+		result, err = generate(options)
+		if err == nil || i == len(chain)-1 || ctx.Err() != nil || !shouldFallback(err) {
+			return result, err
+		}
+		if canFallback != nil && !canFallback() {
+			return result, err
+		}
+		log.Printf("%s で生成できなかったため、%s で生成し直します: %v", model, chain[i+1], err)
+	}
+	return result, err
+}
+
+// generateTextWithFallback は、generateWithFallback でテキストを生成し、最初のモデル以外で回答した場合は結果に最初のモデルを記録します
+func generateTextWithFallback(ctx context.Context, geminiConfig *config.GeminiConfig, options application.TextGenerationOptions, canFallback func() bool, generate func(options application.TextGenerationOptions) (*application.TextGenerationResult, error)) (*application.TextGenerationResult, error) {
+	chain := fallbackModelChain(geminiConfig, options)
+	result, err := generateWithFallback(ctx, chain, options, canFallback, generate)
+	if err != nil {
+		// ストリーミングで停止・失敗した場合は、使用量を記録できるよう途中までの結果も返す
+		return result, err
+	}
+	if result.Model != chain[0] {
+		result.FallbackFrom = chain[0]
+	}
+	return result, nil
+}
+
+// trackFirstChunk は、onChunk を包んだコールバックと、まだ onChunk にテキストを渡していないかどうかを返す関数を返します
+func trackFirstChunk(onChunk application.StreamCallback) (application.StreamCallback, func() bool) {
+	received := false
+	tracked := func(chunk string) {
+		received = true
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+	return tracked, func() bool { return !received }
+}
@@ -0,0 +1,137 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+
+	"google.golang.org/genai"
+)
+
+func TestShouldFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "利用制限", err: classifyAPIError(context.Background(), genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}), want: true},
+		{name: "過負荷", err: classifyAPIError(context.Background(), genai.APIError{Code: 503, Status: "UNAVAILABLE"}), want: true},
+		{name: "サーバー側のタイムアウト", err: classifyAPIError(context.Background(), genai.APIError{Code: 504, Status: "DEADLINE_EXCEEDED"}), want: true},
+		{name: "安全フィルター", err: fmt.Errorf("応答の生成に失敗: %w", &domain.SafetyBlockedError{}), want: true},
+		{name: "不正なリクエスト", err: classifyAPIError(context.Background(), genai.APIError{Code: 400, Status: "INVALID_ARGUMENT"}), want: false},
+		{name: "APIキーの誤り", err: classifyAPIError(context.Background(), genai.APIError{Code: 403, Status: "PERMISSION_DENIED"}), want: false},
+		{name: "分類していないAPIエラー", err: genai.APIError{Code: 429}, want: false},
+		{name: "その他のエラー", err: errors.New("接続できません"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldFallback(tt.err); got != tt.want {
+				t.Errorf("shouldFallback(%v) = %v, 期待値: %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackModelChain(t *testing.T) {
+	geminiConfig := &config.GeminiConfig{ModelName: "gemini-2.5-pro", FallbackModels: []string{"gemini-2.5-flash", "gemini-2.0-flash"}}
+
+	tests := []struct {
+		name    string
+		options application.TextGenerationOptions
+		want    []string
+	}{
+		{name: "全体の既定", want: []string{"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.0-flash"}},
+		{
+			name:    "リクエストのチェーン",
+			options: application.TextGenerationOptions{Model: "gemini-2.5-flash", FallbackModels: []string{"gemini-2.5-flash", "gemini-2.5-flash-lite"}},
+			want:    []string{"gemini-2.5-flash", "gemini-2.5-flash-lite"},
+		},
+		{
+			name:    "フォールバックしない",
+			options: application.TextGenerationOptions{Model: "gemini-2.5-flash-lite", DisableFallback: true},
+			want:    []string{"gemini-2.5-flash-lite"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fallbackModelChain(geminiConfig, tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fallbackModelChain() = %v, 期待値: %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTextWithFallback(t *testing.T) {
+	geminiConfig := &config.GeminiConfig{ModelName: "gemini-2.5-pro"}
+	options := application.TextGenerationOptions{FallbackModels: []string{"gemini-2.5-flash", "gemini-2.0-flash"}}
+
+	// 利用制限・過負荷のモデルを飛ばして、次のモデルで回答する
+	var tried []string
+	result, err := generateTextWithFallback(context.Background(), geminiConfig, options, nil, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		tried = append(tried, options.Model)
+		switch options.Model {
+		case "gemini-2.5-pro":
+			return nil, classifyAPIError(context.Background(), genai.APIError{Code: 429})
+		case "gemini-2.5-flash":
+			return nil, classifyAPIError(context.Background(), genai.APIError{Code: 503})
+		}
+		return &application.TextGenerationResult{Content: "回答", Model: options.Model}, nil
+	})
+	if err != nil {
+		t.Fatalf("generateTextWithFallback() でエラーが発生しました: %v", err)
+	}
+	if want := []string{"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.0-flash"}; !reflect.DeepEqual(tried, want) {
+		t.Errorf("試したモデル = %v, 期待値: %v", tried, want)
+	}
+	if result.Model != "gemini-2.0-flash" || result.FallbackFrom != "gemini-2.5-pro" {
+		t.Errorf("回答したモデルと最初のモデルを記録するべきです: %+v", result)
+	}
+
+	// フォールバックの対象でないエラーは、次のモデルを試さずに返す
+	tried = nil
+	_, err = generateTextWithFallback(context.Background(), geminiConfig, options, nil, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		tried = append(tried, options.Model)
+		return nil, classifyAPIError(context.Background(), genai.APIError{Code: 400})
+	})
+	if err == nil || len(tried) != 1 {
+		t.Errorf("フォールバックの対象でないエラーでは次のモデルを試すべきではありません: 試したモデル %v, エラー: %v", tried, err)
+	}
+
+	// ストリーミングで回答の一部を送信した後は、次のモデルを試さない
+	tried = nil
+	_, err = generateTextWithFallback(context.Background(), geminiConfig, options, func() bool { return false }, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		tried = append(tried, options.Model)
+		return nil, &domain.SafetyBlockedError{}
+	})
+	if !errors.Is(err, domain.ErrSafetyBlocked) || len(tried) != 1 {
+		t.Errorf("回答の一部を送信した後は次のモデルを試すべきではありません: 試したモデル %v, エラー: %v", tried, err)
+	}
+
+	// 最初のモデルで回答した場合は、フォールバックを記録しない
+	result, err = generateTextWithFallback(context.Background(), geminiConfig, options, nil, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		return &application.TextGenerationResult{Content: "回答", Model: options.Model}, nil
+	})
+	if err != nil || result.FallbackFrom != "" {
+		t.Errorf("フォールバックしなかった場合は FallbackFrom を空にするべきです: %+v, %v", result, err)
+	}
+}
+
+func TestTrackFirstChunk(t *testing.T) {
+	var received []string
+	onChunk, beforeFirstChunk := trackFirstChunk(func(chunk string) { received = append(received, chunk) })
+	if !beforeFirstChunk() {
+		t.Error("テキストを渡す前は true を返すべきです")
+	}
+	onChunk("こんにちは")
+	if beforeFirstChunk() || !reflect.DeepEqual(received, []string{"こんにちは"}) {
+		t.Errorf("テキストを渡した後は false を返すべきです: %v", received)
+	}
+}
@@ -0,0 +1,69 @@
+package gemini
+
+import (
+	"geminibot/internal/application"
+	"geminibot/internal/infrastructure/config"
+
+	"google.golang.org/genai"
+)
+
+// applyTextGenerationOptions は、既定の生成設定にリクエスト単位のオプションを上書きし、使用するモデル名を返します
+// オプションのゼロ値の項目は、既定の設定とモデル（geminiConfig.ModelName）をそのまま使用します
+// Google 検索によるグラウンディングやコード実行を使用する場合は、それぞれの組み込みツールを加えます
+// 思考に対応したモデルでは、オプションまたは geminiConfig の既定の思考予算と、思考の要約を受け取るかどうかを設定します
+func applyTextGenerationOptions(generateConfig *genai.GenerateContentConfig, geminiConfig *config.GeminiConfig, options application.TextGenerationOptions) string {
+	if options.MaxTokens > 0 {
+		generateConfig.MaxOutputTokens = int32(options.MaxTokens)
+	}
+	if options.Temperature > 0 {
+		temperature := float32(options.Temperature)
+		generateConfig.Temperature = &temperature
+	}
+	if options.TopP > 0 {
+		topP := float32(options.TopP)
+		generateConfig.TopP = &topP
+	}
+	if options.TopK > 0 {
+		topK := float32(options.TopK)
+		generateConfig.TopK = &topK
+	}
+
+	if options.SearchGrounding {
+		generateConfig.Tools = append(generateConfig.Tools, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}})
+	}
+	if options.CodeExecution {
+		generateConfig.Tools = append(generateConfig.Tools, &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}})
+	}
+
+	model := geminiConfig.ModelName
+	if options.Model != "" {
+		model = options.Model
+	}
+	generateConfig.ThinkingConfig = thinkingConfig(geminiConfig, model, options)
+	return model
+}
+
+// thinkingConfig は、model の思考の設定を返します（思考に対応していないモデルや、既定のままでよい場合は nil を返します）
+func thinkingConfig(geminiConfig *config.GeminiConfig, model string, options application.TextGenerationOptions) *genai.ThinkingConfig {
+	budget := options.ThinkingBudget
+	if budget == nil {
+		budget = geminiConfig.ThinkingBudget
+	}
+	includeThoughts := options.IncludeThoughts || geminiConfig.IncludeThoughts
+	if budget == nil && !includeThoughts {
+		return nil
+	}
+
+	// 思考に対応していないモデルに思考の設定を渡すとリクエストが失敗するため、設定しない
+	if !config.SupportsGeminiThinking(model) {
+		return nil
+	}
+
+	thinking := &genai.ThinkingConfig{IncludeThoughts: includeThoughts}
+	if budget != nil {
+		clamped, _ := config.GeminiThinkingBudget(model, *budget)
+		thinkingBudget := int32(clamped)
+		thinking.ThinkingBudget = &thinkingBudget
+	}
+	return thinking
+}
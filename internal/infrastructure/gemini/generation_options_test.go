@@ -0,0 +1,127 @@
+package gemini
+
+import (
+	"testing"
+
+	"geminibot/internal/application"
+	"geminibot/internal/infrastructure/config"
+
+	"google.golang.org/genai"
+)
+
+func TestApplyTextGenerationOptions(t *testing.T) {
+	temperature := float32(0.7)
+	newConfig := func() *genai.GenerateContentConfig {
+		return &genai.GenerateContentConfig{MaxOutputTokens: 1000, Temperature: &temperature}
+	}
+	geminiConfig := &config.GeminiConfig{ModelName: "gemini-2.5-pro"}
+
+	// オプション未指定時は既定のモデルと設定を使用する
+	generateConfig := newConfig()
+	model := applyTextGenerationOptions(generateConfig, geminiConfig, application.TextGenerationOptions{})
+	if model != "gemini-2.5-pro" {
+		t.Errorf("期待されるモデル: gemini-2.5-pro, 実際: %s", model)
+	}
+	if generateConfig.MaxOutputTokens != 1000 || *generateConfig.Temperature != 0.7 {
+		t.Errorf("オプション未指定時に既定の設定が変更されました: %+v", generateConfig)
+	}
+	if generateConfig.ThinkingConfig != nil {
+		t.Errorf("思考の設定がない場合はモデルの既定を使用するべきです: %+v", generateConfig.ThinkingConfig)
+	}
+
+	// 指定された項目のみ上書きする
+	generateConfig = newConfig()
+	model = applyTextGenerationOptions(generateConfig, geminiConfig, application.TextGenerationOptions{
+		Model:     "gemini-2.0-flash",
+		MaxTokens: 2000,
+	})
+	if model != "gemini-2.0-flash" {
+		t.Errorf("期待されるモデル: gemini-2.0-flash, 実際: %s", model)
+	}
+	if generateConfig.MaxOutputTokens != 2000 {
+		t.Errorf("期待されるMaxOutputTokens: 2000, 実際: %d", generateConfig.MaxOutputTokens)
+	}
+	if *generateConfig.Temperature != 0.7 {
+		t.Errorf("未指定のTemperatureは既定値のままであるべきです: %f", *generateConfig.Temperature)
+	}
+}
+
+func TestApplyTextGenerationOptions_Thinking(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name         string
+		geminiConfig config.GeminiConfig
+		options      application.TextGenerationOptions
+		wantBudget   *int32 // nil の場合は思考予算を指定しないことを期待します
+		wantThoughts bool
+		wantNil      bool
+	}{
+		{
+			name:         "全体の既定の思考予算を使用する",
+			geminiConfig: config.GeminiConfig{ModelName: "gemini-2.5-flash", ThinkingBudget: intPtr(1024)},
+			wantBudget:   genai.Ptr[int32](1024),
+		},
+		{
+			name:         "リクエストの思考予算を優先する",
+			geminiConfig: config.GeminiConfig{ModelName: "gemini-2.5-flash", ThinkingBudget: intPtr(1024)},
+			options:      application.TextGenerationOptions{ThinkingBudget: intPtr(-1)},
+			wantBudget:   genai.Ptr[int32](-1),
+		},
+		{
+			name:         "思考を無効にできないモデルでは最小の予算を使用する",
+			geminiConfig: config.GeminiConfig{ModelName: "gemini-2.5-pro"},
+			options:      application.TextGenerationOptions{ThinkingBudget: intPtr(0)},
+			wantBudget:   genai.Ptr[int32](128),
+		},
+		{
+			name:         "モデルの上限を超える予算は上限に合わせる",
+			geminiConfig: config.GeminiConfig{ModelName: "gemini-2.5-flash"},
+			options:      application.TextGenerationOptions{ThinkingBudget: intPtr(32768)},
+			wantBudget:   genai.Ptr[int32](24576),
+		},
+		{
+			name:         "思考の要約のみを受け取る",
+			geminiConfig: config.GeminiConfig{ModelName: "gemini-2.5-pro"},
+			options:      application.TextGenerationOptions{IncludeThoughts: true},
+			wantThoughts: true,
+		},
+		{
+			name:         "全体の既定で思考の要約を受け取る",
+			geminiConfig: config.GeminiConfig{ModelName: "gemini-2.5-pro", IncludeThoughts: true},
+			wantThoughts: true,
+		},
+		{
+			name:         "思考に対応していないモデルでは設定しない",
+			geminiConfig: config.GeminiConfig{ModelName: "gemini-2.0-flash", ThinkingBudget: intPtr(1024), IncludeThoughts: true},
+			wantNil:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generateConfig := &genai.GenerateContentConfig{}
+			applyTextGenerationOptions(generateConfig, &tt.geminiConfig, tt.options)
+
+			thinking := generateConfig.ThinkingConfig
+			if tt.wantNil {
+				if thinking != nil {
+					t.Errorf("ThinkingConfig = %+v, 期待値: nil", thinking)
+				}
+				return
+			}
+			if thinking == nil {
+				t.Fatal("ThinkingConfig が設定されていません")
+			}
+			if thinking.IncludeThoughts != tt.wantThoughts {
+				t.Errorf("IncludeThoughts = %v, 期待値: %v", thinking.IncludeThoughts, tt.wantThoughts)
+			}
+			switch {
+			case tt.wantBudget == nil && thinking.ThinkingBudget != nil:
+				t.Errorf("ThinkingBudget = %d, 期待値: 未指定", *thinking.ThinkingBudget)
+			case tt.wantBudget != nil && (thinking.ThinkingBudget == nil || *thinking.ThinkingBudget != *tt.wantBudget):
+				t.Errorf("ThinkingBudget = %v, 期待値: %d", thinking.ThinkingBudget, *tt.wantBudget)
+			}
+		})
+	}
+}
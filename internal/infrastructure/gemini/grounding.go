@@ -0,0 +1,83 @@
+package gemini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// groundedContent は、レスポンスのグラウンディングのメタデータから検索クエリと参照したWebページを取り出し、
+// 回答の根拠となった箇所の末尾に引用番号（[1] など）を挿入したテキストと共に返します
+// グラウンディングを使用しなかった場合や、Webページを参照しなかった場合は、テキストをそのまま返し、結果は nil です
+func groundedContent(resp *genai.GenerateContentResponse, content string) (string, *domain.Grounding) {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0] == nil || resp.Candidates[0].GroundingMetadata == nil {
+		return content, nil
+	}
+	metadata := resp.Candidates[0].GroundingMetadata
+
+	// 引用番号は、Webページのチャンクにのみ順番に割り当てる
+	grounding := &domain.Grounding{SearchQueries: metadata.WebSearchQueries}
+	numbers := make(map[int]int)
+	for i, chunk := range metadata.GroundingChunks {
+		if chunk == nil || chunk.Web == nil || chunk.Web.URI == "" {
+			continue
+		}
+		title := chunk.Web.Title
+		if title == "" {
+			title = chunk.Web.Domain
+		}
+		grounding.Sources = append(grounding.Sources, domain.GroundingSource{Title: title, URI: chunk.Web.URI})
+		numbers[i] = len(grounding.Sources)
+	}
+
+	if !grounding.HasSources() && len(grounding.SearchQueries) == 0 {
+		return content, nil
+	}
+	return insertCitationMarkers(content, metadata.GroundingSupports, numbers), grounding
+}
+
+// insertCitationMarkers は、根拠となった箇所（セグメント）の末尾に、参照したWebページの引用番号を挿入します
+// セグメントの位置はUTF-8のバイト単位のため、文字の途中を指す位置や範囲外の位置には挿入しません
+func insertCitationMarkers(text string, supports []*genai.GroundingSupport, numbers map[int]int) string {
+	type marker struct {
+		end   int
+		label string
+	}
+
+	var markers []marker
+	for _, support := range supports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		end := int(support.Segment.EndIndex)
+		if end <= 0 || end > len(text) || (end < len(text) && !utf8.RuneStart(text[end])) {
+			continue
+		}
+
+		var label strings.Builder
+		seen := make(map[int]bool)
+		for _, index := range support.GroundingChunkIndices {
+			number, ok := numbers[int(index)]
+			if !ok || seen[number] {
+				continue
+			}
+			seen[number] = true
+			fmt.Fprintf(&label, "[%d]", number)
+		}
+		if label.Len() > 0 {
+			markers = append(markers, marker{end: end, label: label.String()})
+		}
+	}
+
+	// 後ろの位置から挿入し、挿入によって前の位置がずれないようにする
+	sort.SliceStable(markers, func(i, j int) bool { return markers[i].end > markers[j].end })
+	for _, m := range markers {
+		text = text[:m.end] + m.label + text[m.end:]
+	}
+	return text
+}
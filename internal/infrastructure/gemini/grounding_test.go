@@ -0,0 +1,104 @@
+package gemini
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"geminibot/internal/application"
+	"geminibot/internal/infrastructure/config"
+
+	"google.golang.org/genai"
+)
+
+// loadGroundingResponse は、Google 検索によるグラウンディングを使用したときのレスポンスの記録を読み込みます
+func loadGroundingResponse(t *testing.T) *genai.GenerateContentResponse {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/grounding_response.json")
+	if err != nil {
+		t.Fatalf("レスポンスの記録の読み込みに失敗: %v", err)
+	}
+	var resp genai.GenerateContentResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("レスポンスの記録の解析に失敗: %v", err)
+	}
+	return &resp
+}
+
+func TestGroundedContent(t *testing.T) {
+	resp := loadGroundingResponse(t)
+
+	content, grounding := groundedContent(resp, resp.Text())
+	want := "2024年のパリオリンピックは7月26日に開幕しました。[1][2]閉会式は8月11日に行われました。[2]"
+	if content != want {
+		t.Errorf("引用番号の挿入結果が一致しません:\n実際: %s\n期待値: %s", content, want)
+	}
+
+	if !grounding.HasSources() || len(grounding.Sources) != 2 {
+		t.Fatalf("参照したWebページが取り出されていません: %+v", grounding)
+	}
+	if grounding.Sources[0].Title != "olympics.com" || grounding.Sources[1].URI != "https://vertexaisearch.cloud.google.com/grounding-api-redirect/wikipedia" {
+		t.Errorf("参照したWebページが一致しません: %+v", grounding.Sources)
+	}
+	if len(grounding.SearchQueries) != 2 || grounding.SearchQueries[0] != "パリオリンピック 2024 開幕日" {
+		t.Errorf("検索クエリが一致しません: %v", grounding.SearchQueries)
+	}
+}
+
+func TestGroundedContent_WithoutGrounding(t *testing.T) {
+	resp := finalAnswerResponse("こんにちは")
+
+	content, grounding := groundedContent(resp, "こんにちは")
+	if content != "こんにちは" || grounding != nil {
+		t.Errorf("グラウンディングを使用しなかった応答は変更するべきではありません: content=%s, grounding=%+v", content, grounding)
+	}
+}
+
+func TestInsertCitationMarkers_SkipsInvalidSegments(t *testing.T) {
+	text := "日本語"
+	supports := []*genai.GroundingSupport{
+		{Segment: &genai.Segment{EndIndex: 2}, GroundingChunkIndices: []int32{0}},   // 文字の途中
+		{Segment: &genai.Segment{EndIndex: 100}, GroundingChunkIndices: []int32{0}}, // 範囲外
+		{Segment: &genai.Segment{EndIndex: 9}, GroundingChunkIndices: []int32{5}},   // Webページ以外のチャンク
+	}
+
+	if got := insertCitationMarkers(text, supports, map[int]int{0: 1}); got != text {
+		t.Errorf("無効な位置やチャンクには引用番号を挿入するべきではありません: %s", got)
+	}
+}
+
+func TestCollectStream_KeepsGroundingMetadata(t *testing.T) {
+	recorded := loadGroundingResponse(t)
+	metadata := recorded.Candidates[0].GroundingMetadata
+
+	// グラウンディングのメタデータは、最後のチャンクで受信する
+	last := textResponse("閉会式は8月11日に行われました。", genai.FinishReasonStop, false)
+	last.Candidates[0].GroundingMetadata = metadata
+	stream := fakeStream([]*genai.GenerateContentResponse{
+		textResponse("2024年のパリオリンピックは7月26日に開幕しました。", "", false),
+		last,
+	}, nil)
+
+	resp, err := collectStream(stream, nil)
+	if err != nil {
+		t.Fatalf("collectStream() エラー: %v", err)
+	}
+	if resp.Candidates[0].GroundingMetadata != metadata {
+		t.Errorf("グラウンディングのメタデータがレスポンスに含まれていません")
+	}
+}
+
+func TestSearchGroundingOptions(t *testing.T) {
+	generateConfig := &genai.GenerateContentConfig{}
+	applyTextGenerationOptions(generateConfig, &config.GeminiConfig{ModelName: "gemini-2.5-flash"}, application.TextGenerationOptions{SearchGrounding: true})
+	if len(generateConfig.Tools) != 1 || generateConfig.Tools[0].GoogleSearch == nil {
+		t.Errorf("グラウンディングを使用する場合はGoogle 検索のツールを加えるべきです: %+v", generateConfig.Tools)
+	}
+
+	// Google 検索は関数呼び出しと併用できないため、グラウンディングを使用する場合は関数呼び出しを行わない
+	geminiConfig := &config.GeminiConfig{MaxToolIterations: 5}
+	if got := maxToolIterations(geminiConfig, application.TextGenerationOptions{SearchGrounding: true}); got != 0 {
+		t.Errorf("maxToolIterations() = %d, 期待値: 0", got)
+	}
+}
@@ -89,26 +89,22 @@ func (g *GeminiAPIClient) processImageResponse(resp *genai.GenerateContentRespon
 	candidate := resp.Candidates[0]
 
 	// FinishReasonをチェックして安全フィルターによるブロックを検出
-	if candidate.FinishReason == "SAFETY" {
-		safetyDetails := g.formatSafetyRatings(candidate.SafetyRatings)
-		return nil, fmt.Errorf("Gemini APIの安全フィルターによって画像生成がブロックされました。詳細: %s", safetyDetails)
+	if candidate.FinishReason == genai.FinishReasonSafety || candidate.FinishReason == genai.FinishReasonImageSafety {
+		log.Printf("安全フィルターによって画像生成がブロックされました: %s", g.formatSafetyRatings(candidate.SafetyRatings))
+		return nil, safetyBlockedError(candidate.SafetyRatings)
 	}
 
-	if candidate.FinishReason == "RECITATION" {
-		return nil, fmt.Errorf("Gemini APIが著作権保護された内容を検出しました。著作権で保護されたコンテンツが含まれている可能性があります")
+	if candidate.FinishReason == genai.FinishReasonRecitation {
+		return nil, fmt.Errorf("%w。著作権で保護されたコンテンツが含まれている可能性があります", domain.ErrRecitation)
 	}
 
-	if candidate.FinishReason == "MAX_TOKENS" {
-		return nil, fmt.Errorf("Gemini APIの応答が最大トークン数に達しました。より短いプロンプトを試してください")
+	if candidate.FinishReason == genai.FinishReasonMaxTokens {
+		return nil, fmt.Errorf("%w。より短いプロンプトを試してください", domain.ErrMaxTokens)
 	}
 
-	// Contentがnilの場合のチェック
-	if candidate.Content == nil {
-		return nil, fmt.Errorf("Gemini APIの画像生成応答にContentが含まれていません。FinishReason: %s", candidate.FinishReason)
-	}
-
-	if len(candidate.Content.Parts) == 0 {
-		return nil, fmt.Errorf("Gemini APIの画像生成応答にコンテンツが含まれていません。FinishReason: %s", candidate.FinishReason)
+	// Contentがnil、または空の場合はリトライ対象のエラーを返す
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return nil, fmt.Errorf("%w。FinishReason: %s", errEmptyResponse, candidate.FinishReason)
 	}
 
 	// 画像データを抽出
@@ -137,5 +133,6 @@ func (g *GeminiAPIClient) processImageResponse(resp *genai.GenerateContentRespon
 		Prompt:      prompt,
 		Model:       modelName,
 		GeneratedAt: time.Now(),
+		Usage:       tokenUsage(resp),
 	}, nil
 }
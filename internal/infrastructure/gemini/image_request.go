@@ -0,0 +1,102 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// buildImageContents は、画像生成リクエストをGeminiに送信するコンテンツに変換します
+// 編集モードでは、元画像をインラインデータとしてプロンプトの前に配置します
+func buildImageContents(request domain.ImageGenerationRequest) []*genai.Content {
+	if !request.IsEdit() {
+		return genai.Text(buildImagePrompt(request))
+	}
+
+	parts := make([]*genai.Part, 0, len(request.SourceImages)+1)
+	for _, image := range request.SourceImages {
+		parts = append(parts, genai.NewPartFromBytes(image.Data, image.MimeType))
+	}
+	parts = append(parts, genai.NewPartFromText(buildImagePrompt(request)))
+
+	return []*genai.Content{genai.NewContentFromParts(parts, genai.RoleUser)}
+}
+
+// buildImagePrompt は、画像サイズに対応するアスペクト比の指示をプロンプトに追加します
+// 画像モデルの生成設定では出力サイズを指定できないため、プロンプトで指示します
+// 編集モードでは元画像の構図を保つため、指示を追加しません
+func buildImagePrompt(request domain.ImageGenerationRequest) string {
+	if request.IsEdit() {
+		return request.Prompt
+	}
+	return fmt.Sprintf("%s\n\n画像はアスペクト比 %s で生成してください。", request.Prompt, request.Options.Size.AspectRatio())
+}
+
+// generateImages は、指定された枚数の画像を得るために generateOne を並列に呼び出し、結果を1つのレスポンスにまとめます
+// 画像モデルは1回の呼び出しで1枚の画像を返すため、枚数分のリクエストを同時に送信します
+// 一部のリクエストが失敗しても、1枚以上生成できた場合は生成できた画像を返します
+func generateImages(ctx context.Context, count int, generateOne func(ctx context.Context) (*domain.ImageGenerationResponse, error)) (*domain.ImageGenerationResponse, error) {
+	if count <= 1 {
+		return generateOne(ctx)
+	}
+
+	type result struct {
+		response *domain.ImageGenerationResponse
+		err      error
+	}
+	results := make([]result, count)
+
+	var wg sync.WaitGroup
+	for i := range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := generateOne(ctx)
+			results[i] = result{response: response, err: err}
+		}()
+	}
+	wg.Wait()
+
+	var (
+		merged   *domain.ImageGenerationResponse
+		firstErr error
+	)
+	for i, r := range results {
+		if r.err != nil {
+			log.Printf("画像生成リクエスト %d/%d に失敗: %v", i+1, count, r.err)
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		if merged == nil {
+			merged = &domain.ImageGenerationResponse{
+				Prompt:      r.response.Prompt,
+				Model:       r.response.Model,
+				GeneratedAt: r.response.GeneratedAt,
+			}
+		}
+		merged.Images = append(merged.Images, r.response.Images...)
+		merged.Usage = merged.Usage.Add(r.response.Usage)
+	}
+
+	if merged == nil {
+		return nil, firstErr
+	}
+
+	// 余分な画像を除き、ファイル名を通し番号に振り直す
+	if len(merged.Images) > count {
+		merged.Images = merged.Images[:count]
+	}
+	for i := range merged.Images {
+		merged.Images[i].Filename = fmt.Sprintf("generated_image_%d.png", i+1)
+	}
+
+	log.Printf("画像生成が完了: %d/%d枚", len(merged.Images), count)
+	return merged, nil
+}
@@ -0,0 +1,95 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+func TestBuildImageContents_Generate(t *testing.T) {
+	contents := buildImageContents(domain.ImageGenerationRequest{
+		Prompt:  "青い空",
+		Options: domain.ImageGenerationOptions{Size: domain.ImageSize1024x768},
+	})
+
+	if len(contents) != 1 || len(contents[0].Parts) != 1 {
+		t.Fatalf("生成モードではテキストのみを送信するべきです: %+v", contents)
+	}
+	text := contents[0].Parts[0].Text
+	if !strings.HasPrefix(text, "青い空") || !strings.Contains(text, "アスペクト比 4:3") {
+		t.Errorf("サイズに対応するアスペクト比がプロンプトに含まれていません: %s", text)
+	}
+}
+
+func TestBuildImageContents_Edit(t *testing.T) {
+	request := domain.ImageGenerationRequest{
+		Prompt: "背景を青にして",
+		SourceImages: []domain.Attachment{
+			{Filename: "a.png", MimeType: "image/png", Data: []byte("png-data")},
+			{Filename: "b.jpg", MimeType: "image/jpeg", Data: []byte("jpeg-data")},
+		},
+	}
+
+	contents := buildImageContents(request)
+
+	if len(contents) != 1 || contents[0].Role != genai.RoleUser {
+		t.Fatalf("1つの user ターンにまとめられるべきです: %+v", contents)
+	}
+	parts := contents[0].Parts
+	if len(parts) != 3 {
+		t.Fatalf("期待されるパート数: 3, 実際: %d", len(parts))
+	}
+	if parts[0].InlineData == nil || parts[0].InlineData.MIMEType != "image/png" || parts[1].InlineData == nil {
+		t.Errorf("元画像がインラインデータとして渡されていません: %+v", parts[:2])
+	}
+	if parts[2].Text != "背景を青にして" {
+		t.Errorf("最後のパートがプロンプトではありません: %+v", parts[2])
+	}
+}
+
+func TestGenerateImages_Parallel(t *testing.T) {
+	var calls atomic.Int32
+	response, err := generateImages(context.Background(), 3, func(ctx context.Context) (*domain.ImageGenerationResponse, error) {
+		n := calls.Add(1)
+		if n == 2 {
+			return nil, errors.New("一時的なエラー")
+		}
+		return &domain.ImageGenerationResponse{
+			Images: []domain.GeneratedImage{{Data: []byte(fmt.Sprintf("image-%d", n)), Filename: "generated_image_1.png"}},
+			Model:  "image-model",
+			Usage:  domain.TokenUsage{PromptTokens: 10, OutputTokens: 1290},
+		}, nil
+	})
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+
+	if calls.Load() != 3 {
+		t.Errorf("期待されるリクエスト数: 3, 実際: %d", calls.Load())
+	}
+	if len(response.Images) != 2 {
+		t.Fatalf("失敗したリクエストを除いた画像が返されるべきです: %d枚", len(response.Images))
+	}
+	if response.Images[0].Filename != "generated_image_1.png" || response.Images[1].Filename != "generated_image_2.png" {
+		t.Errorf("ファイル名が通し番号になっていません: %s, %s", response.Images[0].Filename, response.Images[1].Filename)
+	}
+	if want := (domain.TokenUsage{PromptTokens: 20, OutputTokens: 2580}); response.Usage != want {
+		t.Errorf("成功したリクエストの使用量が合算されていません: %+v", response.Usage)
+	}
+}
+
+func TestGenerateImages_AllFailed(t *testing.T) {
+	_, err := generateImages(context.Background(), 2, func(ctx context.Context) (*domain.ImageGenerationResponse, error) {
+		return nil, errors.New("生成失敗")
+	})
+	if err == nil {
+		t.Error("全てのリクエストが失敗した場合はエラーが返されるべきです")
+	}
+}
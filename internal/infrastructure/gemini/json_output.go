@@ -0,0 +1,79 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// maxJSONAttempts は、生成したJSONがスキーマに合わない場合も含めて、JSONを生成する最大回数です
+const maxJSONAttempts = 3
+
+// applyJSONResponse は、応答のMIMEタイプを application/json に、応答のスキーマを schema に設定します
+func applyJSONResponse(config *genai.GenerateContentConfig, schema map[string]any) {
+	config.ResponseMIMEType = "application/json"
+	config.ResponseJsonSchema = schema
+}
+
+// generateJSON は、JSONを生成して schema で検証し、スキーマに合う JSON と全リクエストの合計のトークン数、生成した回数を返します
+// スキーマに合わない場合は、モデルの出力と誤りの内容を会話に加えて、最大 maxJSONAttempts 回まで生成し直します
+// process は、レスポンスの終了理由などを検証してテキストを取り出す関数です（失敗した場合は生成し直しません）
+func generateJSON(
+	ctx context.Context,
+	generate generateFunc,
+	contents []*genai.Content,
+	config *genai.GenerateContentConfig,
+	schema map[string]any,
+	process func(resp *genai.GenerateContentResponse) (string, error),
+) ([]byte, domain.TokenUsage, int, error) {
+	contents = append([]*genai.Content(nil), contents...)
+
+	var usage domain.TokenUsage
+	var lastErr error
+	for attempt := 1; attempt <= maxJSONAttempts; attempt++ {
+		resp, err := generate(ctx, contents, config)
+		if err != nil {
+			return nil, usage, attempt, err
+		}
+		usage = usage.Add(tokenUsage(resp))
+
+		text, err := process(resp)
+		if err != nil {
+			return nil, usage, attempt, err
+		}
+		output := trimJSONFence(text)
+		if err := domain.ValidateJSON(schema, []byte(output)); err != nil {
+			log.Printf("生成したJSONがスキーマに合わないため生成し直します（%d/%d回目）: %v", attempt, maxJSONAttempts, err)
+			lastErr = err
+			contents = append(contents,
+				&genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{{Text: text}}},
+				genai.NewContentFromText(jsonCorrectionPrompt(err), genai.RoleUser),
+			)
+			continue
+		}
+		return []byte(output), usage, attempt, nil
+	}
+	return nil, usage, maxJSONAttempts, fmt.Errorf("%d回生成してもJSONがスキーマに合いませんでした: %w", maxJSONAttempts, lastErr)
+}
+
+// jsonCorrectionPrompt は、スキーマに合わなかったJSONを生成し直させるための指示です
+func jsonCorrectionPrompt(err error) string {
+	return fmt.Sprintf("直前の出力は指定したJSONスキーマに合っていませんでした（%v）。前置きや説明を付けず、スキーマに従ったJSONのみを出力し直してください。", err)
+}
+
+// trimJSONFence は、モデルがJSONをコードブロックで囲んだ場合に、囲みを取り除きます
+func trimJSONFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,103 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// jsonTestSchema は、JSON出力のテストで使用するスキーマです
+var jsonTestSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"answer": map[string]any{"type": "string"},
+	},
+	"required": []any{"answer"},
+}
+
+// responseText は、レスポンスのテキストを取り出すテスト用の関数です
+func responseText(resp *genai.GenerateContentResponse) (string, error) {
+	return resp.Text(), nil
+}
+
+func TestGenerateJSON_RetriesUntilValid(t *testing.T) {
+	outputs := []string{"これはJSONではありません", `{"answer": 42}`, "```json\n{\"answer\": \"42\"}\n```"}
+	var requests [][]*genai.Content
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		requests = append(requests, contents)
+		return finalAnswerResponse(outputs[len(requests)-1]), nil
+	}
+
+	contents := []*genai.Content{genai.NewContentFromText("答えは？", genai.RoleUser)}
+	data, usage, attempts, err := generateJSON(context.Background(), generate, contents, &genai.GenerateContentConfig{}, jsonTestSchema, responseText)
+	if err != nil {
+		t.Fatalf("generateJSON() でエラーが発生しました: %v", err)
+	}
+	if string(data) != `{"answer": "42"}` {
+		t.Errorf("JSON = %s, コードブロックの囲みを取り除いたJSONを返すべきです", data)
+	}
+	if attempts != 3 {
+		t.Errorf("生成回数 = %d, 期待値: 3", attempts)
+	}
+	if usage.PromptTokens != 60 || usage.OutputTokens != 15 {
+		t.Errorf("トークン数 = %+v, すべてのリクエストの合計であるべきです", usage)
+	}
+
+	// 生成し直すときは、前回の出力と誤りの内容を会話に加える
+	if len(requests[1]) != 3 || requests[1][1].Role != genai.RoleModel || requests[1][2].Role != genai.RoleUser {
+		t.Fatalf("2回目のリクエストに前回の出力と修正の指示が含まれていません: %d件", len(requests[1]))
+	}
+	if len(requests[2]) != 5 {
+		t.Errorf("3回目のリクエストの件数 = %d, 期待値: 5", len(requests[2]))
+	}
+	if len(contents) != 1 {
+		t.Errorf("呼び出し元の会話を変更するべきではありません: %d件", len(contents))
+	}
+}
+
+func TestGenerateJSON_GivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		calls++
+		return finalAnswerResponse(`{"other": "x"}`), nil
+	}
+
+	_, _, attempts, err := generateJSON(context.Background(), generate, nil, &genai.GenerateContentConfig{}, jsonTestSchema, responseText)
+	if !errors.Is(err, domain.ErrInvalidJSONOutput) {
+		t.Fatalf("generateJSON() = %v, ErrInvalidJSONOutput を返すべきです", err)
+	}
+	if calls != maxJSONAttempts || attempts != maxJSONAttempts {
+		t.Errorf("生成回数 = %d（呼び出し %d回）, 期待値: %d", attempts, calls, maxJSONAttempts)
+	}
+}
+
+func TestGenerateJSON_DoesNotRetryOnAPIError(t *testing.T) {
+	apiErr := errors.New("unavailable")
+	calls := 0
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		calls++
+		return nil, apiErr
+	}
+
+	if _, _, _, err := generateJSON(context.Background(), generate, nil, &genai.GenerateContentConfig{}, jsonTestSchema, responseText); !errors.Is(err, apiErr) {
+		t.Errorf("generateJSON() = %v, APIのエラーをそのまま返すべきです", err)
+	}
+	if calls != 1 {
+		t.Errorf("APIのエラーでは生成し直すべきではありません: 呼び出し %d回", calls)
+	}
+}
+
+func TestApplyJSONResponse(t *testing.T) {
+	config := &genai.GenerateContentConfig{}
+	applyJSONResponse(config, jsonTestSchema)
+	if config.ResponseMIMEType != "application/json" {
+		t.Errorf("ResponseMIMEType = %q, 期待値: application/json", config.ResponseMIMEType)
+	}
+	if config.ResponseJsonSchema == nil {
+		t.Error("ResponseJsonSchema が設定されていません")
+	}
+}
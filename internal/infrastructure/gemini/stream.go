@@ -0,0 +1,99 @@
+package gemini
+
+import (
+	"iter"
+	"strings"
+
+	"geminibot/internal/application"
+
+	"google.golang.org/genai"
+)
+
+// collectStream は、ストリーミング応答を受信しながら生成されたテキストを onChunk に渡し、
+// 受信した内容を1つのレスポンスにまとめて返します（終了理由などの検証は processResponse で行います）
+// 関数の呼び出しは、テキストとは別にそのままレスポンスに含めます
+// グラウンディングのメタデータは、最後に受信したものをレスポンスに含めます
+// コード実行を使用した場合は、実行したコードと実行結果をテキストとして出現した位置に含め、作成された画像はそのままレスポンスに含めます
+// 思考の要約は onChunk に渡さず、回答とは別の思考の部分としてレスポンスに含めます
+// 受信の途中で停止・失敗した場合は、それまでに受信した使用量のみを含むレスポンスをエラーとともに返します
+func collectStream(stream iter.Seq2[*genai.GenerateContentResponse, error], onChunk application.StreamCallback) (*genai.GenerateContentResponse, error) {
+	var (
+		text          strings.Builder
+		thoughts      strings.Builder
+		functionCalls []*genai.Part
+		images        []*genai.Part
+		last          *genai.Candidate
+		usage         *genai.GenerateContentResponseUsageMetadata
+		grounding     *genai.GroundingMetadata
+	)
+
+	for resp, err := range stream {
+		if err != nil {
+			return &genai.GenerateContentResponse{UsageMetadata: usage}, err
+		}
+		if resp == nil {
+			continue
+		}
+		if resp.UsageMetadata != nil {
+			usage = resp.UsageMetadata
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0] == nil {
+			continue
+		}
+
+		candidate := resp.Candidates[0]
+		if candidate.GroundingMetadata != nil {
+			grounding = candidate.GroundingMetadata
+		}
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				if part != nil && part.FunctionCall != nil {
+					functionCalls = append(functionCalls, part)
+					continue
+				}
+				if isCodeExecutionImage(part) {
+					images = append(images, part)
+					continue
+				}
+				if part != nil && part.Thought {
+					thoughts.WriteString(part.Text)
+					continue
+				}
+				chunk := renderCodeExecutionPart(part)
+				if chunk == "" && part != nil {
+					chunk = part.Text
+				}
+				if chunk == "" {
+					continue
+				}
+				text.WriteString(chunk)
+				if onChunk != nil {
+					onChunk(chunk)
+				}
+			}
+		}
+		last = candidate
+	}
+
+	merged := &genai.GenerateContentResponse{UsageMetadata: usage}
+	if last == nil {
+		return merged, nil
+	}
+
+	content := &genai.Content{Role: genai.RoleModel}
+	if thoughts.Len() > 0 {
+		content.Parts = append(content.Parts, &genai.Part{Text: thoughts.String(), Thought: true})
+	}
+	if text.Len() > 0 {
+		content.Parts = append(content.Parts, &genai.Part{Text: text.String()})
+	}
+	content.Parts = append(content.Parts, functionCalls...)
+	content.Parts = append(content.Parts, images...)
+	merged.Candidates = []*genai.Candidate{{
+		Content:           content,
+		FinishReason:      last.FinishReason,
+		SafetyRatings:     last.SafetyRatings,
+		GroundingMetadata: grounding,
+	}}
+	return merged, nil
+}
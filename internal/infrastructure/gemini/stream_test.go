@@ -0,0 +1,103 @@
+package gemini
+
+import (
+	"errors"
+	"iter"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// fakeStream は、指定したレスポンスを順に返すストリームを作成します
+func fakeStream(responses []*genai.GenerateContentResponse, err error) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		for _, resp := range responses {
+			if !yield(resp, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func textResponse(text string, finishReason genai.FinishReason, thought bool) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{{Text: text, Thought: thought}}},
+			FinishReason: finishReason,
+		}},
+	}
+}
+
+func TestCollectStream(t *testing.T) {
+	stream := fakeStream([]*genai.GenerateContentResponse{
+		textResponse("考え中の内容", "", true),
+		textResponse("こんにちは", "", false),
+		textResponse("、世界", genai.FinishReasonStop, false),
+	}, nil)
+
+	var chunks []string
+	resp, err := collectStream(stream, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+
+	if len(chunks) != 2 || chunks[0] != "こんにちは" || chunks[1] != "、世界" {
+		t.Errorf("チャンクが正しく渡されていません: %q", chunks)
+	}
+
+	candidate := resp.Candidates[0]
+	if candidate.FinishReason != genai.FinishReasonStop {
+		t.Errorf("最後の終了理由が保持されていません: %s", candidate.FinishReason)
+	}
+	if text := candidateText(candidate); text != "こんにちは、世界" {
+		t.Errorf("テキストが結合されていません: %q", text)
+	}
+
+	// 思考の要約は回答に含めず、別の思考の部分として保持する
+	if thoughts := responseThoughts(resp); thoughts != "考え中の内容" {
+		t.Errorf("思考の要約が保持されていません: %q", thoughts)
+	}
+}
+
+func TestCollectStream_Error(t *testing.T) {
+	streamErr := errors.New("stream error")
+	stream := fakeStream([]*genai.GenerateContentResponse{textResponse("途中まで", "", false)}, streamErr)
+
+	if _, err := collectStream(stream, nil); !errors.Is(err, streamErr) {
+		t.Errorf("ストリームのエラーが返されていません: %v", err)
+	}
+
+	// 途中で失敗した場合も、それまでに受信した使用量を返す
+	partial := textResponse("途中まで", "", false)
+	partial.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 5}
+	resp, err := collectStream(fakeStream([]*genai.GenerateContentResponse{partial}, streamErr), nil)
+	if !errors.Is(err, streamErr) {
+		t.Fatalf("ストリームのエラーが返されていません: %v", err)
+	}
+	if usage := tokenUsage(resp); usage.PromptTokens != 100 || usage.OutputTokens != 5 {
+		t.Errorf("途中までの使用量が返されていません: %+v", usage)
+	}
+}
+
+func TestCollectStream_KeepsFunctionCalls(t *testing.T) {
+	call := &genai.Part{FunctionCall: &genai.FunctionCall{Name: "get_current_time"}, ThoughtSignature: []byte("signature")}
+	stream := fakeStream([]*genai.GenerateContentResponse{{
+		Candidates: []*genai.Candidate{{Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{call}}}},
+	}}, nil)
+
+	resp, err := collectStream(stream, nil)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if calls := resp.FunctionCalls(); len(calls) != 1 || calls[0].Name != "get_current_time" {
+		t.Errorf("関数の呼び出しが保持されていません: %+v", resp.Candidates[0].Content.Parts)
+	}
+	if parts := resp.Candidates[0].Content.Parts; string(parts[0].ThoughtSignature) != "signature" {
+		t.Errorf("関数の呼び出しの思考の署名が保持されていません: %+v", parts[0])
+	}
+}
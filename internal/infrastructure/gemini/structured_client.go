@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 
 	"geminibot/internal/application"
 	"geminibot/internal/domain"
@@ -17,6 +16,7 @@ import (
 type StructuredGeminiClient struct {
 	client *genai.Client
 	config *config.GeminiConfig
+	tools  *domain.ToolRegistry // 関数呼び出しでモデルが使用できるツール（nil の場合は使用しません）
 }
 
 // NewStructuredGeminiClient は新しいStructuredGeminiClientインスタンスを作成します
@@ -44,66 +44,195 @@ func NewStructuredGeminiClientWithAPIKey(apiKey string, geminiConfig *config.Gem
 	}, nil
 }
 
+// SetTools は、関数呼び出しでモデルが使用できるツールを設定します
+func (g *StructuredGeminiClient) SetTools(tools *domain.ToolRegistry) {
+	g.tools = tools
+}
+
 // GenerateTextWithStructuredContext は、構造化されたコンテキストを使用してテキストを生成します
+// ツールが設定されている場合は、モデルが最終的な回答を返すまで関数呼び出しを繰り返します
+// 利用制限・過負荷・安全フィルターなどで生成できなかった場合は、フォールバックチェーンの次のモデルで生成し直します
 func (g *StructuredGeminiClient) GenerateTextWithStructuredContext(
 	ctx context.Context,
 	systemPrompt string,
 	conversationHistory []domain.Message,
 	userQuestion string,
-) (string, error) {
+	questionAttachments []domain.Attachment,
+	options application.TextGenerationOptions,
+) (*application.TextGenerationResult, error) {
 	log.Printf("構造化コンテキストでGemini APIにテキスト生成をリクエスト中")
 	log.Printf("システムプロンプト: %d文字", len(systemPrompt))
 	log.Printf("会話履歴: %d件", len(conversationHistory))
 	log.Printf("ユーザー質問: %d文字", len(userQuestion))
 
-	// 構造化されたコンテンツを作成
-	var allContents []*genai.Content
+	return generateTextWithFallback(ctx, g.config, options, nil, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		return g.generateTextWithStructuredContext(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options)
+	})
+}
+
+// generateTextWithStructuredContext は、options のモデルで、構造化されたコンテキストを使用してテキストを生成します
+func (g *StructuredGeminiClient) generateTextWithStructuredContext(
+	ctx context.Context,
+	systemPrompt string,
+	conversationHistory []domain.Message,
+	userQuestion string,
+	questionAttachments []domain.Attachment,
+	options application.TextGenerationOptions,
+) (*application.TextGenerationResult, error) {
+	// 生成設定を作成し、リクエスト単位のオプション（ギルド別モデルなど）を適用
+	config := g.createGenerateConfig()
+	modelName := applyTextGenerationOptions(config, g.config, options)
+	log.Printf("使用モデル: %s", modelName)
 
-	// システムプロンプトを追加
-	allContents = append(allContents, genai.Text(systemPrompt)...)
+	// システムプロンプトはシステム指示として、会話履歴と質問は user / model のマルチターンとして渡す
+	config.SystemInstruction = buildSystemInstruction(systemPrompt)
+	allContents := buildConversationContents(conversationHistory, userQuestion, questionAttachments)
 
-	// 会話履歴を構造化して追加
-	if len(conversationHistory) > 0 {
-		historyText := g.formatConversationHistory(conversationHistory)
-		allContents = append(allContents, genai.Text(historyText)...)
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		return g.client.Models.GenerateContent(ctx, modelName, contents, config)
+	}
+	resp, usage, err := generateWithTools(ctx, generate, allContents, config, g.tools, maxToolIterations(g.config, options))
+	if err != nil {
+		return nil, classifyAPIError(ctx, err)
 	}
 
-	// ユーザーの質問を追加
-	allContents = append(allContents, genai.Text(userQuestion)...)
+	// レスポンス処理（グラウンディングを使用した場合は、根拠の箇所に引用番号を付ける）
+	content, err := g.processResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	content, grounding := groundedContent(resp, content)
+
+	return &application.TextGenerationResult{
+		Content:     content,
+		Model:       modelName,
+		Truncated:   reachedMaxTokens(resp),
+		Usage:       usage,
+		Grounding:   grounding,
+		Attachments: codeExecutionImages(resp),
+		Thoughts:    responseThoughts(resp),
+	}, nil
+}
 
-	// 生成設定を作成
-	config := &genai.GenerateContentConfig{
-		MaxOutputTokens: g.config.MaxTokens,
-		Temperature:     &g.config.Temperature,
-		TopP:            &g.config.TopP,
-		// 安全フィルターの設定
-		SafetySettings: []*genai.SafetySetting{
-			{
-				Category:  genai.HarmCategoryHarassment,
-				Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-			},
-			{
-				Category:  genai.HarmCategoryHateSpeech,
-				Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-			},
-			{
-				Category:  genai.HarmCategorySexuallyExplicit,
-				Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-			},
-			{
-				Category:  genai.HarmCategoryDangerousContent,
-				Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-			},
-		},
+// GenerateTextWithStructuredContextStream は、構造化されたコンテキストを使用してテキストをストリーミング生成します
+// フォールバックチェーンの次のモデルで生成し直すのは、テキストを受信する前に失敗した場合に限ります
+func (g *StructuredGeminiClient) GenerateTextWithStructuredContextStream(
+	ctx context.Context,
+	systemPrompt string,
+	conversationHistory []domain.Message,
+	userQuestion string,
+	questionAttachments []domain.Attachment,
+	options application.TextGenerationOptions,
+	onChunk application.StreamCallback,
+) (*application.TextGenerationResult, error) {
+	log.Printf("構造化コンテキストでGemini APIにストリーミング生成をリクエスト中")
+	log.Printf("システムプロンプト: %d文字", len(systemPrompt))
+	log.Printf("会話履歴: %d件", len(conversationHistory))
+	log.Printf("ユーザー質問: %d文字", len(userQuestion))
+
+	onChunk, beforeFirstChunk := trackFirstChunk(onChunk)
+	return generateTextWithFallback(ctx, g.config, options, beforeFirstChunk, func(options application.TextGenerationOptions) (*application.TextGenerationResult, error) {
+		return g.generateTextWithStructuredContextStream(ctx, systemPrompt, conversationHistory, userQuestion, questionAttachments, options, onChunk)
+	})
+}
+
+// generateTextWithStructuredContextStream は、options のモデルで、構造化されたコンテキストを使用してテキストをストリーミング生成します
+func (g *StructuredGeminiClient) generateTextWithStructuredContextStream(
+	ctx context.Context,
+	systemPrompt string,
+	conversationHistory []domain.Message,
+	userQuestion string,
+	questionAttachments []domain.Attachment,
+	options application.TextGenerationOptions,
+	onChunk application.StreamCallback,
+) (*application.TextGenerationResult, error) {
+	// 生成設定を作成し、リクエスト単位のオプション（ギルド別モデルなど）を適用
+	config := g.createGenerateConfig()
+	modelName := applyTextGenerationOptions(config, g.config, options)
+	log.Printf("使用モデル: %s", modelName)
+
+	config.SystemInstruction = buildSystemInstruction(systemPrompt)
+	allContents := buildConversationContents(conversationHistory, userQuestion, questionAttachments)
+
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		return collectStream(g.client.Models.GenerateContentStream(ctx, modelName, contents, config), onChunk)
+	}
+	resp, usage, err := generateWithTools(ctx, generate, allContents, config, g.tools, maxToolIterations(g.config, options))
+	if err != nil {
+		// 停止・失敗した場合も、それまでに消費したトークン数を記録できるよう使用量を含む結果を返す
+		return &application.TextGenerationResult{Model: modelName, Usage: usage}, classifyAPIError(ctx, err)
 	}
 
-	resp, err := g.client.Models.GenerateContent(ctx, g.config.ModelName, allContents, config)
+	// レスポンス処理（グラウンディングを使用した場合は、根拠の箇所に引用番号を付ける）
+	content, err := g.processResponse(resp)
 	if err != nil {
-		return "", fmt.Errorf("Gemini APIからの応答取得に失敗: %w", err)
+		return &application.TextGenerationResult{Model: modelName, Usage: usage}, err
 	}
+	content, grounding := groundedContent(resp, content)
+
+	return &application.TextGenerationResult{
+		Content:     content,
+		Model:       modelName,
+		Truncated:   reachedMaxTokens(resp),
+		Usage:       usage,
+		Grounding:   grounding,
+		Attachments: codeExecutionImages(resp),
+		Thoughts:    responseThoughts(resp),
+	}, nil
+}
 
-	// レスポンス処理
-	return g.processResponse(resp)
+// GenerateJSON は、構造化されたコンテキストを使用して、schema に沿ったJSONを生成します
+// 生成したJSONがスキーマに合わない場合は、誤りを伝えて生成し直します
+// 利用制限・過負荷・安全フィルターなどで生成できなかった場合は、フォールバックチェーンの次のモデルで生成し直します
+func (g *StructuredGeminiClient) GenerateJSON(
+	ctx context.Context,
+	systemPrompt string,
+	conversationHistory []domain.Message,
+	instruction string,
+	schema map[string]any,
+	options application.TextGenerationOptions,
+) (*application.JSONGenerationResult, error) {
+	log.Printf("構造化コンテキストでGemini APIにJSONの生成をリクエスト中")
+	log.Printf("システムプロンプト: %d文字", len(systemPrompt))
+	log.Printf("会話履歴: %d件", len(conversationHistory))
+
+	return generateWithFallback(ctx, fallbackModelChain(g.config, options), options, nil, func(options application.TextGenerationOptions) (*application.JSONGenerationResult, error) {
+		return g.generateJSONWithModel(ctx, systemPrompt, conversationHistory, instruction, schema, options)
+	})
+}
+
+// generateJSONWithModel は、options のモデルで、schema に沿ったJSONを生成します
+func (g *StructuredGeminiClient) generateJSONWithModel(
+	ctx context.Context,
+	systemPrompt string,
+	conversationHistory []domain.Message,
+	instruction string,
+	schema map[string]any,
+	options application.TextGenerationOptions,
+) (*application.JSONGenerationResult, error) {
+	// 生成設定を作成し、リクエスト単位のオプションと応答のスキーマを適用
+	config := g.createGenerateConfig()
+	modelName := applyTextGenerationOptions(config, g.config, options)
+	log.Printf("使用モデル: %s", modelName)
+	applyJSONResponse(config, schema)
+
+	config.SystemInstruction = buildSystemInstruction(systemPrompt)
+	allContents := buildConversationContents(conversationHistory, instruction, nil)
+
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		return g.client.Models.GenerateContent(ctx, modelName, contents, config)
+	}
+	data, usage, attempts, err := generateJSON(ctx, generate, allContents, config, schema, g.processResponse)
+	if err != nil {
+		return nil, fmt.Errorf("JSONの生成に失敗: %w", err)
+	}
+
+	return &application.JSONGenerationResult{
+		JSON:     data,
+		Model:    modelName,
+		Usage:    usage,
+		Attempts: attempts,
+	}, nil
 }
 
 // GenerateText は、プロンプトを受け取ってGemini APIからテキストを生成します
@@ -119,10 +248,7 @@ func (g *StructuredGeminiClient) GenerateText(ctx context.Context, prompt domain
 
 	resp, err := g.client.Models.GenerateContent(ctx, g.config.ModelName, contents, config)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("Gemini APIへのリクエストがタイムアウトしました: %w", err)
-		}
-		return "", fmt.Errorf("Gemini APIからの応答取得に失敗: %w", err)
+		return "", classifyAPIError(ctx, err)
 	}
 
 	// レスポンス処理
@@ -172,36 +298,24 @@ func (g *StructuredGeminiClient) GenerateTextWithOptions(ctx context.Context, pr
 	contents := genai.Text(prompt.Content)
 	resp, err := g.client.Models.GenerateContent(ctx, modelName, contents, config)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("Gemini APIへのリクエストがタイムアウトしました: %w", err)
-		}
-		return "", fmt.Errorf("Gemini APIからの応答取得に失敗: %w", err)
+		return "", classifyAPIError(ctx, err)
 	}
 
 	// レスポンス処理
 	return g.processResponse(resp)
 }
 
-// formatConversationHistory は、会話履歴を構造化された形式にフォーマットします
-func (g *StructuredGeminiClient) formatConversationHistory(messages []domain.Message) string {
-	var builder strings.Builder
-	builder.WriteString("## 会話履歴\n")
-
-	for _, msg := range messages {
-		displayName := msg.User.DisplayName
-		builder.WriteString(fmt.Sprintf("%s: %s\n", displayName, msg.Content))
-	}
-
-	return builder.String()
-}
-
 // processResponse は、Gemini APIのレスポンスを処理します
 func (g *StructuredGeminiClient) processResponse(resp *genai.GenerateContentResponse) (string, error) {
 	// デバッグ用：レスポンスの詳細をログ出力
 	log.Printf("Gemini APIレスポンス: Candidates数=%d", len(resp.Candidates))
 	if len(resp.Candidates) > 0 {
 		candidate := resp.Candidates[0]
-		log.Printf("Candidate詳細: FinishReason=%s, Parts数=%d", candidate.FinishReason, len(candidate.Content.Parts))
+		if candidate.Content != nil {
+			log.Printf("Candidate詳細: FinishReason=%s, Parts数=%d", candidate.FinishReason, len(candidate.Content.Parts))
+		} else {
+			log.Printf("Candidate詳細: FinishReason=%s, Content=nil", candidate.FinishReason)
+		}
 
 		// SafetyRatingsがある場合はログ出力
 		if len(candidate.SafetyRatings) > 0 {
@@ -212,32 +326,35 @@ func (g *StructuredGeminiClient) processResponse(resp *genai.GenerateContentResp
 	}
 
 	if len(resp.Candidates) == 0 {
+		// プロンプト自体が安全フィルターでブロックされた場合は、候補が返されない
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return "", safetyBlockedError(resp.PromptFeedback.SafetyRatings)
+		}
 		return "", fmt.Errorf("Gemini APIから有効な応答が得られませんでした")
 	}
 
 	candidate := resp.Candidates[0]
 
 	// FinishReasonをチェックして安全フィルターによるブロックを検出
-	if candidate.FinishReason == "SAFETY" {
-		return "", fmt.Errorf("Gemini APIの安全フィルターによって応答がブロックされました")
+	if candidate.FinishReason == genai.FinishReasonSafety {
+		return "", safetyBlockedError(candidate.SafetyRatings)
 	}
 
-	if candidate.FinishReason == "RECITATION" {
-		return "", fmt.Errorf("Gemini APIが著作権保護された内容を検出しました")
+	if candidate.FinishReason == genai.FinishReasonRecitation {
+		return "", domain.ErrRecitation
 	}
 
-	if len(candidate.Content.Parts) == 0 {
-		return "", fmt.Errorf("Gemini APIの応答にコンテンツが含まれていません")
+	if candidate.FinishReason == genai.FinishReasonMaxTokens && candidateText(candidate) == "" {
+		return "", fmt.Errorf("%w。より短い質問を試してください", domain.ErrMaxTokens)
 	}
 
-	// テキスト部分を抽出
-	var result string
-	for _, part := range candidate.Content.Parts {
-		if part.Text != "" {
-			result += part.Text
-		}
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return "", errEmptyResponse
 	}
 
+	// テキスト部分を抽出（コード実行を使用した場合は、実行したコードと実行結果も含める）
+	result := candidateText(candidate)
+
 	log.Printf("Gemini APIから応答を取得: %d文字", len(result))
 	return result, nil
 }
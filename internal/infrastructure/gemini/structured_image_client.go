@@ -17,17 +17,19 @@ func (g *StructuredGeminiClient) GenerateImage(ctx context.Context, request doma
 	if request.Options == (domain.ImageGenerationOptions{}) && g.config != nil {
 		request.Options = g.config.ImageGenerationDefaults()
 	}
-	return g.generateImageWithOptions(ctx, request.Prompt, request.Options)
+	return g.generateImageWithOptions(ctx, request)
 }
 
-// generateImageWithOptions は、オプション付きで画像を生成する内部実装です
-func (g *StructuredGeminiClient) generateImageWithOptions(ctx context.Context, prompt string, options domain.ImageGenerationOptions) (*domain.ImageGenerationResponse, error) {
-	log.Printf("構造化Geminiクライアントで画像生成をリクエスト中: %d文字", len(prompt))
+// generateImageWithOptions は、オプション付きで画像を生成・編集する内部実装です
+func (g *StructuredGeminiClient) generateImageWithOptions(ctx context.Context, request domain.ImageGenerationRequest) (*domain.ImageGenerationResponse, error) {
+	prompt := request.Prompt
+	options := request.Options
+	log.Printf("構造化Geminiクライアントで画像生成をリクエスト中: %d文字（元画像: %d枚）", len(prompt), len(request.SourceImages))
 	log.Printf("プロンプト内容: %s", prompt)
 	log.Printf("オプション: %+v", options)
 
-	// 画像生成用のコンテンツを作成
-	contents := genai.Text(prompt)
+	// 画像生成用のコンテンツを作成（編集モードでは元画像を含める）
+	contents := buildImageContents(request)
 
 	// オプションに基づいて画像生成設定を作成
 	config := g.createImageConfig(options)
@@ -35,13 +37,16 @@ func (g *StructuredGeminiClient) generateImageWithOptions(ctx context.Context, p
 	// モデル名を決定
 	modelName := options.Model
 
-	resp, err := g.client.Models.GenerateContent(ctx, modelName, contents, config)
-	if err != nil {
-		return nil, fmt.Errorf("Gemini APIからの画像生成応答取得に失敗: %w", err)
-	}
+	// 指定枚数分のリクエストを並列に送信
+	return generateImages(ctx, options.ImageCount(), func(ctx context.Context) (*domain.ImageGenerationResponse, error) {
+		resp, err := g.client.Models.GenerateContent(ctx, modelName, contents, config)
+		if err != nil {
+			return nil, classifyAPIError(ctx, err)
+		}
 
-	// 画像生成結果を処理
-	return g.processImageResponse(resp, prompt, modelName)
+		// 画像生成結果を処理
+		return g.processImageResponse(resp, prompt, modelName)
+	})
 }
 
 // createImageConfig は、画像生成設定を作成します
@@ -81,26 +86,22 @@ func (g *StructuredGeminiClient) processImageResponse(resp *genai.GenerateConten
 	candidate := resp.Candidates[0]
 
 	// FinishReasonをチェックして安全フィルターによるブロックを検出
-	if candidate.FinishReason == "SAFETY" {
-		safetyDetails := g.formatSafetyRatings(candidate.SafetyRatings)
-		return nil, fmt.Errorf("Gemini APIの安全フィルターによって画像生成がブロックされました。詳細: %s", safetyDetails)
-	}
-
-	if candidate.FinishReason == "RECITATION" {
-		return nil, fmt.Errorf("Gemini APIが著作権保護された内容を検出しました。著作権で保護されたコンテンツが含まれている可能性があります")
+	if candidate.FinishReason == genai.FinishReasonSafety || candidate.FinishReason == genai.FinishReasonImageSafety {
+		log.Printf("安全フィルターによって画像生成がブロックされました: %s", g.formatSafetyRatings(candidate.SafetyRatings))
+		return nil, safetyBlockedError(candidate.SafetyRatings)
 	}
 
-	if candidate.FinishReason == "MAX_TOKENS" {
-		return nil, fmt.Errorf("Gemini APIの応答が最大トークン数に達しました。より短いプロンプトを試してください")
+	if candidate.FinishReason == genai.FinishReasonRecitation {
+		return nil, fmt.Errorf("%w。著作権で保護されたコンテンツが含まれている可能性があります", domain.ErrRecitation)
 	}
 
-	// Contentがnilの場合のチェック
-	if candidate.Content == nil {
-		return nil, fmt.Errorf("Gemini APIの画像生成応答にContentが含まれていません。FinishReason: %s", candidate.FinishReason)
+	if candidate.FinishReason == genai.FinishReasonMaxTokens {
+		return nil, fmt.Errorf("%w。より短いプロンプトを試してください", domain.ErrMaxTokens)
 	}
 
-	if len(candidate.Content.Parts) == 0 {
-		return nil, fmt.Errorf("Gemini APIの画像生成応答にコンテンツが含まれていません。FinishReason: %s", candidate.FinishReason)
+	// Contentがnil、または空の場合はリトライ対象のエラーを返す
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return nil, fmt.Errorf("%w。FinishReason: %s", errEmptyResponse, candidate.FinishReason)
 	}
 
 	// 画像データを抽出
@@ -129,6 +130,7 @@ func (g *StructuredGeminiClient) processImageResponse(resp *genai.GenerateConten
 		Prompt:      prompt,
 		Model:       modelName,
 		GeneratedAt: time.Now(),
+		Usage:       tokenUsage(resp),
 	}, nil
 }
 
@@ -178,18 +180,7 @@ func (g *StructuredGeminiClient) formatSafetyRatings(ratings []*genai.SafetyRati
 
 // translateSafetyCategory は、SafetyCategoryを日本語に翻訳します
 func (g *StructuredGeminiClient) translateSafetyCategory(category genai.HarmCategory) string {
-	switch category {
-	case genai.HarmCategoryHarassment:
-		return "ハラスメント"
-	case genai.HarmCategoryHateSpeech:
-		return "ヘイトスピーチ"
-	case genai.HarmCategorySexuallyExplicit:
-		return "性的表現"
-	case genai.HarmCategoryDangerousContent:
-		return "危険なコンテンツ"
-	default:
-		return string(category)
-	}
+	return translateSafetyCategory(category)
 }
 
 // translateSafetyProbability は、SafetyProbabilityを日本語に翻訳します
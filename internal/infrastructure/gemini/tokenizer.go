@@ -0,0 +1,135 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"sync"
+	"time"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// countTokensTimeout は、CountTokens APIの1回の呼び出しを待つ最大時間です
+	countTokensTimeout = 5 * time.Second
+	// countTokensBackoff は、CountTokens APIの呼び出しに失敗した後、見積もりのみを使用する期間です
+	countTokensBackoff = time.Minute
+	// tokenCacheCapacity は、数えたトークン数をキャッシュする最大件数です（超えた場合はキャッシュを作り直します）
+	tokenCacheCapacity = 4096
+)
+
+// Tokenizer は、GeminiのCountTokens APIでトークン数を数えるTokenizerです
+// トークン数はギルドのAPIキーのクライアントなど WithCounter で指定したクライアントで数え、
+// 同じテキストはリクエストごとに繰り返し数えるため、結果をクライアントをまたいでキャッシュします
+// クライアントを指定していない場合や、APIを呼び出せなかった場合は、しばらくの間 domain.EstimateTokens の見積もりを返します
+// APIを呼び出さない期間はギルドごとに管理するため、あるギルドのAPIキーが無効でも他のギルドには影響しません
+type Tokenizer struct {
+	countTokens func(ctx context.Context, model, text string) (int, error)
+	guildID     string // APIを呼び出さない期間を管理する単位です
+	state       *tokenizerState
+}
+
+// tokenizerState は、WithCounter で作成したTokenizerの間で共有するキャッシュと、ギルドごとのAPIを呼び出さない期間です
+type tokenizerState struct {
+	now func() time.Time
+
+	mu               sync.Mutex
+	cache            map[[sha256.Size]byte]int
+	unavailableUntil map[string]time.Time
+}
+
+// NewTokenizer は新しいTokenizerインスタンスを作成します
+// トークン数をAPIで数えるには、WithCounter でトークン数を数えるクライアントを指定します
+func NewTokenizer() *Tokenizer {
+	return newTokenizer(nil)
+}
+
+// newTokenizer は、トークン数を数える関数を指定してTokenizerを作成します
+func newTokenizer(countTokens func(ctx context.Context, model, text string) (int, error)) *Tokenizer {
+	return &Tokenizer{
+		countTokens: countTokens,
+		state: &tokenizerState{
+			now:              time.Now,
+			cache:            make(map[[sha256.Size]byte]int),
+			unavailableUntil: make(map[string]time.Time),
+		},
+	}
+}
+
+// WithCounter は、guildID のギルドのAPIキーのGeminiクライアントなど counter でトークン数を数え、キャッシュを共有するTokenizerを返します
+// counter でAPIを呼び出せなかった場合は、そのギルドのみしばらくの間見積もりを使用します
+func (t *Tokenizer) WithCounter(guildID string, counter domain.Tokenizer) domain.Tokenizer {
+	if counter == nil {
+		return t
+	}
+	return &Tokenizer{countTokens: counter.CountTokens, guildID: guildID, state: t.state}
+}
+
+// CountTokens は、model でテキストを送信した場合のトークン数を返します
+func (t *Tokenizer) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	if t.countTokens == nil {
+		return domain.EstimateTokens(text), nil
+	}
+
+	state := t.state
+	key := sha256.Sum256([]byte(model + "\x00" + text))
+	state.mu.Lock()
+	tokens, ok := state.cache[key]
+	until, backingOff := state.unavailableUntil[t.guildID]
+	unavailable := backingOff && state.now().Before(until)
+	if backingOff && !unavailable {
+		delete(state.unavailableUntil, t.guildID)
+	}
+	state.mu.Unlock()
+	if ok {
+		return tokens, nil
+	}
+	if unavailable {
+		return domain.EstimateTokens(text), nil
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, countTokensTimeout)
+	defer cancel()
+
+	tokens, err := t.countTokens(callCtx, model, text)
+	if err != nil {
+		log.Printf("トークン数の取得に失敗したため、%v の間は見積もりを使用します（ギルド: %s、モデル: %s）: %v", countTokensBackoff, t.guildID, model, err)
+		state.mu.Lock()
+		state.unavailableUntil[t.guildID] = state.now().Add(countTokensBackoff)
+		state.mu.Unlock()
+		return domain.EstimateTokens(text), nil
+	}
+
+	state.mu.Lock()
+	if len(state.cache) >= tokenCacheCapacity {
+		state.cache = make(map[[sha256.Size]byte]int)
+	}
+	state.cache[key] = tokens
+	state.mu.Unlock()
+	return tokens, nil
+}
+
+// CountTokens は、model でテキストを送信した場合のトークン数をCountTokens APIで数えます
+func (g *GeminiAPIClient) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	return countTokens(ctx, g.client, model, text)
+}
+
+// CountTokens は、model でテキストを送信した場合のトークン数をCountTokens APIで数えます
+func (g *StructuredGeminiClient) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	return countTokens(ctx, g.client, model, text)
+}
+
+// countTokens は、client のAPIキーでCountTokens APIを呼び出してトークン数を数えます
+func countTokens(ctx context.Context, client *genai.Client, model, text string) (int, error) {
+	resp, err := client.Models.CountTokens(ctx, model, genai.Text(text), nil)
+	if err != nil {
+		return 0, classifyAPIError(ctx, err)
+	}
+	return int(resp.TotalTokens), nil
+}
@@ -0,0 +1,110 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geminibot/internal/domain"
+)
+
+func TestTokenizer_CachesCounts(t *testing.T) {
+	calls := 0
+	tokenizer := newTokenizer(func(ctx context.Context, model, text string) (int, error) {
+		calls++
+		return 7, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		tokens, err := tokenizer.CountTokens(context.Background(), "gemini-2.5-pro", "こんにちは")
+		if err != nil || tokens != 7 {
+			t.Fatalf("CountTokens() = %d, %v, 期待値: 7", tokens, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("同じテキストはキャッシュから返すべきです: APIの呼び出し回数 %d", calls)
+	}
+
+	// モデルが異なる場合は数え直す
+	if _, err := tokenizer.CountTokens(context.Background(), "gemini-2.0-flash", "こんにちは"); err != nil || calls != 2 {
+		t.Errorf("モデルごとにトークン数を数えるべきです: APIの呼び出し回数 %d, エラー: %v", calls, err)
+	}
+}
+
+func TestTokenizer_FallsBackToEstimate(t *testing.T) {
+	calls := 0
+	tokenizer := newTokenizer(func(ctx context.Context, model, text string) (int, error) {
+		calls++
+		return 0, errors.New("unavailable")
+	})
+
+	text := "Hello, world! こんにちは"
+	tokens, err := tokenizer.CountTokens(context.Background(), "gemini-2.5-pro", text)
+	if err != nil {
+		t.Fatalf("APIを呼び出せない場合も見積もりを返すべきです: %v", err)
+	}
+	if want := domain.EstimateTokens(text); tokens != want {
+		t.Errorf("CountTokens() = %d, 期待値: %d", tokens, want)
+	}
+
+	// 失敗した後はしばらくAPIを呼び出さない
+	if _, err := tokenizer.CountTokens(context.Background(), "gemini-2.5-pro", "別のテキスト"); err != nil || calls != 1 {
+		t.Errorf("失敗した直後はAPIを呼び出さずに見積もりを返すべきです: APIの呼び出し回数 %d, エラー: %v", calls, err)
+	}
+}
+
+// countFunc は、関数をdomain.Tokenizerとして使用するためのテスト用の型です
+type countFunc func(ctx context.Context, model, text string) (int, error)
+
+func (f countFunc) CountTokens(ctx context.Context, model, text string) (int, error) {
+	return f(ctx, model, text)
+}
+
+func TestTokenizer_WithCounter(t *testing.T) {
+	calls := 0
+	tokenizer := NewTokenizer().WithCounter("guild-1", countFunc(func(ctx context.Context, model, text string) (int, error) {
+		calls++
+		return 11, nil
+	}))
+
+	tokens, err := tokenizer.CountTokens(context.Background(), "gemini-2.5-pro", "こんにちは")
+	if err != nil || tokens != 11 || calls != 1 {
+		t.Fatalf("指定したクライアントで数えるべきです: CountTokens() = %d, %v, APIの呼び出し回数 %d", tokens, err, calls)
+	}
+
+	// 別のクライアントを指定しても、キャッシュは共有する
+	shared := NewTokenizer()
+	first := shared.WithCounter("guild-1", countFunc(func(ctx context.Context, model, text string) (int, error) { return 3, nil }))
+	second := shared.WithCounter("guild-2", countFunc(func(ctx context.Context, model, text string) (int, error) {
+		t.Error("キャッシュ済みのテキストでAPIを呼び出すべきではありません")
+		return 0, nil
+	}))
+	if _, err := first.CountTokens(context.Background(), "gemini-2.5-pro", "共有"); err != nil {
+		t.Fatalf("CountTokens() error = %v", err)
+	}
+	if tokens, err := second.CountTokens(context.Background(), "gemini-2.5-pro", "共有"); err != nil || tokens != 3 {
+		t.Errorf("CountTokens() = %d, %v, 期待値: 3", tokens, err)
+	}
+}
+
+func TestTokenizer_BacksOffPerGuild(t *testing.T) {
+	shared := NewTokenizer()
+	failing := shared.WithCounter("bad-guild", countFunc(func(ctx context.Context, model, text string) (int, error) {
+		return 0, errors.New("API_KEY_INVALID")
+	}))
+	healthyCalls := 0
+	healthy := shared.WithCounter("good-guild", countFunc(func(ctx context.Context, model, text string) (int, error) {
+		healthyCalls++
+		return 5, nil
+	}))
+
+	if _, err := failing.CountTokens(context.Background(), "gemini-2.5-pro", "失敗"); err != nil {
+		t.Fatalf("APIを呼び出せない場合も見積もりを返すべきです: %v", err)
+	}
+
+	// 他のギルドは、失敗したギルドの影響を受けずにAPIで数える
+	tokens, err := healthy.CountTokens(context.Background(), "gemini-2.5-pro", "成功")
+	if err != nil || tokens != 5 || healthyCalls != 1 {
+		t.Errorf("他のギルドはAPIで数えるべきです: CountTokens() = %d, %v, APIの呼び出し回数 %d", tokens, err, healthyCalls)
+	}
+}
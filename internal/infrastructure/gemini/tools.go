@@ -0,0 +1,118 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+
+	"google.golang.org/genai"
+)
+
+// generateFunc は、コンテンツと生成設定を受け取ってGemini APIに1回リクエストする関数です
+// ストリーミングの場合は、受信した内容を1つのレスポンスにまとめて返します
+type generateFunc func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+
+// generateWithTools は、登録されたツールを関数宣言としてモデルに渡し、モデルが関数の呼び出しを返さなくなるまで
+// ツールの実行と結果の受け渡しを繰り返して、最終的な回答のレスポンスと全リクエストの合計のトークン数を返します
+// maxIterations 回繰り返しても回答しない場合は、関数の呼び出しを禁止して回答を求めます
+// ツールがない場合や maxIterations が0以下の場合は、関数宣言を渡さずに1回だけリクエストします
+func generateWithTools(
+	ctx context.Context,
+	generate generateFunc,
+	contents []*genai.Content,
+	config *genai.GenerateContentConfig,
+	tools *domain.ToolRegistry,
+	maxIterations int,
+) (*genai.GenerateContentResponse, domain.TokenUsage, error) {
+	if tools.IsEmpty() || maxIterations <= 0 {
+		resp, err := generate(ctx, contents, config)
+		return resp, tokenUsage(resp), err
+	}
+
+	// リトライで同じ設定とコンテンツを使い回せるよう、コピーに関数宣言と関数呼び出しのやり取りを加える
+	scoped := *config
+	scoped.Tools = append(append([]*genai.Tool(nil), config.Tools...), &genai.Tool{FunctionDeclarations: functionDeclarations(tools)})
+	config = &scoped
+	contents = append([]*genai.Content(nil), contents...)
+
+	var usage domain.TokenUsage
+	for iteration := 0; ; iteration++ {
+		if iteration == maxIterations {
+			log.Printf("関数呼び出しの最大回数（%d回）に達したため、ツールを使わずに回答を求めます", maxIterations)
+			config.ToolConfig = &genai.ToolConfig{
+				FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeNone},
+			}
+		}
+
+		resp, err := generate(ctx, contents, config)
+		usage = usage.Add(tokenUsage(resp))
+		if err != nil {
+			return nil, usage, err
+		}
+
+		calls := resp.FunctionCalls()
+		if len(calls) == 0 || iteration >= maxIterations {
+			return resp, usage, nil
+		}
+
+		// モデルの関数呼び出し（思考の署名を含む）をそのまま履歴に残し、続けて実行結果を渡す
+		contents = append(contents, resp.Candidates[0].Content, executeFunctionCalls(ctx, tools, calls))
+	}
+}
+
+// maxToolIterations は、リクエストで関数呼び出しを繰り返す最大回数を返します（ツールを使用しないリクエストでは0を返します）
+// Google 検索によるグラウンディングとコード実行は関数呼び出しと併用できないため、これらを使用するリクエストでもツールを使用しません
+func maxToolIterations(geminiConfig *config.GeminiConfig, options application.TextGenerationOptions) int {
+	if options.DisableTools || options.SearchGrounding || options.CodeExecution || geminiConfig == nil {
+		return 0
+	}
+	return geminiConfig.MaxToolIterations
+}
+
+// functionDeclarations は、登録されたツールをGeminiの関数宣言に変換します
+func functionDeclarations(tools *domain.ToolRegistry) []*genai.FunctionDeclaration {
+	var declarations []*genai.FunctionDeclaration
+	for _, tool := range tools.Tools() {
+		declarations = append(declarations, &genai.FunctionDeclaration{
+			Name:                 tool.Name(),
+			Description:          tool.Description(),
+			ParametersJsonSchema: tool.Parameters(),
+		})
+	}
+	return declarations
+}
+
+// executeFunctionCalls は、モデルが呼び出した関数を実行し、結果をモデルに返すコンテンツにまとめます
+// ツールの実行に失敗した場合は、エラーの内容を結果として返し、モデルに回答の仕方を判断させます
+func executeFunctionCalls(ctx context.Context, tools *domain.ToolRegistry, calls []*genai.FunctionCall) *genai.Content {
+	parts := make([]*genai.Part, 0, len(calls))
+	for _, call := range calls {
+		parts = append(parts, &genai.Part{FunctionResponse: &genai.FunctionResponse{
+			ID:       call.ID,
+			Name:     call.Name,
+			Response: executeFunctionCall(ctx, tools, call),
+		}})
+	}
+	return &genai.Content{Role: genai.RoleUser, Parts: parts}
+}
+
+// executeFunctionCall は、1件の関数呼び出しを実行し、モデルに返す結果（成功時は output、失敗時は error）を返します
+func executeFunctionCall(ctx context.Context, tools *domain.ToolRegistry, call *genai.FunctionCall) map[string]any {
+	tool, ok := tools.Get(call.Name)
+	if !ok {
+		log.Printf("不明なツールが呼び出されました: %s", call.Name)
+		return map[string]any{"error": fmt.Sprintf("不明なツールです: %s", call.Name)}
+	}
+
+	log.Printf("ツールを実行中: %s（引数: %v）", call.Name, call.Args)
+	output, err := tool.Execute(ctx, call.Args)
+	if err != nil {
+		log.Printf("ツール %s の実行に失敗: %v", call.Name, err)
+		return map[string]any{"error": err.Error()}
+	}
+	return map[string]any{"output": output}
+}
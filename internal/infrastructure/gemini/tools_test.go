@@ -0,0 +1,155 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// echoTool は、引数をそのまま返すテスト用のツールです
+type echoTool struct {
+	err error
+}
+
+func (t echoTool) Name() string               { return "echo" }
+func (t echoTool) Description() string        { return "引数をそのまま返します" }
+func (t echoTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (t echoTool) Execute(ctx context.Context, args map[string]any) (map[string]any, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return args, nil
+}
+
+func functionCallResponse(name string, args map[string]any) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "call1", Name: name, Args: args}}}},
+		}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 2},
+	}
+}
+
+func finalAnswerResponse(text string) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{{Text: text}}},
+			FinishReason: genai.FinishReasonStop,
+		}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 20, CandidatesTokenCount: 5},
+	}
+}
+
+func TestGenerateWithTools_RunsFunctionCallLoop(t *testing.T) {
+	registry, err := domain.NewToolRegistry(echoTool{})
+	if err != nil {
+		t.Fatalf("ツールの登録に失敗: %v", err)
+	}
+
+	var requests [][]*genai.Content
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		requests = append(requests, contents)
+		if len(config.Tools) != 1 || len(config.Tools[0].FunctionDeclarations) != 1 || config.Tools[0].FunctionDeclarations[0].Name != "echo" {
+			t.Errorf("登録したツールを関数宣言として渡すべきです: %+v", config.Tools)
+		}
+		if len(requests) == 1 {
+			return functionCallResponse("echo", map[string]any{"text": "こんにちは"}), nil
+		}
+		return finalAnswerResponse("ツールは「こんにちは」を返しました"), nil
+	}
+
+	question := []*genai.Content{genai.NewContentFromText("echoして", genai.RoleUser)}
+	config := &genai.GenerateContentConfig{}
+	resp, usage, err := generateWithTools(context.Background(), generate, question, config, registry, 3)
+	if err != nil {
+		t.Fatalf("生成に失敗: %v", err)
+	}
+
+	if got := candidateText(resp.Candidates[0]); got != "ツールは「こんにちは」を返しました" {
+		t.Errorf("最終的な回答を返すべきです: %q", got)
+	}
+	if len(requests) != 2 || len(requests[1]) != 3 {
+		t.Fatalf("関数呼び出しと実行結果を加えて再度リクエストするべきです: %d回", len(requests))
+	}
+	result := requests[1][2].Parts[0].FunctionResponse
+	if result == nil || result.ID != "call1" || result.Name != "echo" {
+		t.Fatalf("関数の実行結果を返すべきです: %+v", requests[1][2].Parts[0])
+	}
+	if output, ok := result.Response["output"].(map[string]any); !ok || output["text"] != "こんにちは" {
+		t.Errorf("ツールの出力を output として返すべきです: %+v", result.Response)
+	}
+	if usage.PromptTokens != 30 || usage.OutputTokens != 7 {
+		t.Errorf("全リクエストのトークン数を合計するべきです: %+v", usage)
+	}
+	if len(question) != 1 || len(config.Tools) != 0 {
+		t.Error("呼び出し元のコンテンツと設定は変更しないべきです")
+	}
+}
+
+func TestGenerateWithTools_StopsAtMaxIterations(t *testing.T) {
+	registry, _ := domain.NewToolRegistry(echoTool{err: errors.New("失敗しました")})
+
+	calls := 0
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		calls++
+		if config.ToolConfig != nil && config.ToolConfig.FunctionCallingConfig.Mode == genai.FunctionCallingConfigModeNone {
+			return finalAnswerResponse("ツールを使わずに回答します"), nil
+		}
+		return functionCallResponse("echo", nil), nil
+	}
+
+	resp, _, err := generateWithTools(context.Background(), generate, nil, &genai.GenerateContentConfig{}, registry, 2)
+	if err != nil {
+		t.Fatalf("生成に失敗: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("最大回数の関数呼び出しの後に、ツールを禁止して回答を求めるべきです: %d回", calls)
+	}
+	if got := candidateText(resp.Candidates[0]); got != "ツールを使わずに回答します" {
+		t.Errorf("最終的な回答を返すべきです: %q", got)
+	}
+}
+
+func TestGenerateWithTools_WithoutTools(t *testing.T) {
+	generate := func(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+		if len(config.Tools) != 0 {
+			t.Errorf("ツールがない場合は関数宣言を渡さないべきです: %+v", config.Tools)
+		}
+		return finalAnswerResponse("回答"), nil
+	}
+
+	for _, registry := range []*domain.ToolRegistry{nil, mustToolRegistry(t, echoTool{})} {
+		// レジストリが空の場合と、最大回数が0の場合
+		iterations := 0
+		if registry == nil {
+			iterations = 5
+		}
+		if _, _, err := generateWithTools(context.Background(), generate, nil, &genai.GenerateContentConfig{}, registry, iterations); err != nil {
+			t.Fatalf("生成に失敗: %v", err)
+		}
+	}
+}
+
+func TestExecuteFunctionCall_Errors(t *testing.T) {
+	registry := mustToolRegistry(t, echoTool{err: errors.New("失敗しました")})
+
+	if got := executeFunctionCall(context.Background(), registry, &genai.FunctionCall{Name: "echo"}); got["error"] != "失敗しました" {
+		t.Errorf("ツールのエラーを error として返すべきです: %+v", got)
+	}
+	if got := executeFunctionCall(context.Background(), registry, &genai.FunctionCall{Name: "unknown"}); got["error"] == nil {
+		t.Errorf("不明なツールはエラーを返すべきです: %+v", got)
+	}
+}
+
+func mustToolRegistry(t *testing.T, tools ...domain.Tool) *domain.ToolRegistry {
+	t.Helper()
+	registry, err := domain.NewToolRegistry(tools...)
+	if err != nil {
+		t.Fatalf("ツールの登録に失敗: %v", err)
+	}
+	return registry
+}
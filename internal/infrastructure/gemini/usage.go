@@ -0,0 +1,22 @@
+package gemini
+
+import (
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+// tokenUsage は、レスポンスの UsageMetadata から入力・出力・思考のトークン数を取り出します
+// ツールの呼び出し結果などの入力も課金対象のため、入力のトークン数に含めます
+func tokenUsage(resp *genai.GenerateContentResponse) domain.TokenUsage {
+	if resp == nil || resp.UsageMetadata == nil {
+		return domain.TokenUsage{}
+	}
+
+	metadata := resp.UsageMetadata
+	return domain.TokenUsage{
+		PromptTokens:   int64(metadata.PromptTokenCount) + int64(metadata.ToolUsePromptTokenCount),
+		OutputTokens:   int64(metadata.CandidatesTokenCount),
+		ThinkingTokens: int64(metadata.ThoughtsTokenCount),
+	}
+}
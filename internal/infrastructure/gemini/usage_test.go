@@ -0,0 +1,30 @@
+package gemini
+
+import (
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"google.golang.org/genai"
+)
+
+func TestTokenUsage(t *testing.T) {
+	resp := &genai.GenerateContentResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:        120,
+			ToolUsePromptTokenCount: 30,
+			CandidatesTokenCount:    80,
+			ThoughtsTokenCount:      40,
+			TotalTokenCount:         270,
+		},
+	}
+
+	want := domain.TokenUsage{PromptTokens: 150, OutputTokens: 80, ThinkingTokens: 40}
+	if got := tokenUsage(resp); got != want {
+		t.Errorf("tokenUsage() = %+v, 期待値: %+v", got, want)
+	}
+
+	if got := tokenUsage(&genai.GenerateContentResponse{}); !got.IsZero() {
+		t.Errorf("UsageMetadata がない場合は0であるべきです: %+v", got)
+	}
+}
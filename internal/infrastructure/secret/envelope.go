@@ -0,0 +1,236 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MasterKeySize は、マスターキーおよびデータ鍵のバイト長です（AES-256）
+const MasterKeySize = 32
+
+// ErrUnknownKeyID は、暗号化に使われたマスターキーがキーリングに存在しない場合のエラーです
+var ErrUnknownKeyID = errors.New("マスターキーが見つかりません")
+
+// MasterKey は、データ鍵を暗号化（ラップ）するためのマスターキーです
+type MasterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewMasterKey は、32バイトの鍵データからMasterKeyを作成します
+// 鍵IDは鍵データのSHA-256ハッシュの先頭から導出されるため、同じ鍵からは常に同じIDになります
+func NewMasterKey(raw []byte) (*MasterKey, error) {
+	if len(raw) != MasterKeySize {
+		return nil, fmt.Errorf("マスターキーは%dバイトである必要があります（実際: %dバイト）", MasterKeySize, len(raw))
+	}
+
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(raw)
+	return &MasterKey{
+		id:   hex.EncodeToString(sum[:8]),
+		aead: aead,
+	}, nil
+}
+
+// ParseMasterKey は、Base64エンコードされた鍵文字列からMasterKeyを作成します
+func ParseMasterKey(encoded string) (*MasterKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("マスターキーのBase64デコードに失敗: %w", err)
+	}
+	return NewMasterKey(raw)
+}
+
+// LoadMasterKey は、環境変数の値または鍵ファイルからマスターキーを読み込みます
+// value が指定されている場合は value を優先します。鍵ファイルにはBase64エンコードした鍵を記載してください
+func LoadMasterKey(value, file string) (*MasterKey, error) {
+	if value != "" {
+		return ParseMasterKey(value)
+	}
+	if file == "" {
+		return nil, fmt.Errorf("マスターキーが指定されていません")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("マスターキーファイルの読み込みに失敗: %w", err)
+	}
+	return ParseMasterKey(string(data))
+}
+
+// GenerateMasterKey は、新しいマスターキーをBase64エンコードした文字列で返します
+func GenerateMasterKey() (string, error) {
+	raw := make([]byte, MasterKeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("マスターキーの生成に失敗: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// ID は、マスターキーの識別子を返します
+func (k *MasterKey) ID() string {
+	return k.id
+}
+
+// Sealed は、エンベロープ暗号化されたデータを表します
+type Sealed struct {
+	KeyID      string // データ鍵の暗号化に使用したマスターキーのID
+	WrappedDEK []byte // マスターキーで暗号化されたデータ鍵
+	Ciphertext []byte // データ鍵で暗号化されたデータ
+}
+
+// Keyring は、暗号化に使うマスターキーと、復号のみに使う旧マスターキーを保持します
+type Keyring struct {
+	primary *MasterKey
+	keys    map[string]*MasterKey
+}
+
+// NewKeyring は新しいKeyringインスタンスを作成します
+// primary は新規の暗号化に使用し、previous は既存データの復号・再ラップにのみ使用します
+func NewKeyring(primary *MasterKey, previous ...*MasterKey) *Keyring {
+	keys := map[string]*MasterKey{primary.ID(): primary}
+	for _, key := range previous {
+		if key != nil {
+			keys[key.ID()] = key
+		}
+	}
+
+	return &Keyring{
+		primary: primary,
+		keys:    keys,
+	}
+}
+
+// PrimaryKeyID は、新規の暗号化に使用するマスターキーのIDを返します
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary.ID()
+}
+
+// Seal は、データ鍵を生成して平文を暗号化し、データ鍵をマスターキーでラップします
+// associatedData は暗号文と紐付ける追加データ（ギルドIDなど）で、復号時にも同じ値が必要です
+func (k *Keyring) Seal(plaintext, associatedData []byte) (Sealed, error) {
+	dek := make([]byte, MasterKeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return Sealed{}, fmt.Errorf("データ鍵の生成に失敗: %w", err)
+	}
+
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	ciphertext, err := seal(dataAEAD, plaintext, associatedData)
+	if err != nil {
+		return Sealed{}, fmt.Errorf("データの暗号化に失敗: %w", err)
+	}
+
+	wrapped, err := seal(k.primary.aead, dek, []byte(k.primary.ID()))
+	if err != nil {
+		return Sealed{}, fmt.Errorf("データ鍵の暗号化に失敗: %w", err)
+	}
+
+	return Sealed{
+		KeyID:      k.primary.ID(),
+		WrappedDEK: wrapped,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+// Open は、エンベロープ暗号化されたデータを復号します
+func (k *Keyring) Open(sealed Sealed, associatedData []byte) ([]byte, error) {
+	dek, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(dataAEAD, sealed.Ciphertext, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("データの復号に失敗: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap は、データ鍵を現在のマスターキーでラップし直します（暗号文自体は変更しません）
+func (k *Keyring) Rewrap(sealed Sealed) (Sealed, error) {
+	if sealed.KeyID == k.primary.ID() {
+		return sealed, nil
+	}
+
+	dek, err := k.unwrap(sealed)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	wrapped, err := seal(k.primary.aead, dek, []byte(k.primary.ID()))
+	if err != nil {
+		return Sealed{}, fmt.Errorf("データ鍵の暗号化に失敗: %w", err)
+	}
+
+	return Sealed{
+		KeyID:      k.primary.ID(),
+		WrappedDEK: wrapped,
+		Ciphertext: sealed.Ciphertext,
+	}, nil
+}
+
+// unwrap は、マスターキーでラップされたデータ鍵を取り出します
+func (k *Keyring) unwrap(sealed Sealed) ([]byte, error) {
+	key, ok := k.keys[sealed.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: 鍵ID %s", ErrUnknownKeyID, sealed.KeyID)
+	}
+
+	dek, err := open(key.aead, sealed.WrappedDEK, []byte(key.ID()))
+	if err != nil {
+		return nil, fmt.Errorf("データ鍵の復号に失敗: %w", err)
+	}
+	return dek, nil
+}
+
+// newAEAD は、AES-GCMの暗号器を作成します
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("AES暗号器の作成に失敗: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("GCMの作成に失敗: %w", err)
+	}
+	return aead, nil
+}
+
+// seal は、ランダムなノンスを先頭に付けて暗号化します
+func seal(aead cipher.AEAD, plaintext, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+// open は、先頭のノンスを取り出して復号します
+func open(aead cipher.AEAD, data, associatedData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("暗号文が短すぎます")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, associatedData)
+}
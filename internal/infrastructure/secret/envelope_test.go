@@ -0,0 +1,117 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestMasterKey(t *testing.T) *MasterKey {
+	t.Helper()
+
+	encoded, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("マスターキーの生成に失敗: %v", err)
+	}
+	key, err := ParseMasterKey(encoded)
+	if err != nil {
+		t.Fatalf("マスターキーの解析に失敗: %v", err)
+	}
+	return key
+}
+
+func TestKeyring_SealAndOpen(t *testing.T) {
+	keyring := NewKeyring(newTestMasterKey(t))
+	plaintext := []byte("AIzaSyTestKey123456")
+
+	sealed, err := keyring.Seal(plaintext, []byte("guild1"))
+	if err != nil {
+		t.Fatalf("暗号化に失敗: %v", err)
+	}
+
+	if sealed.KeyID != keyring.PrimaryKeyID() {
+		t.Errorf("期待される鍵ID: %s, 実際: %s", keyring.PrimaryKeyID(), sealed.KeyID)
+	}
+	if bytes.Contains(sealed.Ciphertext, plaintext) {
+		t.Error("暗号文に平文が含まれています")
+	}
+
+	opened, err := keyring.Open(sealed, []byte("guild1"))
+	if err != nil {
+		t.Fatalf("復号に失敗: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("期待される平文: %s, 実際: %s", plaintext, opened)
+	}
+
+	// 追加データが異なる場合（別ギルドへの付け替え）は復号できない
+	if _, err := keyring.Open(sealed, []byte("guild2")); err == nil {
+		t.Error("異なる追加データで復号できてはいけません")
+	}
+}
+
+func TestKeyring_Rewrap(t *testing.T) {
+	oldKey := newTestMasterKey(t)
+	newKey := newTestMasterKey(t)
+
+	sealed, err := NewKeyring(oldKey).Seal([]byte("secret"), []byte("guild1"))
+	if err != nil {
+		t.Fatalf("暗号化に失敗: %v", err)
+	}
+
+	// 新しいマスターキーだけでは復号できない
+	if _, err := NewKeyring(newKey).Open(sealed, []byte("guild1")); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("ErrUnknownKeyIDが期待されましたが、実際: %v", err)
+	}
+
+	rotation := NewKeyring(newKey, oldKey)
+	rewrapped, err := rotation.Rewrap(sealed)
+	if err != nil {
+		t.Fatalf("再ラップに失敗: %v", err)
+	}
+	if rewrapped.KeyID != newKey.ID() {
+		t.Errorf("再ラップ後の鍵IDは新しいマスターキーである必要があります: %s", rewrapped.KeyID)
+	}
+
+	opened, err := NewKeyring(newKey).Open(rewrapped, []byte("guild1"))
+	if err != nil {
+		t.Fatalf("再ラップ後の復号に失敗: %v", err)
+	}
+	if string(opened) != "secret" {
+		t.Errorf("期待される平文: secret, 実際: %s", opened)
+	}
+}
+
+func TestLoadMasterKey(t *testing.T) {
+	encoded, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("マスターキーの生成に失敗: %v", err)
+	}
+
+	fromValue, err := LoadMasterKey(encoded, "")
+	if err != nil {
+		t.Fatalf("環境変数の値からの読み込みに失敗: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0o600); err != nil {
+		t.Fatalf("鍵ファイルの作成に失敗: %v", err)
+	}
+	fromFile, err := LoadMasterKey("", path)
+	if err != nil {
+		t.Fatalf("鍵ファイルからの読み込みに失敗: %v", err)
+	}
+
+	if fromValue.ID() != fromFile.ID() {
+		t.Errorf("同じ鍵から異なる鍵IDが生成されました: %s, %s", fromValue.ID(), fromFile.ID())
+	}
+
+	if _, err := LoadMasterKey("", ""); err == nil {
+		t.Error("鍵が指定されていない場合はエラーになるべきです")
+	}
+	if _, err := ParseMasterKey("c2hvcnQ="); err == nil {
+		t.Error("32バイト以外の鍵はエラーになるべきです")
+	}
+}
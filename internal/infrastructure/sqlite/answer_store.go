@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// DefaultAnswerRetention は、回答の記録を保持する既定の期間です
+const DefaultAnswerRetention = 30 * 24 * time.Hour
+
+// AnswerStore は、回答の記録を SQLite に永続化する実装です。
+// 元のメンションはJSONとして保存し、保持期間を過ぎた記録は保存時に削除します。
+type AnswerStore struct {
+	db        *DB
+	retention time.Duration
+}
+
+// NewAnswerStore は新しい AnswerStore を作成します。
+// retention が0以下の場合は DefaultAnswerRetention を使用します。
+func NewAnswerStore(db *DB, retention time.Duration) *AnswerStore {
+	if retention <= 0 {
+		retention = DefaultAnswerRetention
+	}
+	return &AnswerStore{
+		db:        db,
+		retention: retention,
+	}
+}
+
+// SaveAnswer は、回答の記録を保存します
+func (s *AnswerStore) SaveAnswer(ctx context.Context, record domain.AnswerRecord) error {
+	// 添付ファイルのデータはリクエストのたびにダウンロードし直すため保存しない
+	mention := record.Mention
+	mention.Attachments = make([]domain.Attachment, len(record.Mention.Attachments))
+	for i, attachment := range record.Mention.Attachments {
+		attachment.Data = nil
+		mention.Attachments[i] = attachment
+	}
+
+	encoded, err := json.Marshal(mention)
+	if err != nil {
+		return fmt.Errorf("メッセージ %s のメンションのエンコードに失敗: %w", record.MessageID, err)
+	}
+
+	createdAt := record.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err = s.db.conn.ExecContext(ctx, `
+		INSERT INTO answer_records (message_id, channel_id, mention, content, truncated, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(message_id) DO UPDATE SET
+			channel_id = excluded.channel_id,
+			mention    = excluded.mention,
+			content    = excluded.content,
+			truncated  = excluded.truncated,
+			created_at = excluded.created_at`,
+		record.MessageID, record.ChannelID, string(encoded), record.Content, record.Truncated, createdAt)
+	if err != nil {
+		return fmt.Errorf("メッセージ %s の回答の保存に失敗: %w", record.MessageID, err)
+	}
+
+	if _, err := s.db.conn.ExecContext(ctx, `DELETE FROM answer_records WHERE created_at < ?`, time.Now().Add(-s.retention)); err != nil {
+		return fmt.Errorf("保持期間を過ぎた回答の削除に失敗: %w", err)
+	}
+	return nil
+}
+
+// GetAnswer は、指定されたメッセージIDの回答の記録を取得します
+func (s *AnswerStore) GetAnswer(ctx context.Context, messageID string) (domain.AnswerRecord, error) {
+	record := domain.AnswerRecord{MessageID: messageID}
+	var encoded string
+
+	err := s.db.conn.QueryRowContext(ctx, `
+		SELECT channel_id, mention, content, truncated, created_at
+		FROM answer_records WHERE message_id = ?`, messageID).
+		Scan(&record.ChannelID, &encoded, &record.Content, &record.Truncated, &record.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.AnswerRecord{}, domain.ErrAnswerNotFound
+	}
+	if err != nil {
+		return domain.AnswerRecord{}, fmt.Errorf("メッセージ %s の回答の取得に失敗: %w", messageID, err)
+	}
+
+	if err := json.Unmarshal([]byte(encoded), &record.Mention); err != nil {
+		return domain.AnswerRecord{}, fmt.Errorf("メッセージ %s のメンションのデコードに失敗: %w", messageID, err)
+	}
+	return record, nil
+}
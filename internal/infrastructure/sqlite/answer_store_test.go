@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+func TestAnswerStore_PersistsAcrossReopen(t *testing.T) {
+	db, path := openTestDB(t)
+	ctx := context.Background()
+
+	record := domain.AnswerRecord{
+		MessageID: "answer1",
+		ChannelID: "thread1",
+		Mention: domain.BotMention{
+			ChannelID: "channel1",
+			GuildID:   "guild1",
+			User:      domain.User{ID: "user1", DisplayName: "テストユーザー"},
+			Content:   "質問",
+			MessageID: "mention1",
+			Attachments: []domain.Attachment{
+				{Filename: "a.png", URL: "https://cdn.example.com/a.png", MimeType: "image/png", Data: []byte("png")},
+			},
+		},
+		Content:   "途中までの回答",
+		Truncated: true,
+	}
+	if err := NewAnswerStore(db, 0).SaveAnswer(ctx, record); err != nil {
+		t.Fatalf("回答の保存に失敗: %v", err)
+	}
+	db.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("データベースの再オープンに失敗: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := NewAnswerStore(reopened, 0).GetAnswer(ctx, "answer1")
+	if err != nil {
+		t.Fatalf("回答の取得に失敗: %v", err)
+	}
+	if got.ChannelID != "thread1" || got.Content != "途中までの回答" || !got.Truncated {
+		t.Errorf("保存した記録と一致しません: %+v", got)
+	}
+	if got.Mention.MessageID != "mention1" || got.Mention.User.ID != "user1" || got.Mention.GuildID != "guild1" {
+		t.Errorf("元のメンションが復元されていません: %+v", got.Mention)
+	}
+	if len(got.Mention.Attachments) != 1 || got.Mention.Attachments[0].URL != "https://cdn.example.com/a.png" {
+		t.Fatalf("添付ファイルの情報が復元されていません: %+v", got.Mention.Attachments)
+	}
+	if got.Mention.Attachments[0].Data != nil {
+		t.Errorf("添付ファイルのデータは保存されるべきではありません")
+	}
+}
+
+func TestAnswerStore_NotFoundAndRetention(t *testing.T) {
+	db, _ := openTestDB(t)
+	store := NewAnswerStore(db, time.Hour)
+	ctx := context.Background()
+
+	if _, err := store.GetAnswer(ctx, "unknown"); !errors.Is(err, domain.ErrAnswerNotFound) {
+		t.Errorf("未保存のメッセージは ErrAnswerNotFound を返すべきです: %v", err)
+	}
+
+	if err := store.SaveAnswer(ctx, domain.AnswerRecord{MessageID: "old", CreatedAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("回答の保存に失敗: %v", err)
+	}
+	if err := store.SaveAnswer(ctx, domain.AnswerRecord{MessageID: "new"}); err != nil {
+		t.Fatalf("回答の保存に失敗: %v", err)
+	}
+
+	if _, err := store.GetAnswer(ctx, "old"); !errors.Is(err, domain.ErrAnswerNotFound) {
+		t.Errorf("保持期間を過ぎた記録は削除されるべきです: %v", err)
+	}
+	if _, err := store.GetAnswer(ctx, "new"); err != nil {
+		t.Errorf("保持期間内の記録は取得できるべきです: %v", err)
+	}
+}
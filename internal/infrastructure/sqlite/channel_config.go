@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// ChannelConfigStore は、チャンネル設定を SQLite に永続化する実装です。
+// 画像生成・グラウンディング・コード実行の可否は未設定（ギルド・全体の既定を継承）を表すため NULL を許可して保存します。
+type ChannelConfigStore struct {
+	db *DB
+}
+
+// NewChannelConfigStore は新しい ChannelConfigStore を作成します
+func NewChannelConfigStore(db *DB) *ChannelConfigStore {
+	return &ChannelConfigStore{db: db}
+}
+
+// GetChannelConfig は、指定されたチャンネルの設定を取得します
+func (s *ChannelConfigStore) GetChannelConfig(ctx context.Context, channelID string) (domain.ChannelConfig, error) {
+	config := domain.ChannelConfig{ChannelID: channelID}
+	var imageGeneration, searchGrounding, codeExecution sql.NullBool
+	var replyStyle string
+
+	err := s.db.conn.QueryRowContext(ctx, `
+		SELECT guild_id, model, system_prompt, history_length, image_generation, reply_style, search_grounding, code_execution, updated_by, updated_at
+		FROM channel_configs WHERE channel_id = ?`, channelID).
+		Scan(&config.GuildID, &config.Model, &config.SystemPrompt, &config.HistoryLength, &imageGeneration, &replyStyle, &searchGrounding, &codeExecution, &config.UpdatedBy, &config.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return config, nil
+	}
+	if err != nil {
+		return domain.ChannelConfig{}, fmt.Errorf("チャンネル %s の設定の取得に失敗: %w", channelID, err)
+	}
+
+	if imageGeneration.Valid {
+		enabled := imageGeneration.Bool
+		config.ImageGeneration = &enabled
+	}
+	if searchGrounding.Valid {
+		enabled := searchGrounding.Bool
+		config.SearchGrounding = &enabled
+	}
+	if codeExecution.Valid {
+		enabled := codeExecution.Bool
+		config.CodeExecution = &enabled
+	}
+	config.ReplyStyle = domain.ReplyStyle(replyStyle)
+	return config, nil
+}
+
+// SaveChannelConfig は、チャンネルの設定を保存します
+func (s *ChannelConfigStore) SaveChannelConfig(ctx context.Context, config domain.ChannelConfig) error {
+	var imageGeneration sql.NullBool
+	if config.ImageGeneration != nil {
+		imageGeneration = sql.NullBool{Bool: *config.ImageGeneration, Valid: true}
+	}
+	var searchGrounding sql.NullBool
+	if config.SearchGrounding != nil {
+		searchGrounding = sql.NullBool{Bool: *config.SearchGrounding, Valid: true}
+	}
+	var codeExecution sql.NullBool
+	if config.CodeExecution != nil {
+		codeExecution = sql.NullBool{Bool: *config.CodeExecution, Valid: true}
+	}
+
+	updatedAt := config.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+
+	_, err := s.db.conn.ExecContext(ctx, `
+		INSERT INTO channel_configs (channel_id, guild_id, model, system_prompt, history_length, image_generation, reply_style, search_grounding, code_execution, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET
+			guild_id         = excluded.guild_id,
+			model            = excluded.model,
+			system_prompt    = excluded.system_prompt,
+			history_length   = excluded.history_length,
+			image_generation = excluded.image_generation,
+			reply_style      = excluded.reply_style,
+			search_grounding = excluded.search_grounding,
+			code_execution   = excluded.code_execution,
+			updated_by       = excluded.updated_by,
+			updated_at       = excluded.updated_at`,
+		config.ChannelID, config.GuildID, config.Model, config.SystemPrompt, config.HistoryLength,
+		imageGeneration, string(config.ReplyStyle), searchGrounding, codeExecution, config.UpdatedBy, updatedAt)
+	if err != nil {
+		return fmt.Errorf("チャンネル %s の設定の保存に失敗: %w", config.ChannelID, err)
+	}
+	return nil
+}
+
+// DeleteChannelConfig は、指定されたチャンネルの設定を削除します
+func (s *ChannelConfigStore) DeleteChannelConfig(ctx context.Context, channelID string) error {
+	if _, err := s.db.conn.ExecContext(ctx, `DELETE FROM channel_configs WHERE channel_id = ?`, channelID); err != nil {
+		return fmt.Errorf("チャンネル %s の設定の削除に失敗: %w", channelID, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"geminibot/internal/domain"
+)
+
+func TestChannelConfigStore_Lifecycle(t *testing.T) {
+	db, path := openTestDB(t)
+	ctx := context.Background()
+
+	empty, err := NewChannelConfigStore(db).GetChannelConfig(ctx, "channel1")
+	if err != nil {
+		t.Fatalf("未設定のチャンネルの取得に失敗: %v", err)
+	}
+	if !empty.IsEmpty() {
+		t.Errorf("未設定のチャンネルは空の設定を返すべきです: %+v", empty)
+	}
+
+	disabled, enabled := false, true
+	config := domain.ChannelConfig{
+		ChannelID:       "channel1",
+		GuildID:         "guild1",
+		Model:           "gemini-2.5-pro",
+		HistoryLength:   50,
+		ImageGeneration: &disabled,
+		ReplyStyle:      domain.ReplyStyleReply,
+		SearchGrounding: &enabled,
+		CodeExecution:   &enabled,
+		UpdatedBy:       "admin",
+	}
+	if err := NewChannelConfigStore(db).SaveChannelConfig(ctx, config); err != nil {
+		t.Fatalf("チャンネル設定の保存に失敗: %v", err)
+	}
+	db.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("データベースの再オープンに失敗: %v", err)
+	}
+	defer reopened.Close()
+	store := NewChannelConfigStore(reopened)
+
+	got, err := store.GetChannelConfig(ctx, "channel1")
+	if err != nil {
+		t.Fatalf("チャンネル設定の取得に失敗: %v", err)
+	}
+	if got.GuildID != "guild1" || got.Model != "gemini-2.5-pro" || got.HistoryLength != 50 || got.ReplyStyle != domain.ReplyStyleReply {
+		t.Errorf("保存した設定と一致しません: %+v", got)
+	}
+	if got.SystemPrompt != "" {
+		t.Errorf("未設定のシステムプロンプトは空であるべきです: %q", got.SystemPrompt)
+	}
+	if got.ImageGeneration == nil || *got.ImageGeneration {
+		t.Errorf("画像生成の無効化が復元されていません: %v", got.ImageGeneration)
+	}
+	if got.SearchGrounding == nil || !*got.SearchGrounding {
+		t.Errorf("グラウンディングの有効化が復元されていません: %v", got.SearchGrounding)
+	}
+	if got.CodeExecution == nil || !*got.CodeExecution {
+		t.Errorf("コード実行の有効化が復元されていません: %v", got.CodeExecution)
+	}
+
+	// 画像生成の可否を未設定に戻すと NULL として保存される
+	got.ImageGeneration = nil
+	if err := store.SaveChannelConfig(ctx, got); err != nil {
+		t.Fatalf("チャンネル設定の更新に失敗: %v", err)
+	}
+	updated, err := store.GetChannelConfig(ctx, "channel1")
+	if err != nil {
+		t.Fatalf("チャンネル設定の取得に失敗: %v", err)
+	}
+	if updated.ImageGeneration != nil {
+		t.Errorf("画像生成の可否は未設定であるべきです: %v", *updated.ImageGeneration)
+	}
+
+	if err := store.DeleteChannelConfig(ctx, "channel1"); err != nil {
+		t.Fatalf("チャンネル設定の削除に失敗: %v", err)
+	}
+	deleted, err := store.GetChannelConfig(ctx, "channel1")
+	if err != nil {
+		t.Fatalf("削除後のチャンネル設定の取得に失敗: %v", err)
+	}
+	if !deleted.IsEmpty() {
+		t.Errorf("削除後は空の設定を返すべきです: %+v", deleted)
+	}
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"geminibot/internal/domain"
+)
+
+// ConversationSummaryStore は、チャンネルまたはスレッドごとの会話の要約を SQLite に永続化する実装です。
+type ConversationSummaryStore struct {
+	db *DB
+}
+
+// NewConversationSummaryStore は新しい ConversationSummaryStore を作成します
+func NewConversationSummaryStore(db *DB) *ConversationSummaryStore {
+	return &ConversationSummaryStore{db: db}
+}
+
+// GetConversationSummary は、チャンネルの会話の要約を取得します（要約がない場合は空の要約を返します）
+func (s *ConversationSummaryStore) GetConversationSummary(ctx context.Context, channelID string) (domain.ConversationSummary, error) {
+	summary := domain.ConversationSummary{ChannelID: channelID}
+	err := s.db.conn.QueryRowContext(ctx, `
+		SELECT content, covered_until, updated_at FROM conversation_summaries WHERE channel_id = ?`, channelID).
+		Scan(&summary.Content, &summary.CoveredUntil, &summary.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ConversationSummary{ChannelID: channelID}, nil
+	}
+	if err != nil {
+		return domain.ConversationSummary{}, fmt.Errorf("チャンネル %s の会話の要約の取得に失敗: %w", channelID, err)
+	}
+	return summary, nil
+}
+
+// SaveConversationSummary は、チャンネルの会話の要約を保存します
+func (s *ConversationSummaryStore) SaveConversationSummary(ctx context.Context, summary domain.ConversationSummary) error {
+	_, err := s.db.conn.ExecContext(ctx, `
+		INSERT INTO conversation_summaries (channel_id, content, covered_until, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET
+			content       = excluded.content,
+			covered_until = excluded.covered_until,
+			updated_at    = excluded.updated_at`,
+		summary.ChannelID, summary.Content, summary.CoveredUntil.UTC(), summary.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("チャンネル %s の会話の要約の保存に失敗: %w", summary.ChannelID, err)
+	}
+	return nil
+}
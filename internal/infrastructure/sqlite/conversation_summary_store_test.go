@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+func TestConversationSummaryStore_SaveAndGet(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+	store := NewConversationSummaryStore(db)
+
+	summary, err := store.GetConversationSummary(ctx, "thread1")
+	if err != nil {
+		t.Fatalf("会話の要約の取得に失敗: %v", err)
+	}
+	if !summary.IsEmpty() || summary.ChannelID != "thread1" {
+		t.Errorf("要約がない場合は空の要約を返すべきです: %+v", summary)
+	}
+
+	coveredUntil := time.Now().UTC().Truncate(time.Second)
+	for _, content := range []string{"最初の要約", "更新した要約"} {
+		if err := store.SaveConversationSummary(ctx, domain.ConversationSummary{
+			ChannelID:    "thread1",
+			Content:      content,
+			CoveredUntil: coveredUntil,
+			UpdatedAt:    coveredUntil,
+		}); err != nil {
+			t.Fatalf("会話の要約の保存に失敗: %v", err)
+		}
+	}
+
+	summary, err = store.GetConversationSummary(ctx, "thread1")
+	if err != nil {
+		t.Fatalf("会話の要約の取得に失敗: %v", err)
+	}
+	if summary.Content != "更新した要約" || !summary.CoveredUntil.Equal(coveredUntil) {
+		t.Errorf("会話の要約 = %+v, 期待値: 更新した要約（%v まで）", summary, coveredUntil)
+	}
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	// SQLiteドライバ（CGO不要の純Go実装）
+	_ "modernc.org/sqlite"
+)
+
+// DB は、SQLiteデータベースへの接続を保持します
+type DB struct {
+	conn *sql.DB
+}
+
+// Open は、指定されたパスのSQLiteデータベースを開き、スキーママイグレーションを適用します
+// path に ":memory:" を指定するとインメモリデータベースを使用します
+func Open(path string) (*DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("データベースのパスが指定されていません")
+	}
+
+	if path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return nil, fmt.Errorf("データベースディレクトリの作成に失敗: %w", err)
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", path)
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("データベースのオープンに失敗: %w", err)
+	}
+
+	// SQLiteは単一ライターのため、接続を1本に制限して書き込み競合を避ける
+	conn.SetMaxOpenConns(1)
+
+	db := &DB{conn: conn}
+	if err := db.migrate(context.Background()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Close は、データベース接続を閉じます
+func (d *DB) Close() error {
+	return d.conn.Close()
+}
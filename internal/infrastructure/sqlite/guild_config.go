@@ -0,0 +1,401 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/secret"
+)
+
+// GuildConfigManager は、ギルド別 API キー／モデル設定を SQLite に永続化する実装です。
+// APIキーはマスターキーによるエンベロープ暗号化（AES-GCM）を行ってから保存します。
+type GuildConfigManager struct {
+	db               *DB
+	keyring          *secret.Keyring
+	defaultTextModel string
+}
+
+// NewGuildConfigManager は新しい GuildConfigManager を作成します。
+// keyring はAPIキーの暗号化・復号に使用します。
+// defaultTextModel はギルド未登録時やモデル未設定時に使う既定のテキスト生成モデル名です。
+func NewGuildConfigManager(db *DB, keyring *secret.Keyring, defaultTextModel string) *GuildConfigManager {
+	return &GuildConfigManager{
+		db:               db,
+		keyring:          keyring,
+		defaultTextModel: defaultTextModel,
+	}
+}
+
+// guildConfigRow は、guild_configs テーブルの1行を表します
+type guildConfigRow struct {
+	config      domain.GuildConfig
+	legacyKey   string // 暗号化導入前に平文で保存されたAPIキー
+	sealed      secret.Sealed
+	fingerprint string
+}
+
+// hasAPIKey は、暗号化済みまたは移行前のAPIキーが保存されているかを返します
+func (r *guildConfigRow) hasAPIKey() bool {
+	return r.sealed.KeyID != "" || r.legacyKey != ""
+}
+
+// SetAPIKey は、指定されたギルドのAPIキーを暗号化して設定します
+func (r *GuildConfigManager) SetAPIKey(ctx context.Context, guildID, apiKey, setBy string) error {
+	sealed, err := r.keyring.Seal([]byte(apiKey), []byte(guildID))
+	if err != nil {
+		return fmt.Errorf("ギルド %s のAPIキーの暗号化に失敗: %w", guildID, err)
+	}
+
+	// 既存の設定がある場合は、モデル設定を保持（未設定のモデルは読み出し時に既定値で補う）
+	_, err = r.db.conn.ExecContext(ctx, `
+		INSERT INTO guild_configs (guild_id, api_key, api_key_ciphertext, api_key_dek, key_id, api_key_fingerprint, set_by, set_at)
+		VALUES (?, '', ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET
+			api_key             = '',
+			api_key_ciphertext  = excluded.api_key_ciphertext,
+			api_key_dek         = excluded.api_key_dek,
+			key_id              = excluded.key_id,
+			api_key_fingerprint = excluded.api_key_fingerprint,
+			set_by              = excluded.set_by,
+			set_at              = excluded.set_at`,
+		guildID, sealed.Ciphertext, sealed.WrappedDEK, sealed.KeyID, domain.FingerprintAPIKey(apiKey), setBy, time.Now())
+	if err != nil {
+		return fmt.Errorf("ギルド %s のAPIキーの保存に失敗: %w", guildID, err)
+	}
+	return nil
+}
+
+// GetAPIKey は、指定されたギルドのAPIキーを復号して取得します
+func (r *GuildConfigManager) GetAPIKey(ctx context.Context, guildID string) (string, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	if row == nil || !row.hasAPIKey() {
+		return "", fmt.Errorf("ギルド %s のAPIキーが設定されていません", guildID)
+	}
+
+	if row.sealed.KeyID == "" {
+		return row.legacyKey, nil
+	}
+
+	apiKey, err := r.keyring.Open(row.sealed, []byte(guildID))
+	if err != nil {
+		return "", fmt.Errorf("ギルド %s のAPIキーの復号に失敗: %w", guildID, err)
+	}
+	return string(apiKey), nil
+}
+
+// DeleteAPIKey は、指定されたギルドのAPIキーを削除します（モデル設定は保持されます）
+func (r *GuildConfigManager) DeleteAPIKey(ctx context.Context, guildID string) error {
+	result, err := r.db.conn.ExecContext(ctx, `
+		UPDATE guild_configs SET
+			api_key = '', api_key_ciphertext = NULL, api_key_dek = NULL, key_id = '', api_key_fingerprint = '',
+			set_by = '', set_at = NULL
+		WHERE guild_id = ? AND (api_key <> '' OR key_id <> '')`, guildID)
+	if err != nil {
+		return fmt.Errorf("ギルド %s のAPIキーの削除に失敗: %w", guildID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ギルド %s のAPIキーの削除結果の取得に失敗: %w", guildID, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("ギルド %s のAPIキーが設定されていません", guildID)
+	}
+
+	return nil
+}
+
+// HasAPIKey は、指定されたギルドにAPIキーが設定されているかを確認します
+func (r *GuildConfigManager) HasAPIKey(ctx context.Context, guildID string) (bool, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return false, err
+	}
+	return row != nil && row.hasAPIKey(), nil
+}
+
+// GetGuildAPIKeyInfo は、指定されたギルドのAPIキー情報を取得します（APIキーは含まれません）
+// APIキーの代わりに、マスクしたフィンガープリントを返します
+func (r *GuildConfigManager) GetGuildAPIKeyInfo(ctx context.Context, guildID string) (domain.GuildConfig, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return domain.GuildConfig{}, err
+	}
+	if row == nil || !row.hasAPIKey() {
+		return domain.GuildConfig{}, fmt.Errorf("ギルド %s のAPIキーが設定されていません", guildID)
+	}
+
+	info := row.config
+	info.APIKeyFingerprint = row.fingerprint
+	if row.sealed.KeyID == "" {
+		info.APIKeyFingerprint = domain.FingerprintAPIKey(row.legacyKey)
+	}
+	return info, nil
+}
+
+// SetGuildModel は、指定されたギルドのAIモデルを設定します
+func (r *GuildConfigManager) SetGuildModel(ctx context.Context, guildID, model string) error {
+	// 既存の設定がある場合は更新、ない場合は新規作成（APIキーは空文字）
+	_, err := r.db.conn.ExecContext(ctx, `
+		INSERT INTO guild_configs (guild_id, model) VALUES (?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET model = excluded.model`,
+		guildID, model)
+	if err != nil {
+		return fmt.Errorf("ギルド %s のモデル設定の保存に失敗: %w", guildID, err)
+	}
+	return nil
+}
+
+// GetGuildModel は、指定されたギルドのAIモデルを取得します
+func (r *GuildConfigManager) GetGuildModel(ctx context.Context, guildID string) (string, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	if row == nil {
+		return r.defaultTextModel, nil
+	}
+
+	return row.config.Model, nil
+}
+
+// SetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを設定します
+func (r *GuildConfigManager) SetGuildSystemPrompt(ctx context.Context, guildID, prompt string) error {
+	// 既存の設定がある場合は更新、ない場合は新規作成（APIキーは空文字）
+	_, err := r.db.conn.ExecContext(ctx, `
+		INSERT INTO guild_configs (guild_id, system_prompt) VALUES (?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET system_prompt = excluded.system_prompt`,
+		guildID, prompt)
+	if err != nil {
+		return fmt.Errorf("ギルド %s のシステムプロンプトの保存に失敗: %w", guildID, err)
+	}
+	return nil
+}
+
+// GetGuildSystemPrompt は、指定されたギルドのシステムプロンプトを取得します
+func (r *GuildConfigManager) GetGuildSystemPrompt(ctx context.Context, guildID string) (string, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	if row == nil {
+		return "", nil
+	}
+
+	return row.config.SystemPrompt, nil
+}
+
+// SetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを設定します
+func (r *GuildConfigManager) SetGuildSearchGrounding(ctx context.Context, guildID string, enabled bool) error {
+	// 既存の設定がある場合は更新、ない場合は新規作成（APIキーは空文字）
+	_, err := r.db.conn.ExecContext(ctx, `
+		INSERT INTO guild_configs (guild_id, search_grounding) VALUES (?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET search_grounding = excluded.search_grounding`,
+		guildID, enabled)
+	if err != nil {
+		return fmt.Errorf("ギルド %s のグラウンディング設定の保存に失敗: %w", guildID, err)
+	}
+	return nil
+}
+
+// GetGuildSearchGrounding は、指定されたギルドでGoogle 検索によるグラウンディングを使用するかどうかを取得します
+func (r *GuildConfigManager) GetGuildSearchGrounding(ctx context.Context, guildID string) (bool, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return false, err
+	}
+	if row == nil {
+		return false, nil
+	}
+
+	return row.config.SearchGrounding, nil
+}
+
+// SetGuildThinking は、指定されたギルドの思考の設定を保存します
+func (r *GuildConfigManager) SetGuildThinking(ctx context.Context, guildID string, thinking domain.ThinkingSettings) error {
+	var budget sql.NullInt64
+	if thinking.Budget != nil {
+		budget = sql.NullInt64{Int64: int64(*thinking.Budget), Valid: true}
+	}
+
+	// 既存の設定がある場合は更新、ない場合は新規作成（APIキーは空文字）
+	_, err := r.db.conn.ExecContext(ctx, `
+		INSERT INTO guild_configs (guild_id, thinking_budget, include_thoughts) VALUES (?, ?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET thinking_budget = excluded.thinking_budget, include_thoughts = excluded.include_thoughts`,
+		guildID, budget, thinking.IncludeThoughts)
+	if err != nil {
+		return fmt.Errorf("ギルド %s の思考の設定の保存に失敗: %w", guildID, err)
+	}
+	return nil
+}
+
+// GetGuildThinking は、指定されたギルドの思考の設定を取得します
+func (r *GuildConfigManager) GetGuildThinking(ctx context.Context, guildID string) (domain.ThinkingSettings, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return domain.ThinkingSettings{}, err
+	}
+	if row == nil {
+		return domain.ThinkingSettings{}, nil
+	}
+
+	return row.config.Thinking, nil
+}
+
+// SetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを保存します（空の場合は全体の既定に戻します）
+func (r *GuildConfigManager) SetGuildFallbackModels(ctx context.Context, guildID string, models []string) error {
+	// 既存の設定がある場合は更新、ない場合は新規作成（APIキーは空文字）
+	_, err := r.db.conn.ExecContext(ctx, `
+		INSERT INTO guild_configs (guild_id, fallback_models) VALUES (?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET fallback_models = excluded.fallback_models`,
+		guildID, strings.Join(models, ","))
+	if err != nil {
+		return fmt.Errorf("ギルド %s のフォールバックチェーンの保存に失敗: %w", guildID, err)
+	}
+	return nil
+}
+
+// GetGuildFallbackModels は、指定されたギルドのフォールバックチェーンを取得します
+func (r *GuildConfigManager) GetGuildFallbackModels(ctx context.Context, guildID string) ([]string, error) {
+	row, err := r.find(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, nil
+	}
+
+	return row.config.FallbackModels, nil
+}
+
+// EncryptLegacyAPIKeys は、暗号化導入前に平文で保存されたAPIキーを暗号化し、暗号化した件数を返します
+func (r *GuildConfigManager) EncryptLegacyAPIKeys(ctx context.Context) (int, error) {
+	return r.reencrypt(ctx, false)
+}
+
+// RotateMasterKey は、全てのAPIキーのデータ鍵を現在のマスターキーでラップし直し、更新した件数を返します
+// キーリングには、既存データの暗号化に使われた旧マスターキーを含めておく必要があります
+// 平文で保存されたAPIキーが残っている場合は、併せて暗号化します
+func (r *GuildConfigManager) RotateMasterKey(ctx context.Context) (int, error) {
+	return r.reencrypt(ctx, true)
+}
+
+// reencrypt は、平文のAPIキーの暗号化と、必要に応じて旧マスターキーで暗号化されたデータ鍵の再ラップを行います
+func (r *GuildConfigManager) reencrypt(ctx context.Context, rewrap bool) (int, error) {
+	rows, err := r.db.conn.QueryContext(ctx, `
+		SELECT guild_id, api_key, api_key_ciphertext, api_key_dek, key_id
+		FROM guild_configs WHERE api_key <> '' OR key_id <> ''`)
+	if err != nil {
+		return 0, fmt.Errorf("APIキーの一覧取得に失敗: %w", err)
+	}
+
+	// 接続数を1に制限しているため、更新前に全件を読み切る
+	var targets []guildConfigRow
+	for rows.Next() {
+		var row guildConfigRow
+		if err := rows.Scan(&row.config.GuildID, &row.legacyKey, &row.sealed.Ciphertext, &row.sealed.WrappedDEK, &row.sealed.KeyID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("APIキーの読み込みに失敗: %w", err)
+		}
+		targets = append(targets, row)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("APIキーの読み込みに失敗: %w", err)
+	}
+	rows.Close()
+
+	tx, err := r.db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("トランザクションの開始に失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, row := range targets {
+		guildID := row.config.GuildID
+
+		var (
+			sealed      secret.Sealed
+			fingerprint string
+		)
+		switch {
+		case row.sealed.KeyID == "":
+			sealed, err = r.keyring.Seal([]byte(row.legacyKey), []byte(guildID))
+			fingerprint = domain.FingerprintAPIKey(row.legacyKey)
+		case rewrap && row.sealed.KeyID != r.keyring.PrimaryKeyID():
+			sealed, err = r.keyring.Rewrap(row.sealed)
+		default:
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("ギルド %s のAPIキーの再暗号化に失敗: %w", guildID, err)
+		}
+
+		if fingerprint != "" {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE guild_configs SET api_key = '', api_key_ciphertext = ?, api_key_dek = ?, key_id = ?, api_key_fingerprint = ?
+				WHERE guild_id = ?`,
+				sealed.Ciphertext, sealed.WrappedDEK, sealed.KeyID, fingerprint, guildID)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE guild_configs SET api_key_dek = ?, key_id = ? WHERE guild_id = ?`,
+				sealed.WrappedDEK, sealed.KeyID, guildID)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("ギルド %s のAPIキーの更新に失敗: %w", guildID, err)
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("再暗号化のコミットに失敗: %w", err)
+	}
+	return updated, nil
+}
+
+// find は、指定されたギルドの設定を取得します。未登録の場合は nil を返します
+func (r *GuildConfigManager) find(ctx context.Context, guildID string) (*guildConfigRow, error) {
+	var (
+		row            guildConfigRow
+		setAt          sql.NullTime
+		thinkingBudget sql.NullInt64
+		fallbackModels string
+	)
+
+	err := r.db.conn.QueryRowContext(ctx, `
+		SELECT guild_id, api_key, api_key_ciphertext, api_key_dek, key_id, api_key_fingerprint, set_by, set_at, model, system_prompt, search_grounding,
+			thinking_budget, include_thoughts, fallback_models
+		FROM guild_configs WHERE guild_id = ?`, guildID).
+		Scan(&row.config.GuildID, &row.legacyKey, &row.sealed.Ciphertext, &row.sealed.WrappedDEK, &row.sealed.KeyID,
+			&row.fingerprint, &row.config.SetBy, &setAt, &row.config.Model, &row.config.SystemPrompt, &row.config.SearchGrounding,
+			&thinkingBudget, &row.config.Thinking.IncludeThoughts, &fallbackModels)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ギルド %s の設定の取得に失敗: %w", guildID, err)
+	}
+
+	if setAt.Valid {
+		row.config.SetAt = setAt.Time
+	}
+	if row.config.Model == "" {
+		row.config.Model = r.defaultTextModel
+	}
+	if thinkingBudget.Valid {
+		budget := int(thinkingBudget.Int64)
+		row.config.Thinking.Budget = &budget
+	}
+	row.config.FallbackModels = domain.ParseModelList(fallbackModels)
+
+	return &row, nil
+}
@@ -0,0 +1,374 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/secret"
+)
+
+// openTestDB は、テスト用の一時ディレクトリにデータベースを作成します
+func openTestDB(t *testing.T) (*DB, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "bot.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("データベースのオープンに失敗: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, path
+}
+
+// newTestMasterKey は、テスト用のマスターキーを生成します
+func newTestMasterKey(t *testing.T) *secret.MasterKey {
+	t.Helper()
+
+	encoded, err := secret.GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("マスターキーの生成に失敗: %v", err)
+	}
+	key, err := secret.ParseMasterKey(encoded)
+	if err != nil {
+		t.Fatalf("マスターキーの解析に失敗: %v", err)
+	}
+	return key
+}
+
+func TestGuildConfigManager_APIKeyLifecycle(t *testing.T) {
+	db, _ := openTestDB(t)
+	manager := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	ctx := context.Background()
+
+	has, err := manager.HasAPIKey(ctx, "guild1")
+	if err != nil || has {
+		t.Fatalf("未登録のギルドはAPIキーを持たないはずです: has=%v, err=%v", has, err)
+	}
+
+	if err := manager.SetAPIKey(ctx, "guild1", "AIzaTestKey1234", "admin"); err != nil {
+		t.Fatalf("APIキーの設定に失敗: %v", err)
+	}
+
+	apiKey, err := manager.GetAPIKey(ctx, "guild1")
+	if err != nil {
+		t.Fatalf("APIキーの取得に失敗: %v", err)
+	}
+	if apiKey != "AIzaTestKey1234" {
+		t.Errorf("期待されるAPIキー: AIzaTestKey1234, 実際: %s", apiKey)
+	}
+
+	info, err := manager.GetGuildAPIKeyInfo(ctx, "guild1")
+	if err != nil {
+		t.Fatalf("APIキー情報の取得に失敗: %v", err)
+	}
+	if info.APIKey != "" {
+		t.Error("APIキー情報にAPIキーが含まれてはいけません")
+	}
+	if info.APIKeyFingerprint != "AIza…1234" {
+		t.Errorf("期待されるフィンガープリント: AIza…1234, 実際: %s", info.APIKeyFingerprint)
+	}
+	if info.SetBy != "admin" || info.SetAt.IsZero() {
+		t.Errorf("設定者・設定日時が正しく保存されていません: %+v", info)
+	}
+	if info.Model != "gemini-2.5-pro" {
+		t.Errorf("モデル未設定時は既定モデルが返されるべきです: %s", info.Model)
+	}
+
+	if err := manager.DeleteAPIKey(ctx, "guild1"); err != nil {
+		t.Fatalf("APIキーの削除に失敗: %v", err)
+	}
+	if has, _ := manager.HasAPIKey(ctx, "guild1"); has {
+		t.Error("削除後はAPIキーを持たないはずです")
+	}
+	if err := manager.DeleteAPIKey(ctx, "guild1"); err == nil {
+		t.Error("APIキー未設定時の削除はエラーになるべきです")
+	}
+}
+
+func TestGuildConfigManager_ModelIsKeptAcrossAPIKeyChanges(t *testing.T) {
+	db, _ := openTestDB(t)
+	manager := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	ctx := context.Background()
+
+	model, err := manager.GetGuildModel(ctx, "guild1")
+	if err != nil || model != "gemini-2.5-pro" {
+		t.Fatalf("未登録のギルドは既定モデルを返すべきです: model=%s, err=%v", model, err)
+	}
+
+	if err := manager.SetGuildModel(ctx, "guild1", "gemini-2.0-flash"); err != nil {
+		t.Fatalf("モデルの設定に失敗: %v", err)
+	}
+
+	// モデルのみの設定ではAPIキーは設定済みにならない
+	if has, _ := manager.HasAPIKey(ctx, "guild1"); has {
+		t.Error("モデルのみ設定したギルドはAPIキーを持たないはずです")
+	}
+
+	if err := manager.SetAPIKey(ctx, "guild1", "AIzaTestKey1234", "admin"); err != nil {
+		t.Fatalf("APIキーの設定に失敗: %v", err)
+	}
+	if err := manager.DeleteAPIKey(ctx, "guild1"); err != nil {
+		t.Fatalf("APIキーの削除に失敗: %v", err)
+	}
+
+	model, err = manager.GetGuildModel(ctx, "guild1")
+	if err != nil || model != "gemini-2.0-flash" {
+		t.Errorf("APIキーの設定・削除でモデル設定が失われてはいけません: model=%s, err=%v", model, err)
+	}
+}
+
+func TestGuildConfigManager_PersistsAcrossReopen(t *testing.T) {
+	db, path := openTestDB(t)
+	keyring := secret.NewKeyring(newTestMasterKey(t))
+	ctx := context.Background()
+
+	if err := NewGuildConfigManager(db, keyring, "gemini-2.5-pro").SetAPIKey(ctx, "guild1", "AIzaTestKey1234", "admin"); err != nil {
+		t.Fatalf("APIキーの設定に失敗: %v", err)
+	}
+	db.Close()
+
+	// 再オープン時にマイグレーションが再適用されず、データが保持されていること
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("データベースの再オープンに失敗: %v", err)
+	}
+	defer reopened.Close()
+
+	apiKey, err := NewGuildConfigManager(reopened, keyring, "gemini-2.5-pro").GetAPIKey(ctx, "guild1")
+	if err != nil {
+		t.Fatalf("再オープン後のAPIキー取得に失敗: %v", err)
+	}
+	if apiKey != "AIzaTestKey1234" {
+		t.Errorf("期待されるAPIキー: AIzaTestKey1234, 実際: %s", apiKey)
+	}
+}
+
+func TestGuildConfigManager_StoresAPIKeyEncrypted(t *testing.T) {
+	db, _ := openTestDB(t)
+	keyring := secret.NewKeyring(newTestMasterKey(t))
+	manager := NewGuildConfigManager(db, keyring, "gemini-2.5-pro")
+	ctx := context.Background()
+
+	if err := manager.SetAPIKey(ctx, "guild1", "AIzaTestKey1234", "admin"); err != nil {
+		t.Fatalf("APIキーの設定に失敗: %v", err)
+	}
+
+	var (
+		plaintext  string
+		ciphertext []byte
+		keyID      string
+	)
+	err := db.conn.QueryRowContext(ctx, `SELECT api_key, api_key_ciphertext, key_id FROM guild_configs WHERE guild_id = ?`, "guild1").
+		Scan(&plaintext, &ciphertext, &keyID)
+	if err != nil {
+		t.Fatalf("保存内容の取得に失敗: %v", err)
+	}
+
+	if plaintext != "" {
+		t.Errorf("APIキーが平文で保存されています: %s", plaintext)
+	}
+	if len(ciphertext) == 0 {
+		t.Error("暗号化されたAPIキーが保存されていません")
+	}
+	if keyID != keyring.PrimaryKeyID() {
+		t.Errorf("期待される鍵ID: %s, 実際: %s", keyring.PrimaryKeyID(), keyID)
+	}
+
+	// 異なるマスターキーでは復号できない
+	other := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	if _, err := other.GetAPIKey(ctx, "guild1"); err == nil {
+		t.Error("異なるマスターキーで復号できてはいけません")
+	}
+}
+
+func TestGuildConfigManager_RotateMasterKey(t *testing.T) {
+	db, _ := openTestDB(t)
+	oldKey := newTestMasterKey(t)
+	newKey := newTestMasterKey(t)
+	ctx := context.Background()
+
+	if err := NewGuildConfigManager(db, secret.NewKeyring(oldKey), "gemini-2.5-pro").SetAPIKey(ctx, "guild1", "AIzaTestKey1234", "admin"); err != nil {
+		t.Fatalf("APIキーの設定に失敗: %v", err)
+	}
+
+	rotated, err := NewGuildConfigManager(db, secret.NewKeyring(newKey, oldKey), "gemini-2.5-pro").RotateMasterKey(ctx)
+	if err != nil {
+		t.Fatalf("マスターキーのローテーションに失敗: %v", err)
+	}
+	if rotated != 1 {
+		t.Errorf("期待される更新件数: 1, 実際: %d", rotated)
+	}
+
+	// 新しいマスターキーのみで復号できる
+	apiKey, err := NewGuildConfigManager(db, secret.NewKeyring(newKey), "gemini-2.5-pro").GetAPIKey(ctx, "guild1")
+	if err != nil {
+		t.Fatalf("ローテーション後のAPIキー取得に失敗: %v", err)
+	}
+	if apiKey != "AIzaTestKey1234" {
+		t.Errorf("期待されるAPIキー: AIzaTestKey1234, 実際: %s", apiKey)
+	}
+}
+
+func TestGuildConfigManager_EncryptLegacyAPIKeys(t *testing.T) {
+	db, _ := openTestDB(t)
+	manager := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	ctx := context.Background()
+
+	// 暗号化導入前の形式で保存されたAPIキー
+	if _, err := db.conn.ExecContext(ctx, `INSERT INTO guild_configs (guild_id, api_key, set_by) VALUES (?, ?, ?)`,
+		"guild1", "AIzaLegacyKey9876", "admin"); err != nil {
+		t.Fatalf("移行前データの作成に失敗: %v", err)
+	}
+
+	info, err := manager.GetGuildAPIKeyInfo(ctx, "guild1")
+	if err != nil {
+		t.Fatalf("移行前のAPIキー情報の取得に失敗: %v", err)
+	}
+	if info.APIKeyFingerprint != "AIza…9876" {
+		t.Errorf("期待されるフィンガープリント: AIza…9876, 実際: %s", info.APIKeyFingerprint)
+	}
+
+	encrypted, err := manager.EncryptLegacyAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("平文のAPIキーの暗号化に失敗: %v", err)
+	}
+	if encrypted != 1 {
+		t.Errorf("期待される暗号化件数: 1, 実際: %d", encrypted)
+	}
+
+	var plaintext string
+	if err := db.conn.QueryRowContext(ctx, `SELECT api_key FROM guild_configs WHERE guild_id = ?`, "guild1").Scan(&plaintext); err != nil {
+		t.Fatalf("保存内容の取得に失敗: %v", err)
+	}
+	if plaintext != "" {
+		t.Errorf("暗号化後も平文のAPIキーが残っています: %s", plaintext)
+	}
+
+	apiKey, err := manager.GetAPIKey(ctx, "guild1")
+	if err != nil || apiKey != "AIzaLegacyKey9876" {
+		t.Errorf("暗号化後のAPIキーが一致しません: apiKey=%s, err=%v", apiKey, err)
+	}
+}
+
+func TestGuildConfigManager_SystemPrompt(t *testing.T) {
+	db, _ := openTestDB(t)
+	manager := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	ctx := context.Background()
+
+	prompt, err := manager.GetGuildSystemPrompt(ctx, "guild1")
+	if err != nil || prompt != "" {
+		t.Fatalf("未登録のギルドのシステムプロンプトは空であるべきです: prompt=%s, err=%v", prompt, err)
+	}
+
+	if err := manager.SetGuildSystemPrompt(ctx, "guild1", "あなたはカジュアルな日本語のアシスタントです"); err != nil {
+		t.Fatalf("システムプロンプトの設定に失敗: %v", err)
+	}
+	if err := manager.SetAPIKey(ctx, "guild1", "AIzaTestKey1234", "admin"); err != nil {
+		t.Fatalf("APIキーの設定に失敗: %v", err)
+	}
+	if err := manager.DeleteAPIKey(ctx, "guild1"); err != nil {
+		t.Fatalf("APIキーの削除に失敗: %v", err)
+	}
+
+	prompt, err = manager.GetGuildSystemPrompt(ctx, "guild1")
+	if err != nil || prompt != "あなたはカジュアルな日本語のアシスタントです" {
+		t.Errorf("APIキーの設定・削除でシステムプロンプトが失われてはいけません: prompt=%s, err=%v", prompt, err)
+	}
+
+	if err := manager.SetGuildSystemPrompt(ctx, "guild1", ""); err != nil {
+		t.Fatalf("システムプロンプトの削除に失敗: %v", err)
+	}
+	if prompt, _ := manager.GetGuildSystemPrompt(ctx, "guild1"); prompt != "" {
+		t.Errorf("空文字の設定でシステムプロンプトが削除されていません: %s", prompt)
+	}
+}
+
+func TestGuildConfigManager_SearchGrounding(t *testing.T) {
+	db, _ := openTestDB(t)
+	manager := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	ctx := context.Background()
+
+	enabled, err := manager.GetGuildSearchGrounding(ctx, "guild1")
+	if err != nil || enabled {
+		t.Fatalf("未登録のギルドのグラウンディングは無効であるべきです: enabled=%v, err=%v", enabled, err)
+	}
+
+	if err := manager.SetGuildSearchGrounding(ctx, "guild1", true); err != nil {
+		t.Fatalf("グラウンディングの設定に失敗: %v", err)
+	}
+	if err := manager.SetGuildModel(ctx, "guild1", "gemini-2.0-flash"); err != nil {
+		t.Fatalf("モデルの設定に失敗: %v", err)
+	}
+
+	enabled, err = manager.GetGuildSearchGrounding(ctx, "guild1")
+	if err != nil || !enabled {
+		t.Errorf("モデルの設定でグラウンディングの設定が失われてはいけません: enabled=%v, err=%v", enabled, err)
+	}
+}
+
+func TestGuildConfigManager_Thinking(t *testing.T) {
+	db, _ := openTestDB(t)
+	manager := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	ctx := context.Background()
+
+	thinking, err := manager.GetGuildThinking(ctx, "guild1")
+	if err != nil || thinking.Budget != nil || thinking.IncludeThoughts {
+		t.Fatalf("未登録のギルドの思考の設定はゼロ値であるべきです: %+v, err=%v", thinking, err)
+	}
+
+	budget := 2048
+	if err := manager.SetGuildThinking(ctx, "guild1", domain.ThinkingSettings{Budget: &budget, IncludeThoughts: true}); err != nil {
+		t.Fatalf("思考の設定に失敗: %v", err)
+	}
+	if err := manager.SetGuildSearchGrounding(ctx, "guild1", true); err != nil {
+		t.Fatalf("グラウンディングの設定に失敗: %v", err)
+	}
+
+	thinking, err = manager.GetGuildThinking(ctx, "guild1")
+	if err != nil || thinking.Budget == nil || *thinking.Budget != 2048 || !thinking.IncludeThoughts {
+		t.Errorf("保存した思考の設定を取得できません: %+v, err=%v", thinking, err)
+	}
+
+	// 思考予算を全体の既定に戻す
+	if err := manager.SetGuildThinking(ctx, "guild1", domain.ThinkingSettings{IncludeThoughts: true}); err != nil {
+		t.Fatalf("思考の設定に失敗: %v", err)
+	}
+	if thinking, _ := manager.GetGuildThinking(ctx, "guild1"); thinking.Budget != nil {
+		t.Errorf("思考予算が削除されていません: %d", *thinking.Budget)
+	}
+}
+
+func TestGuildConfigManager_FallbackModels(t *testing.T) {
+	db, _ := openTestDB(t)
+	manager := NewGuildConfigManager(db, secret.NewKeyring(newTestMasterKey(t)), "gemini-2.5-pro")
+	ctx := context.Background()
+
+	models, err := manager.GetGuildFallbackModels(ctx, "guild1")
+	if err != nil || len(models) != 0 {
+		t.Fatalf("未登録のギルドのフォールバックチェーンは空であるべきです: %v, err=%v", models, err)
+	}
+
+	chain := []string{"gemini-2.5-flash", "gemini-2.0-flash"}
+	if err := manager.SetGuildFallbackModels(ctx, "guild1", chain); err != nil {
+		t.Fatalf("フォールバックチェーンの設定に失敗: %v", err)
+	}
+	if err := manager.SetGuildModel(ctx, "guild1", "gemini-2.5-pro"); err != nil {
+		t.Fatalf("モデルの設定に失敗: %v", err)
+	}
+
+	models, err = manager.GetGuildFallbackModels(ctx, "guild1")
+	if err != nil || !reflect.DeepEqual(models, chain) {
+		t.Errorf("保存したフォールバックチェーンを取得できません: %v, err=%v", models, err)
+	}
+
+	// 全体の既定に戻す
+	if err := manager.SetGuildFallbackModels(ctx, "guild1", nil); err != nil {
+		t.Fatalf("フォールバックチェーンの設定に失敗: %v", err)
+	}
+	if models, _ := manager.GetGuildFallbackModels(ctx, "guild1"); len(models) != 0 {
+		t.Errorf("フォールバックチェーンが削除されていません: %v", models)
+	}
+}
@@ -0,0 +1,231 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// migration は、1つのスキーマ変更を表します
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// migrations は、適用順に並べたスキーママイグレーションの一覧です
+// 既存の項目は変更せず、スキーマ変更時は末尾に新しいバージョンを追加してください
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_guild_configs",
+		statements: []string{
+			`CREATE TABLE guild_configs (
+				guild_id TEXT PRIMARY KEY,
+				api_key  TEXT NOT NULL DEFAULT '',
+				set_by   TEXT NOT NULL DEFAULT '',
+				set_at   DATETIME,
+				model    TEXT NOT NULL DEFAULT ''
+			)`,
+		},
+	},
+	{
+		// api_key 列は暗号化前のデータ移行用にのみ残し、新規の保存は暗号化列に行う
+		version: 2,
+		name:    "encrypt_guild_api_keys",
+		statements: []string{
+			`ALTER TABLE guild_configs ADD COLUMN api_key_ciphertext BLOB`,
+			`ALTER TABLE guild_configs ADD COLUMN api_key_dek BLOB`,
+			`ALTER TABLE guild_configs ADD COLUMN key_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE guild_configs ADD COLUMN api_key_fingerprint TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// 回答に付けたボタンの操作時に、再起動後も元のリクエストを復元できるようにする
+		version: 3,
+		name:    "create_answer_records",
+		statements: []string{
+			`CREATE TABLE answer_records (
+				message_id TEXT PRIMARY KEY,
+				channel_id TEXT NOT NULL DEFAULT '',
+				mention    TEXT NOT NULL,
+				content    TEXT NOT NULL DEFAULT '',
+				truncated  INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME NOT NULL
+			)`,
+			`CREATE INDEX idx_answer_records_created_at ON answer_records (created_at)`,
+		},
+	},
+	{
+		version: 4,
+		name:    "add_guild_system_prompt",
+		statements: []string{
+			`ALTER TABLE guild_configs ADD COLUMN system_prompt TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 5,
+		name:    "create_channel_configs",
+		statements: []string{
+			`CREATE TABLE channel_configs (
+				channel_id       TEXT PRIMARY KEY,
+				guild_id         TEXT NOT NULL DEFAULT '',
+				model            TEXT NOT NULL DEFAULT '',
+				system_prompt    TEXT NOT NULL DEFAULT '',
+				history_length   INTEGER NOT NULL DEFAULT 0,
+				image_generation INTEGER,
+				reply_style      TEXT NOT NULL DEFAULT '',
+				updated_by       TEXT NOT NULL DEFAULT '',
+				updated_at       DATETIME NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 6,
+		name:    "create_permission_rules",
+		statements: []string{
+			`CREATE TABLE permission_rules (
+				guild_id    TEXT NOT NULL,
+				permission  TEXT NOT NULL,
+				target_type TEXT NOT NULL,
+				target_id   TEXT NOT NULL,
+				effect      TEXT NOT NULL,
+				created_by  TEXT NOT NULL DEFAULT '',
+				created_at  DATETIME NOT NULL,
+				PRIMARY KEY (guild_id, permission, target_type, target_id)
+			)`,
+		},
+	},
+	{
+		version: 7,
+		name:    "create_rate_limit_buckets",
+		statements: []string{
+			`CREATE TABLE rate_limit_buckets (
+				bucket_key TEXT PRIMARY KEY,
+				tokens     REAL NOT NULL,
+				updated_at DATETIME NOT NULL
+			)`,
+			`CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at)`,
+		},
+	},
+	{
+		version: 8,
+		name:    "create_usage_records",
+		statements: []string{
+			`CREATE TABLE usage_records (
+				id              INTEGER PRIMARY KEY AUTOINCREMENT,
+				guild_id        TEXT NOT NULL DEFAULT '',
+				user_id         TEXT NOT NULL DEFAULT '',
+				channel_id      TEXT NOT NULL DEFAULT '',
+				model           TEXT NOT NULL DEFAULT '',
+				kind            TEXT NOT NULL DEFAULT '',
+				prompt_tokens   INTEGER NOT NULL DEFAULT 0,
+				output_tokens   INTEGER NOT NULL DEFAULT 0,
+				thinking_tokens INTEGER NOT NULL DEFAULT 0,
+				created_at      DATETIME NOT NULL
+			)`,
+			`CREATE INDEX idx_usage_records_guild_created_at ON usage_records (guild_id, created_at)`,
+			`CREATE TABLE usage_budgets (
+				guild_id            TEXT PRIMARY KEY,
+				monthly_token_limit INTEGER NOT NULL DEFAULT 0,
+				monthly_cost_limit  REAL NOT NULL DEFAULT 0,
+				fallback_model      TEXT NOT NULL DEFAULT '',
+				updated_by          TEXT NOT NULL DEFAULT '',
+				updated_at          DATETIME NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 9,
+		name:    "create_conversation_summaries",
+		statements: []string{
+			`CREATE TABLE conversation_summaries (
+				channel_id    TEXT PRIMARY KEY,
+				content       TEXT NOT NULL,
+				covered_until DATETIME NOT NULL,
+				updated_at    DATETIME NOT NULL
+			)`,
+		},
+	},
+	{
+		// チャンネルの search_grounding は未設定（ギルドの設定を継承）を表すため NULL を許可する
+		version: 10,
+		name:    "add_search_grounding",
+		statements: []string{
+			`ALTER TABLE guild_configs ADD COLUMN search_grounding INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE channel_configs ADD COLUMN search_grounding INTEGER`,
+		},
+	},
+	{
+		// code_execution も未設定を表すため NULL を許可する
+		version: 11,
+		name:    "add_channel_code_execution",
+		statements: []string{
+			`ALTER TABLE channel_configs ADD COLUMN code_execution INTEGER`,
+		},
+	},
+	{
+		// thinking_budget は未設定（全体の既定を使用）を表すため NULL を許可する
+		version: 12,
+		name:    "add_guild_thinking",
+		statements: []string{
+			`ALTER TABLE guild_configs ADD COLUMN thinking_budget INTEGER`,
+			`ALTER TABLE guild_configs ADD COLUMN include_thoughts INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		// fallback_models はモデル名のカンマ区切り（空文字は全体の既定を使用）
+		version: 13,
+		name:    "add_guild_fallback_models",
+		statements: []string{
+			`ALTER TABLE guild_configs ADD COLUMN fallback_models TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+}
+
+// migrate は、未適用のマイグレーションを順番に適用します
+func (d *DB) migrate(ctx context.Context) error {
+	if _, err := d.conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("マイグレーション管理テーブルの作成に失敗: %w", err)
+	}
+
+	var current int
+	if err := d.conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("スキーマバージョンの取得に失敗: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := d.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("マイグレーション %d のトランザクション開始に失敗: %w", m.version, err)
+		}
+
+		for _, stmt := range m.statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("マイグレーション %d (%s) の適用に失敗: %w", m.version, m.name, err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("マイグレーション %d の記録に失敗: %w", m.version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("マイグレーション %d のコミットに失敗: %w", m.version, err)
+		}
+
+		log.Printf("スキーママイグレーションを適用しました: %d (%s)", m.version, m.name)
+	}
+
+	return nil
+}
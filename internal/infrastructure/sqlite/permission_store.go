@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// PermissionStore は、ギルドの権限ルールを SQLite に永続化する実装です
+type PermissionStore struct {
+	db *DB
+}
+
+// NewPermissionStore は新しい PermissionStore を作成します
+func NewPermissionStore(db *DB) *PermissionStore {
+	return &PermissionStore{db: db}
+}
+
+// SavePermissionRule は、権限ルールを保存します（同じ権限・対象のルールは置き換えます）
+func (s *PermissionStore) SavePermissionRule(ctx context.Context, rule domain.PermissionRule) error {
+	createdAt := rule.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err := s.db.conn.ExecContext(ctx, `
+		INSERT INTO permission_rules (guild_id, permission, target_type, target_id, effect, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id, permission, target_type, target_id) DO UPDATE SET
+			effect     = excluded.effect,
+			created_by = excluded.created_by,
+			created_at = excluded.created_at`,
+		rule.GuildID, string(rule.Permission), string(rule.TargetType), rule.TargetID, string(rule.Effect), rule.CreatedBy, createdAt)
+	if err != nil {
+		return fmt.Errorf("ギルド %s の権限ルールの保存に失敗: %w", rule.GuildID, err)
+	}
+	return nil
+}
+
+// DeletePermissionRule は、指定された権限・対象のルールを削除します
+func (s *PermissionStore) DeletePermissionRule(ctx context.Context, guildID string, permission domain.Permission, targetType domain.PermissionTargetType, targetID string) error {
+	result, err := s.db.conn.ExecContext(ctx, `
+		DELETE FROM permission_rules
+		WHERE guild_id = ? AND permission = ? AND target_type = ? AND target_id = ?`,
+		guildID, string(permission), string(targetType), targetID)
+	if err != nil {
+		return fmt.Errorf("ギルド %s の権限ルールの削除に失敗: %w", guildID, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ギルド %s の権限ルールの削除結果の取得に失敗: %w", guildID, err)
+	}
+	if deleted == 0 {
+		return domain.ErrPermissionRuleNotFound
+	}
+	return nil
+}
+
+// ListPermissionRules は、指定されたギルドの権限ルールを登録順に取得します
+func (s *PermissionStore) ListPermissionRules(ctx context.Context, guildID string) ([]domain.PermissionRule, error) {
+	rows, err := s.db.conn.QueryContext(ctx, `
+		SELECT permission, target_type, target_id, effect, created_by, created_at
+		FROM permission_rules WHERE guild_id = ?
+		ORDER BY created_at, rowid`, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("ギルド %s の権限ルールの取得に失敗: %w", guildID, err)
+	}
+	defer rows.Close()
+
+	var rules []domain.PermissionRule
+	for rows.Next() {
+		rule := domain.PermissionRule{GuildID: guildID}
+		var permission, targetType, effect string
+		if err := rows.Scan(&permission, &targetType, &rule.TargetID, &effect, &rule.CreatedBy, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ギルド %s の権限ルールの読み込みに失敗: %w", guildID, err)
+		}
+		rule.Permission = domain.Permission(permission)
+		rule.TargetType = domain.PermissionTargetType(targetType)
+		rule.Effect = domain.PermissionEffect(effect)
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ギルド %s の権限ルールの読み込みに失敗: %w", guildID, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geminibot/internal/domain"
+)
+
+func TestPermissionStore_Lifecycle(t *testing.T) {
+	db, path := openTestDB(t)
+	ctx := context.Background()
+	store := NewPermissionStore(db)
+
+	rules := []domain.PermissionRule{
+		{GuildID: "guild1", Permission: domain.PermissionManageModels, TargetType: domain.PermissionTargetRole, TargetID: "moderator", Effect: domain.PermissionAllow},
+		{GuildID: "guild1", Permission: domain.PermissionUseBot, TargetType: domain.PermissionTargetChannel, TargetID: "off-topic", Effect: domain.PermissionDeny},
+		{GuildID: "guild2", Permission: domain.PermissionUseBot, TargetType: domain.PermissionTargetRole, TargetID: "member", Effect: domain.PermissionAllow},
+	}
+	for _, rule := range rules {
+		if err := store.SavePermissionRule(ctx, rule); err != nil {
+			t.Fatalf("権限ルールの保存に失敗: %v", err)
+		}
+	}
+	// 同じ権限・対象のルールは置き換える
+	if err := store.SavePermissionRule(ctx, domain.PermissionRule{GuildID: "guild1", Permission: domain.PermissionUseBot, TargetType: domain.PermissionTargetChannel, TargetID: "off-topic", Effect: domain.PermissionAllow}); err != nil {
+		t.Fatalf("権限ルールの更新に失敗: %v", err)
+	}
+	db.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("データベースの再オープンに失敗: %v", err)
+	}
+	defer reopened.Close()
+	store = NewPermissionStore(reopened)
+
+	got, err := store.ListPermissionRules(ctx, "guild1")
+	if err != nil {
+		t.Fatalf("権限ルールの取得に失敗: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ギルドの権限ルールは2件であるべきです: %+v", got)
+	}
+	if got[0].TargetID != "moderator" || got[0].Permission != domain.PermissionManageModels || got[0].Effect != domain.PermissionAllow {
+		t.Errorf("保存したルールと一致しません: %+v", got[0])
+	}
+	if got[1].Effect != domain.PermissionAllow {
+		t.Errorf("置き換えたルールが反映されていません: %+v", got[1])
+	}
+
+	if err := store.DeletePermissionRule(ctx, "guild1", domain.PermissionManageModels, domain.PermissionTargetRole, "moderator"); err != nil {
+		t.Fatalf("権限ルールの削除に失敗: %v", err)
+	}
+	err = store.DeletePermissionRule(ctx, "guild1", domain.PermissionManageModels, domain.PermissionTargetRole, "moderator")
+	if !errors.Is(err, domain.ErrPermissionRuleNotFound) {
+		t.Errorf("存在しないルールの削除は ErrPermissionRuleNotFound を返すべきです: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// RateLimitStore は、レート制限のトークンバケットの状態を SQLite に永続化する実装です。
+// 再起動しても、上限まで使い切った枠がすぐに回復しないようにするために使用します。
+type RateLimitStore struct {
+	db *DB
+}
+
+// NewRateLimitStore は新しい RateLimitStore を作成します
+func NewRateLimitStore(db *DB) *RateLimitStore {
+	return &RateLimitStore{db: db}
+}
+
+// GetTokenBucket は、キーに対応するトークンバケットを取得します
+func (s *RateLimitStore) GetTokenBucket(ctx context.Context, key string) (domain.TokenBucket, bool, error) {
+	var bucket domain.TokenBucket
+	err := s.db.conn.QueryRowContext(ctx, `
+		SELECT tokens, updated_at FROM rate_limit_buckets WHERE bucket_key = ?`, key).
+		Scan(&bucket.Tokens, &bucket.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.TokenBucket{}, false, nil
+	}
+	if err != nil {
+		return domain.TokenBucket{}, false, fmt.Errorf("レート制限 %s の状態の取得に失敗: %w", key, err)
+	}
+	return bucket, true, nil
+}
+
+// SaveTokenBucket は、トークンバケットの状態を保存します
+func (s *RateLimitStore) SaveTokenBucket(ctx context.Context, key string, bucket domain.TokenBucket) error {
+	_, err := s.db.conn.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(bucket_key) DO UPDATE SET
+			tokens     = excluded.tokens,
+			updated_at = excluded.updated_at`,
+		key, bucket.Tokens, bucket.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("レート制限 %s の状態の保存に失敗: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteTokenBucketsBefore は、指定された時刻より前から更新されていないトークンバケットを削除します
+func (s *RateLimitStore) DeleteTokenBucketsBefore(ctx context.Context, before time.Time) error {
+	if _, err := s.db.conn.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < ?`, before); err != nil {
+		return fmt.Errorf("古いレート制限の状態の削除に失敗: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+func TestRateLimitStore_Lifecycle(t *testing.T) {
+	db, path := openTestDB(t)
+	ctx := context.Background()
+	store := NewRateLimitStore(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := store.SaveTokenBucket(ctx, "user:text:alice", domain.TokenBucket{Tokens: 1.5, UpdatedAt: now}); err != nil {
+		t.Fatalf("トークンバケットの保存に失敗: %v", err)
+	}
+	if err := store.SaveTokenBucket(ctx, "user:text:bob", domain.TokenBucket{Tokens: 3, UpdatedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("トークンバケットの保存に失敗: %v", err)
+	}
+	db.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("データベースの再オープンに失敗: %v", err)
+	}
+	defer reopened.Close()
+	store = NewRateLimitStore(reopened)
+
+	bucket, found, err := store.GetTokenBucket(ctx, "user:text:alice")
+	if err != nil || !found {
+		t.Fatalf("保存したトークンバケットが取得できません: found=%v, err=%v", found, err)
+	}
+	if bucket.Tokens != 1.5 || !bucket.UpdatedAt.Equal(now) {
+		t.Errorf("トークンバケット = %+v, 期待値: 残り1.5件, 更新日時 %v", bucket, now)
+	}
+
+	if err := store.DeleteTokenBucketsBefore(ctx, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("古いトークンバケットの削除に失敗: %v", err)
+	}
+	if _, found, _ := store.GetTokenBucket(ctx, "user:text:bob"); found {
+		t.Error("古いトークンバケットが削除されていません")
+	}
+	if _, found, _ := store.GetTokenBucket(ctx, "user:text:alice"); !found {
+		t.Error("新しいトークンバケットまで削除されています")
+	}
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+// UsageStore は、トークンの使用量の記録とギルドの利用上限を SQLite に永続化する実装です。
+// 時刻の比較を文字列の順序で行えるよう、日時はUTCに揃えて保存します。
+type UsageStore struct {
+	db *DB
+}
+
+// NewUsageStore は新しい UsageStore を作成します
+func NewUsageStore(db *DB) *UsageStore {
+	return &UsageStore{db: db}
+}
+
+// RecordUsage は、使用量の記録を追加します
+func (s *UsageStore) RecordUsage(ctx context.Context, record domain.UsageRecord) error {
+	createdAt := record.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err := s.db.conn.ExecContext(ctx, `
+		INSERT INTO usage_records (guild_id, user_id, channel_id, model, kind, prompt_tokens, output_tokens, thinking_tokens, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.GuildID, record.UserID, record.ChannelID, record.Model, string(record.Kind),
+		record.Usage.PromptTokens, record.Usage.OutputTokens, record.Usage.ThinkingTokens, createdAt.UTC())
+	if err != nil {
+		return fmt.Errorf("ギルド %s の使用量の記録に失敗: %w", record.GuildID, err)
+	}
+	return nil
+}
+
+// SummarizeUsage は、条件に一致する使用量をモデルごとに集計して返します（モデル名順）
+func (s *UsageStore) SummarizeUsage(ctx context.Context, query domain.UsageQuery) ([]domain.ModelUsage, error) {
+	conditions := []string{"created_at >= ?"}
+	args := []any{query.Since.UTC()}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, query.Until.UTC())
+	}
+	for _, filter := range []struct {
+		column string
+		value  string
+	}{
+		{"guild_id", query.GuildID},
+		{"user_id", query.UserID},
+		{"channel_id", query.ChannelID},
+	} {
+		if filter.value != "" {
+			conditions = append(conditions, filter.column+" = ?")
+			args = append(args, filter.value)
+		}
+	}
+
+	rows, err := s.db.conn.QueryContext(ctx, `
+		SELECT model, COUNT(*), SUM(prompt_tokens), SUM(output_tokens), SUM(thinking_tokens)
+		FROM usage_records WHERE `+strings.Join(conditions, " AND ")+`
+		GROUP BY model ORDER BY model`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ギルド %s の使用量の集計に失敗: %w", query.GuildID, err)
+	}
+	defer rows.Close()
+
+	var result []domain.ModelUsage
+	for rows.Next() {
+		var usage domain.ModelUsage
+		if err := rows.Scan(&usage.Model, &usage.Requests, &usage.Usage.PromptTokens, &usage.Usage.OutputTokens, &usage.Usage.ThinkingTokens); err != nil {
+			return nil, fmt.Errorf("ギルド %s の使用量の読み込みに失敗: %w", query.GuildID, err)
+		}
+		result = append(result, usage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ギルド %s の使用量の読み込みに失敗: %w", query.GuildID, err)
+	}
+	return result, nil
+}
+
+// GetUsageBudget は、ギルドの利用上限を取得します（設定されていない場合は空の上限を返します）
+func (s *UsageStore) GetUsageBudget(ctx context.Context, guildID string) (domain.UsageBudget, error) {
+	budget := domain.UsageBudget{GuildID: guildID}
+	err := s.db.conn.QueryRowContext(ctx, `
+		SELECT monthly_token_limit, monthly_cost_limit, fallback_model, updated_by, updated_at
+		FROM usage_budgets WHERE guild_id = ?`, guildID).
+		Scan(&budget.MonthlyTokenLimit, &budget.MonthlyCostLimit, &budget.FallbackModel, &budget.UpdatedBy, &budget.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return budget, nil
+	}
+	if err != nil {
+		return domain.UsageBudget{}, fmt.Errorf("ギルド %s の利用上限の取得に失敗: %w", guildID, err)
+	}
+	return budget, nil
+}
+
+// SaveUsageBudget は、ギルドの利用上限を保存します
+func (s *UsageStore) SaveUsageBudget(ctx context.Context, budget domain.UsageBudget) error {
+	updatedAt := budget.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+
+	_, err := s.db.conn.ExecContext(ctx, `
+		INSERT INTO usage_budgets (guild_id, monthly_token_limit, monthly_cost_limit, fallback_model, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET
+			monthly_token_limit = excluded.monthly_token_limit,
+			monthly_cost_limit  = excluded.monthly_cost_limit,
+			fallback_model      = excluded.fallback_model,
+			updated_by          = excluded.updated_by,
+			updated_at          = excluded.updated_at`,
+		budget.GuildID, budget.MonthlyTokenLimit, budget.MonthlyCostLimit, budget.FallbackModel, budget.UpdatedBy, updatedAt)
+	if err != nil {
+		return fmt.Errorf("ギルド %s の利用上限の保存に失敗: %w", budget.GuildID, err)
+	}
+	return nil
+}
+
+// DeleteUsageBudget は、ギルドの利用上限を削除します
+func (s *UsageStore) DeleteUsageBudget(ctx context.Context, guildID string) error {
+	if _, err := s.db.conn.ExecContext(ctx, `DELETE FROM usage_budgets WHERE guild_id = ?`, guildID); err != nil {
+		return fmt.Errorf("ギルド %s の利用上限の削除に失敗: %w", guildID, err)
+	}
+	return nil
+}
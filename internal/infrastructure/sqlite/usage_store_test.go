@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geminibot/internal/domain"
+)
+
+func TestUsageStore_RecordAndSummarize(t *testing.T) {
+	db, path := openTestDB(t)
+	ctx := context.Background()
+	store := NewUsageStore(db)
+
+	monthStart := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	records := []domain.UsageRecord{
+		{GuildID: "guild1", UserID: "alice", Model: "gemini-2.5-pro", Kind: domain.RequestKindText, Usage: domain.TokenUsage{PromptTokens: 100, OutputTokens: 50, ThinkingTokens: 10}, CreatedAt: monthStart.Add(time.Hour)},
+		{GuildID: "guild1", UserID: "bob", Model: "gemini-2.5-pro", Kind: domain.RequestKindText, Usage: domain.TokenUsage{PromptTokens: 200, OutputTokens: 20}, CreatedAt: monthStart.Add(2 * time.Hour)},
+		{GuildID: "guild1", UserID: "alice", Model: "gemini-2.0-flash", Kind: domain.RequestKindText, Usage: domain.TokenUsage{PromptTokens: 10, OutputTokens: 5}, CreatedAt: monthStart.Add(3 * time.Hour)},
+		// 前月と他のギルドの記録は集計しない
+		{GuildID: "guild1", UserID: "alice", Model: "gemini-2.5-pro", Usage: domain.TokenUsage{PromptTokens: 1000}, CreatedAt: monthStart.Add(-time.Hour)},
+		{GuildID: "guild2", UserID: "alice", Model: "gemini-2.5-pro", Usage: domain.TokenUsage{PromptTokens: 1000}, CreatedAt: monthStart.Add(time.Hour)},
+	}
+	for _, record := range records {
+		if err := store.RecordUsage(ctx, record); err != nil {
+			t.Fatalf("使用量の記録に失敗: %v", err)
+		}
+	}
+	db.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("データベースの再オープンに失敗: %v", err)
+	}
+	defer reopened.Close()
+	store = NewUsageStore(reopened)
+
+	got, err := store.SummarizeUsage(ctx, domain.UsageQuery{GuildID: "guild1", Since: monthStart})
+	if err != nil {
+		t.Fatalf("使用量の集計に失敗: %v", err)
+	}
+	want := []domain.ModelUsage{
+		{Model: "gemini-2.0-flash", Requests: 1, Usage: domain.TokenUsage{PromptTokens: 10, OutputTokens: 5}},
+		{Model: "gemini-2.5-pro", Requests: 2, Usage: domain.TokenUsage{PromptTokens: 300, OutputTokens: 70, ThinkingTokens: 10}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("集計結果 = %+v, 期待値: %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("集計結果[%d] = %+v, 期待値: %+v", i, got[i], want[i])
+		}
+	}
+
+	// ユーザーで絞り込む
+	got, err = store.SummarizeUsage(ctx, domain.UsageQuery{GuildID: "guild1", UserID: "bob", Since: monthStart})
+	if err != nil || len(got) != 1 || got[0].Usage.PromptTokens != 200 {
+		t.Errorf("ユーザーで絞り込んだ集計結果が正しくありません: %+v, err=%v", got, err)
+	}
+}
+
+func TestUsageStore_Budget(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+	store := NewUsageStore(db)
+
+	budget, err := store.GetUsageBudget(ctx, "guild1")
+	if err != nil || !budget.IsEmpty() {
+		t.Fatalf("未設定の利用上限は空であるべきです: %+v, err=%v", budget, err)
+	}
+
+	want := domain.UsageBudget{GuildID: "guild1", MonthlyTokenLimit: 1_000_000, MonthlyCostLimit: 5.5, FallbackModel: "gemini-2.0-flash", UpdatedBy: "admin"}
+	if err := store.SaveUsageBudget(ctx, want); err != nil {
+		t.Fatalf("利用上限の保存に失敗: %v", err)
+	}
+	budget, err = store.GetUsageBudget(ctx, "guild1")
+	if err != nil {
+		t.Fatalf("利用上限の取得に失敗: %v", err)
+	}
+	if budget.MonthlyTokenLimit != want.MonthlyTokenLimit || budget.MonthlyCostLimit != want.MonthlyCostLimit || budget.FallbackModel != want.FallbackModel || budget.UpdatedBy != want.UpdatedBy {
+		t.Errorf("利用上限 = %+v, 期待値: %+v", budget, want)
+	}
+
+	if err := store.DeleteUsageBudget(ctx, "guild1"); err != nil {
+		t.Fatalf("利用上限の削除に失敗: %v", err)
+	}
+	if budget, _ := store.GetUsageBudget(ctx, "guild1"); !budget.IsEmpty() {
+		t.Errorf("削除した利用上限が残っています: %+v", budget)
+	}
+}
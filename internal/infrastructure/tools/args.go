@@ -0,0 +1,24 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stringArg は、モデルが指定した引数から必須の文字列を取り出します
+func stringArg(args map[string]any, name string) (string, error) {
+	value, ok := optionalStringArg(args, name)
+	if !ok {
+		return "", fmt.Errorf("引数 %s を文字列で指定してください", name)
+	}
+	return value, nil
+}
+
+// optionalStringArg は、モデルが指定した引数から省略可能な文字列を取り出します（空文字は省略として扱います）
+func optionalStringArg(args map[string]any, name string) (string, bool) {
+	value, ok := args[name].(string)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
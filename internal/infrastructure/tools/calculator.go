@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxExpressionLength は、計算する式の最大文字数です
+const maxExpressionLength = 500
+
+// CalculatorTool は、四則演算などの数式を計算するツールです
+// モデルは桁の多い計算を誤りやすいため、正確な値が必要な場合に使用させます
+type CalculatorTool struct{}
+
+// NewCalculatorTool は新しいCalculatorToolインスタンスを作成します
+func NewCalculatorTool() *CalculatorTool {
+	return &CalculatorTool{}
+}
+
+// Name は、ツールの名前を返します
+func (t *CalculatorTool) Name() string {
+	return "calculate"
+}
+
+// Description は、ツールの説明を返します
+func (t *CalculatorTool) Description() string {
+	return "数式を計算します。+ - * / % ^（べき乗）、括弧、関数 sqrt abs round floor ceil sin cos tan ln log10、定数 pi e を使用できます。"
+}
+
+// Parameters は、引数のJSON Schemaを返します
+func (t *CalculatorTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"expression": map[string]any{
+				"type":        "string",
+				"description": "計算する数式（例: (1200 * 1.1) / 3, sqrt(2) ^ 2）",
+			},
+		},
+		"required": []string{"expression"},
+	}
+}
+
+// Execute は、数式を計算して結果を返します
+func (t *CalculatorTool) Execute(_ context.Context, args map[string]any) (map[string]any, error) {
+	expression, err := stringArg(args, "expression")
+	if err != nil {
+		return nil, err
+	}
+	result, err := Evaluate(expression)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"expression": expression,
+		"result":     result,
+	}, nil
+}
+
+// Evaluate は、数式を計算します
+func Evaluate(expression string) (float64, error) {
+	if len(expression) > maxExpressionLength {
+		return 0, fmt.Errorf("数式が長すぎます（最大%d文字）", maxExpressionLength)
+	}
+
+	p := &expressionParser{input: []rune(expression)}
+	result, err := p.parseExpression()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("数式を解釈できません: %q の位置 %d", expression, p.pos+1)
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("計算結果が数値になりません: %s", expression)
+	}
+	return result, nil
+}
+
+// expressionParser は、数式を再帰下降で解析しながら計算するパーサーです
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/" | "%") unary }
+//	unary      = { "+" | "-" } power
+//	power      = primary [ "^" unary ]
+//	primary    = number | constant | function "(" expression ")" | "(" expression ")"
+type expressionParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *expressionParser) parseExpression() (float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case '-':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *expressionParser) parseTerm() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("0で割ることはできません")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("0で割ることはできません")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+func (p *expressionParser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	case '-':
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	default:
+		return p.parsePower()
+	}
+}
+
+func (p *expressionParser) parsePower() (float64, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	// べき乗は右結合（2^3^2 = 2^9）
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (p *expressionParser) parsePrimary() (float64, error) {
+	r := p.peek()
+	switch {
+	case r == '(':
+		p.pos++
+		value, err := p.parseExpression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("括弧が閉じられていません")
+		}
+		p.pos++
+		return value, nil
+	case unicode.IsDigit(r) || r == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(r):
+		return p.parseIdentifier()
+	case r == 0:
+		return 0, fmt.Errorf("数式が途中で終わっています")
+	default:
+		return 0, fmt.Errorf("使用できない文字です: %q", r)
+	}
+}
+
+func (p *expressionParser) parseNumber() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.' || p.input[p.pos] == '_' || p.input[p.pos] == ',') {
+		p.pos++
+	}
+	// 桁区切りの「,」「_」は取り除く
+	text := strings.NewReplacer(",", "", "_", "").Replace(string(p.input[start:p.pos]))
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("数値を解釈できません: %s", string(p.input[start:p.pos]))
+	}
+	return value, nil
+}
+
+func (p *expressionParser) parseIdentifier() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(string(p.input[start:p.pos]))
+
+	switch name {
+	case "pi":
+		return math.Pi, nil
+	case "e":
+		return math.E, nil
+	}
+
+	function, ok := calculatorFunctions[name]
+	if !ok {
+		return 0, fmt.Errorf("不明な関数または定数です: %s", name)
+	}
+	if p.peek() != '(' {
+		return 0, fmt.Errorf("関数 %s の後には括弧で引数を指定してください", name)
+	}
+	argument, err := p.parsePrimary()
+	if err != nil {
+		return 0, err
+	}
+	return function(argument), nil
+}
+
+// peek は、空白を読み飛ばして次の文字を返します（終端の場合は0を返します）
+func (p *expressionParser) peek() rune {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *expressionParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// calculatorFunctions は、数式で使用できる1引数の関数です
+var calculatorFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"round": math.Round,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"ln":    math.Log,
+	"log10": math.Log10,
+}
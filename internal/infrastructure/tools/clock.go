@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	// コンテナにタイムゾーンのデータがない場合も変換できるよう、Goに組み込まれたデータを使用する
+	_ "time/tzdata"
+)
+
+// dateTimeLayouts は、タイムゾーンの変換で受け付ける日時の書式です（オフセットのない書式は変換元のタイムゾーンの日時として扱います）
+var dateTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// CurrentTimeTool は、現在の日時を返すツールです
+type CurrentTimeTool struct {
+	now func() time.Time
+}
+
+// NewCurrentTimeTool は新しいCurrentTimeToolインスタンスを作成します
+func NewCurrentTimeTool() *CurrentTimeTool {
+	return &CurrentTimeTool{now: time.Now}
+}
+
+// Name は、ツールの名前を返します
+func (t *CurrentTimeTool) Name() string {
+	return "get_current_time"
+}
+
+// Description は、ツールの説明を返します
+func (t *CurrentTimeTool) Description() string {
+	return "現在の日時と曜日を取得します。timezone を省略した場合はサーバーのタイムゾーンで返します。"
+}
+
+// Parameters は、引数のJSON Schemaを返します
+func (t *CurrentTimeTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"timezone": map[string]any{
+				"type":        "string",
+				"description": "IANAのタイムゾーン名（例: Asia/Tokyo, America/New_York, UTC）",
+			},
+		},
+	}
+}
+
+// Execute は、指定されたタイムゾーンの現在の日時を返します
+func (t *CurrentTimeTool) Execute(_ context.Context, args map[string]any) (map[string]any, error) {
+	location := time.Local
+	if name, ok := optionalStringArg(args, "timezone"); ok {
+		loaded, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("不明なタイムゾーンです: %s", name)
+		}
+		location = loaded
+	}
+	return formatDateTime(t.now().In(location)), nil
+}
+
+// ConvertTimezoneTool は、日時を別のタイムゾーンに変換するツールです
+type ConvertTimezoneTool struct{}
+
+// NewConvertTimezoneTool は新しいConvertTimezoneToolインスタンスを作成します
+func NewConvertTimezoneTool() *ConvertTimezoneTool {
+	return &ConvertTimezoneTool{}
+}
+
+// Name は、ツールの名前を返します
+func (t *ConvertTimezoneTool) Name() string {
+	return "convert_timezone"
+}
+
+// Description は、ツールの説明を返します
+func (t *ConvertTimezoneTool) Description() string {
+	return "日時をあるタイムゾーンから別のタイムゾーンに変換します。"
+}
+
+// Parameters は、引数のJSON Schemaを返します
+func (t *ConvertTimezoneTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"datetime": map[string]any{
+				"type":        "string",
+				"description": "変換する日時（例: 2025-01-02 15:04, 2025-01-02T15:04:05+09:00）",
+			},
+			"from_timezone": map[string]any{
+				"type":        "string",
+				"description": "変換元のIANAのタイムゾーン名（datetime にオフセットがない場合に使用）",
+			},
+			"to_timezone": map[string]any{
+				"type":        "string",
+				"description": "変換先のIANAのタイムゾーン名",
+			},
+		},
+		"required": []string{"datetime", "to_timezone"},
+	}
+}
+
+// Execute は、日時を変換先のタイムゾーンに変換して返します
+func (t *ConvertTimezoneTool) Execute(_ context.Context, args map[string]any) (map[string]any, error) {
+	value, err := stringArg(args, "datetime")
+	if err != nil {
+		return nil, err
+	}
+	toName, err := stringArg(args, "to_timezone")
+	if err != nil {
+		return nil, err
+	}
+	to, err := time.LoadLocation(toName)
+	if err != nil {
+		return nil, fmt.Errorf("不明なタイムゾーンです: %s", toName)
+	}
+
+	from := time.Local
+	if fromName, ok := optionalStringArg(args, "from_timezone"); ok {
+		from, err = time.LoadLocation(fromName)
+		if err != nil {
+			return nil, fmt.Errorf("不明なタイムゾーンです: %s", fromName)
+		}
+	}
+
+	for _, layout := range dateTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, from); err == nil {
+			return formatDateTime(parsed.In(to)), nil
+		}
+	}
+	return nil, fmt.Errorf("日時の書式を解釈できません: %s（例: 2025-01-02 15:04）", value)
+}
+
+// formatDateTime は、日時をモデルに返す形式に変換します
+func formatDateTime(t time.Time) map[string]any {
+	return map[string]any{
+		"datetime": t.Format(time.RFC3339),
+		"timezone": t.Location().String(),
+		"weekday":  t.Weekday().String(),
+	}
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-2 ^ 2", -4},
+		{"2 ^ 3 ^ 2", 512},
+		{"10 % 4", 2},
+		{"1,200 * 1.1", 1320},
+		{"sqrt(16) + abs(-3)", 7},
+		{"round(pi * 100) / 100", 3.14},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.expression)
+		if err != nil {
+			t.Errorf("Evaluate(%q) でエラーが発生しました: %v", tt.expression, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Evaluate(%q) = %v, 期待値: %v", tt.expression, got, tt.want)
+		}
+	}
+
+	for _, expression := range []string{"1 / 0", "(1 + 2", "2 +", "unknown(1)", "1 2", "sqrt(-1)"} {
+		if _, err := Evaluate(expression); err == nil {
+			t.Errorf("Evaluate(%q) はエラーを返すべきです", expression)
+		}
+	}
+}
+
+func TestCurrentTimeTool(t *testing.T) {
+	tool := NewCurrentTimeTool()
+	tool.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	got, err := tool.Execute(context.Background(), map[string]any{"timezone": "Asia/Tokyo"})
+	if err != nil {
+		t.Fatalf("現在の日時の取得に失敗: %v", err)
+	}
+	if got["datetime"] != "2025-01-02T12:04:05+09:00" || got["weekday"] != "Thursday" {
+		t.Errorf("指定したタイムゾーンの日時を返すべきです: %+v", got)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]any{"timezone": "Mars/Olympus"}); err == nil {
+		t.Error("不明なタイムゾーンはエラーを返すべきです")
+	}
+}
+
+func TestConvertTimezoneTool(t *testing.T) {
+	tool := NewConvertTimezoneTool()
+
+	got, err := tool.Execute(context.Background(), map[string]any{
+		"datetime":      "2025-07-01 09:30",
+		"from_timezone": "Asia/Tokyo",
+		"to_timezone":   "America/New_York",
+	})
+	if err != nil {
+		t.Fatalf("タイムゾーンの変換に失敗: %v", err)
+	}
+	if got["datetime"] != "2025-06-30T20:30:00-04:00" {
+		t.Errorf("変換結果が正しくありません: %+v", got)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]any{"datetime": "明日の朝", "to_timezone": "UTC"}); err == nil {
+		t.Error("解釈できない日時はエラーを返すべきです")
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"datetime": "2025-07-01 09:30"}); err == nil {
+		t.Error("変換先のタイムゾーンがない場合はエラーを返すべきです")
+	}
+}
@@ -0,0 +1,385 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 回答に付けるボタンのカスタムIDです
+const (
+	answerComponentPrefix = "answer:"
+	answerRegenerateID    = answerComponentPrefix + "regenerate"
+	answerContinueID      = answerComponentPrefix + "continue"
+	answerStopID          = answerComponentPrefix + "stop"
+)
+
+// 回答の末尾に付ける補足です（回答の記録には含めません）
+const (
+	answerStoppedNotice   = "⏹️ *生成を停止しました*"
+	answerTruncatedNotice = "✂️ *最大トークン数に達したため、回答が途中で終了しました*"
+)
+
+// errGenerationNotFound は、停止する生成が見つからない場合のエラーです
+var errGenerationNotFound = errors.New("実行中の生成が見つかりません")
+
+// errNotRequester は、質問したユーザー以外がボタンを操作した場合のエラーです
+var errNotRequester = errors.New("質問したユーザー以外は操作できません")
+
+// answerGenerator は、メンションへの回答（previousAnswer が指定された場合はその続き）をストリーミングで生成します
+type answerGenerator func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error)
+
+// generation は、実行中の生成と、それを停止できるユーザーを表します
+type generation struct {
+	userID string
+	cancel context.CancelFunc
+}
+
+// generationRegistry は、実行中の生成を処理中メッセージのID単位で管理します
+type generationRegistry struct {
+	generations map[string]generation
+	mutex       sync.Mutex
+}
+
+// newGenerationRegistry は新しい generationRegistry を作成します
+func newGenerationRegistry() *generationRegistry {
+	return &generationRegistry{generations: make(map[string]generation)}
+}
+
+// register は、処理中メッセージに対応する生成を登録します
+func (r *generationRegistry) register(messageID, userID string, cancel context.CancelFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.generations[messageID] = generation{userID: userID, cancel: cancel}
+}
+
+// unregister は、完了した生成の登録を解除します
+func (r *generationRegistry) unregister(messageID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.generations, messageID)
+}
+
+// stop は、処理中メッセージに対応する生成を停止します（停止できるのは質問したユーザーのみです）
+func (r *generationRegistry) stop(messageID, userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	g, exists := r.generations[messageID]
+	if !exists {
+		return errGenerationNotFound
+	}
+	if g.userID != userID {
+		return errNotRequester
+	}
+
+	g.cancel()
+	delete(r.generations, messageID)
+	return nil
+}
+
+// AnswerController は、メンションへの回答のストリーミング表示と、回答に付けたボタンの操作を担当します
+// 回答メッセージには「再生成」「続きを生成」、生成中のメッセージには「停止」のボタンを付けます
+type AnswerController struct {
+	responseHandler *ResponseHandler
+	store           domain.AnswerStore
+	permissions     *application.PermissionApplicationService
+	rateLimiter     *application.RateLimiter
+	generations     *generationRegistry
+	generate        answerGenerator
+}
+
+// NewAnswerController は新しいAnswerControllerインスタンスを作成します
+// store が nil の場合は、回答に再生成・続きを生成のボタンを付けません
+// permissionService は、ボタンを操作したユーザーが現在もBotを利用できるかを判定するために使用します
+// rateLimiter は、再生成・続きを生成をメンションと同じレート制限で制限するために使用します（nil の場合は制限しません）
+func NewAnswerController(
+	mentionService *application.MentionApplicationService,
+	responseHandler *ResponseHandler,
+	store domain.AnswerStore,
+	permissionService *application.PermissionApplicationService,
+	rateLimiter *application.RateLimiter,
+) *AnswerController {
+	return &AnswerController{
+		responseHandler: responseHandler,
+		store:           store,
+		permissions:     permissionService,
+		rateLimiter:     rateLimiter,
+		generations:     newGenerationRegistry(),
+		generate: func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+			if previousAnswer != "" {
+				return mentionService.ContinueMentionStream(ctx, mention, previousAnswer, onChunk)
+			}
+			return mentionService.HandleMentionStream(ctx, mention, onChunk)
+		},
+	}
+}
+
+// stopComponents は、生成中のメッセージに付けるボタンを返します
+func stopComponents() []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "停止",
+					Style:    discordgo.DangerButton,
+					CustomID: answerStopID,
+					Emoji:    &discordgo.ComponentEmoji{Name: "⏹️"},
+				},
+			},
+		},
+	}
+}
+
+// answerComponents は、回答メッセージに付けるボタンを返します
+// canContinue が true の場合は「続きを生成」ボタンを含めます
+func answerComponents(canContinue bool) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "再生成",
+			Style:    discordgo.SecondaryButton,
+			CustomID: answerRegenerateID,
+			Emoji:    &discordgo.ComponentEmoji{Name: "🔄"},
+		},
+	}
+	if canContinue {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "続きを生成",
+			Style:    discordgo.PrimaryButton,
+			CustomID: answerContinueID,
+			Emoji:    &discordgo.ComponentEmoji{Name: "⏩"},
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// streamAnswer は、回答を生成して stream に表示し、回答メッセージにボタンを付けて記録します
+// previousAnswer が指定された場合は、その回答の続きを生成します
+func (c *AnswerController) streamAnswer(stream *StreamingResponse, mention domain.BotMention, previousAnswer string) {
+	// 停止ボタンで生成を中断できるよう、処理中メッセージに対応付けて登録
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.generations.register(stream.FirstMessageID(), mention.User.ID, cancel)
+	defer c.generations.unregister(stream.FirstMessageID())
+
+	// 停止ボタンの操作より先に生成が完了していた場合は、通常の回答として扱う
+	result, err := c.generate(ctx, mention, previousAnswer, stream.Append)
+	stopped := err != nil && errors.Is(ctx.Err(), context.Canceled)
+	if err != nil && !stopped {
+		log.Printf("メンション処理に失敗: %v", err)
+
+		// 途中まで表示した応答をエラーメッセージに置き換える
+		errorResponse := domain.NewErrorResponse(err, "text")
+		stream.Fail(c.responseHandler.formatUnifiedError(errorResponse))
+		return
+	}
+
+	var content, display string
+	var truncated bool
+	response := &domain.UnifiedResponse{}
+	if stopped {
+		log.Printf("ユーザーの操作により生成を停止しました: %s", stream.FirstMessageID())
+		content = stream.Received()
+		truncated = content != ""
+		display = joinNotice(content, answerStoppedNotice)
+	} else {
+		content = result.Content
+		truncated = result.Truncated
+		display = content
+		// 回答に続けて表示する思考の要約・引用元・コード実行で作成された画像
+		response = domain.NewTextResponse(content, mention.Content, result.Model)
+		response.Grounding = result.Grounding
+		response.Attachments = result.Attachments
+		response.Thoughts = result.Thoughts
+		if truncated {
+			display = joinNotice(content, answerTruncatedNotice)
+		}
+		if notice := fallbackNotice(result.FallbackFrom, result.Model); notice != "" {
+			display = joinNotice(display, notice)
+		}
+	}
+
+	// 最終的な応答で表示を確定し、回答メッセージにボタンを付ける
+	if c.store == nil {
+		stream.Finish(display, nil)
+		c.sendResponseExtras(stream, response)
+		return
+	}
+	messageID := stream.Finish(display, answerComponents(truncated))
+	c.sendResponseExtras(stream, response)
+
+	record := domain.AnswerRecord{
+		MessageID: messageID,
+		ChannelID: stream.ChannelID(),
+		Mention:   mention,
+		Content:   previousAnswer + content,
+		Truncated: truncated,
+		CreatedAt: time.Now(),
+	}
+	if err := c.store.SaveAnswer(context.Background(), record); err != nil {
+		log.Printf("回答の記録に失敗: %v", err)
+	}
+}
+
+// sendResponseExtras は、確定した回答の後ろに、思考の要約、Google 検索で参照した引用元の一覧、コード実行で作成された画像を送信します
+func (c *AnswerController) sendResponseExtras(stream *StreamingResponse, response *domain.UnifiedResponse) {
+	stream.SendEmbed(c.responseHandler.thoughtsEmbed(response.Thoughts))
+	stream.SendEmbed(c.responseHandler.citationEmbed(response.Grounding))
+	if response.HasAttachments() {
+		stream.SendFiles(c.responseHandler.imageFiles(response.Attachments))
+	}
+}
+
+// joinNotice は、回答の末尾に補足を付けます
+func joinNotice(content, notice string) string {
+	if content == "" {
+		return notice
+	}
+	return content + "\n\n" + notice
+}
+
+// HandleComponent は、回答に付けたボタンの操作を処理します
+func (c *AnswerController) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := interactionUserID(i)
+
+	switch customID := i.MessageComponentData().CustomID; customID {
+	case answerStopID:
+		c.handleStop(s, i, userID)
+	case answerRegenerateID:
+		c.handleRegenerate(s, i, userID, false)
+	case answerContinueID:
+		c.handleRegenerate(s, i, userID, true)
+	default:
+		log.Printf("未知の回答ボタン: %s", customID)
+	}
+}
+
+// handleStop は、停止ボタンの操作を処理します
+func (c *AnswerController) handleStop(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
+	err := c.generations.stop(i.Message.ID, userID)
+	switch {
+	case errors.Is(err, errNotRequester):
+		c.respondEphemeral(s, i, "🚫 生成を停止できるのは質問したユーザーのみです。")
+		return
+	case err != nil:
+		c.respondEphemeral(s, i, "⚠️ 停止できる生成がありません（すでに完了している可能性があります）。")
+		return
+	}
+
+	// 表示の更新は生成側で行うため、ここでは操作の受け付けのみを応答する
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		log.Printf("インタラクションへの応答に失敗: %v", err)
+	}
+}
+
+// handleRegenerate は、再生成・続きを生成ボタンの操作を処理します
+// 元のリクエストを回答の記録から復元し、押されたメッセージへのリプライとして新しい回答を生成します
+func (c *AnswerController) handleRegenerate(s *discordgo.Session, i *discordgo.InteractionCreate, userID string, continueAnswer bool) {
+	if c.store == nil {
+		c.respondEphemeral(s, i, "⚠️ 回答の記録が無効になっているため、この操作は利用できません。")
+		return
+	}
+
+	record, err := c.store.GetAnswer(context.Background(), i.Message.ID)
+	if errors.Is(err, domain.ErrAnswerNotFound) {
+		c.respondEphemeral(s, i, "⚠️ 元のリクエストが見つからないため、この回答は再生成できません。")
+		return
+	}
+	if err != nil {
+		log.Printf("回答の記録の取得に失敗: %v", err)
+		c.respondEphemeral(s, i, "❌ 元のリクエストの取得に失敗しました。")
+		return
+	}
+
+	if record.Mention.User.ID != userID {
+		c.respondEphemeral(s, i, "🚫 この操作は質問したユーザーのみ実行できます。")
+		return
+	}
+
+	// 回答した後に拒否リストに追加されたユーザーやチャンネルでは、生成し直さない
+	if !interactionHasPermission(s, i, c.permissions, domain.PermissionUseBot) {
+		log.Printf("権限がないため回答の再生成を拒否します: ユーザー %s, チャンネル %s", userID, i.ChannelID)
+		c.respondEphemeral(s, i, "🚫 Botを利用する権限がありません。")
+		return
+	}
+
+	if continueAnswer && !record.Truncated {
+		c.respondEphemeral(s, i, "⚠️ この回答は最後まで生成されています。")
+		return
+	}
+
+	// 再生成・続きを生成も新しい生成のため、ボタンを押したユーザー・チャンネル・ギルドのレート制限を確認する
+	if err := c.rateLimiter.Allow(context.Background(), application.RateLimitRequest{
+		Kind:      domain.RequestKindText,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		UserID:    userID,
+	}); err != nil {
+		log.Printf("レート制限により回答の再生成を拒否します: ユーザー %s, チャンネル %s: %v", userID, i.ChannelID, err)
+		c.respondEphemeral(s, i, c.responseHandler.formatError(err))
+		return
+	}
+
+	previousAnswer := ""
+	response := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	if continueAnswer {
+		previousAnswer = record.Content
+
+		// 同じ回答の続きが重複して生成されないよう、押されたメッセージから「続きを生成」ボタンを取り除く
+		response = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    i.Message.Content,
+				Components: answerComponents(false),
+			},
+		}
+	}
+
+	if err := s.InteractionRespond(i.Interaction, response); err != nil {
+		log.Printf("インタラクションへの応答に失敗: %v", err)
+		return
+	}
+
+	stream, err := c.responseHandler.StartStreamingReply(s, i.ChannelID, i.Message.ID, i.GuildID, stopComponents())
+	if err != nil {
+		log.Printf("ストリーミング応答の開始に失敗: %v", err)
+		return
+	}
+
+	go c.streamAnswer(stream, record.Mention, previousAnswer)
+}
+
+// respondEphemeral は、操作したユーザーにのみ表示されるメッセージで応答します
+func (c *AnswerController) respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("インタラクションへの応答に失敗: %v", err)
+	}
+}
+
+// interactionUserID は、インタラクションを実行したユーザーのIDを返します
+// サーバー内では Member、DMでは User にユーザー情報が設定されます
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
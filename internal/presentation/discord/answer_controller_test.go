@@ -0,0 +1,206 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+	discordInfra "geminibot/internal/infrastructure/discord"
+)
+
+// newTestAnswerController は、生成処理を generate に置き換えた AnswerController を作成します
+func newTestAnswerController(generate answerGenerator) (*AnswerController, *discordInfra.AnswerStore) {
+	store := discordInfra.NewAnswerStore(0)
+	controller := NewAnswerController(nil, NewResponseHandler(), store, application.NewPermissionApplicationService(nil), nil)
+	controller.generate = generate
+	return controller, store
+}
+
+func startStopStream(t *testing.T) (*StreamingResponse, *fakeStreamMessenger) {
+	t.Helper()
+	messenger := newFakeStreamMessenger()
+	placeholderID, _ := messenger.Send("🤔 考え中...", stopComponents())
+	return newStreamingResponse(messenger, placeholderID, 0), messenger
+}
+
+var testMention = domain.BotMention{
+	ChannelID: "channel1",
+	User:      domain.User{ID: "user1"},
+	Content:   "質問",
+	MessageID: "mention1",
+}
+
+func TestAnswerController_StreamAnswerRecordsAnswer(t *testing.T) {
+	controller, store := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		onChunk("回答")
+		return &application.TextGenerationResult{Content: "回答"}, nil
+	})
+	stream, messenger := startStopStream(t)
+
+	controller.streamAnswer(stream, testMention, "")
+
+	if ids := messenger.customIDs("msg1"); len(ids) != 1 || ids[0] != answerRegenerateID {
+		t.Errorf("最後まで生成された回答には再生成ボタンのみを付けるべきです: %v", ids)
+	}
+
+	record, err := store.GetAnswer(context.Background(), "msg1")
+	if err != nil {
+		t.Fatalf("回答が記録されていません: %v", err)
+	}
+	if record.Mention.MessageID != "mention1" || record.Content != "回答" || record.Truncated || record.ChannelID != "channel1" {
+		t.Errorf("回答の記録が正しくありません: %+v", record)
+	}
+}
+
+func TestAnswerController_StreamAnswerTruncated(t *testing.T) {
+	controller, store := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		return &application.TextGenerationResult{Content: "続き", Truncated: true}, nil
+	})
+	stream, messenger := startStopStream(t)
+
+	controller.streamAnswer(stream, testMention, "前半、")
+
+	if ids := messenger.customIDs("msg1"); len(ids) != 2 || ids[1] != answerContinueID {
+		t.Errorf("途中で終了した回答には続きを生成ボタンを付けるべきです: %v", ids)
+	}
+	if visible := messenger.visible(); visible[0] != "続き\n\n"+answerTruncatedNotice {
+		t.Errorf("途中で終了したことが表示されていません: %q", visible)
+	}
+
+	record, _ := store.GetAnswer(context.Background(), "msg1")
+	if record.Content != "前半、続き" || !record.Truncated {
+		t.Errorf("続きを生成した場合はそれまでの回答全体を記録するべきです: %+v", record)
+	}
+}
+
+func TestAnswerController_StreamAnswerFallbackNotice(t *testing.T) {
+	controller, store := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		return &application.TextGenerationResult{Content: "回答", Model: "gemini-2.5-flash", FallbackFrom: "gemini-2.5-pro"}, nil
+	})
+	stream, messenger := startStopStream(t)
+
+	controller.streamAnswer(stream, testMention, "")
+
+	want := "回答\n\n" + fallbackNotice("gemini-2.5-pro", "gemini-2.5-flash")
+	if visible := messenger.visible(); visible[0] != want {
+		t.Errorf("回答したモデルが表示されていません: %q", visible)
+	}
+
+	// 補足は表示のみで、回答の記録には含めない
+	if record, _ := store.GetAnswer(context.Background(), "msg1"); record.Content != "回答" {
+		t.Errorf("回答の記録に補足を含めるべきではありません: %+v", record)
+	}
+}
+
+func TestAnswerController_StopGeneration(t *testing.T) {
+	stream, messenger := startStopStream(t)
+	var controller *AnswerController
+	controller, store := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		onChunk("途中まで")
+
+		if err := controller.generations.stop("msg1", "other"); !errors.Is(err, errNotRequester) {
+			t.Errorf("質問したユーザー以外は停止できないべきです: %v", err)
+		}
+		if err := controller.generations.stop("msg1", "user1"); err != nil {
+			t.Errorf("生成の停止に失敗: %v", err)
+		}
+
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	controller.streamAnswer(stream, testMention, "")
+
+	if visible := messenger.visible(); len(visible) != 1 || visible[0] != "途中まで\n\n"+answerStoppedNotice {
+		t.Errorf("停止した時点までの回答が表示されていません: %q", visible)
+	}
+	record, err := store.GetAnswer(context.Background(), "msg1")
+	if err != nil || record.Content != "途中まで" || !record.Truncated {
+		t.Errorf("停止した回答は続きを生成できるよう記録するべきです: %+v, %v", record, err)
+	}
+	if err := controller.generations.stop("msg1", "user1"); !errors.Is(err, errGenerationNotFound) {
+		t.Errorf("完了した生成の登録は解除されるべきです: %v", err)
+	}
+}
+
+func TestAnswerController_StreamAnswerError(t *testing.T) {
+	controller, store := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		return nil, errors.New("APIエラー")
+	})
+	stream, messenger := startStopStream(t)
+
+	controller.streamAnswer(stream, testMention, "")
+
+	if ids := messenger.customIDs("msg1"); len(ids) != 0 {
+		t.Errorf("エラー時は停止ボタンを取り除くべきです: %v", ids)
+	}
+	if _, err := store.GetAnswer(context.Background(), "msg1"); !errors.Is(err, domain.ErrAnswerNotFound) {
+		t.Errorf("エラー時は回答を記録するべきではありません: %v", err)
+	}
+}
+
+func TestAnswerController_StreamAnswerSendsCitations(t *testing.T) {
+	controller, _ := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		return &application.TextGenerationResult{
+			Content: "回答[1]",
+			Grounding: &domain.Grounding{
+				SearchQueries: []string{"検索語"},
+				Sources:       []domain.GroundingSource{{Title: "example.com", URI: "https://example.com"}},
+			},
+		}, nil
+	})
+	stream, messenger := startStopStream(t)
+
+	controller.streamAnswer(stream, testMention, "")
+
+	if len(messenger.embeds) != 1 {
+		t.Fatalf("情報源の埋め込みが1件送信されるべきです: %d件", len(messenger.embeds))
+	}
+	for _, embed := range messenger.embeds {
+		if !strings.Contains(embed.Description, "[example.com](https://example.com)") {
+			t.Errorf("情報源へのリンクが含まれていません: %q", embed.Description)
+		}
+	}
+}
+
+func TestAnswerController_StreamAnswerSendsThoughts(t *testing.T) {
+	controller, _ := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		return &application.TextGenerationResult{Content: "回答", Thoughts: "質問の意図を整理する"}, nil
+	})
+	stream, messenger := startStopStream(t)
+
+	controller.streamAnswer(stream, testMention, "")
+
+	if len(messenger.embeds) != 1 {
+		t.Fatalf("思考の要約の埋め込みが1件送信されるべきです: %d件", len(messenger.embeds))
+	}
+	for _, embed := range messenger.embeds {
+		if embed.Description != "||質問の意図を整理する||" {
+			t.Errorf("思考の要約がスポイラーで送信されていません: %q", embed.Description)
+		}
+	}
+}
+
+func TestAnswerController_StreamAnswerSendsCodeExecutionImages(t *testing.T) {
+	controller, _ := newTestAnswerController(func(ctx context.Context, mention domain.BotMention, previousAnswer string, onChunk application.StreamCallback) (*application.TextGenerationResult, error) {
+		return &application.TextGenerationResult{
+			Content:     "グラフを作成しました。",
+			Attachments: []domain.Attachment{{Data: []byte("png"), MimeType: "image/png", IsImage: true}},
+		}, nil
+	})
+	stream, messenger := startStopStream(t)
+
+	controller.streamAnswer(stream, testMention, "")
+
+	if len(messenger.files) != 1 {
+		t.Fatalf("作成された画像が1件のメッセージで送信されるべきです: %d件", len(messenger.files))
+	}
+	for _, files := range messenger.files {
+		if len(files) != 1 || files[0].Name != "attachment_1.png" || files[0].ContentType != "image/png" {
+			t.Errorf("送信したファイルが正しくありません: %+v", files)
+		}
+	}
+}
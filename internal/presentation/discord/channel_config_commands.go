@@ -0,0 +1,298 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// showChannelPromptLimit は、/channel-config showで表示するシステムプロンプトの最大文字数です
+const showChannelPromptLimit = 200
+
+// channelConfigCommand は、チャンネル単位の設定を管理するスラッシュコマンドの定義を返します
+func channelConfigCommand() *discordgo.ApplicationCommand {
+	channelOption := &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionChannel,
+		Name:         "channel",
+		Description:  "対象のチャンネル（省略するとこのチャンネル）",
+		Required:     false,
+		ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews, discordgo.ChannelTypeGuildForum},
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:        "channel-config",
+		Description: "チャンネル単位の設定（モデル・システムプロンプト・履歴の件数など）を管理します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "このチャンネルの設定を変更します（指定した項目のみ上書きします）",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "model",
+						Description: "このチャンネルで使用するAIモデル",
+						Required:    false,
+						Choices: func() []*discordgo.ApplicationCommandOptionChoice {
+							models := config.GeminiTextModelChoices()
+							out := make([]*discordgo.ApplicationCommandOptionChoice, len(models))
+							for i, m := range models {
+								out[i] = &discordgo.ApplicationCommandOptionChoice{Name: m.DisplayName, Value: m.ModelID}
+							}
+							return out
+						}(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "prompt",
+						Description: "このチャンネルで使用するシステムプロンプト",
+						Required:    false,
+						MaxLength:   domain.MaxGuildSystemPromptLength,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "history",
+						Description: fmt.Sprintf("会話履歴として取得するメッセージ数（1〜%d）", domain.MaxChannelHistoryLength),
+						Required:    false,
+						MinValue:    func() *float64 { v := 1.0; return &v }(),
+						MaxValue:    domain.MaxChannelHistoryLength,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "image-generation",
+						Description: "画像生成・編集を許可するかどうか",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reply-style",
+						Description: "メンションへの応答方法",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: domain.ReplyStyleThread.DisplayName(), Value: string(domain.ReplyStyleThread)},
+							{Name: domain.ReplyStyleReply.DisplayName(), Value: string(domain.ReplyStyleReply)},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "search",
+						Description: "Google 検索で最新の情報を調べ、情報源付きで回答するかどうか",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "code-execution",
+						Description: "計算やデータ処理の質問で、モデルがPythonのコードを実行して回答するかどうか",
+						Required:    false,
+					},
+					channelOption,
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "このチャンネルで適用される設定を表示します",
+				Options:     []*discordgo.ApplicationCommandOption{channelOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset",
+				Description: "このチャンネルの設定を削除し、サーバーの設定に戻します",
+				Options:     []*discordgo.ApplicationCommandOption{channelOption},
+			},
+		},
+	}
+}
+
+// handleChannelConfigCommand は、/channel-configコマンドを処理します
+func (h *SlashCommandHandler) handleChannelConfigCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		h.respondToInteraction(s, i, "❌ サブコマンドが指定されていません。", true)
+		return
+	}
+	if i.GuildID == "" {
+		h.respondToInteraction(s, i, "❌ このコマンドはサーバー内でのみ使用できます。", true)
+		return
+	}
+
+	subcommand := options[0]
+	update := channelConfigUpdate(subcommand.Options)
+	if update.ChannelID == "" {
+		update.ChannelID = settingsChannelID(s, i.ChannelID)
+	}
+	update.GuildID = i.GuildID
+
+	switch subcommand.Name {
+	case "set":
+		h.handleChannelConfigSet(s, i, update)
+	case "show":
+		h.handleChannelConfigShow(s, i, update.ChannelID)
+	case "reset":
+		h.handleChannelConfigReset(s, i, update.ChannelID)
+	default:
+		log.Printf("未知のサブコマンド: channel-config %s", subcommand.Name)
+	}
+}
+
+// handleChannelConfigSet は、/channel-config setを処理します
+func (h *SlashCommandHandler) handleChannelConfigSet(s *discordgo.Session, i *discordgo.InteractionCreate, update domain.ChannelConfig) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	update.UpdatedBy = i.Member.User.Username
+	updated, err := h.channelConfigService.UpdateChannelConfig(context.Background(), update)
+	if err != nil {
+		log.Printf("チャンネル設定の変更に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ チャンネル設定の変更に失敗しました: %v", err), true)
+		return
+	}
+
+	settings := h.channelConfigService.ResolveSettings(context.Background(), i.GuildID, updated.ChannelID)
+	message := fmt.Sprintf("✅ <#%s> の設定を変更しました。\n設定者: %s\n\n%s",
+		updated.ChannelID, updated.UpdatedBy, formatChannelSettings(updated, settings))
+	h.respondToInteraction(s, i, message, false)
+}
+
+// handleChannelConfigShow は、/channel-config showを処理します（実行したユーザーにのみ表示します）
+func (h *SlashCommandHandler) handleChannelConfigShow(s *discordgo.Session, i *discordgo.InteractionCreate, channelID string) {
+	channelConfig, err := h.channelConfigService.GetChannelConfig(context.Background(), channelID)
+	if err != nil {
+		log.Printf("チャンネル設定の取得に失敗: %v", err)
+		h.respondToInteraction(s, i, "❌ チャンネル設定の取得に失敗しました。", true)
+		return
+	}
+
+	settings := h.channelConfigService.ResolveSettings(context.Background(), i.GuildID, channelID)
+	message := fmt.Sprintf("📊 **<#%s> で適用される設定**\n\n%s", channelID, formatChannelSettings(channelConfig, settings))
+	h.respondToInteraction(s, i, message, true)
+}
+
+// handleChannelConfigReset は、/channel-config resetを処理します
+func (h *SlashCommandHandler) handleChannelConfigReset(s *discordgo.Session, i *discordgo.InteractionCreate, channelID string) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	if err := h.channelConfigService.ResetChannelConfig(context.Background(), channelID); err != nil {
+		log.Printf("チャンネル設定の削除に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ チャンネル設定の削除に失敗しました: %v", err), true)
+		return
+	}
+
+	h.respondToInteraction(s, i, fmt.Sprintf("✅ <#%s> の設定を削除しました。\n今後はサーバーの設定を使用します。", channelID), false)
+}
+
+// channelConfigUpdate は、サブコマンドのオプションからチャンネル設定の変更内容を作成します
+// channel オプションが指定された場合は、そのチャンネルを対象とします
+func channelConfigUpdate(options []*discordgo.ApplicationCommandInteractionDataOption) domain.ChannelConfig {
+	var update domain.ChannelConfig
+	for _, option := range options {
+		switch option.Name {
+		case "model":
+			update.Model = option.StringValue()
+		case "prompt":
+			update.SystemPrompt = option.StringValue()
+		case "history":
+			update.HistoryLength = int(option.IntValue())
+		case "image-generation":
+			enabled := option.BoolValue()
+			update.ImageGeneration = &enabled
+		case "reply-style":
+			update.ReplyStyle = domain.ReplyStyle(option.StringValue())
+		case "search":
+			enabled := option.BoolValue()
+			update.SearchGrounding = &enabled
+		case "code-execution":
+			enabled := option.BoolValue()
+			update.CodeExecution = &enabled
+		case "channel":
+			if channelID, ok := option.Value.(string); ok {
+				update.ChannelID = channelID
+			}
+		}
+	}
+	return update
+}
+
+// formatChannelSettings は、適用される設定を、チャンネルで上書きしている項目がわかる形式で整形します
+func formatChannelSettings(channelConfig domain.ChannelConfig, settings domain.ChannelSettings) string {
+	source := func(overridden bool) string {
+		if overridden {
+			return "（チャンネル設定）"
+		}
+		return "（サーバー設定／既定）"
+	}
+
+	model := settings.Model
+	if model == "" {
+		model = "既定のモデル"
+	}
+
+	history := fmt.Sprintf("%d件", settings.HistoryLength)
+	if settings.HistoryLength <= 0 {
+		history = fmt.Sprintf("既定（直近%d件、スレッドは全件）", domain.DefaultChannelHistoryLength)
+	}
+
+	imageGeneration := "許可"
+	if !settings.ImageGeneration {
+		imageGeneration = "禁止"
+	}
+
+	searchGrounding := "無効"
+	if settings.SearchGrounding {
+		searchGrounding = "有効"
+	}
+
+	codeExecution := "無効"
+	if settings.CodeExecution {
+		codeExecution = "有効"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🤖 **モデル**: %s%s\n", model, source(channelConfig.Model != ""))
+	fmt.Fprintf(&b, "📜 **会話履歴**: %s%s\n", history, source(channelConfig.HistoryLength > 0))
+	fmt.Fprintf(&b, "🎨 **画像生成**: %s%s\n", imageGeneration, source(channelConfig.ImageGeneration != nil))
+	fmt.Fprintf(&b, "💬 **応答方法**: %s%s\n", settings.ReplyStyle.DisplayName(), source(channelConfig.ReplyStyle != ""))
+	fmt.Fprintf(&b, "🔎 **Google 検索**: %s%s\n", searchGrounding, source(channelConfig.SearchGrounding != nil))
+	fmt.Fprintf(&b, "🧮 **コード実行**: %s%s\n", codeExecution, source(channelConfig.CodeExecution != nil))
+	fmt.Fprintf(&b, "📝 **システムプロンプト**: %d文字%s", len([]rune(settings.SystemPrompt)), source(channelConfig.SystemPrompt != ""))
+	if settings.SystemPrompt != "" {
+		fmt.Fprintf(&b, "\n```\n%s\n```", truncateRunes(settings.SystemPrompt, showChannelPromptLimit))
+	}
+	return b.String()
+}
+
+// settingsChannelID は、チャンネル設定の解決に使用するチャンネルIDを返します
+// スレッドの場合は親チャンネルのIDを返し、チャンネル情報を取得できない場合は指定されたIDをそのまま返します
+func settingsChannelID(s *discordgo.Session, channelID string) string {
+	channel, err := lookupChannel(s, channelID)
+	if err != nil {
+		log.Printf("チャンネル情報の取得に失敗: %v", err)
+		return channelID
+	}
+	if channel.IsThread() && channel.ParentID != "" {
+		return channel.ParentID
+	}
+	return channelID
+}
+
+// lookupChannel は、チャンネル情報を取得します
+// まずキャッシュ（State）を参照し、見つからない場合のみDiscord APIから取得します
+func lookupChannel(s *discordgo.Session, channelID string) (*discordgo.Channel, error) {
+	if channel, err := s.State.Channel(channelID); err == nil {
+		return channel, nil
+	}
+	return s.Channel(channelID)
+}
@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestChannelConfigUpdate(t *testing.T) {
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "model", Type: discordgo.ApplicationCommandOptionString, Value: "gemini-2.5-flash-lite"},
+		{Name: "history", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(30)},
+		{Name: "image-generation", Type: discordgo.ApplicationCommandOptionBoolean, Value: false},
+		{Name: "reply-style", Type: discordgo.ApplicationCommandOptionString, Value: "reply"},
+		{Name: "search", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+		{Name: "code-execution", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+		{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "help"},
+	}
+
+	update := channelConfigUpdate(options)
+	if update.ChannelID != "help" || update.Model != "gemini-2.5-flash-lite" || update.HistoryLength != 30 || update.ReplyStyle != domain.ReplyStyleReply {
+		t.Errorf("オプションが変更内容に反映されていません: %+v", update)
+	}
+	if update.ImageGeneration == nil || *update.ImageGeneration {
+		t.Errorf("画像生成の無効化が反映されていません: %v", update.ImageGeneration)
+	}
+	if update.SearchGrounding == nil || !*update.SearchGrounding {
+		t.Errorf("Google 検索の有効化が反映されていません: %v", update.SearchGrounding)
+	}
+	if update.CodeExecution == nil || !*update.CodeExecution {
+		t.Errorf("コード実行の有効化が反映されていません: %v", update.CodeExecution)
+	}
+	if update.SystemPrompt != "" {
+		t.Errorf("未指定の項目は空であるべきです: %q", update.SystemPrompt)
+	}
+}
+
+func TestFormatChannelSettings(t *testing.T) {
+	channelConfig := domain.ChannelConfig{ChannelID: "help", Model: "gemini-2.5-flash-lite"}
+	settings := domain.ChannelSettings{
+		Model:           "gemini-2.5-flash-lite",
+		SystemPrompt:    "ギルドのプロンプト",
+		ImageGeneration: true,
+		ReplyStyle:      domain.ReplyStyleThread,
+	}
+
+	message := formatChannelSettings(channelConfig, settings)
+	if !strings.Contains(message, "gemini-2.5-flash-lite（チャンネル設定）") {
+		t.Errorf("チャンネルで上書きしたモデルが示されていません: %s", message)
+	}
+	if !strings.Contains(message, "スレッド（サーバー設定／既定）") {
+		t.Errorf("継承した応答方法が示されていません: %s", message)
+	}
+	if !strings.Contains(message, "既定（直近10件、スレッドは全件）") {
+		t.Errorf("既定の履歴件数が示されていません: %s", message)
+	}
+}
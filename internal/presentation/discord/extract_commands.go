@@ -0,0 +1,204 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// extractionEmbedColor は、抽出結果の埋め込みの色です
+	extractionEmbedColor = 0x5865f2
+	// maxExtractionFieldRunes は、抽出結果の埋め込みで1つのフィールドの値を表示する最大文字数です
+	maxExtractionFieldRunes = 200
+)
+
+// conversationExtractor は、会話から構造化データを抽出するサービスです
+type conversationExtractor interface {
+	Extract(ctx context.Context, request domain.ExtractionRequest) (*domain.ExtractionResult, error)
+}
+
+// extractCommand は、/extractコマンドの定義を返します
+func extractCommand() *discordgo.ApplicationCommand {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, preset := range domain.ExtractionPresets() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: preset.DisplayName, Value: preset.ID})
+	}
+	minCount := float64(1)
+
+	return &discordgo.ApplicationCommand{
+		Name:        "extract",
+		Description: "このチャンネルの直近の会話から、アクションアイテムなどをJSONとして抽出します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "preset",
+				Description: "抽出する内容",
+				Required:    true,
+				Choices:     choices,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "messages",
+				Description: fmt.Sprintf("抽出の対象とする直近のメッセージ数（省略時は%d件）", domain.DefaultExtractionMessageCount),
+				Required:    false,
+				MinValue:    &minCount,
+				MaxValue:    domain.MaxChannelHistoryLength,
+			},
+		},
+	}
+}
+
+// extractionRequest は、/extractのオプションから抽出のリクエストを作成します（プリセットが不明な場合は false を返します）
+func extractionRequest(options []*discordgo.ApplicationCommandInteractionDataOption) (domain.ExtractionRequest, bool) {
+	var request domain.ExtractionRequest
+	found := false
+	for _, option := range options {
+		switch option.Name {
+		case "preset":
+			request.Preset, found = domain.FindExtractionPreset(option.StringValue())
+		case "messages":
+			request.MessageCount = int(option.IntValue())
+		}
+	}
+	return request, found
+}
+
+// handleExtractCommand は、/extractコマンドを処理します
+// 抽出した項目を埋め込みで表示し、スキーマで検証したJSONをファイルとして添付します
+func (h *SlashCommandHandler) handleExtractCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if h.extractor == nil {
+		h.respondToInteraction(s, i, "⚠️ このコマンドは現在利用できません。", true)
+		return
+	}
+	if !h.hasPermission(s, i, domain.PermissionUseBot) {
+		h.respondToInteraction(s, i, "🚫 Botを利用する権限がありません。", true)
+		return
+	}
+
+	request, ok := extractionRequest(i.ApplicationCommandData().Options)
+	if !ok {
+		h.respondToInteraction(s, i, "❌ 抽出する内容が正しく指定されていません。", true)
+		return
+	}
+	request.GuildID = i.GuildID
+	request.ChannelID = i.ChannelID
+	request.UserID = interactionUserID(i)
+
+	// ユーザー・チャンネル・ギルドごとのレート制限を確認（利用上限は抽出時に確認する）
+	if err := h.rateLimiter.Allow(context.Background(), application.RateLimitRequest{
+		Kind:      domain.RequestKindText,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		UserID:    request.UserID,
+	}); err != nil {
+		log.Printf("レート制限により抽出コマンドを拒否します: %v", err)
+		message, ok := formatKnownError(err)
+		if !ok {
+			message = fmt.Sprintf("❌ **エラーが発生しました**\n%s", err)
+		}
+		h.respondToInteraction(s, i, message, true)
+		return
+	}
+
+	// スレッド内で実行された場合は、設定の解決に使う親チャンネルIDを設定
+	if channel, err := lookupChannel(s, i.ChannelID); err != nil {
+		log.Printf("チャンネル情報の取得に失敗: %v", err)
+	} else if channel.IsThread() {
+		request.ParentChannelID = channel.ParentID
+	}
+
+	// 抽出には時間がかかるため、先に処理中であることを応答する
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		log.Printf("抽出コマンドの応答に失敗: %v", err)
+		return
+	}
+
+	result, err := h.extractor.Extract(context.Background(), request)
+	if err != nil {
+		log.Printf("会話からの抽出に失敗: %v", err)
+		message, ok := formatKnownError(err)
+		if !ok {
+			message = fmt.Sprintf("❌ 会話からの抽出に失敗しました: %v", err)
+		}
+		h.followUpInteraction(s, i, message, true)
+		return
+	}
+
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{extractionEmbed(result)},
+		Files: []*discordgo.File{{
+			Name:        result.Preset.ID + ".json",
+			ContentType: "application/json",
+			Reader:      bytes.NewReader(prettyJSON(result.JSON)),
+		}},
+	})
+	if err != nil {
+		log.Printf("抽出結果の送信に失敗: %v", err)
+		h.followUpInteraction(s, i, "❌ 抽出結果の送信に失敗しました。", true)
+	}
+}
+
+// extractionEmbed は、抽出した項目を番号付きの一覧にした埋め込みを作成します
+// 先頭のフィールドを項目の見出しとし、それ以外の空でないフィールドを見出しの下に表示します
+func extractionEmbed(result *domain.ExtractionResult) *discordgo.MessageEmbed {
+	preset := result.Preset
+
+	var description strings.Builder
+	for n, item := range result.Items {
+		var entry strings.Builder
+		for j, field := range preset.Fields {
+			value := strings.TrimSpace(item[field.Key])
+			if value == "" {
+				continue
+			}
+			value = truncateRunes(value, maxExtractionFieldRunes)
+			if j == 0 {
+				fmt.Fprintf(&entry, "**%d.** %s\n", n+1, value)
+				continue
+			}
+			fmt.Fprintf(&entry, "　%s: %s\n", field.Label, value)
+		}
+
+		// 表示しきれない項目は件数のみを表示する（すべての項目は添付のJSONで確認できる）
+		rest := fmt.Sprintf("ほか%d件", len(result.Items)-n)
+		if utf8.RuneCountInString(description.String())+utf8.RuneCountInString(entry.String())+utf8.RuneCountInString(rest) > embedDescriptionLimit {
+			description.WriteString(rest)
+			break
+		}
+		description.WriteString(entry.String())
+	}
+	if len(result.Items) == 0 {
+		description.WriteString("該当する項目は見つかりませんでした。")
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%s %s（%d件）", preset.Emoji, preset.DisplayName, len(result.Items)),
+		Description: strings.TrimSpace(description.String()),
+		Color:       extractionEmbedColor,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("直近%d件のメッセージから抽出 | モデル: %s", result.MessageCount, result.Model),
+		},
+	}
+}
+
+// prettyJSON は、添付ファイル用にJSONを整形します（整形できない場合はそのまま返します）
+func prettyJSON(data []byte) []byte {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return data
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
@@ -0,0 +1,83 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestExtractionRequest(t *testing.T) {
+	request, ok := extractionRequest([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "preset", Type: discordgo.ApplicationCommandOptionString, Value: "decisions"},
+		{Name: "messages", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(20)},
+	})
+	if !ok || request.Preset.ID != domain.ExtractionDecisions.ID || request.MessageCount != 20 {
+		t.Errorf("extractionRequest() = %+v, %v", request, ok)
+	}
+
+	if _, ok := extractionRequest([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "preset", Type: discordgo.ApplicationCommandOptionString, Value: "unknown"},
+	}); ok {
+		t.Error("不明なプリセットは受け付けないべきです")
+	}
+}
+
+func TestExtractionEmbed(t *testing.T) {
+	result := &domain.ExtractionResult{
+		Preset: domain.ExtractionActionItems,
+		Items: []map[string]string{
+			{"task": "議事録を共有する", "assignee": "Alice", "due": ""},
+			{"task": "見積もりを出す"},
+		},
+		MessageCount: 30,
+		Model:        "gemini-2.5-flash",
+	}
+
+	embed := extractionEmbed(result)
+	if embed.Title != "✅ アクションアイテム（2件）" {
+		t.Errorf("タイトル = %q", embed.Title)
+	}
+	want := "**1.** 議事録を共有する\n　担当者: Alice\n**2.** 見積もりを出す"
+	if embed.Description != want {
+		t.Errorf("説明 = %q, 期待値: %q", embed.Description, want)
+	}
+	if embed.Footer == nil || !strings.Contains(embed.Footer.Text, "直近30件") || !strings.Contains(embed.Footer.Text, "gemini-2.5-flash") {
+		t.Errorf("フッター = %+v", embed.Footer)
+	}
+
+	empty := extractionEmbed(&domain.ExtractionResult{Preset: domain.ExtractionDecisions})
+	if empty.Description != "該当する項目は見つかりませんでした。" {
+		t.Errorf("項目がない場合の説明 = %q", empty.Description)
+	}
+}
+
+func TestExtractionEmbed_TruncatesLongResults(t *testing.T) {
+	result := &domain.ExtractionResult{Preset: domain.ExtractionQAPairs}
+	for n := 0; n < 100; n++ {
+		result.Items = append(result.Items, map[string]string{
+			"question": fmt.Sprintf("質問%d %s", n, strings.Repeat("あ", 150)),
+			"answer":   strings.Repeat("い", 150),
+		})
+	}
+
+	embed := extractionEmbed(result)
+	if len([]rune(embed.Description)) > embedDescriptionLimit {
+		t.Errorf("説明の長さ %d が上限を超えています", len([]rune(embed.Description)))
+	}
+	if !strings.Contains(embed.Description, "ほか") {
+		t.Error("表示しきれない項目の件数を表示するべきです")
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	if got := string(prettyJSON([]byte(`{"a":[1]}`))); got != "{\n  \"a\": [\n    1\n  ]\n}\n" {
+		t.Errorf("prettyJSON() = %q", got)
+	}
+	if got := string(prettyJSON([]byte("not json"))); got != "not json" {
+		t.Errorf("整形できない場合はそのまま返すべきです: %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fallbackModelOptionNames は、/set-fallbackでフォールバックのモデルを指定するオプションの名前です（使用する順）
+var fallbackModelOptionNames = []string{"model-1", "model-2", "model-3"}
+
+// textModelOption は、サポートするテキストモデルから選ぶ文字列のオプションを作成します
+func textModelOption(name, description string) *discordgo.ApplicationCommandOption {
+	models := config.GeminiTextModelChoices()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(models))
+	for i, m := range models {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: m.DisplayName, Value: m.ModelID}
+	}
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        name,
+		Description: description,
+		Required:    false,
+		Choices:     choices,
+	}
+}
+
+// fallbackCommand は、/set-fallbackコマンドの定義を返します
+func fallbackCommand() *discordgo.ApplicationCommand {
+	options := make([]*discordgo.ApplicationCommandOption, 0, len(fallbackModelOptionNames)+1)
+	for i, name := range fallbackModelOptionNames {
+		options = append(options, textModelOption(name, fmt.Sprintf("%d番目に使用するモデル", i+1)))
+	}
+	options = append(options, &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionBoolean,
+		Name:        "reset",
+		Description: "フォールバックチェーンを全体の既定に戻すかどうか",
+		Required:    false,
+	})
+
+	return &discordgo.ApplicationCommand{
+		Name:        "set-fallback",
+		Description: "このサーバーのモデルが利用制限や過負荷などで使えないときに、代わりに使うモデルを設定します",
+		Options:     options,
+	}
+}
+
+// fallbackModelsFromOptions は、/set-fallbackのオプションで指定されたフォールバックチェーンを返します
+// reset を指定した場合は空のチェーンを返し、変更する項目が指定されなかった場合は false を返します
+func fallbackModelsFromOptions(options []*discordgo.ApplicationCommandInteractionDataOption) ([]string, bool) {
+	selected := make(map[string]string, len(options))
+	for _, option := range options {
+		if option.Name == "reset" {
+			if option.BoolValue() {
+				return nil, true
+			}
+			continue
+		}
+		selected[option.Name] = option.StringValue()
+	}
+
+	var models []string
+	for _, name := range fallbackModelOptionNames {
+		if model, ok := selected[name]; ok {
+			models = append(models, model)
+		}
+	}
+	return models, len(models) > 0
+}
+
+// fallbackSettingsLine は、ギルドのフォールバックチェーンを1行で表示します
+func fallbackSettingsLine(models []string) string {
+	if len(models) == 0 {
+		return "🔁 **フォールバック**: 既定"
+	}
+	return "🔁 **フォールバック**: " + domain.FormatModelChain(models)
+}
+
+// fallbackNotice は、フォールバックチェーンの別のモデルで回答した場合に、回答の末尾に付ける補足を返します（フォールバックしなかった場合は空文字）
+func fallbackNotice(from, model string) string {
+	if from == "" {
+		return ""
+	}
+	return fmt.Sprintf("-# 🔁 %s が利用できなかったため、%s で回答しました", from, model)
+}
+
+// handleSetFallbackCommand は、/set-fallbackコマンドを処理します
+// オプションを指定しなかった場合は、現在の設定を表示します
+func (h *SlashCommandHandler) handleSetFallbackCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	ctx := context.Background()
+	models, changed := fallbackModelsFromOptions(i.ApplicationCommandData().Options)
+	if !changed {
+		current, err := h.apiKeyService.GetGuildFallbackModels(ctx, i.GuildID)
+		if err != nil {
+			log.Printf("フォールバックチェーンの取得に失敗: %v", err)
+			h.respondToInteraction(s, i, "❌ フォールバックチェーンの取得に失敗しました。", true)
+			return
+		}
+		h.respondToInteraction(s, i, "📊 **このサーバーのフォールバックチェーン**\n"+fallbackSettingsLine(current), true)
+		return
+	}
+
+	if err := h.apiKeyService.SetGuildFallbackModels(ctx, i.GuildID, models); err != nil {
+		log.Printf("フォールバックチェーンの設定に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ フォールバックチェーンの設定に失敗しました: %v", err), true)
+		return
+	}
+
+	message := fmt.Sprintf("✅ このサーバーのフォールバックチェーンを更新しました。\n%s\n設定者: %s\n\n-# サーバーのモデルが利用制限・過負荷・安全フィルターで応答できなかった場合に、指定した順にモデルを切り替えて回答します。",
+		fallbackSettingsLine(models), i.Member.User.Username)
+	h.respondToInteraction(s, i, message, false)
+}
@@ -0,0 +1,48 @@
+package discord
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestFallbackModelsFromOptions(t *testing.T) {
+	if _, changed := fallbackModelsFromOptions(nil); changed {
+		t.Error("オプションがない場合は変更なしとするべきです")
+	}
+
+	// オプションの指定順にかかわらず、model-1 から順に並べる
+	models, changed := fallbackModelsFromOptions([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "model-3", Type: discordgo.ApplicationCommandOptionString, Value: "gemini-2.0-flash"},
+		{Name: "model-1", Type: discordgo.ApplicationCommandOptionString, Value: "gemini-2.5-flash"},
+	})
+	if want := []string{"gemini-2.5-flash", "gemini-2.0-flash"}; !changed || !reflect.DeepEqual(models, want) {
+		t.Errorf("fallbackModelsFromOptions() = %v, %v, 期待値: %v", models, changed, want)
+	}
+
+	// reset を指定した場合は、全体の既定に戻す
+	models, changed = fallbackModelsFromOptions([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "model-1", Type: discordgo.ApplicationCommandOptionString, Value: "gemini-2.5-flash"},
+		{Name: "reset", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+	})
+	if !changed || len(models) != 0 {
+		t.Errorf("reset を指定した場合は空のチェーンを返すべきです: %v, %v", models, changed)
+	}
+}
+
+func TestFallbackSettingsLine(t *testing.T) {
+	if line := fallbackSettingsLine(nil); !strings.Contains(line, "既定") {
+		t.Errorf("未設定の場合は既定と表示するべきです: %q", line)
+	}
+	if line := fallbackSettingsLine([]string{"gemini-2.5-flash", "gemini-2.0-flash"}); !strings.Contains(line, "gemini-2.5-flash → gemini-2.0-flash") {
+		t.Errorf("フォールバックチェーンが表示されていません: %q", line)
+	}
+	if notice := fallbackNotice("", "gemini-2.5-pro"); notice != "" {
+		t.Errorf("フォールバックしなかった場合は補足を付けるべきではありません: %q", notice)
+	}
+	if notice := fallbackNotice("gemini-2.5-pro", "gemini-2.5-flash"); !strings.HasPrefix(notice, "-# ") || !strings.Contains(notice, "gemini-2.5-flash で回答しました") {
+		t.Errorf("回答したモデルの補足が正しくありません: %q", notice)
+	}
+}
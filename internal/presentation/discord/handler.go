@@ -2,6 +2,7 @@ package discord
 
 import (
 	"geminibot/internal/application"
+	"geminibot/internal/domain"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -13,20 +14,32 @@ type DiscordHandler struct {
 	botID               string
 	mentionHandler      *MentionHandler
 	slashCommandHandler *SlashCommandHandler
+	answerController    *AnswerController
 }
 
 // NewDiscordHandler は新しいDiscordHandlerインスタンスを作成します
+// answerStore は、回答のボタン操作で元のリクエストを復元するための回答の記録です
+// permissionService は、メンションや回答のボタン操作を処理するかどうかをロール・チャンネルの権限で判定するために使用します
+// rateLimiter は、メンションと回答の再生成をユーザー・チャンネル・ギルドごとのレート制限で制限するために使用します（nil の場合は制限しません）
+// usageService は、画像生成の使用量の記録と利用上限の確認に使用します（nil の場合は行いません）
 func NewDiscordHandler(
 	session *discordgo.Session,
 	mentionService *application.MentionApplicationService,
 	botID string,
 	slashCommandHandler *SlashCommandHandler,
+	answerStore domain.AnswerStore,
+	permissionService *application.PermissionApplicationService,
+	rateLimiter *application.RateLimiter,
+	usageService *application.UsageApplicationService,
 ) *DiscordHandler {
 	// ResponseHandlerを作成
 	responseHandler := NewResponseHandler()
 
+	// 回答のストリーミング表示とボタン操作を担当するAnswerControllerを作成
+	answerController := NewAnswerController(mentionService, responseHandler, answerStore, permissionService, rateLimiter)
+
 	// MentionHandlerを作成
-	mentionHandler := NewMentionHandler(session, mentionService, botID, responseHandler)
+	mentionHandler := NewMentionHandler(session, mentionService, botID, responseHandler, answerController, permissionService, rateLimiter, usageService)
 
 	return &DiscordHandler{
 		session:             session,
@@ -34,6 +47,7 @@ func NewDiscordHandler(
 		botID:               botID,
 		mentionHandler:      mentionHandler,
 		slashCommandHandler: slashCommandHandler,
+		answerController:    answerController,
 	}
 }
 
@@ -42,8 +56,13 @@ func (h *DiscordHandler) SetupHandlers() {
 	// メンションハンドラーを設定
 	h.mentionHandler.SetupHandlers()
 
-	// スラッシュコマンドハンドラーを設定
+	// スラッシュコマンドハンドラーを設定（回答のボタン操作と/askの回答もAnswerControllerで処理する）
 	if h.slashCommandHandler != nil {
+		h.slashCommandHandler.RegisterComponentHandler(answerComponentPrefix, h.answerController.HandleComponent)
+		h.slashCommandHandler.setAnswerController(h.answerController)
+		if h.mentionService != nil {
+			h.slashCommandHandler.setExtractor(h.mentionService)
+		}
 		h.slashCommandHandler.SetupSlashCommandHandlers()
 	}
 }
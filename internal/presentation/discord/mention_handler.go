@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
+	"unicode"
 
 	"geminibot/internal/application"
 	"geminibot/internal/domain"
+	discordInfra "geminibot/internal/infrastructure/discord"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -19,6 +22,10 @@ type MentionHandler struct {
 	botID           string
 	botUsername     string
 	responseHandler *ResponseHandler
+	answers         *AnswerController
+	permissions     *application.PermissionApplicationService
+	rateLimiter     *application.RateLimiter
+	usageService    *application.UsageApplicationService
 }
 
 // NewMentionHandler は新しいMentionHandlerインスタンスを作成します
@@ -27,12 +34,20 @@ func NewMentionHandler(
 	mentionService *application.MentionApplicationService,
 	botID string,
 	responseHandler *ResponseHandler,
+	answers *AnswerController,
+	permissions *application.PermissionApplicationService,
+	rateLimiter *application.RateLimiter,
+	usageService *application.UsageApplicationService,
 ) *MentionHandler {
 	return &MentionHandler{
 		session:         session,
 		mentionService:  mentionService,
 		botID:           botID,
 		responseHandler: responseHandler,
+		answers:         answers,
+		permissions:     permissions,
+		rateLimiter:     rateLimiter,
+		usageService:    usageService,
 	}
 }
 
@@ -67,19 +82,69 @@ func (h *MentionHandler) handleMessageCreate(s *discordgo.Session, m *discordgo.
 
 	log.Printf("Botへのメンションを検出: %s", m.Content)
 
-	// 画像生成リクエストかどうかをチェック
-	if h.isImageGenerationRequest(m.Content) {
-		log.Printf("画像生成リクエストを検出: %s", m.Content)
-		// 非同期で画像生成を処理
-		go h.processImageGenerationAsync(s, m)
+	// メンション情報を作成し、チャンネル → ギルド → 全体の既定の順に設定を解決
+	mention := h.createBotMention(m)
+	settings := h.mentionService.ResolveSettings(context.Background(), mention)
+
+	// ロール・チャンネルの許可リストと拒否リストに従って、Botを利用できるかを判定
+	subject := h.permissionSubject(s, m, mention)
+	if !h.permissions.HasPermission(context.Background(), m.GuildID, domain.PermissionUseBot, subject) {
+		log.Printf("権限がないためメンションを無視します: ユーザー %s, チャンネル %s", m.Author.ID, m.ChannelID)
+		if err := s.MessageReactionAdd(m.ChannelID, m.ID, "🚫"); err != nil {
+			log.Printf("リアクションの追加に失敗: %v", err)
+		}
 		return
 	}
 
-	// メンション情報を作成
-	mention := h.createBotMention(m)
+	// 画像生成が禁止されたチャンネルや、画像生成の権限がないユーザーの画像に関するリクエストは通常の質問として扱う
+	if settings.ImageGeneration && h.permissions.HasPermission(context.Background(), m.GuildID, domain.PermissionGenerateImages, subject) {
+		// 添付画像または返信先の画像に対する編集リクエストかどうかをチェック
+		if h.isImageEditRequest(h.extractUserContent(m)) {
+			if sourceImages := h.collectSourceImages(s, m); len(sourceImages) > 0 {
+				log.Printf("画像編集リクエストを検出: %s（元画像: %d枚）", m.Content, len(sourceImages))
+				if h.allowRequest(s, m, domain.RequestKindImage, settings.ReplyStyle) {
+					// 非同期で画像編集を処理
+					go h.processImageGenerationAsync(s, m, sourceImages, settings.ReplyStyle)
+				}
+				return
+			}
+		}
+
+		// 画像生成リクエストかどうかをチェック（添付ファイルがある場合は添付内容への質問として扱う）
+		if len(m.Attachments) == 0 && h.isImageGenerationRequest(m.Content) {
+			log.Printf("画像生成リクエストを検出: %s", m.Content)
+			if h.allowRequest(s, m, domain.RequestKindImage, settings.ReplyStyle) {
+				// 非同期で画像生成を処理
+				go h.processImageGenerationAsync(s, m, nil, settings.ReplyStyle)
+			}
+			return
+		}
+	}
+
+	if !h.allowRequest(s, m, domain.RequestKindText, settings.ReplyStyle) {
+		return
+	}
 
 	// 非同期でメンションを処理
-	go h.processMentionAsync(s, m, mention)
+	go h.processMentionAsync(s, m, mention, settings.ReplyStyle)
+}
+
+// allowRequest は、ユーザー・チャンネル・ギルドごとのレート制限の枠が残っているかを判定します
+// 上限を超えている場合は、その旨をメンションへのリプライで伝えて false を返します
+func (h *MentionHandler) allowRequest(s *discordgo.Session, m *discordgo.MessageCreate, kind domain.RequestKind, style domain.ReplyStyle) bool {
+	err := h.rateLimiter.Allow(context.Background(), application.RateLimitRequest{
+		Kind:      kind,
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		UserID:    m.Author.ID,
+	})
+	if err == nil {
+		return true
+	}
+
+	log.Printf("レート制限によりメンションを拒否します: ユーザー %s, チャンネル %s: %v", m.Author.ID, m.ChannelID, err)
+	h.responseHandler.SendUnifiedResponse(s, m, domain.NewErrorResponse(err, string(kind)), style)
+	return false
 }
 
 // isMentioned は、メッセージがBotへのメンションかどうかを判定します
@@ -116,13 +181,53 @@ func (h *MentionHandler) createBotMention(m *discordgo.MessageCreate) domain.Bot
 		IsBot:         m.Author.Bot,
 	}
 
-	return domain.BotMention{
-		ChannelID: m.ChannelID,
-		GuildID:   m.GuildID,
-		User:      user,
-		Content:   content,
-		MessageID: m.ID,
+	mention := domain.BotMention{
+		ChannelID:   m.ChannelID,
+		GuildID:     m.GuildID,
+		User:        user,
+		Content:     content,
+		MessageID:   m.ID,
+		Attachments: discordInfra.ToDomainAttachments(m.Attachments),
 	}
+
+	// スレッド内のメンションであればスレッドIDと、設定の解決に使う親チャンネルIDを設定
+	channel, err := lookupChannel(h.session, m.ChannelID)
+	if err != nil {
+		log.Printf("チャンネル情報の取得に失敗: %v", err)
+	} else if channel.IsThread() {
+		mention.ThreadID = m.ChannelID
+		mention.ParentChannelID = channel.ParentID
+	}
+
+	// 同じチャンネル内のメッセージへの返信であれば、返信先を会話履歴で優先できるよう記録
+	if ref := m.MessageReference; ref != nil && ref.Type == discordgo.MessageReferenceTypeDefault &&
+		(ref.ChannelID == "" || ref.ChannelID == m.ChannelID) {
+		mention.ReferencedMessageID = ref.MessageID
+	}
+
+	return mention
+}
+
+// permissionSubject は、メンションしたメンバーのロールと、メンションが発生したチャンネルから権限の判定対象を作成します
+func (h *MentionHandler) permissionSubject(s *discordgo.Session, m *discordgo.MessageCreate, mention domain.BotMention) domain.PermissionSubject {
+	subject := domain.PermissionSubject{
+		RoleIDs:    memberRoleIDs(m.GuildID, m.Member),
+		ChannelIDs: []string{mention.ChannelID},
+	}
+	if mention.ParentChannelID != "" {
+		subject.ChannelIDs = append(subject.ChannelIDs, mention.ParentChannelID)
+	}
+
+	// メッセージのメンバー情報には権限が含まれないため、チャンネルでの権限を計算する
+	if m.GuildID != "" {
+		permissions, err := s.UserChannelPermissions(m.Author.ID, m.ChannelID)
+		if err != nil {
+			log.Printf("ユーザー %s の権限の取得に失敗: %v", m.Author.ID, err)
+		} else {
+			subject.IsAdministrator = permissions&discordgo.PermissionAdministrator != 0
+		}
+	}
+	return subject
 }
 
 // extractUserContent は、メンション部分を除去したユーザーのコンテンツを抽出します
@@ -160,38 +265,18 @@ func (h *MentionHandler) getDisplayName(m *discordgo.MessageCreate) string {
 	return m.Author.Username
 }
 
-// processMentionAsync は、メンションを非同期で処理します
-func (h *MentionHandler) processMentionAsync(s *discordgo.Session, m *discordgo.MessageCreate, mention domain.BotMention) {
-	// 処理中メッセージを送信
-	thinkingMsg, err := s.ChannelMessageSendReply(m.ChannelID, "🤔 考え中...", &discordgo.MessageReference{
-		MessageID: m.ID,
-		ChannelID: m.ChannelID,
-		GuildID:   m.GuildID,
-	})
+// processMentionAsync は、メンションを非同期で処理し、生成中の応答をストリーミングで表示します
+// style はスレッド外のメンションへの応答方法です
+func (h *MentionHandler) processMentionAsync(s *discordgo.Session, m *discordgo.MessageCreate, mention domain.BotMention, style domain.ReplyStyle) {
+	// 停止ボタン付きの処理中メッセージを送信（以降はこのメッセージを編集して応答を表示）
+	stream, err := h.responseHandler.StartStreamingResponse(s, m, mention, style, stopComponents())
 	if err != nil {
-		log.Printf("処理中メッセージの送信に失敗: %v", err)
+		log.Printf("ストリーミング応答の開始に失敗: %v", err)
 		return
 	}
 
-	// メンションを処理
-	ctx := context.Background()
-	response, err := h.mentionService.HandleMention(ctx, mention)
-
-	// 処理中メッセージを削除
-	s.ChannelMessageDelete(m.ChannelID, thinkingMsg.ID)
-
-	if err != nil {
-		log.Printf("メンション処理に失敗: %v", err)
-
-		// エラーレスポンスを作成
-		errorResponse := domain.NewErrorResponse(err, "text")
-		h.responseHandler.SendUnifiedResponse(s, m, errorResponse)
-		return
-	}
-
-	// テキストレスポンスを作成
-	textResponse := domain.NewTextResponse(response, mention.Content, "gemini-pro")
-	h.responseHandler.SendUnifiedResponse(s, m, textResponse)
+	// メンションを処理し、回答に再生成・続きを生成のボタンを付ける
+	h.answers.streamAnswer(stream, mention, "")
 }
 
 // isImageGenerationRequest は、メッセージが画像生成リクエストかどうかを判定します
@@ -212,10 +297,138 @@ func (h *MentionHandler) isImageGenerationRequest(content string) bool {
 	return false
 }
 
+// imageEditPrefixes は、画像の編集を明示するメッセージの先頭の語です
+var imageEditPrefixes = []string{"編集:", "編集：", "edit:"}
+
+// imageEditNouns は、編集の対象として画像やその一部を指す日本語の語です
+var imageEditNouns = []string{"画像", "写真", "イラスト", "絵", "背景"}
+
+// imageEditNounsEn は、編集の対象として画像やその一部を指す英語の語です
+var imageEditNounsEn = []string{"image", "images", "photo", "photos", "picture", "pictures", "pic", "background"}
+
+// imageEditVerbs は、画像の編集を依頼する日本語の動詞です
+var imageEditVerbs = []string{"編集して", "加工して", "変えて", "変更して", "にして", "消して", "削除して", "追加して", "差し替えて", "描き足して"}
+
+// imageEditVerbsEn は、画像の編集を指示する英語の動詞です（命令文の先頭にある場合のみ編集の指示とみなします）
+var imageEditVerbsEn = []string{"edit", "make", "change", "remove", "replace", "add", "turn"}
+
+// isImageEditRequest は、メッセージが画像の編集を明示的に指示するリクエストかどうかを判定します
+// 「編集:」などのプレフィックスで始まる場合か、編集を依頼する動詞と画像を指す語の両方を含む場合に限り、
+// 「この画像の背景は何？」のような添付画像への質問は通常の質問として扱います
+func (h *MentionHandler) isImageEditRequest(content string) bool {
+	content = strings.ToLower(strings.TrimSpace(content))
+
+	for _, prefix := range imageEditPrefixes {
+		if strings.HasPrefix(content, prefix) {
+			return true
+		}
+	}
+
+	if containsAny(content, imageEditVerbs) && containsAny(content, imageEditNouns) {
+		return true
+	}
+
+	// 英語は、編集の動詞で始まる命令文（please や can you などの前置きは除く）のみを対象にする
+	words := strings.FieldsFunc(content, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words = trimPoliteWords(words)
+	if len(words) == 0 || !slices.Contains(imageEditVerbsEn, words[0]) {
+		return false
+	}
+	for _, word := range words[1:] {
+		if slices.Contains(imageEditNounsEn, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimPoliteWords は、英語の依頼文の先頭にある please や can you などの前置きを取り除きます
+func trimPoliteWords(words []string) []string {
+	for len(words) > 0 {
+		switch {
+		case words[0] == "please" || words[0] == "pls":
+			words = words[1:]
+		case len(words) > 1 && (words[0] == "can" || words[0] == "could" || words[0] == "would") && words[1] == "you":
+			words = words[2:]
+		default:
+			return words
+		}
+	}
+	return words
+}
+
+// containsAny は、content に keywords のいずれかが含まれるかどうかを返します
+func containsAny(content string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(content, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSourceImages は、メンションに添付された画像と返信先のメッセージの画像を編集元として収集します
+func (h *MentionHandler) collectSourceImages(s *discordgo.Session, m *discordgo.MessageCreate) []domain.Attachment {
+	var sourceImages []domain.Attachment
+	for _, attachment := range discordInfra.ToDomainAttachments(m.Attachments) {
+		if attachment.IsSourceImage() {
+			sourceImages = append(sourceImages, attachment)
+		}
+	}
+
+	if referenced := h.referencedMessage(s, m); referenced != nil {
+		for _, attachment := range discordInfra.ToDomainAttachments(referenced.Attachments) {
+			if attachment.IsSourceImage() {
+				sourceImages = append(sourceImages, attachment)
+			}
+		}
+	}
+
+	return sourceImages
+}
+
+// referencedMessage は、メッセージの返信先を取得します（返信でない場合や取得できない場合は nil）
+func (h *MentionHandler) referencedMessage(s *discordgo.Session, m *discordgo.MessageCreate) *discordgo.Message {
+	if m.ReferencedMessage != nil {
+		return m.ReferencedMessage
+	}
+	if m.MessageReference == nil || m.MessageReference.MessageID == "" {
+		return nil
+	}
+
+	channelID := m.MessageReference.ChannelID
+	if channelID == "" {
+		channelID = m.ChannelID
+	}
+	referenced, err := s.ChannelMessage(channelID, m.MessageReference.MessageID)
+	if err != nil {
+		log.Printf("返信先のメッセージの取得に失敗: %v", err)
+		return nil
+	}
+	return referenced
+}
+
 // processImageGenerationAsync は、画像生成を非同期で処理します
-func (h *MentionHandler) processImageGenerationAsync(s *discordgo.Session, m *discordgo.MessageCreate) {
+// sourceImages が指定された場合は、それらの画像をプロンプトに従って編集します
+// style はスレッド外のメンションへの応答方法です
+func (h *MentionHandler) processImageGenerationAsync(s *discordgo.Session, m *discordgo.MessageCreate, sourceImages []domain.Attachment, style domain.ReplyStyle) {
+	thinkingText := "🎨 画像を生成中..."
+	if len(sourceImages) > 0 {
+		thinkingText = "🎨 画像を編集中..."
+	}
+
+	// ギルドの今月の利用上限を確認（画像生成は代わりのモデルに切り替えずに拒否する）
+	ctx := context.Background()
+	if _, err := h.usageService.CheckBudget(ctx, m.GuildID, domain.RequestKindImage); err != nil {
+		log.Printf("利用上限により画像生成を拒否します: %v", err)
+		h.responseHandler.SendUnifiedResponse(s, m, domain.NewErrorResponse(err, "image"), style)
+		return
+	}
+
 	// 処理中メッセージを送信
-	thinkingMsg, err := s.ChannelMessageSendReply(m.ChannelID, "🎨 画像を生成中...", &discordgo.MessageReference{
+	thinkingMsg, err := s.ChannelMessageSendReply(m.ChannelID, thinkingText, &discordgo.MessageReference{
 		MessageID: m.ID,
 		ChannelID: m.ChannelID,
 		GuildID:   m.GuildID,
@@ -226,8 +439,7 @@ func (h *MentionHandler) processImageGenerationAsync(s *discordgo.Session, m *di
 	}
 
 	// 画像生成を処理
-	ctx := context.Background()
-	imageResult, err := h.generateImage(ctx, m)
+	imageResult, err := h.generateImage(ctx, m, sourceImages)
 
 	// 処理中メッセージを削除
 	s.ChannelMessageDelete(m.ChannelID, thinkingMsg.ID)
@@ -236,17 +448,28 @@ func (h *MentionHandler) processImageGenerationAsync(s *discordgo.Session, m *di
 		log.Printf("画像生成に失敗: %v", err)
 		// エラーレスポンスを作成
 		errorResponse := domain.NewErrorResponse(err, "image")
-		h.responseHandler.SendUnifiedResponse(s, m, errorResponse)
+		h.responseHandler.SendUnifiedResponse(s, m, errorResponse, style)
 		return
 	}
 
+	if imageResult.Response != nil {
+		h.usageService.RecordUsage(ctx, domain.UsageRecord{
+			GuildID:   m.GuildID,
+			UserID:    m.Author.ID,
+			ChannelID: settingsChannelID(s, m.ChannelID),
+			Model:     imageResult.Response.Model,
+			Kind:      domain.RequestKindImage,
+			Usage:     imageResult.Response.Usage,
+		})
+	}
+
 	// 画像生成結果を統一レスポンスに変換
 	unifiedResponse := h.responseHandler.convertImageResultToUnifiedResponse(imageResult, m)
-	h.responseHandler.SendUnifiedResponse(s, m, unifiedResponse)
+	h.responseHandler.SendUnifiedResponse(s, m, unifiedResponse, style)
 }
 
 // generateImage は、画像生成を実行します
-func (h *MentionHandler) generateImage(ctx context.Context, m *discordgo.MessageCreate) (*domain.ImageGenerationResult, error) {
+func (h *MentionHandler) generateImage(ctx context.Context, m *discordgo.MessageCreate, sourceImages []domain.Attachment) (*domain.ImageGenerationResult, error) {
 	// メンション部分を除去したコンテンツを取得
 	content := h.extractUserContent(m)
 
@@ -255,12 +478,14 @@ func (h *MentionHandler) generateImage(ctx context.Context, m *discordgo.Message
 
 	// Geminiクライアントを使用して画像生成
 	response, err := h.mentionService.GenerateImage(ctx, domain.ImageGenerationRequest{
-		Prompt: prompt,
+		Prompt:       prompt,
+		SourceImages: sourceImages,
 	})
 	if err != nil {
 		return &domain.ImageGenerationResult{
 			Success: false,
 			Error:   err.Error(),
+			Err:     err,
 		}, nil
 	}
 
@@ -0,0 +1,40 @@
+package discord
+
+import "testing"
+
+func TestMentionHandler_IsImageEditRequest(t *testing.T) {
+	handler := &MentionHandler{}
+
+	// 編集を明示的に指示するリクエスト
+	editRequests := []string{
+		"この画像の背景を消して",
+		"写真を白黒にして",
+		"背景を青空に変えてください",
+		"編集: 明るくして",
+		"edit: add a hat",
+		"Make this image brighter",
+		"please remove the background",
+		"Can you change the photo to black and white?",
+	}
+	for _, content := range editRequests {
+		if !handler.isImageEditRequest(content) {
+			t.Errorf("画像の編集リクエストとして認識されるべき: %s", content)
+		}
+	}
+
+	// 添付画像への質問は、編集の語を含んでいても通常の質問（テキストの経路）として扱う
+	questions := []string{
+		"what does the background of this screenshot mean?",
+		"make sense of this chart",
+		"Why did the background change in this picture?",
+		"この画像の背景は何を表していますか？",
+		"この写真について詳しく説明して",
+		"このグラフを削除してもいいか教えて",
+		"このエラーの変更点をまとめて",
+	}
+	for _, content := range questions {
+		if handler.isImageEditRequest(content) {
+			t.Errorf("画像の編集リクエストとして認識されるべきではない: %s", content)
+		}
+	}
+}
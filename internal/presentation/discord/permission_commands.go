@@ -0,0 +1,271 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// permissionsCommand は、ギルドの権限ルールを管理するスラッシュコマンドの定義を返します
+func permissionsCommand() *discordgo.ApplicationCommand {
+	permissionChoices := func() []*discordgo.ApplicationCommandOptionChoice {
+		permissions := domain.AllPermissions()
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, len(permissions))
+		for i, permission := range permissions {
+			choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: permission.DisplayName(), Value: string(permission)}
+		}
+		return choices
+	}()
+
+	targetOptions := func() []*discordgo.ApplicationCommandOption {
+		return []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "permission",
+				Description: "対象の権限",
+				Required:    true,
+				Choices:     permissionChoices,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionRole,
+				Name:        "role",
+				Description: "対象のロール（ロールかチャンネルのどちらかを指定）",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "対象のチャンネル（Botの利用・画像生成の権限のみ）",
+				Required:    false,
+			},
+		}
+	}
+
+	grantOptions := append(targetOptions(), &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "mode",
+		Description: "許可リストに追加するか、拒否リストに追加するか（省略すると許可）",
+		Required:    false,
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "許可", Value: string(domain.PermissionAllow)},
+			{Name: "拒否", Value: string(domain.PermissionDeny)},
+		},
+	})
+
+	return &discordgo.ApplicationCommand{
+		Name:        "permissions",
+		Description: "ロール・チャンネルごとの権限を管理します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "grant",
+				Description: "ロールまたはチャンネルに権限を許可（または拒否）します",
+				Options:     grantOptions,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "revoke",
+				Description: "ロールまたはチャンネルに設定した権限のルールを削除します",
+				Options:     targetOptions(),
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "このサーバーの権限のルールを一覧表示します",
+			},
+		},
+	}
+}
+
+// handlePermissionsCommand は、/permissionsコマンドを処理します（管理者のみ実行できます）
+func (h *SlashCommandHandler) handlePermissionsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 権限チェック（管理者権限が必要）
+	if !h.hasAdminPermission(i.Member) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行するには管理者権限が必要です。", true)
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		h.respondToInteraction(s, i, "❌ サブコマンドが指定されていません。", true)
+		return
+	}
+
+	subcommand := options[0]
+	switch subcommand.Name {
+	case "grant":
+		h.handlePermissionsGrant(s, i, permissionRuleFromOptions(subcommand.Options))
+	case "revoke":
+		h.handlePermissionsRevoke(s, i, permissionRuleFromOptions(subcommand.Options))
+	case "list":
+		h.handlePermissionsList(s, i)
+	default:
+		log.Printf("未知のサブコマンド: permissions %s", subcommand.Name)
+	}
+}
+
+// handlePermissionsGrant は、/permissions grantを処理します
+func (h *SlashCommandHandler) handlePermissionsGrant(s *discordgo.Session, i *discordgo.InteractionCreate, rule domain.PermissionRule) {
+	if rule.TargetID == "" {
+		h.respondToInteraction(s, i, "❌ ロールまたはチャンネルのどちらかを指定してください。", true)
+		return
+	}
+
+	rule.GuildID = i.GuildID
+	rule.CreatedBy = i.Member.User.Username
+	if err := h.permissionService.GrantPermission(context.Background(), rule); err != nil {
+		log.Printf("権限ルールの設定に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ 権限の設定に失敗しました: %v", err), true)
+		return
+	}
+
+	h.respondToInteraction(s, i, fmt.Sprintf("✅ 権限のルールを設定しました。\n%s\n設定者: %s", formatPermissionRule(rule), rule.CreatedBy), false)
+}
+
+// handlePermissionsRevoke は、/permissions revokeを処理します
+func (h *SlashCommandHandler) handlePermissionsRevoke(s *discordgo.Session, i *discordgo.InteractionCreate, rule domain.PermissionRule) {
+	if rule.TargetID == "" {
+		h.respondToInteraction(s, i, "❌ ロールまたはチャンネルのどちらかを指定してください。", true)
+		return
+	}
+
+	err := h.permissionService.RevokePermission(context.Background(), i.GuildID, rule.Permission, rule.TargetType, rule.TargetID)
+	if errors.Is(err, domain.ErrPermissionRuleNotFound) {
+		h.respondToInteraction(s, i, "⚠️ 指定された権限のルールは設定されていません。", true)
+		return
+	}
+	if err != nil {
+		log.Printf("権限ルールの削除に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ 権限のルールの削除に失敗しました: %v", err), true)
+		return
+	}
+
+	h.respondToInteraction(s, i, fmt.Sprintf("✅ %s の「%s」のルールを削除しました。", formatPermissionTarget(rule), rule.Permission.DisplayName()), false)
+}
+
+// handlePermissionsList は、/permissions listを処理します（実行したユーザーにのみ表示します）
+func (h *SlashCommandHandler) handlePermissionsList(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	rules, err := h.permissionService.ListPermissions(context.Background(), i.GuildID)
+	if err != nil {
+		log.Printf("権限ルールの取得に失敗: %v", err)
+		h.respondToInteraction(s, i, "❌ 権限のルールの取得に失敗しました。", true)
+		return
+	}
+
+	h.respondToInteraction(s, i, formatPermissionRules(rules), true)
+}
+
+// hasPermission は、インタラクションを実行したメンバーが指定された権限を持っているかを判定します
+func (h *SlashCommandHandler) hasPermission(s *discordgo.Session, i *discordgo.InteractionCreate, permission domain.Permission) bool {
+	return interactionHasPermission(s, i, h.permissionService, permission)
+}
+
+// interactionHasPermission は、インタラクションを実行したメンバーが、インタラクションのチャンネルで指定された権限を持っているかを判定します
+// ギルド外（DM）のインタラクションでは、制限のない権限のみを許可します
+func interactionHasPermission(s *discordgo.Session, i *discordgo.InteractionCreate, permissionService *application.PermissionApplicationService, permission domain.Permission) bool {
+	if i.Member == nil {
+		return !permission.IsRestricted()
+	}
+
+	subject := domain.PermissionSubject{
+		IsAdministrator: i.Member.Permissions&discordgo.PermissionAdministrator != 0,
+		RoleIDs:         memberRoleIDs(i.GuildID, i.Member),
+		ChannelIDs:      permissionChannelIDs(s, i.ChannelID),
+	}
+	return permissionService.HasPermission(context.Background(), i.GuildID, permission, subject)
+}
+
+// permissionRuleFromOptions は、サブコマンドのオプションから権限ルールを作成します
+// ロールとチャンネルの両方が指定された場合はロールを対象とします
+func permissionRuleFromOptions(options []*discordgo.ApplicationCommandInteractionDataOption) domain.PermissionRule {
+	rule := domain.PermissionRule{Effect: domain.PermissionAllow}
+	var roleID, channelID string
+	for _, option := range options {
+		switch option.Name {
+		case "permission":
+			rule.Permission = domain.Permission(option.StringValue())
+		case "mode":
+			rule.Effect = domain.PermissionEffect(option.StringValue())
+		case "role":
+			roleID, _ = option.Value.(string)
+		case "channel":
+			channelID, _ = option.Value.(string)
+		}
+	}
+
+	switch {
+	case roleID != "":
+		rule.TargetType, rule.TargetID = domain.PermissionTargetRole, roleID
+	case channelID != "":
+		rule.TargetType, rule.TargetID = domain.PermissionTargetChannel, channelID
+	}
+	return rule
+}
+
+// formatPermissionRules は、権限のルールの一覧を表示用に整形します
+func formatPermissionRules(rules []domain.PermissionRule) string {
+	var b strings.Builder
+	b.WriteString("🔐 **権限の設定**\n")
+	for _, permission := range domain.AllPermissions() {
+		var lines []string
+		for _, rule := range rules {
+			if rule.Permission == permission {
+				lines = append(lines, "　"+formatPermissionRule(rule))
+			}
+		}
+
+		fmt.Fprintf(&b, "\n**%s**", permission.DisplayName())
+		if len(lines) == 0 {
+			if permission.IsRestricted() {
+				b.WriteString("\n　管理者のみ（既定）")
+			} else {
+				b.WriteString("\n　全員に許可（既定）")
+			}
+			continue
+		}
+		b.WriteString("\n" + strings.Join(lines, "\n"))
+	}
+	return b.String()
+}
+
+// formatPermissionRule は、権限のルールを1行で表示用に整形します
+func formatPermissionRule(rule domain.PermissionRule) string {
+	effect := "✅ 許可"
+	if rule.Effect == domain.PermissionDeny {
+		effect = "🚫 拒否"
+	}
+	return fmt.Sprintf("%s: %s（%s）", effect, formatPermissionTarget(rule), rule.Permission.DisplayName())
+}
+
+// formatPermissionTarget は、権限のルールの対象をDiscordのメンション形式で返します
+func formatPermissionTarget(rule domain.PermissionRule) string {
+	if rule.TargetType == domain.PermissionTargetChannel {
+		return fmt.Sprintf("<#%s>", rule.TargetID)
+	}
+	return fmt.Sprintf("<@&%s>", rule.TargetID)
+}
+
+// memberRoleIDs は、メンバーのロールIDを返します
+// すべてのメンバーが持つ @everyone ロール（IDはギルドIDと同じ）も含めます
+func memberRoleIDs(guildID string, member *discordgo.Member) []string {
+	roleIDs := []string{guildID}
+	if member != nil {
+		roleIDs = append(roleIDs, member.Roles...)
+	}
+	return roleIDs
+}
+
+// permissionChannelIDs は、権限の判定に使用するチャンネルIDを返します（スレッドの場合は親チャンネルも含めます）
+func permissionChannelIDs(s *discordgo.Session, channelID string) []string {
+	if parentID := settingsChannelID(s, channelID); parentID != channelID {
+		return []string{channelID, parentID}
+	}
+	return []string{channelID}
+}
@@ -0,0 +1,49 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPermissionRuleFromOptions(t *testing.T) {
+	rule := permissionRuleFromOptions([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "permission", Type: discordgo.ApplicationCommandOptionString, Value: "generate-images"},
+		{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "off-topic"},
+		{Name: "mode", Type: discordgo.ApplicationCommandOptionString, Value: "deny"},
+	})
+	want := domain.PermissionRule{
+		Permission: domain.PermissionGenerateImages,
+		TargetType: domain.PermissionTargetChannel,
+		TargetID:   "off-topic",
+		Effect:     domain.PermissionDeny,
+	}
+	if rule != want {
+		t.Errorf("permissionRuleFromOptions() = %+v, 期待値: %+v", rule, want)
+	}
+
+	// モードを省略した場合は許可ルールとして扱う
+	rule = permissionRuleFromOptions([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "permission", Type: discordgo.ApplicationCommandOptionString, Value: "manage-keys"},
+		{Name: "role", Type: discordgo.ApplicationCommandOptionRole, Value: "moderator"},
+	})
+	if rule.Effect != domain.PermissionAllow || rule.TargetType != domain.PermissionTargetRole || rule.TargetID != "moderator" {
+		t.Errorf("ロールへの許可ルールが作成されていません: %+v", rule)
+	}
+}
+
+func TestFormatPermissionRules(t *testing.T) {
+	message := formatPermissionRules([]domain.PermissionRule{
+		{Permission: domain.PermissionManageModels, TargetType: domain.PermissionTargetRole, TargetID: "moderator", Effect: domain.PermissionAllow},
+	})
+
+	if !strings.Contains(message, "✅ 許可: <@&moderator>（モデル・プロンプトの変更）") {
+		t.Errorf("設定したルールが表示されていません: %s", message)
+	}
+	if !strings.Contains(message, "管理者のみ（既定）") || !strings.Contains(message, "全員に許可（既定）") {
+		t.Errorf("ルールのない権限の既定値が表示されていません: %s", message)
+	}
+}
@@ -0,0 +1,169 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// システムプロンプト入力用モーダルのカスタムIDです
+const (
+	promptModalPrefix  = "set-prompt:"
+	promptModalID      = promptModalPrefix + "modal"
+	promptModalInputID = "prompt"
+)
+
+// showPromptLimit は、/show-promptで表示するシステムプロンプトの最大文字数です（メッセージの文字数制限に収めるため）
+const showPromptLimit = 1800
+
+// promptCommands は、ギルド固有のシステムプロンプトを管理するスラッシュコマンドの定義を返します
+func promptCommands() []*discordgo.ApplicationCommand {
+	return []*discordgo.ApplicationCommand{
+		{
+			Name:        "set-prompt",
+			Description: "このサーバーで使用するシステムプロンプト（Botの人格・指示）を設定します",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "prompt",
+					Description: "システムプロンプト（省略すると長文を入力できるフォームを開きます）",
+					Required:    false,
+					MaxLength:   domain.MaxGuildSystemPromptLength,
+				},
+			},
+		},
+		{
+			Name:        "reset-prompt",
+			Description: "このサーバーのシステムプロンプトを削除し、既定のプロンプトに戻します",
+		},
+		{
+			Name:        "show-prompt",
+			Description: "このサーバーで使用しているシステムプロンプトを表示します",
+		},
+	}
+}
+
+// handleSetPromptCommand は、/set-promptコマンドを処理します
+// プロンプトが指定されていない場合は、現在のプロンプトを入力済みのモーダルを表示します
+func (h *SlashCommandHandler) handleSetPromptCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		h.saveGuildSystemPrompt(s, i, options[0].StringValue())
+		return
+	}
+
+	current, err := h.apiKeyService.GetGuildSystemPrompt(context.Background(), i.GuildID)
+	if err != nil {
+		log.Printf("システムプロンプトの取得に失敗: %v", err)
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: promptModalID,
+			Title:    "システムプロンプトの設定",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    promptModalInputID,
+							Label:       "システムプロンプト",
+							Style:       discordgo.TextInputParagraph,
+							Placeholder: "例: あなたは厳格なコードレビュアーです。指摘は根拠と改善案を添えてください。",
+							Value:       current,
+							Required:    true,
+							MaxLength:   domain.MaxGuildSystemPromptLength,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("モーダルの表示に失敗: %v", err)
+	}
+}
+
+// handlePromptModalSubmit は、システムプロンプト入力用モーダルの送信を処理します
+func (h *SlashCommandHandler) handlePromptModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	h.saveGuildSystemPrompt(s, i, modalTextInputValue(i.ModalSubmitData(), promptModalInputID))
+}
+
+// saveGuildSystemPrompt は、ギルドのシステムプロンプトを保存して結果を応答します
+func (h *SlashCommandHandler) saveGuildSystemPrompt(s *discordgo.Session, i *discordgo.InteractionCreate, prompt string) {
+	err := h.apiKeyService.SetGuildSystemPrompt(context.Background(), i.GuildID, prompt)
+	if err != nil {
+		log.Printf("システムプロンプトの設定に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ システムプロンプトの設定に失敗しました: %v", err), true)
+		return
+	}
+
+	successMsg := fmt.Sprintf("✅ このサーバーのシステムプロンプトを設定しました（%d文字）。\n設定者: %s\n内容は `/show-prompt` で確認できます。",
+		len([]rune(prompt)), i.Member.User.Username)
+	h.respondToInteraction(s, i, successMsg, false)
+}
+
+// handleResetPromptCommand は、/reset-promptコマンドを処理します
+func (h *SlashCommandHandler) handleResetPromptCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	if err := h.apiKeyService.ResetGuildSystemPrompt(context.Background(), i.GuildID); err != nil {
+		log.Printf("システムプロンプトの削除に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ システムプロンプトの削除に失敗しました: %v", err), true)
+		return
+	}
+
+	h.respondToInteraction(s, i, "✅ このサーバーのシステムプロンプトを削除しました。\n今後は既定のシステムプロンプトを使用します。", false)
+}
+
+// handleShowPromptCommand は、/show-promptコマンドを処理します（実行したユーザーにのみ表示します）
+func (h *SlashCommandHandler) handleShowPromptCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	prompt, err := h.apiKeyService.GetGuildSystemPrompt(context.Background(), i.GuildID)
+	if err != nil {
+		log.Printf("システムプロンプトの取得に失敗: %v", err)
+		h.respondToInteraction(s, i, "❌ システムプロンプトの取得に失敗しました。", true)
+		return
+	}
+
+	if prompt == "" {
+		h.respondToInteraction(s, i, "📝 このサーバー固有のシステムプロンプトは設定されていません（既定のプロンプトを使用中）。\n`/set-prompt` で設定できます。", true)
+		return
+	}
+
+	message := fmt.Sprintf("📝 **このサーバーのシステムプロンプト**（%d文字）\n```\n%s\n```", len([]rune(prompt)), truncateRunes(prompt, showPromptLimit))
+	h.respondToInteraction(s, i, message, true)
+}
+
+// modalTextInputValue は、モーダルの送信内容から指定されたカスタムIDのテキスト入力の値を取得します
+func modalTextInputValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, component := range data.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			if input, ok := c.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
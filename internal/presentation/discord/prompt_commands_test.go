@@ -0,0 +1,27 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestModalTextInputValue(t *testing.T) {
+	data := discordgo.ModalSubmitInteractionData{
+		CustomID: promptModalID,
+		Components: []discordgo.MessageComponent{
+			&discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					&discordgo.TextInput{CustomID: promptModalInputID, Value: "あなたは厳格なコードレビュアーです"},
+				},
+			},
+		},
+	}
+
+	if value := modalTextInputValue(data, promptModalInputID); value != "あなたは厳格なコードレビュアーです" {
+		t.Errorf("テキスト入力の値が取得できていません: %q", value)
+	}
+	if value := modalTextInputValue(data, "unknown"); value != "" {
+		t.Errorf("存在しない入力は空文字を返すべきです: %q", value)
+	}
+}
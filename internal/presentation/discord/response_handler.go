@@ -1,9 +1,15 @@
 package discord
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"geminibot/internal/domain"
 
@@ -16,13 +22,25 @@ type ResponseHandler struct{}
 // DiscordMessageLimit は、Discordのメッセージ文字数制限です
 const DiscordMessageLimit = 2000
 
+// 回答の引用元の埋め込みの制限です
+const (
+	maxCitationSources     = 10   // 引用元として表示するWebページの最大件数
+	maxCitationTitleRunes  = 100  // 引用元のタイトルの最大文字数
+	embedDescriptionLimit  = 4096 // Discordの埋め込みの説明の文字数制限
+	embedFooterLimit       = 2048 // Discordの埋め込みのフッターの文字数制限
+	citationEmbedColor     = 0x4285f4
+	citationFooterPrefix   = "🔎 Google 検索"
+	citationQuerySeparator = " / "
+)
+
 // NewResponseHandler は新しいResponseHandlerインスタンスを作成します
 func NewResponseHandler() *ResponseHandler {
 	return &ResponseHandler{}
 }
 
 // SendUnifiedResponse は、統一レスポンスを送信します（ThreadIDに基づいてスレッドまたはリプライで送信）
-func (h *ResponseHandler) SendUnifiedResponse(s *discordgo.Session, m *discordgo.MessageCreate, response *domain.UnifiedResponse) {
+// style がリプライの場合は、スレッドを作成せずにリプライで送信します
+func (h *ResponseHandler) SendUnifiedResponse(s *discordgo.Session, m *discordgo.MessageCreate, response *domain.UnifiedResponse, style domain.ReplyStyle) {
 	// エラーレスポンスの場合は直接リプライで送信
 	if !response.Success {
 		errorMsg := h.formatUnifiedError(response)
@@ -41,6 +59,9 @@ func (h *ResponseHandler) SendUnifiedResponse(s *discordgo.Session, m *discordgo
 	if response.ThreadID != "" {
 		targetChannelID = response.ThreadID
 		isReply = false
+	} else if style == domain.ReplyStyleReply {
+		targetChannelID = m.ChannelID
+		isReply = true
 	} else {
 		// ThreadIDが空の場合はスレッド作成を試行
 		threadID, err := h.createThreadForResponse(s, m, response)
@@ -65,6 +86,16 @@ func (h *ResponseHandler) SendUnifiedResponse(s *discordgo.Session, m *discordgo
 		}
 	}
 
+	// 思考の要約を受け取った場合は、回答とは別にスポイラーで送信
+	if embed := h.thoughtsEmbed(response.Thoughts); embed != nil {
+		h.sendEmbed(s, m, targetChannelID, isReply, embed)
+	}
+
+	// Google 検索によるグラウンディングを使用した場合は、引用元の一覧を送信
+	if embed := h.citationEmbed(response.Grounding); embed != nil {
+		h.sendEmbed(s, m, targetChannelID, isReply, embed)
+	}
+
 	// 添付ファイルがある場合は送信
 	if response.HasAttachments() {
 		if isReply {
@@ -75,6 +106,69 @@ func (h *ResponseHandler) SendUnifiedResponse(s *discordgo.Session, m *discordgo
 	}
 }
 
+// citationEmbed は、Google 検索によるグラウンディングで参照したWebページを、回答中の引用番号とリンク付きで一覧にした埋め込みを作成します
+// フッターには実行した検索クエリを表示します（参照したWebページがない場合は nil を返します）
+func (h *ResponseHandler) citationEmbed(grounding *domain.Grounding) *discordgo.MessageEmbed {
+	if !grounding.HasSources() {
+		return nil
+	}
+
+	var description strings.Builder
+	for i, source := range grounding.Sources {
+		if i >= maxCitationSources {
+			fmt.Fprintf(&description, "ほか%d件", len(grounding.Sources)-i)
+			break
+		}
+		line := fmt.Sprintf("`[%d]` [%s](%s)\n", i+1, citationTitle(source), source.URI)
+		if utf8.RuneCountInString(description.String())+utf8.RuneCountInString(line) > embedDescriptionLimit {
+			break
+		}
+		description.WriteString(line)
+	}
+
+	footer := citationFooterPrefix
+	if len(grounding.SearchQueries) > 0 {
+		footer += ": " + strings.Join(grounding.SearchQueries, citationQuerySeparator)
+	}
+	if runes := []rune(footer); len(runes) > embedFooterLimit {
+		footer = string(runes[:embedFooterLimit-1]) + "…"
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "📚 情報源",
+		Description: strings.TrimSpace(description.String()),
+		Color:       citationEmbedColor,
+		Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+	}
+}
+
+// citationTitle は、引用元のリンクに表示するタイトルを返します（Markdownのリンクを壊す角括弧は置き換えます）
+func citationTitle(source domain.GroundingSource) string {
+	title := strings.TrimSpace(source.Title)
+	if title == "" {
+		title = source.URI
+	}
+	if runes := []rune(title); len(runes) > maxCitationTitleRunes {
+		title = string(runes[:maxCitationTitleRunes-1]) + "…"
+	}
+	return strings.NewReplacer("[", "［", "]", "］").Replace(title)
+}
+
+// sendEmbed は、埋め込みのみのメッセージをリプライまたはスレッド内に送信します
+func (h *ResponseHandler) sendEmbed(s *discordgo.Session, m *discordgo.MessageCreate, channelID string, isReply bool, embed *discordgo.MessageEmbed) {
+	message := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}}
+	if isReply {
+		message.Reference = &discordgo.MessageReference{
+			MessageID: m.ID,
+			ChannelID: m.ChannelID,
+			GuildID:   m.GuildID,
+		}
+	}
+	if _, err := s.ChannelMessageSendComplex(channelID, message); err != nil {
+		log.Printf("埋め込みの送信に失敗: %v", err)
+	}
+}
+
 // createThreadForResponse は、レスポンス用のスレッドを作成します
 func (h *ResponseHandler) createThreadForResponse(s *discordgo.Session, m *discordgo.MessageCreate, response *domain.UnifiedResponse) (string, error) {
 	// 既にスレッド内の場合はスレッド作成をスキップ
@@ -87,10 +181,11 @@ func (h *ResponseHandler) createThreadForResponse(s *discordgo.Session, m *disco
 		return "", fmt.Errorf("既にスレッド内です")
 	}
 
-	// スレッド名を生成
-	threadName := h.generateThreadName(m, response)
+	return h.startThread(s, m, h.generateThreadName(m, response))
+}
 
-	// スレッドを作成
+// startThread は、ユーザーのメッセージを起点にスレッドを作成します
+func (h *ResponseHandler) startThread(s *discordgo.Session, m *discordgo.MessageCreate, threadName string) (string, error) {
 	thread, err := s.MessageThreadStartComplex(m.ChannelID, m.ID, &discordgo.ThreadStart{
 		Name:                threadName,
 		AutoArchiveDuration: 60, // 1時間後に自動アーカイブ
@@ -104,6 +199,66 @@ func (h *ResponseHandler) createThreadForResponse(s *discordgo.Session, m *disco
 	return thread.ID, nil
 }
 
+// StartStreamingResponse は、ストリーミング応答の送信先を決めて処理中メッセージを送信します
+// スレッド外のメンションでは style に従ってスレッドを作成し、リプライが指定された場合や作成できない場合はリプライで送信します
+// components は処理中メッセージに付けるボタン（生成の停止など）です
+func (h *ResponseHandler) StartStreamingResponse(s *discordgo.Session, m *discordgo.MessageCreate, mention domain.BotMention, style domain.ReplyStyle, components []discordgo.MessageComponent) (*StreamingResponse, error) {
+	messenger := &sessionStreamMessenger{session: s, channelID: m.ChannelID}
+
+	if !mention.IsThread() {
+		reference := &discordgo.MessageReference{
+			MessageID: m.ID,
+			ChannelID: m.ChannelID,
+			GuildID:   m.GuildID,
+		}
+
+		if style == domain.ReplyStyleReply {
+			messenger.reference = reference
+		} else if threadID, err := h.startThread(s, m, "💬 "+truncateRunes(mention.Content, 20)); err != nil {
+			log.Printf("スレッド作成に失敗、リプライで送信します: %v", err)
+			messenger.reference = reference
+		} else {
+			messenger.channelID = threadID
+		}
+	}
+
+	return h.startStreaming(messenger, components)
+}
+
+// StartStreamingReply は、指定されたメッセージへのリプライとして処理中メッセージを送信します
+// ボタン操作による再生成など、既存の回答に続けて応答する場合に使用します
+func (h *ResponseHandler) StartStreamingReply(s *discordgo.Session, channelID, messageID, guildID string, components []discordgo.MessageComponent) (*StreamingResponse, error) {
+	messenger := &sessionStreamMessenger{
+		session:   s,
+		channelID: channelID,
+		reference: &discordgo.MessageReference{
+			MessageID: messageID,
+			ChannelID: channelID,
+			GuildID:   guildID,
+		},
+	}
+	return h.startStreaming(messenger, components)
+}
+
+// startStreaming は、処理中メッセージを送信してストリーミング応答を開始します
+func (h *ResponseHandler) startStreaming(messenger streamMessenger, components []discordgo.MessageComponent) (*StreamingResponse, error) {
+	placeholderID, err := messenger.Send("🤔 考え中...", components)
+	if err != nil {
+		return nil, fmt.Errorf("処理中メッセージの送信に失敗: %w", err)
+	}
+
+	return newStreamingResponse(messenger, placeholderID, StreamEditInterval), nil
+}
+
+// truncateRunes は、文字列を指定した文字数に切り詰め、切り詰めた場合は末尾に "..." を付けます
+func truncateRunes(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes]) + "..."
+}
+
 // generateThreadName は、スレッド名を生成します
 func (h *ResponseHandler) generateThreadName(_ *discordgo.MessageCreate, response *domain.UnifiedResponse) string {
 	// レスポンスタイプに基づいてスレッド名を生成
@@ -128,9 +283,13 @@ func (h *ResponseHandler) sendTextContentToThread(s *discordgo.Session, threadID
 		h.sendAsFileToThread(s, threadID, content, "response.txt")
 		return
 	}
+	h.sendChunksToThread(s, threadID, content)
+}
 
+// sendChunksToThread は、テキストコンテンツをDiscordの制限に合わせて分割し、スレッド内に送信します
+func (h *ResponseHandler) sendChunksToThread(s *discordgo.Session, threadID string, content string) {
 	// 応答をDiscordの制限に合わせて分割
-	chunks := h.splitMessage(content)
+	chunks := splitMessage(content)
 
 	// すべてのチャンクをスレッド内に送信
 	for i, chunk := range chunks {
@@ -149,9 +308,13 @@ func (h *ResponseHandler) sendTextContentToChannel(s *discordgo.Session, m *disc
 		h.sendAsFile(s, m, content, "response.txt")
 		return
 	}
+	h.sendChunksAsReply(s, m, content)
+}
 
+// sendChunksAsReply は、テキストコンテンツをDiscordの制限に合わせて分割し、チャンネルにリプライ付きで送信します
+func (h *ResponseHandler) sendChunksAsReply(s *discordgo.Session, m *discordgo.MessageCreate, content string) {
 	// 応答をDiscordの制限に合わせて分割
-	chunks := h.splitMessage(content)
+	chunks := splitMessage(content)
 
 	if len(chunks) == 1 {
 		// 単一メッセージの場合
@@ -194,42 +357,34 @@ func (h *ResponseHandler) sendAttachmentsToThread(s *discordgo.Session, threadID
 		}
 	}
 
-	// 各添付ファイルを送信
-	for i, attachment := range attachments {
-		if attachment.IsImage {
-			err := h.uploadAttachmentToThread(s, threadID, attachment, i+1)
-			if err != nil {
-				log.Printf("添付ファイルのアップロードに失敗 (ファイル %d): %v", i+1, err)
-			}
-		}
+	// 全ての画像を1つのメッセージにまとめて送信
+	if err := h.uploadImages(s, threadID, attachments, nil); err != nil {
+		log.Printf("添付ファイルのアップロードに失敗: %v", err)
 	}
 }
 
 // sendAttachmentsToChannel は、添付ファイルをチャンネルにリプライ付きで送信します
 func (h *ResponseHandler) sendAttachmentsToChannel(s *discordgo.Session, m *discordgo.MessageCreate, attachments []domain.Attachment, metadata domain.ResponseMetadata) {
+	reference := &discordgo.MessageReference{
+		MessageID: m.ID,
+		ChannelID: m.ChannelID,
+		GuildID:   m.GuildID,
+	}
+
 	// 画像添付がある場合のメッセージを作成
 	if len(attachments) > 0 {
 		message := h.createAttachmentMessage(metadata)
 		if message != "" {
-			_, err := s.ChannelMessageSendReply(m.ChannelID, message, &discordgo.MessageReference{
-				MessageID: m.ID,
-				ChannelID: m.ChannelID,
-				GuildID:   m.GuildID,
-			})
+			_, err := s.ChannelMessageSendReply(m.ChannelID, message, reference)
 			if err != nil {
 				log.Printf("添付ファイルメッセージの送信に失敗: %v", err)
 			}
 		}
 	}
 
-	// 各添付ファイルを送信
-	for i, attachment := range attachments {
-		if attachment.IsImage {
-			err := h.uploadAttachmentToChannel(s, m, attachment, i+1)
-			if err != nil {
-				log.Printf("添付ファイルのアップロードに失敗 (ファイル %d): %v", i+1, err)
-			}
-		}
+	// 全ての画像を1つのメッセージにまとめてリプライで送信
+	if err := h.uploadImages(s, m.ChannelID, attachments, reference); err != nil {
+		log.Printf("添付ファイルのアップロードに失敗: %v", err)
 	}
 }
 
@@ -244,58 +399,60 @@ func (h *ResponseHandler) createAttachmentMessage(metadata domain.ResponseMetada
 	}
 }
 
-// uploadAttachmentToThread は、添付ファイルをスレッド内にアップロードします
-func (h *ResponseHandler) uploadAttachmentToThread(s *discordgo.Session, threadID string, attachment domain.Attachment, index int) error {
-	// ファイル名を生成
-	filename := attachment.Filename
-	if filename == "" {
-		filename = fmt.Sprintf("attachment_%d", index)
-		if attachment.MimeType == "image/png" {
-			filename += ".png"
-		} else if attachment.MimeType == "image/jpeg" {
-			filename += ".jpg"
-		} else if attachment.MimeType == "image/gif" {
-			filename += ".gif"
-		} else if attachment.MimeType == "image/webp" {
-			filename += ".webp"
-		}
+// uploadImages は、画像の添付ファイルを1つのメッセージにまとめてアップロードします（Discord上ではギャラリーとして表示されます）
+// reference を指定した場合はリプライとして送信します
+func (h *ResponseHandler) uploadImages(s *discordgo.Session, channelID string, attachments []domain.Attachment, reference *discordgo.MessageReference) error {
+	files := h.imageFiles(attachments)
+	if len(files) == 0 {
+		return nil
 	}
 
 	// Discordにファイルをアップロード
-	_, err := s.ChannelFileSend(threadID, filename, strings.NewReader(string(attachment.Data)))
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Files:     files,
+		Reference: reference,
+	})
 	if err != nil {
 		return fmt.Errorf("Discordへのファイルアップロードに失敗: %w", err)
 	}
 
-	log.Printf("添付ファイルのアップロードが完了しました: %s", filename)
+	log.Printf("添付ファイルのアップロードが完了しました: %d件", len(files))
 	return nil
 }
 
-// uploadAttachmentToChannel は、添付ファイルをチャンネルにリプライ付きでアップロードします
-func (h *ResponseHandler) uploadAttachmentToChannel(s *discordgo.Session, m *discordgo.MessageCreate, attachment domain.Attachment, index int) error {
-	// ファイル名を生成
-	filename := attachment.Filename
-	if filename == "" {
-		filename = fmt.Sprintf("attachment_%d", index)
-		if attachment.MimeType == "image/png" {
-			filename += ".png"
-		} else if attachment.MimeType == "image/jpeg" {
-			filename += ".jpg"
-		} else if attachment.MimeType == "image/gif" {
-			filename += ".gif"
-		} else if attachment.MimeType == "image/webp" {
-			filename += ".webp"
+// imageFiles は、添付ファイルのうち画像を、Discordにアップロードするファイルに変換します
+func (h *ResponseHandler) imageFiles(attachments []domain.Attachment) []*discordgo.File {
+	var files []*discordgo.File
+	for i, attachment := range attachments {
+		if !attachment.IsImage {
+			continue
 		}
+		files = append(files, &discordgo.File{
+			Name:        h.attachmentFilename(attachment, i+1),
+			ContentType: attachment.MimeType,
+			Reader:      bytes.NewReader(attachment.Data),
+		})
 	}
+	return files
+}
 
-	// Discordにファイルをアップロード（リプライ付き）
-	_, err := s.ChannelFileSendWithMessage(m.ChannelID, "", filename, strings.NewReader(string(attachment.Data)))
-	if err != nil {
-		return fmt.Errorf("Discordへのファイルアップロードに失敗: %w", err)
+// attachmentFilename は、添付ファイルのファイル名を返します（未設定の場合はMIMEタイプから生成）
+func (h *ResponseHandler) attachmentFilename(attachment domain.Attachment, index int) string {
+	if attachment.Filename != "" {
+		return attachment.Filename
 	}
 
-	log.Printf("添付ファイルのアップロードが完了しました: %s", filename)
-	return nil
+	filename := fmt.Sprintf("attachment_%d", index)
+	if attachment.MimeType == "image/png" {
+		filename += ".png"
+	} else if attachment.MimeType == "image/jpeg" {
+		filename += ".jpg"
+	} else if attachment.MimeType == "image/gif" {
+		filename += ".gif"
+	} else if attachment.MimeType == "image/webp" {
+		filename += ".webp"
+	}
+	return filename
 }
 
 // formatUnifiedError は、統一レスポンスのエラーを適切なメッセージにフォーマットします
@@ -305,58 +462,59 @@ func (h *ResponseHandler) formatUnifiedError(response *domain.UnifiedResponse) s
 	}
 
 	errorMsg := response.Error
-
-	// タイムアウトエラーの場合
-	if h.isTimeoutError(fmt.Errorf(errorMsg)) {
-		return "⏰ **タイムアウトしました**\n\n処理に時間がかかりすぎました。以下の対処法をお試しください：\n\n" +
-			"• 質問を短くしてみる\n" +
-			"• 複雑な質問を分割する\n" +
-			"• しばらく待ってから再度お試しください\n\n" +
-			"ご不便をおかけして申し訳ございません。"
+	err := response.Err
+	if err == nil {
+		err = errors.New(errorMsg)
 	}
 
 	// 画像生成関連のエラー
 	if response.Metadata.Type == "image" {
 		// 安全フィルターエラーの場合
-		if strings.Contains(errorMsg, "安全フィルター") {
+		if errors.Is(err, domain.ErrSafetyBlocked) {
 			return "🚫 **安全フィルターにより画像生成がブロックされました**\n\n" +
 				"プロンプトに不適切な内容が含まれている可能性があります。\n" +
 				"より適切な表現で再度お試しください。"
 		}
 
 		// 画像生成タイムアウトエラーの場合
-		if h.isTimeoutError(fmt.Errorf(errorMsg)) {
+		if h.isTimeoutError(err) {
 			return "⏰ **画像生成がタイムアウトしました**\n\n" +
 				"処理に時間がかかりすぎました。以下の対処法をお試しください：\n\n" +
 				"• プロンプトを短くしてみる\n" +
 				"• しばらく待ってから再度お試しください\n\n" +
 				"ご不便をおかけして申し訳ございません。"
 		}
+	}
 
-		return fmt.Sprintf("❌ **画像生成エラー**\n%s", errorMsg)
+	// タイムアウトエラーの場合
+	if h.isTimeoutError(err) {
+		return "⏰ **タイムアウトしました**\n\n処理に時間がかかりすぎました。以下の対処法をお試しください：\n\n" +
+			"• 質問を短くしてみる\n" +
+			"• 複雑な質問を分割する\n" +
+			"• しばらく待ってから再度お試しください\n\n" +
+			"ご不便をおかけして申し訳ございません。"
 	}
 
-	// テキスト生成関連のエラー
-	switch errorMsg {
-	case "レート制限を超過しました":
-		return "⚠️ **レート制限を超過しました**\nしばらく待ってから再度お試しください。"
-	case "スパムが検出されました":
-		return "🚫 **スパムが検出されました**\n短時間での大量メッセージは禁止されています。"
-	case "不適切なコンテンツが検出されました":
-		return "🚫 **不適切なコンテンツが検出されました**\n禁止ワードが含まれています。"
-	case "メッセージが長すぎます":
-		return "📏 **メッセージが長すぎます**\n2000文字以内でお願いします。"
-	case "重複メッセージが検出されました":
-		return "🔄 **重複メッセージが検出されました**\n同じ内容のメッセージを連続で送信しないでください。"
-	default:
-		return fmt.Sprintf("❌ **エラーが発生しました**\n%s", errorMsg)
+	// レート制限などの種類がわかるエラーの場合
+	if message, ok := formatKnownError(err); ok {
+		return message
 	}
+
+	if response.Metadata.Type == "image" {
+		return fmt.Sprintf("❌ **画像生成エラー**\n%s", errorMsg)
+	}
+
+	return fmt.Sprintf("❌ **エラーが発生しました**\n%s", errorMsg)
 }
 
 // convertImageResultToUnifiedResponse は、画像生成結果を統一レスポンスに変換します
 func (h *ResponseHandler) convertImageResultToUnifiedResponse(imageResult *domain.ImageGenerationResult, m *discordgo.MessageCreate) *domain.UnifiedResponse {
 	if !imageResult.Success {
-		return domain.NewErrorResponse(fmt.Errorf(imageResult.Error), "image")
+		err := imageResult.Err
+		if err == nil {
+			err = errors.New(imageResult.Error)
+		}
+		return domain.NewErrorResponse(err, "image")
 	}
 
 	// メンション部分を除去したコンテンツを取得
@@ -398,7 +556,7 @@ func (h *ResponseHandler) sendAsFileToThread(s *discordgo.Session, threadID stri
 	if err != nil {
 		log.Printf("ファイル送信に失敗: %v", err)
 		// ファイル送信に失敗した場合は通常の分割送信にフォールバック
-		h.sendTextContentToThread(s, threadID, content)
+		h.sendChunksToThread(s, threadID, content)
 		return
 	}
 
@@ -407,13 +565,6 @@ func (h *ResponseHandler) sendAsFileToThread(s *discordgo.Session, threadID stri
 	s.ChannelMessageSend(threadID, fileMsg)
 }
 
-// sendSplitResponse は、長い応答を複数のメッセージに分割して送信します（後方互換性のため残す）
-func (h *ResponseHandler) sendSplitResponse(s *discordgo.Session, m *discordgo.MessageCreate, response string) {
-	// テキストレスポンスを作成
-	textResponse := domain.NewTextResponse(response, "", "gemini-pro")
-	h.SendUnifiedResponse(s, m, textResponse)
-}
-
 // sendAsFile は、長い応答をファイルとして送信します
 func (h *ResponseHandler) sendAsFile(s *discordgo.Session, m *discordgo.MessageCreate, content, filename string) {
 	// ファイルデータを作成
@@ -429,7 +580,7 @@ func (h *ResponseHandler) sendAsFile(s *discordgo.Session, m *discordgo.MessageC
 	if err != nil {
 		log.Printf("ファイル送信に失敗: %v", err)
 		// ファイル送信に失敗した場合は通常の分割送信にフォールバック
-		h.sendSplitResponse(s, m, content)
+		h.sendChunksAsReply(s, m, content)
 		return
 	}
 
@@ -443,7 +594,7 @@ func (h *ResponseHandler) sendAsFile(s *discordgo.Session, m *discordgo.MessageC
 }
 
 // splitMessage は、長いメッセージをDiscordの制限に合わせて分割します
-func (h *ResponseHandler) splitMessage(message string) []string {
+func splitMessage(message string) []string {
 	if len(message) <= DiscordMessageLimit {
 		return []string{message}
 	}
@@ -457,30 +608,7 @@ func (h *ResponseHandler) splitMessage(message string) []string {
 			break
 		}
 
-		// 2000文字以内で最も近い改行位置を探す
-		splitIndex := DiscordMessageLimit
-		for i := DiscordMessageLimit; i > 0; i-- {
-			if remaining[i-1] == '\n' {
-				splitIndex = i
-				break
-			}
-		}
-
-		// 改行が見つからない場合は、単語の境界で分割
-		if splitIndex == DiscordMessageLimit {
-			for i := DiscordMessageLimit; i > 0; i-- {
-				if remaining[i-1] == ' ' {
-					splitIndex = i
-					break
-				}
-			}
-		}
-
-		// それでも見つからない場合は強制的に分割
-		if splitIndex == DiscordMessageLimit {
-			splitIndex = DiscordMessageLimit
-		}
-
+		splitIndex := findSplitIndex(remaining, DiscordMessageLimit)
 		chunk := remaining[:splitIndex]
 		remaining = remaining[splitIndex:]
 
@@ -493,34 +621,47 @@ func (h *ResponseHandler) splitMessage(message string) []string {
 	return chunks
 }
 
+// findSplitIndex は、limit バイト以内でメッセージを分割する位置を返します
+// 改行、単語の境界の順に探し、見つからない場合はUTF-8の文字を壊さない位置で分割します
+func findSplitIndex(message string, limit int) int {
+	if len(message) <= limit {
+		return len(message)
+	}
+
+	// 制限以内で最も近い改行位置を探す
+	if i := strings.LastIndexByte(message[:limit], '\n'); i > 0 {
+		return i + 1
+	}
+
+	// 改行が見つからない場合は、単語の境界で分割
+	if i := strings.LastIndexByte(message[:limit], ' '); i > 0 {
+		return i + 1
+	}
+
+	// それでも見つからない場合は、文字の途中で切らないように強制的に分割
+	splitIndex := limit
+	for splitIndex > 0 && !utf8.RuneStart(message[splitIndex]) {
+		splitIndex--
+	}
+	if splitIndex == 0 {
+		return limit
+	}
+	return splitIndex
+}
+
 // isTimeoutError は、エラーがタイムアウトエラーかどうかを判定します
 func (h *ResponseHandler) isTimeoutError(err error) bool {
 	if err == nil {
 		return false
 	}
 
-	// context.DeadlineExceeded エラーの検出
-	if err.Error() == "context deadline exceeded" {
+	// メッセージの文字列ではなく、エラーの種類で判定する
+	if errors.Is(err, domain.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
 		return true
 	}
 
-	// タイムアウト関連のエラーメッセージを検出
-	errorMsg := err.Error()
-	timeoutKeywords := []string{
-		"timeout",
-		"タイムアウト",
-		"deadline exceeded",
-		"context deadline",
-		"request timeout",
-	}
-
-	for _, keyword := range timeoutKeywords {
-		if strings.Contains(strings.ToLower(errorMsg), strings.ToLower(keyword)) {
-			return true
-		}
-	}
-
-	return false
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 // formatError は、エラーを適切なメッセージにフォーマットします
@@ -534,19 +675,55 @@ func (h *ResponseHandler) formatError(err error) string {
 			"ご不便をおかけして申し訳ございません。"
 	}
 
-	// 荒らし対策エラーの場合
-	switch err.Error() {
-	case "レート制限を超過しました":
-		return "⚠️ **レート制限を超過しました**\nしばらく待ってから再度お試しください。"
-	case "スパムが検出されました":
-		return "🚫 **スパムが検出されました**\n短時間での大量メッセージは禁止されています。"
-	case "不適切なコンテンツが検出されました":
-		return "🚫 **不適切なコンテンツが検出されました**\n禁止ワードが含まれています。"
-	case "メッセージが長すぎます":
-		return "📏 **メッセージが長すぎます**\n2000文字以内でお願いします。"
-	case "重複メッセージが検出されました":
-		return "🔄 **重複メッセージが検出されました**\n同じ内容のメッセージを連続で送信しないでください。"
+	// 荒らし対策やGemini APIのエラーなど、種類がわかるエラーの場合
+	if message, ok := formatKnownError(err); ok {
+		return message
+	}
+	return fmt.Sprintf("❌ **エラーが発生しました**\n%s", err.Error())
+}
+
+// formatKnownError は、レート制限などドメインで定義された種類のエラーをユーザー向けのメッセージにフォーマットします
+// 該当する種類のエラーでない場合は false を返します
+func formatKnownError(err error) (string, bool) {
+	switch {
+	case err == nil:
+		return "", false
+	case errors.Is(err, domain.ErrSpamDetected):
+		return "🚫 **スパムが検出されました**\n短時間での大量メッセージは禁止されています。", true
+	case errors.Is(err, domain.ErrRateLimited):
+		var rateLimitErr *domain.RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+			retryAfter := (rateLimitErr.RetryAfter + time.Second - 1).Truncate(time.Second)
+			return fmt.Sprintf("⚠️ **レート制限を超過しました**\n%sごとの%sの上限に達しました。%s後に再度お試しください。",
+				rateLimitErr.Scope.DisplayName(), rateLimitErr.Kind.DisplayName(), retryAfter), true
+		}
+		return "⚠️ **レート制限を超過しました**\nしばらく待ってから再度お試しください。", true
+	case errors.Is(err, domain.ErrUsageBudgetExceeded):
+		return "💸 **今月の利用上限に達しました**\nサーバーの管理者が `/usage set-budget` で上限を変更するまでお待ちください。", true
+	case errors.Is(err, domain.ErrInappropriateContent):
+		return "🚫 **不適切なコンテンツが検出されました**\n禁止ワードが含まれています。", true
+	case errors.Is(err, domain.ErrMessageTooLong):
+		return "📏 **メッセージが長すぎます**\n2000文字以内でお願いします。", true
+	case errors.Is(err, domain.ErrDuplicateMessage):
+		return "🔄 **重複メッセージが検出されました**\n同じ内容のメッセージを連続で送信しないでください。", true
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		return "⏳ **Gemini APIの利用制限に達しました**\nしばらく時間を置いてから再度お試しください。", true
+	case errors.Is(err, domain.ErrUnauthorized):
+		return "🔑 **Gemini APIへのアクセス権限がありません**\nサーバーの管理者にAPIキーの設定を確認するよう依頼してください。", true
+	case errors.Is(err, domain.ErrSafetyBlocked):
+		var safetyErr *domain.SafetyBlockedError
+		if errors.As(err, &safetyErr) && len(safetyErr.Categories) > 0 {
+			return fmt.Sprintf("🚫 **安全フィルターにより応答がブロックされました**\n該当したカテゴリ: %s\n表現を変えて再度お試しください。",
+				strings.Join(safetyErr.Categories, "、")), true
+		}
+		return "🚫 **安全フィルターにより応答がブロックされました**\n表現を変えて再度お試しください。", true
+	case errors.Is(err, domain.ErrRecitation):
+		return "©️ **著作権で保護された内容が含まれる可能性があるため、応答が停止されました**\n引用を求める部分を減らして再度お試しください。", true
+	case errors.Is(err, domain.ErrMaxTokens):
+		return "📏 **応答が最大トークン数に達しました**\n質問を短くするか、複数の質問に分けてお試しください。", true
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return "🛠️ **Gemini APIが一時的に利用できません**\nしばらく時間を置いてから再度お試しください。", true
 	default:
-		return fmt.Sprintf("❌ **エラーが発生しました**\n%s", err.Error())
+		return "", false
 	}
 }
@@ -0,0 +1,59 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"geminibot/internal/domain"
+)
+
+func TestResponseHandler_CitationEmbed(t *testing.T) {
+	handler := NewResponseHandler()
+
+	embed := handler.citationEmbed(&domain.Grounding{
+		SearchQueries: []string{"パリ五輪 開幕", "パリ五輪 閉会式"},
+		Sources: []domain.GroundingSource{
+			{Title: "olympics.com", URI: "https://olympics.com/paris"},
+			{Title: "[速報] 五輪", URI: "https://example.com/news"},
+		},
+	})
+	if embed == nil {
+		t.Fatal("情報源がある場合は埋め込みを作成するべきです")
+	}
+
+	lines := strings.Split(embed.Description, "\n")
+	if len(lines) != 2 || lines[0] != "`[1]` [olympics.com](https://olympics.com/paris)" {
+		t.Errorf("情報源に番号付きのリンクを表示するべきです: %q", embed.Description)
+	}
+	if !strings.Contains(lines[1], "［速報］ 五輪") {
+		t.Errorf("タイトルの角括弧はリンクの記法と衝突しないよう置き換えるべきです: %q", lines[1])
+	}
+	if embed.Footer == nil || embed.Footer.Text != citationFooterPrefix+": パリ五輪 開幕 / パリ五輪 閉会式" {
+		t.Errorf("検索クエリがフッターに表示されていません: %+v", embed.Footer)
+	}
+
+	if handler.citationEmbed(nil) != nil || handler.citationEmbed(&domain.Grounding{SearchQueries: []string{"q"}}) != nil {
+		t.Error("情報源がない場合は埋め込みを作成するべきではありません")
+	}
+}
+
+func TestResponseHandler_CitationEmbedLimitsSources(t *testing.T) {
+	handler := NewResponseHandler()
+
+	grounding := &domain.Grounding{}
+	for i := 0; i < maxCitationSources+3; i++ {
+		grounding.Sources = append(grounding.Sources, domain.GroundingSource{
+			Title: fmt.Sprintf("source%d", i+1),
+			URI:   fmt.Sprintf("https://example.com/%d", i+1),
+		})
+	}
+
+	embed := handler.citationEmbed(grounding)
+	if lines := strings.Split(embed.Description, "\n"); len(lines) != maxCitationSources+1 || !strings.Contains(lines[maxCitationSources], "3") {
+		t.Errorf("表示しきれない情報源は件数のみを表示するべきです: %q", embed.Description)
+	}
+	if embed.Footer == nil || embed.Footer.Text != citationFooterPrefix {
+		t.Errorf("検索クエリがない場合もGoogle 検索の結果であることを表示するべきです: %+v", embed.Footer)
+	}
+}
@@ -0,0 +1,230 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxAskQuestionLength は、/askで指定できる質問の最大文字数です
+const maxAskQuestionLength = 2000
+
+// searchCommands は、質問とGoogle 検索によるグラウンディングに関するスラッシュコマンドの定義を返します
+func searchCommands() []*discordgo.ApplicationCommand {
+	return []*discordgo.ApplicationCommand{
+		{
+			Name:        "ask",
+			Description: "Botに質問します（search を指定するとGoogle 検索で調べて情報源付きで回答します）",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "question",
+					Description: "質問の内容",
+					Required:    true,
+					MaxLength:   maxAskQuestionLength,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "search",
+					Description: "Google 検索で最新の情報を調べて回答するかどうか（省略するとチャンネル・サーバーの設定に従います）",
+					Required:    false,
+				},
+				thinkingBudgetOption("thinking-budget", "この質問で思考に使う最大のトークン数（-1 でモデルが決める、0 で思考しない）"),
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "show-thinking",
+					Description: "回答とは別に思考の要約を表示するかどうか（省略するとサーバーの設定に従います）",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "set-search",
+			Description: "このサーバーでGoogle 検索による情報源付きの回答を使用するかどうかを設定します",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "enabled",
+					Description: "Google 検索を使用するかどうか",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+// askRequest は、/askのオプションから質問と、Google 検索を使用するかどうかを取り出します
+// search オプションが省略された場合、search は nil です
+func askRequest(options []*discordgo.ApplicationCommandInteractionDataOption) (question string, search *bool) {
+	for _, option := range options {
+		switch option.Name {
+		case "question":
+			question = strings.TrimSpace(option.StringValue())
+		case "search":
+			enabled := option.BoolValue()
+			search = &enabled
+		}
+	}
+	return question, search
+}
+
+// askThinking は、/askのオプションから、この質問だけに適用する思考の設定を取り出します
+func askThinking(options []*discordgo.ApplicationCommandInteractionDataOption) domain.ThinkingSettings {
+	var thinking domain.ThinkingSettings
+	for _, option := range options {
+		switch option.Name {
+		case "thinking-budget":
+			budget := int(option.IntValue())
+			thinking.Budget = &budget
+		case "show-thinking":
+			thinking.IncludeThoughts = option.BoolValue()
+		}
+	}
+	return thinking
+}
+
+// askQuestionMessage は、/askの質問をチャンネルに表示するメッセージを作成します（回答はこのメッセージへのリプライとして表示します）
+func askQuestionMessage(user domain.User, question string, search bool) string {
+	message := fmt.Sprintf("❓ **%s さんの質問**\n%s", user.DisplayName, question)
+	if search {
+		message += "\n\n-# 🔎 Google 検索で調べて回答します"
+	}
+	return message
+}
+
+// handleAskCommand は、/askコマンドを処理します
+// 質問をチャンネルに表示し、メンションと同じようにそのメッセージへのリプライとして回答をストリーミングで表示します
+func (h *SlashCommandHandler) handleAskCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if h.answers == nil {
+		h.respondToInteraction(s, i, "⚠️ このコマンドは現在利用できません。", true)
+		return
+	}
+	if !h.hasPermission(s, i, domain.PermissionUseBot) {
+		h.respondToInteraction(s, i, "🚫 Botを利用する権限がありません。", true)
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	question, search := askRequest(options)
+	if question == "" {
+		h.respondToInteraction(s, i, "❌ 質問が指定されていません。", true)
+		return
+	}
+
+	// ユーザー・チャンネル・ギルドごとのレート制限を確認（利用上限は回答の生成時に確認する）
+	if err := h.rateLimiter.Allow(context.Background(), application.RateLimitRequest{
+		Kind:      domain.RequestKindText,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		UserID:    interactionUserID(i),
+	}); err != nil {
+		log.Printf("レート制限により質問コマンドを拒否します: %v", err)
+		message, ok := formatKnownError(err)
+		if !ok {
+			message = fmt.Sprintf("❌ **エラーが発生しました**\n%s", err)
+		}
+		h.respondToInteraction(s, i, message, true)
+		return
+	}
+
+	mention := domain.BotMention{
+		ChannelID: i.ChannelID,
+		GuildID:   i.GuildID,
+		User:      interactionDomainUser(i),
+		Content:   question,
+		Search:    search,
+		Thinking:  askThinking(options),
+	}
+
+	// スレッド内で実行された場合は、スレッドIDと、設定の解決に使う親チャンネルIDを設定
+	if channel, err := lookupChannel(s, i.ChannelID); err != nil {
+		log.Printf("チャンネル情報の取得に失敗: %v", err)
+	} else if channel.IsThread() {
+		mention.ThreadID = i.ChannelID
+		mention.ParentChannelID = channel.ParentID
+	}
+
+	h.respondToInteraction(s, i, askQuestionMessage(mention.User, question, search != nil && *search), false)
+
+	// 質問のメッセージより前の会話を履歴として使用し、回答はこのメッセージへのリプライとして表示する
+	questionMessage, err := s.InteractionResponse(i.Interaction)
+	if err != nil {
+		log.Printf("質問メッセージの取得に失敗: %v", err)
+		return
+	}
+	mention.MessageID = questionMessage.ID
+
+	stream, err := h.answers.responseHandler.StartStreamingReply(s, i.ChannelID, questionMessage.ID, i.GuildID, stopComponents())
+	if err != nil {
+		log.Printf("ストリーミング応答の開始に失敗: %v", err)
+		return
+	}
+
+	go h.answers.streamAnswer(stream, mention, "")
+}
+
+// handleSetSearchCommand は、/set-searchコマンドを処理します
+func (h *SlashCommandHandler) handleSetSearchCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		h.respondToInteraction(s, i, "❌ Google 検索を使用するかどうかが指定されていません。", true)
+		return
+	}
+	enabled := options[0].BoolValue()
+
+	if err := h.apiKeyService.SetGuildSearchGrounding(context.Background(), i.GuildID, enabled); err != nil {
+		log.Printf("Google 検索の設定に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ Google 検索の設定に失敗しました: %v", err), true)
+		return
+	}
+
+	message := "✅ このサーバーでGoogle 検索による情報源付きの回答を **無効** にしました。"
+	if enabled {
+		message = "✅ このサーバーでGoogle 検索による情報源付きの回答を **有効** にしました。\n最新の情報を調べて回答し、参照したWebページを番号付きで表示します。"
+	}
+	message += fmt.Sprintf("\n設定者: %s\n\nチャンネルごとの設定は `/channel-config set search:` で変更できます。", i.Member.User.Username)
+	h.respondToInteraction(s, i, message, false)
+}
+
+// interactionDomainUser は、インタラクションを実行したユーザーの情報を返します
+// サーバー内ではニックネーム、それ以外では表示名を優先して表示名とします
+func interactionDomainUser(i *discordgo.InteractionCreate) domain.User {
+	user := i.User
+	nick := ""
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+		nick = i.Member.Nick
+	}
+	if user == nil {
+		return domain.User{}
+	}
+
+	displayName := nick
+	if displayName == "" {
+		displayName = user.GlobalName
+	}
+	if displayName == "" {
+		displayName = user.Username
+	}
+
+	return domain.User{
+		ID:            user.ID,
+		Username:      user.Username,
+		DisplayName:   displayName,
+		Avatar:        user.Avatar,
+		Discriminator: user.Discriminator,
+		IsBot:         user.Bot,
+	}
+}
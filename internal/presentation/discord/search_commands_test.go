@@ -0,0 +1,47 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestAskRequest(t *testing.T) {
+	question, search := askRequest([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "question", Type: discordgo.ApplicationCommandOptionString, Value: "  今日の天気は？  "},
+		{Name: "search", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+	})
+	if question != "今日の天気は？" || search == nil || !*search {
+		t.Errorf("askRequest() = %q, %v", question, search)
+	}
+
+	// false を指定した場合は、設定より優先して無効にするため nil と区別する
+	if _, search := askRequest([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "question", Type: discordgo.ApplicationCommandOptionString, Value: "質問"},
+		{Name: "search", Type: discordgo.ApplicationCommandOptionBoolean, Value: false},
+	}); search == nil || *search {
+		t.Errorf("search:false を指定した場合は無効にするべきです: %v", search)
+	}
+
+	if _, search := askRequest([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "question", Type: discordgo.ApplicationCommandOptionString, Value: "質問"},
+	}); search != nil {
+		t.Errorf("search を省略した場合はチャンネル・サーバーの設定に従うべきです: %v", *search)
+	}
+}
+
+func TestAskQuestionMessage(t *testing.T) {
+	alice := domain.User{ID: "user1", Username: "alice", DisplayName: "Alice"}
+
+	message := askQuestionMessage(alice, "質問", false)
+	if message != "❓ **Alice さんの質問**\n質問" {
+		t.Errorf("askQuestionMessage() = %q", message)
+	}
+
+	if message := askQuestionMessage(alice, "質問", true); !strings.Contains(message, "Google 検索") {
+		t.Errorf("Google 検索を使用することが表示されていません: %q", message)
+	}
+}
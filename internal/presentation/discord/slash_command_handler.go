@@ -5,11 +5,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"geminibot/internal/application"
 	"geminibot/internal/domain"
 	"geminibot/internal/infrastructure/config"
+	discordInfra "geminibot/internal/infrastructure/discord"
 	"geminibot/internal/infrastructure/gemini"
 
 	"github.com/bwmarrin/discordgo"
@@ -17,22 +19,66 @@ import (
 
 // SlashCommandHandler は、Discordのスラッシュコマンドを処理するハンドラーです
 type SlashCommandHandler struct {
-	session             *discordgo.Session
-	apiKeyService       *application.APIKeyApplicationService
-	defaultGeminiConfig *config.GeminiConfig
+	session              *discordgo.Session
+	apiKeyService        *application.APIKeyApplicationService
+	channelConfigService *application.ChannelConfigApplicationService
+	permissionService    *application.PermissionApplicationService
+	rateLimiter          *application.RateLimiter
+	usageService         *application.UsageApplicationService
+	defaultGeminiConfig  *config.GeminiConfig
+	attachmentDownloader application.AttachmentDownloader
+	maxAttachmentBytes   int64
+	responseHandler      *ResponseHandler                // 画像生成の失敗などのエラーをメッセージにフォーマットします
+	componentHandlers    map[string]ComponentHandlerFunc // カスタムIDの接頭辞ごとのボタン操作・モーダル送信ハンドラー
+	answers              *AnswerController               // /askの回答をストリーミングで表示します（nil の場合は/askを利用できません）
+	extractor            conversationExtractor           // /extractで会話から構造化データを抽出します（nil の場合は/extractを利用できません）
 }
 
+// ComponentHandlerFunc は、メッセージに付けたボタンなどのコンポーネント操作やモーダルの送信を処理する関数です
+type ComponentHandlerFunc func(s *discordgo.Session, i *discordgo.InteractionCreate)
+
 // NewSlashCommandHandler は新しいSlashCommandHandlerインスタンスを作成します
 func NewSlashCommandHandler(
 	session *discordgo.Session,
 	apiKeyService *application.APIKeyApplicationService,
+	channelConfigService *application.ChannelConfigApplicationService,
+	permissionService *application.PermissionApplicationService,
+	rateLimiter *application.RateLimiter,
+	usageService *application.UsageApplicationService,
 	defaultGeminiConfig *config.GeminiConfig,
+	attachmentDownloader application.AttachmentDownloader,
+	maxAttachmentBytes int64,
 ) *SlashCommandHandler {
-	return &SlashCommandHandler{
-		session:             session,
-		apiKeyService:       apiKeyService,
-		defaultGeminiConfig: defaultGeminiConfig,
-	}
+	h := &SlashCommandHandler{
+		session:              session,
+		apiKeyService:        apiKeyService,
+		channelConfigService: channelConfigService,
+		permissionService:    permissionService,
+		rateLimiter:          rateLimiter,
+		usageService:         usageService,
+		defaultGeminiConfig:  defaultGeminiConfig,
+		attachmentDownloader: attachmentDownloader,
+		maxAttachmentBytes:   maxAttachmentBytes,
+		responseHandler:      NewResponseHandler(),
+		componentHandlers:    make(map[string]ComponentHandlerFunc),
+	}
+	h.RegisterComponentHandler(promptModalPrefix, h.handlePromptModalSubmit)
+	return h
+}
+
+// RegisterComponentHandler は、カスタムIDが prefix で始まるコンポーネント操作・モーダル送信のハンドラーを登録します
+func (h *SlashCommandHandler) RegisterComponentHandler(prefix string, handler ComponentHandlerFunc) {
+	h.componentHandlers[prefix] = handler
+}
+
+// setAnswerController は、/askの回答の表示に使用するAnswerControllerを設定します
+func (h *SlashCommandHandler) setAnswerController(answers *AnswerController) {
+	h.answers = answers
+}
+
+// setExtractor は、/extractで会話から構造化データを抽出するサービスを設定します
+func (h *SlashCommandHandler) setExtractor(extractor conversationExtractor) {
+	h.extractor = extractor
 }
 
 // SetupSlashCommands は、スラッシュコマンドを設定します
@@ -129,9 +175,45 @@ func (h *SlashCommandHandler) SetupSlashCommands() error {
 						return choices
 					}(),
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "size",
+					Description: "画像のサイズ（縦横比として反映されます）",
+					Required:    false,
+					Choices: func() []*discordgo.ApplicationCommandOptionChoice {
+						sizes := domain.AllImageSizes()
+						choices := make([]*discordgo.ApplicationCommandOptionChoice, len(sizes))
+						for i, size := range sizes {
+							choices[i] = &discordgo.ApplicationCommandOptionChoice{
+								Name:  fmt.Sprintf("%s（%s）", size.DisplayName(), size.AspectRatio()),
+								Value: size.String(),
+							}
+						}
+						return choices
+					}(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "count",
+					Description: fmt.Sprintf("生成する画像の枚数（1〜%d）", domain.MaxImageCount),
+					Required:    false,
+					MinValue:    func() *float64 { v := 1.0; return &v }(),
+					MaxValue:    domain.MaxImageCount,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "image",
+					Description: "編集元の画像（指定するとプロンプトに従って画像を編集します）",
+					Required:    false,
+				},
 			},
 		},
 	}
+	commands = append(commands, promptCommands()...)
+	commands = append(commands, channelConfigCommand(), permissionsCommand(), usageCommand())
+	commands = append(commands, searchCommands()...)
+	commands = append(commands, extractCommand())
+	commands = append(commands, thinkingCommand(), fallbackCommand())
 
 	// グローバルコマンドとして登録
 	for _, command := range commands {
@@ -153,6 +235,10 @@ func (h *SlashCommandHandler) SetupSlashCommandHandlers() {
 
 // handleInteractionCreate は、インタラクション作成イベントを処理します
 func (h *SlashCommandHandler) handleInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionMessageComponent || i.Type == discordgo.InteractionModalSubmit {
+		h.handleComponentInteraction(s, i)
+		return
+	}
 	if i.Type != discordgo.InteractionApplicationCommand {
 		return
 	}
@@ -168,16 +254,56 @@ func (h *SlashCommandHandler) handleInteractionCreate(s *discordgo.Session, i *d
 		h.handleStatusCommand(s, i)
 	case "generate-image":
 		h.handleGenerateImageCommand(s, i)
+	case "set-prompt":
+		h.handleSetPromptCommand(s, i)
+	case "reset-prompt":
+		h.handleResetPromptCommand(s, i)
+	case "show-prompt":
+		h.handleShowPromptCommand(s, i)
+	case "channel-config":
+		h.handleChannelConfigCommand(s, i)
+	case "permissions":
+		h.handlePermissionsCommand(s, i)
+	case "usage":
+		h.handleUsageCommand(s, i)
+	case "ask":
+		h.handleAskCommand(s, i)
+	case "set-search":
+		h.handleSetSearchCommand(s, i)
+	case "extract":
+		h.handleExtractCommand(s, i)
+	case "set-thinking":
+		h.handleSetThinkingCommand(s, i)
+	case "set-fallback":
+		h.handleSetFallbackCommand(s, i)
 	default:
 		log.Printf("未知のスラッシュコマンド: %s", i.ApplicationCommandData().Name)
 	}
 }
 
+// handleComponentInteraction は、カスタムIDの接頭辞に対応するハンドラーにコンポーネント操作・モーダル送信を振り分けます
+func (h *SlashCommandHandler) handleComponentInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := ""
+	if i.Type == discordgo.InteractionModalSubmit {
+		customID = i.ModalSubmitData().CustomID
+	} else {
+		customID = i.MessageComponentData().CustomID
+	}
+
+	for prefix, handler := range h.componentHandlers {
+		if strings.HasPrefix(customID, prefix) {
+			handler(s, i)
+			return
+		}
+	}
+	log.Printf("未知のコンポーネント操作: %s", customID)
+}
+
 // handleSetAPICommand は、/set-apiコマンドを処理します
 func (h *SlashCommandHandler) handleSetAPICommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	// 権限チェック（管理者権限が必要）
-	if !h.hasAdminPermission(i.Member) {
-		h.respondToInteraction(s, i, "❌ このコマンドを実行するには管理者権限が必要です。", true)
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageAPIKeys) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
 		return
 	}
 
@@ -208,9 +334,9 @@ func (h *SlashCommandHandler) handleSetAPICommand(s *discordgo.Session, i *disco
 
 // handleDelAPICommand は、/del-apiコマンドを処理します
 func (h *SlashCommandHandler) handleDelAPICommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	// 権限チェック（管理者権限が必要）
-	if !h.hasAdminPermission(i.Member) {
-		h.respondToInteraction(s, i, "❌ このコマンドを実行するには管理者権限が必要です。", true)
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageAPIKeys) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
 		return
 	}
 
@@ -232,9 +358,9 @@ func (h *SlashCommandHandler) handleDelAPICommand(s *discordgo.Session, i *disco
 
 // handleSetModelCommand は、/set-modelコマンドを処理します
 func (h *SlashCommandHandler) handleSetModelCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	// 権限チェック（管理者権限が必要）
-	if !h.hasAdminPermission(i.Member) {
-		h.respondToInteraction(s, i, "❌ このコマンドを実行するには管理者権限が必要です。", true)
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
 		return
 	}
 
@@ -292,10 +418,11 @@ func (h *SlashCommandHandler) handleStatusCommand(s *discordgo.Session, i *disco
 
 		statusMessage = fmt.Sprintf(`📊 **サーバー設定状況**
 
-✅ **APIキー**: 設定済み
+✅ **APIキー**: 設定済み（%s）
 👤 **設定者**: %s
 📅 **設定日**: %s
 🤖 **使用モデル**: %s`,
+			apiKeyInfo.APIKeyFingerprint,
 			apiKeyInfo.SetBy,
 			setDate,
 			apiKeyInfo.Model)
@@ -312,6 +439,35 @@ func (h *SlashCommandHandler) handleStatusCommand(s *discordgo.Session, i *disco
 🤖 **使用モデル**: %s（デフォルト）`, model)
 	}
 
+	// 直近の応答で実際に使用したモデルを表示
+	if lastModel, ok := h.apiKeyService.GetLastUsedModel(guildID); ok {
+		statusMessage += fmt.Sprintf("\n🕒 **直近の応答で使用したモデル**: %s", lastModel)
+	}
+
+	// システムプロンプトの設定状況を表示
+	if prompt, err := h.apiKeyService.GetGuildSystemPrompt(ctx, guildID); err == nil && prompt != "" {
+		statusMessage += fmt.Sprintf("\n📝 **システムプロンプト**: サーバー固有（%d文字）", len([]rune(prompt)))
+	} else {
+		statusMessage += "\n📝 **システムプロンプト**: 既定"
+	}
+
+	// Google 検索によるグラウンディングの設定状況を表示
+	if enabled, err := h.apiKeyService.GetGuildSearchGrounding(ctx, guildID); err == nil && enabled {
+		statusMessage += "\n🔎 **Google 検索**: 有効"
+	} else {
+		statusMessage += "\n🔎 **Google 検索**: 無効"
+	}
+
+	// 思考の設定状況を表示
+	if thinking, err := h.apiKeyService.GetGuildThinking(ctx, guildID); err == nil {
+		statusMessage += "\n" + thinkingSettingsLine(thinking)
+	}
+
+	// フォールバックチェーンの設定状況を表示
+	if models, err := h.apiKeyService.GetGuildFallbackModels(ctx, guildID); err == nil {
+		statusMessage += "\n" + fallbackSettingsLine(models)
+	}
+
 	h.respondToInteraction(s, i, statusMessage, false)
 }
 
@@ -347,6 +503,46 @@ func (h *SlashCommandHandler) respondToInteraction(s *discordgo.Session, i *disc
 
 // handleGenerateImageCommand は、/generate-imageコマンドを処理します
 func (h *SlashCommandHandler) handleGenerateImageCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// ロール・チャンネルの許可リストと拒否リストに従って実行できるかを判定
+	if !h.hasPermission(s, i, domain.PermissionUseBot) || !h.hasPermission(s, i, domain.PermissionGenerateImages) {
+		h.respondToInteraction(s, i, "🚫 画像を生成する権限がありません。", true)
+		return
+	}
+
+	// チャンネル設定で画像生成が禁止されている場合は実行しない
+	settings := h.channelConfigService.ResolveSettings(context.Background(), i.GuildID, settingsChannelID(s, i.ChannelID))
+	if !settings.ImageGeneration {
+		h.respondToInteraction(s, i, "🚫 このチャンネルでは画像生成が無効になっています。", true)
+		return
+	}
+
+	// ユーザー・チャンネル・ギルドごとの画像生成のレート制限を確認
+	if err := h.rateLimiter.Allow(context.Background(), application.RateLimitRequest{
+		Kind:      domain.RequestKindImage,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		UserID:    interactionUserID(i),
+	}); err != nil {
+		log.Printf("レート制限により画像生成コマンドを拒否します: %v", err)
+		message, ok := formatKnownError(err)
+		if !ok {
+			message = fmt.Sprintf("❌ **エラーが発生しました**\n%s", err)
+		}
+		h.respondToInteraction(s, i, message, true)
+		return
+	}
+
+	// ギルドの今月の利用上限を確認（画像生成は代わりのモデルに切り替えずに拒否する）
+	if _, err := h.usageService.CheckBudget(context.Background(), i.GuildID, domain.RequestKindImage); err != nil {
+		log.Printf("利用上限により画像生成コマンドを拒否します: %v", err)
+		message, ok := formatKnownError(err)
+		if !ok {
+			message = fmt.Sprintf("❌ **エラーが発生しました**\n%s", err)
+		}
+		h.respondToInteraction(s, i, message, true)
+		return
+	}
+
 	// まず処理中メッセージを送信
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
@@ -375,6 +571,14 @@ func (h *SlashCommandHandler) handleGenerateImageCommand(s *discordgo.Session, i
 			request.Options.Style = domain.ImageStyleFromString(option.StringValue())
 		case "quality":
 			request.Options.Quality = domain.ImageQualityFromString(option.StringValue())
+		case "size":
+			request.Options.Size = domain.ImageSizeFromString(option.StringValue())
+		case "count":
+			request.Options.Count = int(option.IntValue())
+		case "image":
+			if attachment := h.resolveAttachment(i, option); attachment != nil {
+				request.SourceImages = append(request.SourceImages, *attachment)
+			}
 		}
 	}
 
@@ -408,6 +612,17 @@ func (h *SlashCommandHandler) handleGenerateImageCommand(s *discordgo.Session, i
 		log.Printf("ギルド %s のAPIキーが設定されていないため、デフォルトのAPIキーを使用", i.GuildID)
 	}
 
+	// 編集モードの場合は元画像を取得
+	if request.IsEdit() {
+		sourceImages, err := application.LoadSourceImages(ctx, h.attachmentDownloader, request.SourceImages, h.maxAttachmentBytes)
+		if err != nil {
+			log.Printf("編集元の画像の取得に失敗: %v", err)
+			h.followUpInteraction(s, i, fmt.Sprintf("❌ 編集元の画像を取得できませんでした: %v", err), true)
+			return
+		}
+		request.SourceImages = sourceImages
+	}
+
 	// Geminiクライアントを作成
 	geminiClient, err := gemini.NewStructuredGeminiClientWithAPIKey(apiKey, h.defaultGeminiConfig)
 	if err != nil {
@@ -420,26 +635,46 @@ func (h *SlashCommandHandler) handleGenerateImageCommand(s *discordgo.Session, i
 	response, err := geminiClient.GenerateImage(ctx, request)
 	if err != nil {
 		log.Printf("画像生成に失敗: %v", err)
-		h.followUpInteraction(s, i, fmt.Sprintf("❌ 画像生成に失敗しました: %v", err), true)
+		h.followUpInteraction(s, i, h.responseHandler.formatUnifiedError(domain.NewErrorResponse(err, "image")), true)
 		return
 	}
 
+	h.usageService.RecordUsage(ctx, domain.UsageRecord{
+		GuildID:   i.GuildID,
+		UserID:    interactionUserID(i),
+		ChannelID: settingsChannelID(s, i.ChannelID),
+		Model:     response.Model,
+		Kind:      domain.RequestKindImage,
+		Usage:     response.Usage,
+	})
+
 	if len(response.Images) == 0 {
 		h.followUpInteraction(s, i, "❌ 画像が生成されませんでした。", true)
 		return
 	}
 
-	// 生成された画像をDiscordに送信
-	image := response.Images[0]
-	file := &discordgo.File{
-		Name:        image.Filename,
-		ContentType: image.MimeType,
-		Reader:      bytes.NewReader(image.Data),
+	// 生成された全ての画像を1つのメッセージにまとめてギャラリーとして送信
+	files := make([]*discordgo.File, len(response.Images))
+	for idx, image := range response.Images {
+		files[idx] = &discordgo.File{
+			Name:        image.Filename,
+			ContentType: image.MimeType,
+			Reader:      bytes.NewReader(image.Data),
+		}
+	}
+
+	title := "🎨 画像生成完了"
+	if request.IsEdit() {
+		title = "🎨 画像編集完了"
 	}
 
+	description := fmt.Sprintf("**プロンプト:** %s\n**スタイル:** %s\n**品質:** %s\n**サイズ:** %s（%s）\n**枚数:** %d/%d枚",
+		request.Prompt, request.Options.Style, request.Options.Quality,
+		request.Options.Size, request.Options.Size.AspectRatio(), len(response.Images), request.Options.ImageCount())
+
 	embed := &discordgo.MessageEmbed{
-		Title:       "🎨 画像生成完了",
-		Description: fmt.Sprintf("**プロンプト:** %s\n**スタイル:** %s\n**品質:** %s", request.Prompt, request.Options.Style, request.Options.Quality),
+		Title:       title,
+		Description: description,
 		Color:       0x00ff00,
 		Timestamp:   response.GeneratedAt.Format(time.RFC3339),
 		Footer: &discordgo.MessageEmbedFooter{
@@ -449,7 +684,7 @@ func (h *SlashCommandHandler) handleGenerateImageCommand(s *discordgo.Session, i
 
 	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 		Embeds: []*discordgo.MessageEmbed{embed},
-		Files:  []*discordgo.File{file},
+		Files:  files,
 	})
 	if err != nil {
 		log.Printf("画像の送信に失敗: %v", err)
@@ -458,6 +693,31 @@ func (h *SlashCommandHandler) handleGenerateImageCommand(s *discordgo.Session, i
 	}
 }
 
+// resolveAttachment は、添付ファイル型のオプションに指定されたファイルを取得します
+func (h *SlashCommandHandler) resolveAttachment(i *discordgo.InteractionCreate, option *discordgo.ApplicationCommandInteractionDataOption) *domain.Attachment {
+	resolved := i.ApplicationCommandData().Resolved
+	if resolved == nil {
+		return nil
+	}
+
+	attachmentID, ok := option.Value.(string)
+	if !ok {
+		return nil
+	}
+
+	attachment, ok := resolved.Attachments[attachmentID]
+	if !ok {
+		log.Printf("添付ファイル %s が見つかりません", attachmentID)
+		return nil
+	}
+
+	converted := discordInfra.ToDomainAttachments([]*discordgo.MessageAttachment{attachment})
+	if len(converted) == 0 {
+		return nil
+	}
+	return &converted[0]
+}
+
 // followUpInteraction は、フォローアップメッセージを送信します
 func (h *SlashCommandHandler) followUpInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, content string, ephemeral bool) {
 	var flags discordgo.MessageFlags
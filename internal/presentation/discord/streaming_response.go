@@ -0,0 +1,242 @@
+package discord
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// StreamEditInterval は、ストリーミング中にメッセージを編集する最小間隔です（Discordのレート制限対策）
+const StreamEditInterval = 1500 * time.Millisecond
+
+// streamCursor は、生成中であることを示すためにメッセージ末尾に表示する記号です
+const streamCursor = " ▌"
+
+// streamMessenger は、ストリーミング応答で使用するメッセージ操作を抽象化します
+// Edit の components が nil の場合は、メッセージのボタンを変更しません
+type streamMessenger interface {
+	ChannelID() string
+	Send(content string, components []discordgo.MessageComponent) (string, error)
+	Edit(messageID, content string, components *[]discordgo.MessageComponent) error
+	Delete(messageID string) error
+	SendEmbed(embed *discordgo.MessageEmbed) (string, error)
+	SendFiles(files []*discordgo.File) (string, error)
+}
+
+// sessionStreamMessenger は、discordgo.Session を使用して送信先チャンネルのメッセージを操作します
+type sessionStreamMessenger struct {
+	session   *discordgo.Session
+	channelID string
+	reference *discordgo.MessageReference // リプライで送信する場合のみ設定
+}
+
+// ChannelID は、メッセージの送信先チャンネルのIDを返します
+func (m *sessionStreamMessenger) ChannelID() string {
+	return m.channelID
+}
+
+// Send は、メッセージを送信し、送信したメッセージのIDを返します
+func (m *sessionStreamMessenger) Send(content string, components []discordgo.MessageComponent) (string, error) {
+	msg, err := m.session.ChannelMessageSendComplex(m.channelID, &discordgo.MessageSend{
+		Content:    content,
+		Components: components,
+		Reference:  m.reference,
+	})
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// Edit は、送信済みのメッセージを編集します
+func (m *sessionStreamMessenger) Edit(messageID, content string, components *[]discordgo.MessageComponent) error {
+	edit := discordgo.NewMessageEdit(m.channelID, messageID).SetContent(content)
+	edit.Components = components
+	_, err := m.session.ChannelMessageEditComplex(edit)
+	return err
+}
+
+// SendEmbed は、埋め込みのみのメッセージを送信し、送信したメッセージのIDを返します
+func (m *sessionStreamMessenger) SendEmbed(embed *discordgo.MessageEmbed) (string, error) {
+	msg, err := m.session.ChannelMessageSendComplex(m.channelID, &discordgo.MessageSend{
+		Embeds:    []*discordgo.MessageEmbed{embed},
+		Reference: m.reference,
+	})
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// SendFiles は、ファイルのみのメッセージを送信し、送信したメッセージのIDを返します
+func (m *sessionStreamMessenger) SendFiles(files []*discordgo.File) (string, error) {
+	msg, err := m.session.ChannelMessageSendComplex(m.channelID, &discordgo.MessageSend{
+		Files:     files,
+		Reference: m.reference,
+	})
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// Delete は、送信済みのメッセージを削除します
+func (m *sessionStreamMessenger) Delete(messageID string) error {
+	return m.session.ChannelMessageDelete(m.channelID, messageID)
+}
+
+// StreamingResponse は、生成中のテキストを処理中メッセージの編集で段階的に表示します
+// 文字数制限に達した場合は、続きを新しいメッセージに送信します
+type StreamingResponse struct {
+	messenger  streamMessenger
+	interval   time.Duration
+	messageIDs []string // 送信済みメッセージ（先頭は処理中メッセージ）
+	current    string   // 最後のメッセージに表示中のテキスト
+	received   strings.Builder
+	lastEdit   time.Time
+}
+
+// newStreamingResponse は、送信済みの処理中メッセージを起点とする StreamingResponse を作成します
+func newStreamingResponse(messenger streamMessenger, placeholderID string, interval time.Duration) *StreamingResponse {
+	return &StreamingResponse{
+		messenger:  messenger,
+		interval:   interval,
+		messageIDs: []string{placeholderID},
+	}
+}
+
+// Append は、受信したテキストを追加し、編集間隔を空けてメッセージに反映します
+func (r *StreamingResponse) Append(chunk string) {
+	if chunk == "" {
+		return
+	}
+	r.received.WriteString(chunk)
+	r.current += chunk
+
+	// カーソルを含めて制限を超える場合は、現在のメッセージを確定して続きを新しいメッセージに送信
+	limit := DiscordMessageLimit - len(streamCursor)
+	for len(r.current) > limit {
+		splitIndex := findSplitIndex(r.current, limit)
+		head := r.current[:splitIndex]
+		rest := strings.TrimLeft(r.current[splitIndex:], " \n")
+
+		if err := r.messenger.Edit(r.lastMessageID(), head, nil); err != nil {
+			log.Printf("ストリーミング中のメッセージ編集に失敗: %v", err)
+		}
+
+		id, err := r.messenger.Send(rest[:findSplitIndex(rest, limit)]+streamCursor, nil)
+		if err != nil {
+			log.Printf("ストリーミング中の続きのメッセージ送信に失敗: %v", err)
+			r.current = rest
+			return
+		}
+		r.messageIDs = append(r.messageIDs, id)
+		r.current = rest
+		r.lastEdit = time.Now()
+	}
+
+	if time.Since(r.lastEdit) < r.interval {
+		return
+	}
+	r.flush()
+}
+
+// Finish は、最終的な応答テキストを分割し直して送信済みメッセージに反映し、最後のメッセージのIDを返します
+// 不足するメッセージは送信し、余ったメッセージは削除します
+// components は最後のメッセージにのみ付け、それ以外のメッセージのボタン（生成中の停止ボタンなど）は取り除きます
+func (r *StreamingResponse) Finish(content string, components []discordgo.MessageComponent) string {
+	chunks := splitMessage(content)
+
+	for i, chunk := range chunks {
+		chunkComponents := []discordgo.MessageComponent{}
+		if i == len(chunks)-1 && components != nil {
+			chunkComponents = components
+		}
+
+		if i < len(r.messageIDs) {
+			if err := r.messenger.Edit(r.messageIDs[i], chunk, &chunkComponents); err != nil {
+				log.Printf("応答メッセージの編集に失敗 (チャンク %d): %v", i+1, err)
+			}
+			continue
+		}
+
+		id, err := r.messenger.Send(chunk, chunkComponents)
+		if err != nil {
+			log.Printf("応答メッセージの送信に失敗 (チャンク %d): %v", i+1, err)
+			break
+		}
+		r.messageIDs = append(r.messageIDs, id)
+	}
+
+	for _, id := range r.messageIDs[min(len(chunks), len(r.messageIDs)):] {
+		if err := r.messenger.Delete(id); err != nil {
+			log.Printf("不要になったメッセージの削除に失敗: %v", err)
+		}
+	}
+	r.messageIDs = r.messageIDs[:min(len(chunks), len(r.messageIDs))]
+	return r.lastMessageID()
+}
+
+// Fail は、途中まで表示した応答を取り消し、先頭のメッセージをエラーメッセージに置き換えます
+func (r *StreamingResponse) Fail(errorMessage string) {
+	if err := r.messenger.Edit(r.messageIDs[0], errorMessage, &[]discordgo.MessageComponent{}); err != nil {
+		log.Printf("エラーメッセージの表示に失敗: %v", err)
+	}
+
+	for _, id := range r.messageIDs[1:] {
+		if err := r.messenger.Delete(id); err != nil {
+			log.Printf("途中まで送信したメッセージの削除に失敗: %v", err)
+		}
+	}
+	r.messageIDs = r.messageIDs[:1]
+}
+
+// SendEmbed は、確定した応答の後ろに、埋め込み（回答の引用元など）のみのメッセージを送信します
+func (r *StreamingResponse) SendEmbed(embed *discordgo.MessageEmbed) {
+	if embed == nil {
+		return
+	}
+	if _, err := r.messenger.SendEmbed(embed); err != nil {
+		log.Printf("埋め込みの送信に失敗: %v", err)
+	}
+}
+
+// SendFiles は、確定した応答の後ろに、ファイル（コード実行で作成された画像など）のみのメッセージを送信します
+func (r *StreamingResponse) SendFiles(files []*discordgo.File) {
+	if len(files) == 0 {
+		return
+	}
+	if _, err := r.messenger.SendFiles(files); err != nil {
+		log.Printf("ファイルの送信に失敗: %v", err)
+	}
+}
+
+// Received は、これまでに受信したテキスト全体を返します（生成を停止した場合の途中までの応答）
+func (r *StreamingResponse) Received() string {
+	return r.received.String()
+}
+
+// FirstMessageID は、ストリーミング応答の先頭のメッセージ（処理中メッセージ）のIDを返します
+func (r *StreamingResponse) FirstMessageID() string {
+	return r.messageIDs[0]
+}
+
+// ChannelID は、ストリーミング応答を送信しているチャンネル（スレッド）のIDを返します
+func (r *StreamingResponse) ChannelID() string {
+	return r.messenger.ChannelID()
+}
+
+// flush は、現在のテキストをカーソル付きで最後のメッセージに反映します
+func (r *StreamingResponse) flush() {
+	if err := r.messenger.Edit(r.lastMessageID(), r.current+streamCursor, nil); err != nil {
+		log.Printf("ストリーミング中のメッセージ編集に失敗: %v", err)
+	}
+	r.lastEdit = time.Now()
+}
+
+// lastMessageID は、現在テキストを表示している最後のメッセージのIDを返します
+func (r *StreamingResponse) lastMessageID() string {
+	return r.messageIDs[len(r.messageIDs)-1]
+}
@@ -0,0 +1,236 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeStreamMessenger は、送信・編集・削除の結果をメモリ上に保持するテスト用の streamMessenger です
+type fakeStreamMessenger struct {
+	messages   map[string]string
+	components map[string][]discordgo.MessageComponent
+	order      []string
+	edits      int
+	embeds     map[string]*discordgo.MessageEmbed
+	files      map[string][]*discordgo.File
+}
+
+func newFakeStreamMessenger() *fakeStreamMessenger {
+	return &fakeStreamMessenger{
+		messages:   make(map[string]string),
+		components: make(map[string][]discordgo.MessageComponent),
+		embeds:     make(map[string]*discordgo.MessageEmbed),
+		files:      make(map[string][]*discordgo.File),
+	}
+}
+
+func (f *fakeStreamMessenger) ChannelID() string {
+	return "channel1"
+}
+
+func (f *fakeStreamMessenger) Send(content string, components []discordgo.MessageComponent) (string, error) {
+	id := fmt.Sprintf("msg%d", len(f.order)+1)
+	f.messages[id] = content
+	f.components[id] = components
+	f.order = append(f.order, id)
+	return id, nil
+}
+
+func (f *fakeStreamMessenger) Edit(messageID, content string, components *[]discordgo.MessageComponent) error {
+	if _, ok := f.messages[messageID]; !ok {
+		return fmt.Errorf("メッセージ %s が存在しません", messageID)
+	}
+	f.messages[messageID] = content
+	if components != nil {
+		f.components[messageID] = *components
+	}
+	f.edits++
+	return nil
+}
+
+func (f *fakeStreamMessenger) SendEmbed(embed *discordgo.MessageEmbed) (string, error) {
+	id, _ := f.Send("", nil)
+	f.embeds[id] = embed
+	return id, nil
+}
+
+func (f *fakeStreamMessenger) SendFiles(files []*discordgo.File) (string, error) {
+	id, _ := f.Send("", nil)
+	f.files[id] = files
+	return id, nil
+}
+
+func (f *fakeStreamMessenger) Delete(messageID string) error {
+	delete(f.messages, messageID)
+	delete(f.components, messageID)
+	return nil
+}
+
+// customIDs は、メッセージに付いているボタンのカスタムIDを返します
+func (f *fakeStreamMessenger) customIDs(messageID string) []string {
+	var ids []string
+	for _, component := range f.components[messageID] {
+		row, ok := component.(discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			if button, ok := c.(discordgo.Button); ok {
+				ids = append(ids, button.CustomID)
+			}
+		}
+	}
+	return ids
+}
+
+// visible は、削除されていないメッセージの内容を送信順に返します
+func (f *fakeStreamMessenger) visible() []string {
+	var contents []string
+	for _, id := range f.order {
+		if content, ok := f.messages[id]; ok {
+			contents = append(contents, content)
+		}
+	}
+	return contents
+}
+
+func startFakeStream(t *testing.T) (*StreamingResponse, *fakeStreamMessenger) {
+	t.Helper()
+	messenger := newFakeStreamMessenger()
+	placeholderID, _ := messenger.Send("🤔 考え中...", nil)
+	return newStreamingResponse(messenger, placeholderID, 0), messenger
+}
+
+func TestStreamingResponse_AppendEditsPlaceholder(t *testing.T) {
+	stream, messenger := startFakeStream(t)
+
+	stream.Append("こんにちは")
+	stream.Append("、世界")
+
+	visible := messenger.visible()
+	if len(visible) != 1 || visible[0] != "こんにちは、世界"+streamCursor {
+		t.Errorf("処理中メッセージが編集されていません: %q", visible)
+	}
+
+	stream.Finish("こんにちは、世界", nil)
+	visible = messenger.visible()
+	if len(visible) != 1 || visible[0] != "こんにちは、世界" {
+		t.Errorf("最終的な応答が反映されていません: %q", visible)
+	}
+}
+
+func TestStreamingResponse_ThrottlesEdits(t *testing.T) {
+	messenger := newFakeStreamMessenger()
+	placeholderID, _ := messenger.Send("🤔 考え中...", nil)
+	stream := newStreamingResponse(messenger, placeholderID, StreamEditInterval)
+
+	for i := 0; i < 10; i++ {
+		stream.Append("あ")
+	}
+
+	if messenger.edits != 1 {
+		t.Errorf("編集間隔内の編集はまとめられるべきです: %d回", messenger.edits)
+	}
+}
+
+func TestStreamingResponse_RollsOverLongResponse(t *testing.T) {
+	stream, messenger := startFakeStream(t)
+
+	line := strings.Repeat("あ", 100) + "\n"
+	var full strings.Builder
+	for i := 0; i < 20; i++ {
+		stream.Append(line)
+		full.WriteString(line)
+	}
+
+	visible := messenger.visible()
+	if len(visible) < 3 {
+		t.Fatalf("文字数制限で新しいメッセージに分割されるべきです: %d件", len(visible))
+	}
+	for i, content := range visible {
+		if len(content) > DiscordMessageLimit {
+			t.Errorf("メッセージ %d が文字数制限を超えています: %d", i+1, len(content))
+		}
+		if !utf8.ValidString(content) {
+			t.Errorf("メッセージ %d が文字の途中で分割されています", i+1)
+		}
+	}
+
+	stream.Finish(full.String(), nil)
+	visible = messenger.visible()
+	expected := splitMessage(full.String())
+	if len(visible) != len(expected) {
+		t.Fatalf("最終的なメッセージ数: 期待 %d, 実際 %d", len(expected), len(visible))
+	}
+	for i := range expected {
+		if visible[i] != expected[i] {
+			t.Errorf("メッセージ %d の内容が最終的な応答と一致しません", i+1)
+		}
+	}
+}
+
+func TestStreamingResponse_FinishDeletesExtraMessages(t *testing.T) {
+	stream, messenger := startFakeStream(t)
+
+	stream.Append(strings.Repeat("a ", DiscordMessageLimit))
+	if len(messenger.visible()) < 2 {
+		t.Fatalf("ストリーミング中に複数のメッセージが送信されるべきです")
+	}
+
+	stream.Finish("短い応答", nil)
+	visible := messenger.visible()
+	if len(visible) != 1 || visible[0] != "短い応答" {
+		t.Errorf("余ったメッセージが削除されていません: %q", visible)
+	}
+}
+
+func TestStreamingResponse_Fail(t *testing.T) {
+	stream, messenger := startFakeStream(t)
+
+	stream.Append(strings.Repeat("a ", DiscordMessageLimit))
+	stream.Fail("❌ **エラーが発生しました**")
+
+	visible := messenger.visible()
+	if len(visible) != 1 || visible[0] != "❌ **エラーが発生しました**" {
+		t.Errorf("エラーメッセージに置き換えられていません: %q", visible)
+	}
+}
+
+func TestStreamingResponse_FinishMovesComponentsToLastMessage(t *testing.T) {
+	messenger := newFakeStreamMessenger()
+	placeholderID, _ := messenger.Send("🤔 考え中...", stopComponents())
+	stream := newStreamingResponse(messenger, placeholderID, 0)
+
+	long := strings.Repeat("a ", DiscordMessageLimit)
+	stream.Append(long)
+	lastID := stream.Finish(long, answerComponents(true))
+
+	if lastID == placeholderID {
+		t.Fatalf("長い応答は複数のメッセージに分割されるべきです")
+	}
+	if ids := messenger.customIDs(placeholderID); len(ids) != 0 {
+		t.Errorf("先頭のメッセージから停止ボタンが取り除かれていません: %v", ids)
+	}
+	if ids := messenger.customIDs(lastID); len(ids) != 2 || ids[0] != answerRegenerateID || ids[1] != answerContinueID {
+		t.Errorf("最後のメッセージに回答のボタンが付いていません: %v", ids)
+	}
+	if stream.Received() != long {
+		t.Errorf("受信したテキスト全体が保持されていません")
+	}
+}
+
+func TestFindSplitIndex_DoesNotBreakRunes(t *testing.T) {
+	message := strings.Repeat("あ", 1000)
+
+	splitIndex := findSplitIndex(message, DiscordMessageLimit)
+	if splitIndex > DiscordMessageLimit {
+		t.Errorf("分割位置が制限を超えています: %d", splitIndex)
+	}
+	if !utf8.ValidString(message[:splitIndex]) || !utf8.ValidString(message[splitIndex:]) {
+		t.Errorf("文字の途中で分割されています: %d", splitIndex)
+	}
+}
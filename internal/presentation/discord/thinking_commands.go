@@ -0,0 +1,141 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// thoughtsEmbedColor は、思考の要約の埋め込みの色です
+	thoughtsEmbedColor = 0x9b59b6
+	// thoughtsSpoiler は、思考の要約をクリックで展開できるよう囲むスポイラーの記号です
+	thoughtsSpoiler = "||"
+)
+
+// thinkingBudgetOption は、思考予算を指定する整数のオプションを作成します
+func thinkingBudgetOption(name, description string) *discordgo.ApplicationCommandOption {
+	minBudget := float64(domain.ThinkingBudgetDynamic)
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        name,
+		Description: description,
+		Required:    false,
+		MinValue:    &minBudget,
+		MaxValue:    domain.MaxThinkingBudget,
+	}
+}
+
+// thinkingCommand は、/set-thinkingコマンドの定義を返します
+func thinkingCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "set-thinking",
+		Description: "このサーバーでモデルが思考に使うトークン数と、思考の要約を表示するかどうかを設定します",
+		Options: []*discordgo.ApplicationCommandOption{
+			thinkingBudgetOption("budget", "思考に使う最大のトークン数（-1 でモデルが決める、0 で思考しない）"),
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "show-thinking",
+				Description: "回答とは別に思考の要約を表示するかどうか",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "reset",
+				Description: "思考に使うトークン数を全体の既定に戻すかどうか",
+				Required:    false,
+			},
+		},
+	}
+}
+
+// applyThinkingOptions は、/set-thinkingのオプションで指定された項目を current に適用した設定を返します
+// 変更する項目が指定されなかった場合は false を返します
+func applyThinkingOptions(current domain.ThinkingSettings, options []*discordgo.ApplicationCommandInteractionDataOption) (domain.ThinkingSettings, bool) {
+	settings := current
+	changed := false
+	for _, option := range options {
+		switch option.Name {
+		case "budget":
+			budget := int(option.IntValue())
+			settings.Budget = &budget
+			changed = true
+		case "show-thinking":
+			settings.IncludeThoughts = option.BoolValue()
+			changed = true
+		case "reset":
+			if option.BoolValue() {
+				settings.Budget = nil
+				changed = true
+			}
+		}
+	}
+	return settings, changed
+}
+
+// thinkingSettingsLine は、思考の設定を1行で表示します
+func thinkingSettingsLine(settings domain.ThinkingSettings) string {
+	show := "非表示"
+	if settings.IncludeThoughts {
+		show = "表示"
+	}
+	return fmt.Sprintf("💭 **思考**: 予算 %s・要約 %s", domain.FormatThinkingBudget(settings.Budget), show)
+}
+
+// handleSetThinkingCommand は、/set-thinkingコマンドを処理します
+// オプションを指定しなかった場合は、現在の設定を表示します
+func (h *SlashCommandHandler) handleSetThinkingCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageModels) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	ctx := context.Background()
+	current, err := h.apiKeyService.GetGuildThinking(ctx, i.GuildID)
+	if err != nil {
+		log.Printf("思考の設定の取得に失敗: %v", err)
+		h.respondToInteraction(s, i, "❌ 思考の設定の取得に失敗しました。", true)
+		return
+	}
+
+	settings, changed := applyThinkingOptions(current, i.ApplicationCommandData().Options)
+	if !changed {
+		h.respondToInteraction(s, i, "📊 **このサーバーの思考の設定**\n"+thinkingSettingsLine(current), true)
+		return
+	}
+
+	if err := h.apiKeyService.SetGuildThinking(ctx, i.GuildID, settings); err != nil {
+		log.Printf("思考の設定に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ 思考の設定に失敗しました: %v", err), true)
+		return
+	}
+
+	message := fmt.Sprintf("✅ このサーバーの思考の設定を更新しました。\n%s\n設定者: %s\n\n-# 思考に対応していないモデルでは無視し、モデルの範囲外のトークン数は範囲内に収めます。`/ask` の thinking-budget・show-thinking で質問ごとに変更できます。",
+		thinkingSettingsLine(settings), i.Member.User.Username)
+	h.respondToInteraction(s, i, message, false)
+}
+
+// thoughtsEmbed は、モデルの思考の要約を、クリックで展開できるスポイラーで囲んだ埋め込みを作成します（要約がない場合は nil を返します）
+func (h *ResponseHandler) thoughtsEmbed(thoughts string) *discordgo.MessageEmbed {
+	thoughts = strings.TrimSpace(thoughts)
+	if thoughts == "" {
+		return nil
+	}
+
+	// スポイラーを途中で閉じてしまう記号は全角に置き換える
+	thoughts = strings.ReplaceAll(thoughts, thoughtsSpoiler, "｜｜")
+	thoughts = truncateRunes(thoughts, embedDescriptionLimit-2*len(thoughtsSpoiler)-len("..."))
+
+	return &discordgo.MessageEmbed{
+		Title:       "💭 思考の要約",
+		Description: thoughtsSpoiler + thoughts + thoughtsSpoiler,
+		Color:       thoughtsEmbedColor,
+		Footer:      &discordgo.MessageEmbedFooter{Text: "クリックで表示"},
+	}
+}
@@ -0,0 +1,72 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestApplyThinkingOptions(t *testing.T) {
+	current := domain.ThinkingSettings{IncludeThoughts: true}
+
+	if _, changed := applyThinkingOptions(current, nil); changed {
+		t.Error("オプションがない場合は変更なしとするべきです")
+	}
+
+	settings, changed := applyThinkingOptions(current, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "budget", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(2048)},
+	})
+	if !changed || settings.Budget == nil || *settings.Budget != 2048 || !settings.IncludeThoughts {
+		t.Errorf("指定した項目のみ変更するべきです: %+v", settings)
+	}
+
+	settings, changed = applyThinkingOptions(settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "reset", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+		{Name: "show-thinking", Type: discordgo.ApplicationCommandOptionBoolean, Value: false},
+	})
+	if !changed || settings.Budget != nil || settings.IncludeThoughts {
+		t.Errorf("思考予算を既定に戻し、思考の要約を非表示にするべきです: %+v", settings)
+	}
+}
+
+func TestAskThinking(t *testing.T) {
+	thinking := askThinking([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "question", Type: discordgo.ApplicationCommandOptionString, Value: "質問"},
+		{Name: "thinking-budget", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(-1)},
+		{Name: "show-thinking", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+	})
+	if thinking.Budget == nil || *thinking.Budget != domain.ThinkingBudgetDynamic || !thinking.IncludeThoughts {
+		t.Errorf("askThinking() = %+v", thinking)
+	}
+
+	if thinking := askThinking(nil); thinking.Budget != nil || thinking.IncludeThoughts {
+		t.Errorf("オプションがない場合はゼロ値であるべきです: %+v", thinking)
+	}
+}
+
+func TestThoughtsEmbed(t *testing.T) {
+	h := NewResponseHandler()
+
+	if h.thoughtsEmbed("  ") != nil {
+		t.Error("思考の要約がない場合は埋め込みを作成するべきではありません")
+	}
+
+	embed := h.thoughtsEmbed("まず||条件||を整理する")
+	if embed == nil {
+		t.Fatal("思考の要約の埋め込みが作成されていません")
+	}
+	if embed.Description != "||まず｜｜条件｜｜を整理する||" {
+		t.Errorf("思考の要約はスポイラーで囲むべきです: %q", embed.Description)
+	}
+
+	long := h.thoughtsEmbed(strings.Repeat("考", embedDescriptionLimit))
+	if n := len([]rune(long.Description)); n > embedDescriptionLimit {
+		t.Errorf("説明の長さ %d が上限を超えています", n)
+	}
+	if !strings.HasSuffix(long.Description, thoughtsSpoiler) {
+		t.Error("長い思考の要約も最後までスポイラーで囲むべきです")
+	}
+}
@@ -1,40 +1,42 @@
 package discord
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"strings"
 	"testing"
+	"time"
+
+	"geminibot/internal/domain"
 )
 
 func TestDiscordHandler_IsTimeoutError(t *testing.T) {
 	handler := NewResponseHandler()
 
-	// タイムアウトエラーのテストケース
-	timeoutErrors := []string{
-		"context deadline exceeded",
-		"timeout",
-		"タイムアウト",
-		"deadline exceeded",
-		"context deadline",
-		"request timeout",
-		"TIMEOUT",
-		"タイムアウトしました",
-		"Request timeout",
+	// タイムアウトエラーのテストケース（ラップされたエラーも種類で判定する）
+	timeoutErrors := []error{
+		domain.ErrTimeout,
+		fmt.Errorf("チャット履歴の取得が%w: %w", domain.ErrTimeout, errors.New("HTTP 500")),
+		context.DeadlineExceeded,
+		fmt.Errorf("送信に失敗: %w", context.DeadlineExceeded),
+		&net.OpError{Op: "dial", Err: &timeoutNetError{}},
 	}
 
-	for _, errMsg := range timeoutErrors {
-		err := &timeoutError{message: errMsg}
+	for _, err := range timeoutErrors {
 		if !handler.isTimeoutError(err) {
-			t.Errorf("タイムアウトエラーとして認識されるべき: %s", errMsg)
+			t.Errorf("タイムアウトエラーとして認識されるべき: %v", err)
 		}
 	}
 
-	// 非タイムアウトエラーのテストケース
+	// 非タイムアウトエラーのテストケース（メッセージにタイムアウトを含むだけのエラーも含む）
 	nonTimeoutErrors := []string{
 		"network error",
 		"permission denied",
-		"invalid token",
-		"エラーが発生しました",
-		"something went wrong",
+		"context deadline exceeded",
+		"タイムアウトしました",
+		"Request timeout",
 	}
 
 	for _, errMsg := range nonTimeoutErrors {
@@ -43,6 +45,9 @@ func TestDiscordHandler_IsTimeoutError(t *testing.T) {
 			t.Errorf("タイムアウトエラーとして認識されるべきではない: %s", errMsg)
 		}
 	}
+	if handler.isTimeoutError(fmt.Errorf("%w。APIキーを確認してください", domain.ErrUnauthorized)) {
+		t.Error("権限エラーはタイムアウトエラーとして認識されるべきではない")
+	}
 
 	// nilエラーのテスト
 	if handler.isTimeoutError(nil) {
@@ -54,7 +59,7 @@ func TestDiscordHandler_FormatError(t *testing.T) {
 	handler := NewResponseHandler()
 
 	// タイムアウトエラーのフォーマットテスト
-	timeoutErr := &timeoutError{message: "context deadline exceeded"}
+	timeoutErr := fmt.Errorf("Gemini APIからの応答取得が%w: %w", domain.ErrTimeout, context.DeadlineExceeded)
 	formatted := handler.formatError(timeoutErr)
 
 	if !strings.Contains(formatted, "⏰ **タイムアウトしました**") {
@@ -65,12 +70,54 @@ func TestDiscordHandler_FormatError(t *testing.T) {
 		t.Error("タイムアウトエラーメッセージに対処法が含まれていません")
 	}
 
-	// 荒らし対策エラーのフォーマットテスト
-	spamErr := &timeoutError{message: "レート制限を超過しました"}
-	formatted = handler.formatError(spamErr)
+	// 荒らし対策エラーのフォーマットテスト（ラップされたエラーも種類で判定する）
+	rateLimitErr := fmt.Errorf("メンションの処理を拒否: %w", &domain.RateLimitError{
+		Scope:      domain.RateLimitScopeChannel,
+		Kind:       domain.RequestKindImage,
+		RetryAfter: 90 * time.Second,
+	})
+	formatted = handler.formatError(rateLimitErr)
+
+	if !strings.Contains(formatted, "⚠️ **レート制限を超過しました**") || !strings.Contains(formatted, "チャンネルごとの画像生成の上限に達しました。1m30s後") {
+		t.Errorf("レート制限エラーメッセージが正しくフォーマットされていません: %s", formatted)
+	}
+
+	formatted = handler.formatError(&domain.RateLimitError{Scope: domain.RateLimitScopeUser, Kind: domain.RequestKindText, Spam: true})
+	if !strings.Contains(formatted, "🚫 **スパムが検出されました**") {
+		t.Errorf("スパムエラーメッセージが正しくフォーマットされていません: %s", formatted)
+	}
 
-	if !strings.Contains(formatted, "⚠️ **レート制限を超過しました**") {
-		t.Error("レート制限エラーメッセージが正しくフォーマットされていません")
+	// エラーメッセージが同じでも、種類の異なるエラーは荒らし対策エラーとして扱わない
+	formatted = handler.formatError(&timeoutError{message: "レート制限を超過しました"})
+	if strings.Contains(formatted, "⚠️") {
+		t.Error("文字列の一致だけでレート制限エラーと判定されています")
+	}
+
+	// 統一レスポンスのエラーも種類で判定する
+	formatted = handler.formatUnifiedError(domain.NewErrorResponse(domain.ErrDuplicateMessage, "image"))
+	if !strings.Contains(formatted, "🔄 **重複メッセージが検出されました**") {
+		t.Errorf("統一レスポンスのエラーメッセージが正しくフォーマットされていません: %s", formatted)
+	}
+
+	// Gemini APIのエラーも種類で判定する
+	formatted = handler.formatError(fmt.Errorf("応答の生成に失敗: %w", &domain.SafetyBlockedError{Categories: []string{"ハラスメント"}}))
+	if !strings.Contains(formatted, "🚫 **安全フィルターにより応答がブロックされました**") || !strings.Contains(formatted, "該当したカテゴリ: ハラスメント") {
+		t.Errorf("安全フィルターのエラーメッセージが正しくフォーマットされていません: %s", formatted)
+	}
+
+	formatted = handler.formatError(fmt.Errorf("%w。しばらく時間を置いてから再度お試しください", domain.ErrQuotaExceeded))
+	if !strings.Contains(formatted, "⏳ **Gemini APIの利用制限に達しました**") {
+		t.Errorf("利用制限のエラーメッセージが正しくフォーマットされていません: %s", formatted)
+	}
+
+	formatted = handler.formatError(&timeoutError{message: "Gemini APIの利用制限に達しました"})
+	if strings.Contains(formatted, "⏳") {
+		t.Error("文字列の一致だけで利用制限のエラーと判定されています")
+	}
+
+	formatted = handler.formatUnifiedError(domain.NewErrorResponse(&domain.SafetyBlockedError{}, "image"))
+	if !strings.Contains(formatted, "🚫 **安全フィルターにより画像生成がブロックされました**") {
+		t.Errorf("画像生成の安全フィルターのエラーメッセージが正しくフォーマットされていません: %s", formatted)
 	}
 
 	// 一般的なエラーのフォーマットテスト
@@ -90,3 +137,10 @@ type timeoutError struct {
 func (e *timeoutError) Error() string {
 	return e.message
 }
+
+// timeoutNetError は、タイムアウトしたネットワークエラーを模したテスト用のエラー型です
+type timeoutNetError struct{}
+
+func (e *timeoutNetError) Error() string   { return "i/o timeout" }
+func (e *timeoutNetError) Timeout() bool   { return true }
+func (e *timeoutNetError) Temporary() bool { return true }
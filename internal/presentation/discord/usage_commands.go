@@ -0,0 +1,260 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+	"geminibot/internal/infrastructure/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// usageCommand は、トークンの使用量の表示と、ギルドの利用上限を管理するスラッシュコマンドの定義を返します
+func usageCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "usage",
+		Description: "このサーバーのトークンの使用量と推定コスト、利用上限を管理します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "今日と今月の使用量と推定コストを表示します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "指定したユーザーの使用量のみを表示します",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set-budget",
+				Description: "このサーバーの1か月あたりの利用上限を設定します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "monthly-tokens",
+						Description: "1か月あたりのトークン数の上限（0で無制限）",
+						Required:    false,
+						MinValue:    func() *float64 { v := 0.0; return &v }(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "monthly-cost",
+						Description: "1か月あたりの推定コストの上限（米ドル、0で無制限）",
+						Required:    false,
+						MinValue:    func() *float64 { v := 0.0; return &v }(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "fallback-model",
+						Description: "上限に達した後に使用するモデル（省略すると上限に達した後は応答しません）",
+						Required:    false,
+						Choices: func() []*discordgo.ApplicationCommandOptionChoice {
+							models := config.GeminiTextModelChoices()
+							out := make([]*discordgo.ApplicationCommandOptionChoice, len(models))
+							for i, m := range models {
+								out[i] = &discordgo.ApplicationCommandOptionChoice{Name: m.DisplayName, Value: m.ModelID}
+							}
+							return out
+						}(),
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset-budget",
+				Description: "このサーバーの利用上限を削除します",
+			},
+		},
+	}
+}
+
+// handleUsageCommand は、/usageコマンドを処理します
+func (h *SlashCommandHandler) handleUsageCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		h.respondToInteraction(s, i, "❌ サブコマンドが指定されていません。", true)
+		return
+	}
+	if i.GuildID == "" {
+		h.respondToInteraction(s, i, "❌ このコマンドはサーバー内でのみ使用できます。", true)
+		return
+	}
+
+	subcommand := options[0]
+	switch subcommand.Name {
+	case "show":
+		h.handleUsageShow(s, i, subcommand.Options)
+	case "set-budget":
+		h.handleUsageSetBudget(s, i, usageBudgetFromOptions(subcommand.Options))
+	case "reset-budget":
+		h.handleUsageResetBudget(s, i)
+	default:
+		log.Printf("未知のサブコマンド: usage %s", subcommand.Name)
+	}
+}
+
+// handleUsageShow は、/usage showを処理します（実行したユーザーにのみ表示します）
+func (h *SlashCommandHandler) handleUsageShow(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var userID string
+	for _, option := range options {
+		if option.Name == "user" {
+			userID, _ = option.Value.(string)
+		}
+	}
+
+	report, err := h.usageService.GetUsageReport(context.Background(), i.GuildID, userID)
+	if err != nil {
+		log.Printf("使用量の取得に失敗: %v", err)
+		h.respondToInteraction(s, i, "❌ 使用量の取得に失敗しました。", true)
+		return
+	}
+
+	title := "📊 **このサーバーの使用量**"
+	if userID != "" {
+		title = fmt.Sprintf("📊 **<@%s> の使用量**", userID)
+	}
+	h.respondToInteraction(s, i, title+"\n\n"+formatUsageReport(report), true)
+}
+
+// handleUsageSetBudget は、/usage set-budgetを処理します
+func (h *SlashCommandHandler) handleUsageSetBudget(s *discordgo.Session, i *discordgo.InteractionCreate, budget domain.UsageBudget) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageAPIKeys) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	budget.GuildID = i.GuildID
+	budget.UpdatedBy = i.Member.User.Username
+	if err := h.usageService.SetUsageBudget(context.Background(), budget); err != nil {
+		log.Printf("利用上限の設定に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ 利用上限の設定に失敗しました: %v", err), true)
+		return
+	}
+
+	h.respondToInteraction(s, i, fmt.Sprintf("✅ このサーバーの利用上限を設定しました。\n%s\n設定者: %s", formatUsageBudget(budget), budget.UpdatedBy), false)
+}
+
+// handleUsageResetBudget は、/usage reset-budgetを処理します
+func (h *SlashCommandHandler) handleUsageResetBudget(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 権限チェック（管理者または権限を付与されたロールが必要）
+	if !h.hasPermission(s, i, domain.PermissionManageAPIKeys) {
+		h.respondToInteraction(s, i, "❌ このコマンドを実行する権限がありません。", true)
+		return
+	}
+
+	if err := h.usageService.ResetUsageBudget(context.Background(), i.GuildID); err != nil {
+		log.Printf("利用上限の削除に失敗: %v", err)
+		h.respondToInteraction(s, i, fmt.Sprintf("❌ 利用上限の削除に失敗しました: %v", err), true)
+		return
+	}
+
+	h.respondToInteraction(s, i, "✅ このサーバーの利用上限を削除しました。", false)
+}
+
+// usageBudgetFromOptions は、サブコマンドのオプションから利用上限を作成します
+func usageBudgetFromOptions(options []*discordgo.ApplicationCommandInteractionDataOption) domain.UsageBudget {
+	var budget domain.UsageBudget
+	for _, option := range options {
+		switch option.Name {
+		case "monthly-tokens":
+			budget.MonthlyTokenLimit = option.IntValue()
+		case "monthly-cost":
+			budget.MonthlyCostLimit = option.FloatValue()
+		case "fallback-model":
+			budget.FallbackModel = option.StringValue()
+		}
+	}
+	return budget
+}
+
+// formatUsageReport は、今日と今月の使用量、今月のモデル別の内訳、利用上限を表示用に整形します
+func formatUsageReport(report application.UsageReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**今日**: %s\n", formatUsageSummary(report.Daily))
+	fmt.Fprintf(&b, "**今月**: %s\n", formatUsageSummary(report.Monthly))
+
+	if len(report.Monthly.Models) > 0 {
+		b.WriteString("\n**今月のモデル別の内訳**\n")
+		for _, model := range report.Monthly.Models {
+			cost := "料金不明"
+			if pricing, ok := config.GeminiModelPricing(model.Model); ok {
+				cost = formatCost(pricing.Cost(model.Usage))
+			}
+			fmt.Fprintf(&b, "・%s: %d件 / %sトークン / 推定 %s\n", model.Model, model.Requests, formatTokenCount(model.Usage.TotalTokens()), cost)
+		}
+	}
+
+	b.WriteString("\n" + formatUsageBudget(report.Budget))
+	if report.Monthly.UnpricedModel {
+		b.WriteString("\n※ 料金が不明なモデルの使用量は推定コストに含まれていません。")
+	}
+	return b.String()
+}
+
+// formatUsageSummary は、期間内の使用量を1行で表示用に整形します
+func formatUsageSummary(summary application.UsageSummary) string {
+	return fmt.Sprintf("%d件 / %sトークン（入力 %s・出力 %s・思考 %s）/ 推定 %s",
+		summary.Requests,
+		formatTokenCount(summary.Usage.TotalTokens()),
+		formatTokenCount(summary.Usage.PromptTokens),
+		formatTokenCount(summary.Usage.OutputTokens),
+		formatTokenCount(summary.Usage.ThinkingTokens),
+		formatCost(summary.EstimatedCost))
+}
+
+// formatUsageBudget は、利用上限を表示用に整形します
+func formatUsageBudget(budget domain.UsageBudget) string {
+	if budget.IsEmpty() {
+		return "💰 **利用上限**: 未設定"
+	}
+
+	var limits []string
+	if budget.MonthlyTokenLimit > 0 {
+		limits = append(limits, formatTokenCount(budget.MonthlyTokenLimit)+"トークン")
+	}
+	if budget.MonthlyCostLimit > 0 {
+		limits = append(limits, "推定 "+formatCost(budget.MonthlyCostLimit))
+	}
+
+	after := "応答を停止"
+	if budget.FallbackModel != "" {
+		after = budget.FallbackModel + " で応答（画像生成は停止）"
+	}
+	return fmt.Sprintf("💰 **利用上限**: 1か月あたり %s（上限に達した後は%s）", strings.Join(limits, "・"), after)
+}
+
+// formatTokenCount は、トークン数を3桁区切りで整形します
+func formatTokenCount(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+// formatCost は、推定コスト（米ドル）を表示用に整形します（1ドル未満は小数点以下4桁まで表示します）
+func formatCost(cost float64) string {
+	if cost < 1 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
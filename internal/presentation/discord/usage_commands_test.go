@@ -0,0 +1,62 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"geminibot/internal/application"
+	"geminibot/internal/domain"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestUsageBudgetFromOptions(t *testing.T) {
+	budget := usageBudgetFromOptions([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "monthly-tokens", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(5_000_000)},
+		{Name: "monthly-cost", Type: discordgo.ApplicationCommandOptionNumber, Value: 12.5},
+		{Name: "fallback-model", Type: discordgo.ApplicationCommandOptionString, Value: "gemini-2.5-flash-lite"},
+	})
+	want := domain.UsageBudget{MonthlyTokenLimit: 5_000_000, MonthlyCostLimit: 12.5, FallbackModel: "gemini-2.5-flash-lite"}
+	if budget != want {
+		t.Errorf("usageBudgetFromOptions() = %+v, 期待値: %+v", budget, want)
+	}
+}
+
+func TestFormatTokenCount(t *testing.T) {
+	tests := map[int64]string{
+		0:          "0",
+		999:        "999",
+		1000:       "1,000",
+		1234567:    "1,234,567",
+		-12345:     "-12,345",
+		100000:     "100,000",
+		1000000000: "1,000,000,000",
+	}
+	for n, want := range tests {
+		if got := formatTokenCount(n); got != want {
+			t.Errorf("formatTokenCount(%d) = %q, 期待値: %q", n, got, want)
+		}
+	}
+}
+
+func TestFormatUsageReport(t *testing.T) {
+	message := formatUsageReport(application.UsageReport{
+		Monthly: application.UsageSummary{
+			Requests:      3,
+			Usage:         domain.TokenUsage{PromptTokens: 12000, OutputTokens: 3000},
+			EstimatedCost: 0.0111,
+			Models:        []domain.ModelUsage{{Model: "gemini-2.5-flash", Requests: 3, Usage: domain.TokenUsage{PromptTokens: 12000, OutputTokens: 3000}}},
+		},
+		Budget: domain.UsageBudget{MonthlyCostLimit: 20, FallbackModel: "gemini-2.5-flash-lite"},
+	})
+
+	for _, want := range []string{
+		"**今月**: 3件 / 15,000トークン（入力 12,000・出力 3,000・思考 0）/ 推定 $0.0111",
+		"・gemini-2.5-flash: 3件 / 15,000トークン / 推定 $0.0111",
+		"1か月あたり 推定 $20.00（上限に達した後はgemini-2.5-flash-lite で応答（画像生成は停止））",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("使用量の表示に %q が含まれていません:\n%s", want, message)
+		}
+	}
+}